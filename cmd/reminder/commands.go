@@ -0,0 +1,688 @@
+package reminder
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goyalmunish/reminder/internal/model"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+/*
+A command represents a non-interactive (sub)command of the app.
+
+Commands are meant for scripting (shell scripts, cron jobs, git hooks, etc.),
+and so they never prompt the user for any input (except for the full-screen "tui" command).
+*/
+type command struct {
+	usage   string
+	mutates bool // whether the command updates the data file (and so requires the lock on it)
+	// lockFree tells if the command must never take the lock (not even for persisting the migrated data),
+	// such as a long-running command which runs alongside the interactive session
+	lockFree bool
+	run      func(rd *model.ReminderData, args []string, out io.Writer) error
+}
+
+// commands is the registry of all non-interactive commands, keyed by their name.
+var commands = map[string]command{
+	"note add": {
+		mutates: true,
+		usage:   "note add --text TEXT [--tag SLUG]... [--due \"DATE [HH:MM [ZONE]]\"] [--repeat RULE] [--main] [--json]",
+		run:     noteAddCommand,
+	},
+	"note list": {
+		usage: "note list [--status pending|suspended|done|all] [--tag SLUG] [--query NAME|QUERY] [--archived] [--json]",
+		run:   noteListCommand,
+	},
+	"note done": {
+		mutates: true,
+		usage:   "note done [--json] ID",
+		run:     noteDoneCommand,
+	},
+	"note trash": {
+		mutates: true,
+		usage:   "note trash [--json] ID",
+		run:     noteTrashCommand,
+	},
+	"note restore": {
+		mutates: true,
+		usage:   "note restore [--json] ID",
+		run:     noteRestoreCommand,
+	},
+	"note purge": {
+		mutates: true,
+		usage:   "note purge ID",
+		run:     notePurgeCommand,
+	},
+	"trash list": {
+		usage: "trash list [--json]",
+		run:   trashListCommand,
+	},
+	"trash purge": {
+		mutates: true,
+		usage:   "trash purge [--older-than DAYS | --all] [--json]",
+		run:     trashPurgeCommand,
+	},
+	"archive": {
+		mutates: true,
+		usage:   "archive [--older-than DAYS] [--json]",
+		run:     archiveCommand,
+	},
+	"note comment": {
+		mutates: true,
+		usage:   "note comment --text TEXT [--json] ID",
+		run:     noteCommentCommand,
+	},
+	"tag add": {
+		mutates: true,
+		usage:   "tag add --slug SLUG --group GROUP [--json]",
+		run:     tagAddCommand,
+	},
+	"tag list": {
+		usage: "tag list [--json]",
+		run:   tagListCommand,
+	},
+	"tag rename": {
+		mutates: true,
+		usage:   "tag rename [--json] SLUG NEW_SLUG",
+		run:     tagRenameCommand,
+	},
+	"tag regroup": {
+		mutates: true,
+		usage:   "tag regroup [--json] SLUG GROUP",
+		run:     tagRegroupCommand,
+	},
+	"tag merge": {
+		mutates: true,
+		usage:   "tag merge [--json] SLUG INTO_SLUG",
+		run:     tagMergeCommand,
+	},
+	"tag delete": {
+		mutates: true,
+		usage:   "tag delete [--reassign SLUG] [--json] SLUG",
+		run:     tagDeleteCommand,
+	},
+	"query save": {
+		mutates: true,
+		usage:   "query save [--json] NAME QUERY",
+		run:     querySaveCommand,
+	},
+	"query list": {
+		usage: "query list [--json]",
+		run:   queryListCommand,
+	},
+	"query delete": {
+		mutates: true,
+		usage:   "query delete NAME",
+		run:     queryDeleteCommand,
+	},
+	"daemon": {
+		lockFree: true,
+		usage:    "daemon [--interval DURATION] [--notifier desktop|bell|command]... [--command CMD] [--state-file PATH] [--once]",
+		run:      daemonCommand,
+	},
+	"digest": {
+		usage: "digest [--view approaching|look-ahead|main]... [--to EMAIL] [--smtp-host HOST] [--smtp-port PORT] [--dry-run]",
+		run:   digestCommand,
+	},
+	"export ics": {
+		usage: "export ics [--out PATH] [--todo] [--serve ADDRESS]",
+		run:   exportICSCommand,
+	},
+	"import ics": {
+		mutates: true,
+		usage:   "import ics [--json] FILE",
+		run:     importICSCommand,
+	},
+	"migrate": {
+		usage: "migrate --to sqlite|json [--out PATH] [--json]",
+		run:   migrateCommand,
+	},
+	"doctor": {
+		mutates: true,
+		usage:   "doctor [--fix] [--json]",
+		run:     doctorCommand,
+	},
+	"tui": {
+		mutates: true,
+		usage:   "tui",
+		run:     tuiCommand,
+	},
+	"serve": {
+		lockFree: true,
+		usage:    "serve [--address ADDRESS] [--token TOKEN]",
+		run:      serveCommand,
+	},
+	"stats": {
+		usage: "stats [--archived] [--json]",
+		run:   statsCommand,
+	},
+}
+
+// stringsFlag is a flag.Value which can be passed multiple times.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string     { return strings.Join(*s, ",") }
+func (s *stringsFlag) Set(v string) error { *s = append(*s, v); return nil }
+
+// noteView is the external (JSON) representation of a note.
+type noteView struct {
+	Id         string   `json:"id"`
+	Text       string   `json:"text"`
+	Summary    string   `json:"summary,omitempty"`
+	Status     string   `json:"status"`
+	Tags       []string `json:"tags"`
+	IsMain     bool     `json:"is_main"`
+	CompleteBy string   `json:"complete_by,omitempty"`
+	TimeZone   string   `json:"time_zone,omitempty"`
+	Repeat     string   `json:"repeat,omitempty"`
+	Comments   []string `json:"comments"`
+	CreatedAt  string   `json:"created_at,omitempty"`
+	UpdatedAt  string   `json:"updated_at,omitempty"`
+}
+
+// tagView is the external (JSON) representation of a tag.
+type tagView struct {
+	Id    int    `json:"id"`
+	Slug  string `json:"slug"`
+	Group string `json:"group"`
+}
+
+// CommandUsage returns usage text of all the non-interactive commands.
+func CommandUsage() string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var lines []string
+	lines = append(lines, "Usage:")
+	lines = append(lines, "  reminder [--data-file PATH]                  start the interactive session")
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("  reminder [--data-file PATH] %s", commands[name].usage))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// lookupCommand finds the command (and its remaining arguments) for given arguments.
+func lookupCommand(args []string) (command, []string, error) {
+	if len(args) >= 2 {
+		if cmd, ok := commands[args[0]+" "+args[1]]; ok {
+			return cmd, args[2:], nil
+		}
+	}
+	if len(args) >= 1 {
+		if cmd, ok := commands[args[0]]; ok {
+			return cmd, args[1:], nil
+		}
+	}
+	return command{}, nil, fmt.Errorf("Unknown command %q\n%s", strings.Join(args, " "), CommandUsage())
+}
+
+// RunCommand runs the non-interactive command represented by args
+// against the data file, and writes its output to out.
+func RunCommand(dataFile string, args []string, out io.Writer) error {
+	cmd, cmdArgs, err := lookupCommand(args)
+	if err != nil {
+		return err
+	}
+	// make sure DataFile exists (without asking anything to the user)
+	if err := model.MakeSureFileExists(dataFile, false); err != nil {
+		return err
+	}
+	// the commands which update the data file must hold the lock on it
+	if cmd.mutates {
+		lock, err := model.LockDataFile(dataFile)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			utils.LogError(lock.Release())
+		}()
+	}
+	reminderData, err := model.ReadDataFile(dataFile, true)
+	if err != nil {
+		return err
+	}
+	if !cmd.mutates && !cmd.lockFree && reminderData.Migrated() {
+		reminderData = persistMigration(dataFile, reminderData)
+	}
+	// there is no one to ask about conflicting changes
+	reminderData.SetConflictResolver(model.RejectConflicts)
+	reminderData.SetIncludeArchived(appInfoOptions().IncludeArchived)
+	rules, err := tagGroupRules()
+	if err != nil {
+		return err
+	}
+	reminderData.SetTagGroupRules(rules)
+	return cmd.run(reminderData, cmdArgs, out)
+}
+
+// persistMigration persists the data migrated while being read (such as back-filled ids of notes),
+// so that the ids printed by a read-only command remain valid for later commands.
+// If the data file is locked by an interactive session, that session persists the migration instead,
+// and the passed (unpersisted) data is returned as it is.
+func persistMigration(dataFile string, rd *model.ReminderData) *model.ReminderData {
+	lock, err := model.LockDataFile(dataFile)
+	if err != nil {
+		utils.LogError(err)
+		return rd
+	}
+	defer func() {
+		utils.LogError(lock.Release())
+	}()
+	// re-read the data, as it may have changed before the lock was acquired
+	reminderData, err := model.ReadDataFile(dataFile, true)
+	if err != nil {
+		utils.LogError(err)
+		return rd
+	}
+	if err := reminderData.UpdateDataFile("Persisting the migrated data!"); err != nil {
+		utils.LogError(err)
+		return rd
+	}
+	return reminderData
+}
+
+// newFlagSet returns a flag set which reports errors instead of exiting.
+func newFlagSet(name string) (*flag.FlagSet, *bool) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	asJSON := fs.Bool("json", false, "print output as JSON")
+	return fs, asJSON
+}
+
+// parseFlags parses flags which can be interspersed with positional arguments,
+// and returns the positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// findNote finds the note with given id, or with a unique prefix of an id.
+func findNote(rd *model.ReminderData, id string) (*model.Note, error) {
+	return findNoteIn(rd.Notes, id)
+}
+
+// findNoteIn finds the note with given id (or with a unique prefix of an id) among the notes.
+func findNoteIn(notes model.Notes, id string) (*model.Note, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil, errors.New("Note id is empty")
+	}
+	if note := notes.WithId(id); note != nil {
+		return note, nil
+	}
+	var matches model.Notes
+	for _, note := range notes {
+		if strings.HasPrefix(note.Id, id) {
+			matches = append(matches, note)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("No note found with id %q", id)
+	case 1:
+		return matches[0], nil
+	default:
+		return nil, fmt.Errorf("The id %q matches %d notes; use a longer id", id, len(matches))
+	}
+}
+
+// findTag returns the tag with given slug.
+func findTag(rd *model.ReminderData, slug string) (*model.Tag, error) {
+	tag := rd.TagFromSlug(strings.ToLower(strings.TrimSpace(slug)))
+	if tag == nil {
+		return nil, fmt.Errorf("No tag found with slug %q", slug)
+	}
+	return tag, nil
+}
+
+// tagIdsFromSlugs converts tag slugs to tag ids.
+func tagIdsFromSlugs(rd *model.ReminderData, slugs []string) ([]int, error) {
+	tagIDs := make([]int, 0, len(slugs))
+	for _, slug := range slugs {
+		tag, err := findTag(rd, slug)
+		if err != nil {
+			return nil, err
+		}
+		if !utils.IsMemberOfSlice(tag.Id, tagIDs) {
+			tagIDs = append(tagIDs, tag.Id)
+		}
+	}
+	return tagIDs, nil
+}
+
+// newNoteView returns external representation of the note.
+func newNoteView(rd *model.ReminderData, note *model.Note) noteView {
+	view := noteView{
+		Id:       note.Id,
+		Text:     note.Text,
+		Summary:  note.Summary,
+		Status:   string(note.Status),
+		Tags:     rd.TagsFromIds(note.TagIds),
+		IsMain:   note.IsMain,
+		Comments: note.Comments.Strings(),
+	}
+	if note.CompleteBy > 0 {
+		view.CompleteBy = utils.TimeToStr(utils.UnixTimestampToTime(note.CompleteBy))
+		if note.HasDueTime {
+			view.CompleteBy = utils.TimeToStr(time.Unix(note.CompleteBy, 0).In(note.DueLocation()))
+			view.TimeZone = note.TimeZone
+		}
+	}
+	if note.Recurrence != nil {
+		view.Repeat = note.Recurrence.String()
+	}
+	if note.CreatedAt > 0 {
+		view.CreatedAt = utils.TimeToStr(utils.UnixTimestampToTime(note.CreatedAt))
+	}
+	if note.UpdatedAt > 0 {
+		view.UpdatedAt = utils.TimeToStr(utils.UnixTimestampToTime(note.UpdatedAt))
+	}
+	return view
+}
+
+// printJSON prints value as indented JSON.
+func printJSON(out io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// printNotes prints given notes as plain text (one line per note) or as JSON.
+func printNotes(rd *model.ReminderData, notes model.Notes, asJSON bool, out io.Writer) error {
+	if asJSON {
+		views := make([]noteView, 0, len(notes))
+		for _, note := range notes {
+			views = append(views, newNoteView(rd, note))
+		}
+		return printJSON(out, views)
+	}
+	repeatAnnuallyTagId, repeatMonthlyTagId := rd.RepeatTagIds()
+	texts := notes.ExternalTexts(0, repeatAnnuallyTagId, repeatMonthlyTagId)
+	for index, note := range notes {
+		fmt.Fprintf(out, "%s  %s\n", note.Id, texts[index])
+	}
+	return nil
+}
+
+// noteAddCommand adds a new note.
+func noteAddCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	var tagSlugs stringsFlag
+	fs, asJSON := newFlagSet("note add")
+	text := fs.String("text", "", "text of the note")
+	due := fs.String("due", "", "due date of the note (such as DD-MM-YYYY, DD-MM, YYYY-MM-DD, tomorrow, next friday, in 3 weeks, or +10d), optionally followed by due time (HH:MM) and time zone")
+	repeat := fs.String("repeat", "", "recurrence of the note, such as daily, weekly/2:mo,we, or monthly:last (requires --due)")
+	isMain := fs.Bool("main", false, "flag the note as main")
+	fs.Var(&tagSlugs, "tag", "slug of a tag of the note (can be repeated)")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*text) == "" {
+		return errors.New("Note's text is empty; pass it with --text")
+	}
+	if (*repeat != "") && (*due == "") {
+		return errors.New("Note's due date is required for recurrence; pass it with --due")
+	}
+	note, err := addNote(rd, *text, tagSlugs, *due, *repeat, *isMain)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(out, newNoteView(rd, note))
+	}
+	fmt.Fprintln(out, note.Id)
+	return nil
+}
+
+// addNote adds a new note with given text, tags, and (optional) due date, recurrence and main flag.
+// All the input is validated before the note is added, and the complete note is saved at once.
+func addNote(rd *model.ReminderData, text string, tagSlugs []string, due string, repeat string, isMain bool) (*model.Note, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, errors.New("Note's text is empty")
+	}
+	if due != "" {
+		if err := utils.ValidateDateString()(due); err != nil {
+			return nil, err
+		}
+	}
+	if repeat != "" {
+		if due == "" {
+			return nil, errors.New("Note's due date is required for recurrence")
+		}
+		if _, err := model.ParseRecurrence(repeat); err != nil {
+			return nil, err
+		}
+	}
+	tagIDs, err := tagIdsFromSlugs(rd, tagSlugs)
+	if err != nil {
+		return nil, err
+	}
+	note, err := model.NewNote(tagIDs, text)
+	if err != nil {
+		return nil, err
+	}
+	if due != "" {
+		if err := note.UpdateCompleteBy(due); err != nil {
+			return nil, err
+		}
+	}
+	if repeat != "" {
+		if err := note.UpdateRecurrence(repeat); err != nil {
+			return nil, err
+		}
+	}
+	note.IsMain = isMain
+	if err := rd.AddNote(note); err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+// noteListCommand lists notes with given status (and tag, or tag query).
+func noteListCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("note list")
+	status := fs.String("status", string(model.NoteStatus_Pending), "status of the notes; use \"all\" for all notes")
+	tagSlug := fs.String("tag", "", "slug of the tag (or any of its sub-tags) of the notes")
+	tagQuery := fs.String("query", "", "name of a saved tag query, or a query (such as \"current AND NOT waiting\")")
+	archived := fs.Bool("archived", false, "include the archived notes")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	query := model.NoteQuery{Status: model.NoteStatus(*status)}
+	if *status == "all" {
+		query.Status = ""
+	}
+	if *tagSlug != "" && *tagQuery != "" {
+		return errors.New("Pass either a tag or a tag query")
+	}
+	if *tagSlug != "" {
+		tag, err := findTag(rd, *tagSlug)
+		if err != nil {
+			return err
+		}
+		query.TagExpr = model.TagSlugExpr(tag.Slug, rd.Tags)
+	}
+	if *tagQuery != "" {
+		expr, err := tagExprFromText(rd, *tagQuery)
+		if err != nil {
+			return err
+		}
+		query.TagExpr = expr
+	}
+	notes, err := rd.QueryNotes(query)
+	if err != nil {
+		return err
+	}
+	if *archived {
+		rd.SetIncludeArchived(true)
+	}
+	notes = append(notes, rd.IncludedArchivedNotes().Query(query)...)
+	sort.Sort(notes)
+	return printNotes(rd, notes, *asJSON, out)
+}
+
+// noteDoneCommand marks the note as done.
+func noteDoneCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("note done")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("Pass exactly one note id")
+	}
+	note, err := findNote(rd, positional[0])
+	if err != nil {
+		return err
+	}
+	if err := rd.UpdateNoteStatus(note, model.NoteStatus_Done); err != nil {
+		return err
+	}
+	return printNotes(rd, model.Notes{note}, *asJSON, out)
+}
+
+// noteCommentCommand adds a comment to the note.
+func noteCommentCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("note comment")
+	text := fs.String("text", "", "text of the comment")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("Pass exactly one note id")
+	}
+	note, err := findNote(rd, positional[0])
+	if err != nil {
+		return err
+	}
+	if err := rd.AddNoteComment(note, *text); err != nil {
+		return err
+	}
+	return printNotes(rd, model.Notes{note}, *asJSON, out)
+}
+
+// tagAddCommand adds a new tag.
+func tagAddCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("tag add")
+	slug := fs.String("slug", "", "slug of the tag")
+	group := fs.String("group", "", "group of the tag")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if strings.TrimSpace(*slug) == "" || strings.TrimSpace(*group) == "" {
+		return errors.New("Both --slug and --group are required")
+	}
+	tagID, err := rd.NewTagRegistration(*slug, *group)
+	if err != nil {
+		return err
+	}
+	return printTag(rd.Tags.FromIds([]int{tagID})[0], *asJSON, out)
+}
+
+// tagListCommand lists all the tags.
+func tagListCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("tag list")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	rd.SortedTagSlugs()
+	if *asJSON {
+		views := make([]tagView, 0, len(rd.Tags))
+		for _, tag := range rd.Tags {
+			views = append(views, tagView{Id: tag.Id, Slug: tag.Slug, Group: tag.Group})
+		}
+		return printJSON(out, views)
+	}
+	for _, tag := range rd.Tags {
+		fmt.Fprintln(out, tag)
+	}
+	return nil
+}
+
+// statsCommand prints stats of the data file.
+func statsCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("stats")
+	archived := fs.Bool("archived", false, "include the archived notes")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *archived {
+		rd.SetIncludeArchived(true)
+	}
+	if *asJSON {
+		notes := rd.AllNotes()
+		return printJSON(out, map[string]interface{}{
+			"data_file":       rd.DataFile,
+			"tags":            len(rd.Tags),
+			"notes":           len(notes),
+			"pending_notes":   len(notes.WithStatus(model.NoteStatus_Pending)),
+			"suspended_notes": len(notes.WithStatus(model.NoteStatus_Suspended)),
+			"done_notes":      len(notes.WithStatus(model.NoteStatus_Done)),
+			"archived_notes":  len(rd.IncludedArchivedNotes()),
+			"trashed_notes":   len(rd.Trash),
+		})
+	}
+	stats, err := rd.Stats()
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(out, strings.TrimPrefix(stats, "\n"))
+	return nil
+}
+
+// migrateCommand copies the data to a data file of another storage backend.
+func migrateCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("migrate")
+	to := fs.String("to", "", "storage backend to migrate to (sqlite or json)")
+	outFile := fs.String("out", "", "path of the new data file (defaults to the current one with extension of the backend)")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	var ext string
+	switch *to {
+	case "sqlite":
+		ext = model.SQLiteExtensions[0]
+	case "json":
+		ext = ".json"
+	default:
+		return fmt.Errorf("Unknown storage backend %q; pass either --to sqlite or --to json", *to)
+	}
+	if model.IsSQLiteDataFile(rd.DataFile) == (*to == "sqlite") {
+		return fmt.Errorf("The data file %q is already stored as %s", rd.DataFile, *to)
+	}
+	dataFile := *outFile
+	if dataFile == "" {
+		dataFile = strings.TrimSuffix(rd.DataFile, path.Ext(rd.DataFile)) + ext
+	}
+	if (*to == "sqlite") != model.IsSQLiteDataFile(dataFile) {
+		return fmt.Errorf("The extension of %q doesn't match the storage backend %s", dataFile, *to)
+	}
+	if err := rd.MigrateTo(dataFile); err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(out, map[string]interface{}{"data_file": dataFile, "notes": len(rd.Notes), "tags": len(rd.Tags)})
+	}
+	fmt.Fprintf(out, "Migrated %d notes and %d tags to %q.\n", len(rd.Notes), len(rd.Tags), dataFile)
+	fmt.Fprintln(out, "Set it as `data_file` (under `appinfo`) in the config file, or pass it with --data-file, to use it.")
+	return nil
+}
@@ -0,0 +1,650 @@
+package reminder_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goyalmunish/reminder/cmd/reminder"
+	"github.com/goyalmunish/reminder/internal/model"
+	"github.com/goyalmunish/reminder/pkg/ical"
+	"github.com/goyalmunish/reminder/pkg/mailer/mailertest"
+	"github.com/goyalmunish/reminder/pkg/notify"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+// runCommand runs the command, and returns its output.
+func runCommand(t *testing.T, dataFilePath string, args ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	err := reminder.RunCommand(dataFilePath, args, &out)
+	if err != nil {
+		t.Fatalf("Command %q failed with error: %v", strings.Join(args, " "), err)
+	}
+	return out.String()
+}
+
+// removeNoteIds removes ids of the notes in the (JSON) data file, as they were persisted before notes had ids.
+func removeNoteIds(t *testing.T, dataFilePath string) {
+	t.Helper()
+	byteValue, _ := os.ReadFile(dataFilePath)
+	var data map[string]interface{}
+	utils.AssertEqual(t, json.Unmarshal(byteValue, &data), nil)
+	for _, note := range data["notes"].([]interface{}) {
+		delete(note.(map[string]interface{}), "id")
+	}
+	byteValue, _ = json.Marshal(data)
+	utils.AssertEqual(t, os.WriteFile(dataFilePath, byteValue, 0644), nil)
+}
+
+// notesWithoutIds returns the number of the notes without ids in the (JSON) data file, as it is persisted.
+func notesWithoutIds(t *testing.T, dataFilePath string) int {
+	t.Helper()
+	byteValue, _ := os.ReadFile(dataFilePath)
+	var data model.ReminderData
+	utils.AssertEqual(t, json.Unmarshal(byteValue, &data), nil)
+	count := 0
+	for _, note := range data.Notes {
+		if note.Id == "" {
+			count++
+		}
+	}
+	return count
+}
+
+func TestRunCommandNotes(t *testing.T) {
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll("temp_test_dir")
+	// same commands work for each of the storage backends
+	for _, dataFilePath := range []string{"temp_test_dir/mydata.json", "temp_test_dir/mydata.db"} {
+		testRunCommandNotes(t, dataFilePath)
+	}
+}
+
+func testRunCommandNotes(t *testing.T, dataFilePath string) {
+	// add notes (the data file gets created with basic tags)
+	id1 := strings.TrimSpace(runCommand(t, dataFilePath, "note", "add", "--text", "pay bills", "--tag", "current", "--due", "12-05-2030"))
+	utils.AssertEqual(t, id1 != "", true)
+	var view map[string]interface{}
+	output := runCommand(t, dataFilePath, "note", "add", "--text", "call mom", "--tag", "priority-urgent", "--tag", "current", "--main", "--due", "01-01-2030 18:30 Asia/Kolkata", "--repeat", "weekly:su", "--json")
+	utils.AssertEqual(t, json.Unmarshal([]byte(output), &view), nil)
+	utils.AssertEqual(t, view["text"], "call mom")
+	utils.AssertEqual(t, view["repeat"], "weekly:su")
+	utils.AssertEqual(t, view["complete_by"], "2030-01-01T18:30:00+05:30")
+	utils.AssertEqual(t, view["time_zone"], "Asia/Kolkata")
+	utils.AssertEqual(t, view["tags"], []interface{}{"priority-urgent", "current"})
+	utils.AssertEqual(t, view["is_main"], true)
+	// the note is saved at once, without any changes recorded in its history
+	addedData, _ := model.ReadDataFile(dataFilePath, true)
+	utils.AssertEqual(t, len(addedData.FindNoteById(view["id"].(string)).History), 0)
+	// list notes
+	output = runCommand(t, dataFilePath, "note", "list", "--tag", "current")
+	utils.AssertEqual(t, strings.Count(output, "\n"), 2)
+	utils.AssertEqual(t, strings.Contains(output, id1), true)
+	output = runCommand(t, dataFilePath, "note", "list", "--tag", "priority-urgent")
+	utils.AssertEqual(t, strings.Count(output, "\n"), 1)
+	utils.AssertEqual(t, strings.Contains(output, id1), false)
+	// comment on and mark a note as done (using prefix of its id)
+	_ = runCommand(t, dataFilePath, "note", "comment", id1[:8], "--text", "paid the electricity bill")
+	_ = runCommand(t, dataFilePath, "note", "done", id1[:8])
+	reminderData, _ := model.ReadDataFile(dataFilePath, true)
+	note := reminderData.FindNoteById(id1)
+	utils.AssertEqual(t, note.Status, model.NoteStatus_Done)
+	utils.AssertEqual(t, note.Comments[0].Text, "paid the electricity bill")
+	output = runCommand(t, dataFilePath, "note", "list", "--status", "done", "--json")
+	var views []map[string]interface{}
+	utils.AssertEqual(t, json.Unmarshal([]byte(output), &views), nil)
+	utils.AssertEqual(t, len(views), 1)
+	utils.AssertEqual(t, views[0]["id"], id1)
+	// stats
+	var stats map[string]interface{}
+	output = runCommand(t, dataFilePath, "stats", "--json")
+	utils.AssertEqual(t, json.Unmarshal([]byte(output), &stats), nil)
+	utils.AssertEqual(t, stats["notes"], 2)
+	utils.AssertEqual(t, stats["pending_notes"], 1)
+	utils.AssertEqual(t, stats["done_notes"], 1)
+}
+
+func TestRunCommandTrash(t *testing.T) {
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll("temp_test_dir")
+	// same commands work for each of the storage backends (with different names, so that archive files don't clash)
+	for _, dataFilePath := range []string{"temp_test_dir/mydata.json", "temp_test_dir/otherdata.db"} {
+		testRunCommandTrash(t, dataFilePath)
+	}
+}
+
+func testRunCommandTrash(t *testing.T, dataFilePath string) {
+	id1 := strings.TrimSpace(runCommand(t, dataFilePath, "note", "add", "--text", "pay bills"))
+	id2 := strings.TrimSpace(runCommand(t, dataFilePath, "note", "add", "--text", "call mom"))
+	// move a note to the trash, and restore it
+	_ = runCommand(t, dataFilePath, "note", "trash", id1[:8])
+	output := runCommand(t, dataFilePath, "note", "list")
+	utils.AssertEqual(t, strings.Contains(output, id1), false)
+	output = runCommand(t, dataFilePath, "trash", "list")
+	utils.AssertEqual(t, strings.Contains(output, id1), true)
+	var out bytes.Buffer
+	err := reminder.RunCommand(dataFilePath, []string{"note", "done", id1}, &out)
+	utils.AssertEqual(t, err != nil, true)
+	_ = runCommand(t, dataFilePath, "note", "restore", id1[:8])
+	output = runCommand(t, dataFilePath, "note", "list")
+	utils.AssertEqual(t, strings.Contains(output, id1), true)
+	// only a note in the trash can be purged
+	err = reminder.RunCommand(dataFilePath, []string{"note", "purge", id1}, &out)
+	utils.AssertEqual(t, strings.HasSuffix(err.Error(), "only a note in the trash can be purged"), true)
+	_ = runCommand(t, dataFilePath, "note", "trash", id1)
+	output = runCommand(t, dataFilePath, "trash", "purge")
+	utils.AssertEqual(t, output, "Purged 0 notes from the trash (1 remaining).\n")
+	output = runCommand(t, dataFilePath, "trash", "purge", "--all")
+	utils.AssertEqual(t, output, "Purged 1 notes from the trash (0 remaining).\n")
+	reminderData, _ := model.ReadDataFile(dataFilePath, true)
+	utils.AssertEqual(t, len(reminderData.Trash), 0)
+	utils.AssertEqual(t, reminderData.FindNoteById(id1) == nil, true)
+	// archive the done notes
+	_ = runCommand(t, dataFilePath, "note", "done", id2)
+	output = runCommand(t, dataFilePath, "archive")
+	utils.AssertEqual(t, output, "Archived 0 notes.\n")
+	output = runCommand(t, dataFilePath, "archive", "--older-than", "0")
+	utils.AssertEqual(t, output, "Archived 1 notes.\n")
+	output = runCommand(t, dataFilePath, "note", "list", "--status", "done")
+	utils.AssertEqual(t, strings.Contains(output, id2), false)
+	output = runCommand(t, dataFilePath, "note", "list", "--status", "done", "--archived")
+	utils.AssertEqual(t, strings.Contains(output, id2), true)
+	var stats map[string]interface{}
+	output = runCommand(t, dataFilePath, "stats", "--archived", "--json")
+	utils.AssertEqual(t, json.Unmarshal([]byte(output), &stats), nil)
+	utils.AssertEqual(t, stats["notes"], 1)
+	utils.AssertEqual(t, stats["archived_notes"], 1)
+	utils.AssertEqual(t, stats["trashed_notes"], 0)
+}
+
+func TestRunCommandTags(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	// add tags (the data file gets created with 7 basic tags)
+	utils.AssertEqual(t, runCommand(t, dataFilePath, "tag", "add", "--slug", "Work", "--group", "area"), "area#work#7\n")
+	utils.AssertEqual(t, runCommand(t, dataFilePath, "tag", "add", "--slug", "home", "--group", "area"), "area#home#8\n")
+	// list tags
+	output := runCommand(t, dataFilePath, "tag", "list")
+	utils.AssertEqual(t, strings.Count(output, "\n"), 9)
+	utils.AssertEqual(t, strings.HasPrefix(output, "#current#0\narea#home#8\n"), true)
+	// adding a tag with existing slug fails
+	var out bytes.Buffer
+	err := reminder.RunCommand(dataFilePath, []string{"tag", "add", "--slug", "home", "--group", "area"}, &out)
+	utils.AssertEqual(t, err != nil, true)
+	// adding a note with unknown tag fails
+	err = reminder.RunCommand(dataFilePath, []string{"note", "add", "--text", "t", "--tag", "nope"}, &out)
+	utils.AssertEqual(t, err != nil, true)
+	// updating the data file fails while it is locked by another session
+	lock, err := model.LockDataFile(dataFilePath)
+	utils.AssertEqual(t, err, nil)
+	err = reminder.RunCommand(dataFilePath, []string{"tag", "add", "--slug", "travel", "--group", "area"}, &out)
+	utils.AssertEqual(t, errors.Is(err, model.ErrorDataFileLocked), true)
+	// but, reading it is still allowed
+	utils.AssertEqual(t, reminder.RunCommand(dataFilePath, []string{"tag", "list"}, &out), nil)
+	utils.AssertEqual(t, lock.Release(), nil)
+	// unknown commands fail
+	err = reminder.RunCommand(dataFilePath, []string{"note", "frobnicate"}, &out)
+	utils.AssertEqual(t, strings.Contains(err.Error(), "Unknown command"), true)
+}
+
+func TestRunCommandEditTags(t *testing.T) {
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll("temp_test_dir")
+	// same commands work for each of the storage backends
+	for _, dataFilePath := range []string{"temp_test_dir/mydata.json", "temp_test_dir/mydata.db"} {
+		testRunCommandEditTags(t, dataFilePath)
+	}
+}
+
+func testRunCommandEditTags(t *testing.T, dataFilePath string) {
+	_ = runCommand(t, dataFilePath, "tag", "add", "--slug", "work", "--group", "area")
+	_ = runCommand(t, dataFilePath, "tag", "add", "--slug", "office", "--group", "place")
+	id1 := strings.TrimSpace(runCommand(t, dataFilePath, "note", "add", "--text", "pay bills", "--tag", "office", "--tag", "current"))
+	id2 := strings.TrimSpace(runCommand(t, dataFilePath, "note", "add", "--text", "call bank", "--tag", "work", "--tag", "office"))
+	// rename and regroup a tag
+	utils.AssertEqual(t, runCommand(t, dataFilePath, "tag", "rename", "current", "Now"), "#now#0\n")
+	utils.AssertEqual(t, runCommand(t, dataFilePath, "tag", "regroup", "now", "time"), "time#now#0\n")
+	var out bytes.Buffer
+	err := reminder.RunCommand(dataFilePath, []string{"tag", "rename", "now", "work"}, &out)
+	utils.AssertEqual(t, err != nil, true)
+	// the tags looked up by their slugs are protected
+	err = reminder.RunCommand(dataFilePath, []string{"tag", "rename", "repeat-annually", "yearly"}, &out)
+	utils.AssertEqual(t, errors.Is(err, model.ErrorTagProtected), true)
+	err = reminder.RunCommand(dataFilePath, []string{"tag", "delete", "repeat-monthly"}, &out)
+	utils.AssertEqual(t, errors.Is(err, model.ErrorTagProtected), true)
+	// merge a tag into another one
+	utils.AssertEqual(t, runCommand(t, dataFilePath, "tag", "merge", "office", "work"), "Updated 2 notes.\n")
+	reminderData, _ := model.ReadDataFile(dataFilePath, true)
+	utils.AssertEqual(t, reminderData.TagFromSlug("office") == nil, true)
+	utils.AssertEqual(t, reminderData.TagsFromIds(reminderData.FindNoteById(id1).TagIds), []string{"work", "now"})
+	utils.AssertEqual(t, reminderData.TagsFromIds(reminderData.FindNoteById(id2).TagIds), []string{"work"})
+	// delete a tag, reassigning its notes to another tag
+	utils.AssertEqual(t, runCommand(t, dataFilePath, "tag", "delete", "--reassign", "tips", "now"), "Updated 1 notes.\n")
+	// delete a tag, detaching its notes
+	utils.AssertEqual(t, runCommand(t, dataFilePath, "tag", "delete", "work", "--json"), "{\n  \"updated_notes\": 2\n}\n")
+	reminderData, _ = model.ReadDataFile(dataFilePath, true)
+	utils.AssertEqual(t, len(reminderData.Tags), 6)
+	utils.AssertEqual(t, reminderData.TagsFromIds(reminderData.FindNoteById(id1).TagIds), []string{"tips"})
+	utils.AssertEqual(t, reminderData.FindNoteById(id2).TagIds, []int{})
+	// ids of the deleted tags aren't reused
+	utils.AssertEqual(t, runCommand(t, dataFilePath, "tag", "add", "--slug", "home", "--group", "area"), "area#home#9\n")
+}
+
+func TestRunCommandTagQueries(t *testing.T) {
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll("temp_test_dir")
+	// same commands work for each of the storage backends
+	for _, dataFilePath := range []string{"temp_test_dir/mydata.json", "temp_test_dir/mydata.db"} {
+		testRunCommandTagQueries(t, dataFilePath)
+	}
+}
+
+func testRunCommandTagQueries(t *testing.T, dataFilePath string) {
+	_ = runCommand(t, dataFilePath, "tag", "add", "--slug", "work", "--group", "area")
+	_ = runCommand(t, dataFilePath, "tag", "add", "--slug", "work/projectx", "--group", "project")
+	_ = runCommand(t, dataFilePath, "tag", "add", "--slug", "waiting", "--group", "state")
+	id1 := strings.TrimSpace(runCommand(t, dataFilePath, "note", "add", "--text", "pay bills", "--tag", "current", "--tag", "priority-urgent"))
+	id2 := strings.TrimSpace(runCommand(t, dataFilePath, "note", "add", "--text", "call bank", "--tag", "current", "--tag", "priority-urgent", "--tag", "waiting"))
+	id3 := strings.TrimSpace(runCommand(t, dataFilePath, "note", "add", "--text", "write api", "--tag", "work/projectx"))
+	// a tag includes notes of its sub-tags
+	output := runCommand(t, dataFilePath, "note", "list", "--tag", "work")
+	utils.AssertEqual(t, strings.HasPrefix(output, id3), true)
+	utils.AssertEqual(t, strings.Count(output, "\n"), 1)
+	// list notes with a tag query
+	output = runCommand(t, dataFilePath, "note", "list", "--query", "current AND NOT waiting OR work")
+	utils.AssertEqual(t, strings.Contains(output, id1), true)
+	utils.AssertEqual(t, strings.Contains(output, id2), false)
+	utils.AssertEqual(t, strings.Contains(output, id3), true)
+	var out bytes.Buffer
+	err := reminder.RunCommand(dataFilePath, []string{"note", "list", "--query", "current AND"}, &out)
+	utils.AssertEqual(t, err != nil, true)
+	// save, list and delete the queries
+	utils.AssertEqual(t, runCommand(t, dataFilePath, "query", "save", "urgent", "current and priority-urgent and not waiting"),
+		"urgent: current AND priority-urgent AND NOT waiting\n")
+	utils.AssertEqual(t, runCommand(t, dataFilePath, "query", "save", "projects", "work", "--json"),
+		"{\n  \"name\": \"projects\",\n  \"query\": \"work\"\n}\n")
+	output = runCommand(t, dataFilePath, "note", "list", "--query", "urgent")
+	utils.AssertEqual(t, strings.HasPrefix(output, id1), true)
+	utils.AssertEqual(t, strings.Count(output, "\n"), 1)
+	utils.AssertEqual(t, runCommand(t, dataFilePath, "tag", "rename", "waiting", "on-hold"), "state#on-hold#9\n")
+	utils.AssertEqual(t, runCommand(t, dataFilePath, "query", "list"),
+		"urgent: current AND priority-urgent AND NOT on-hold\nprojects: work\n")
+	utils.AssertEqual(t, runCommand(t, dataFilePath, "query", "delete", "urgent"), "Deleted the tag query \"urgent\".\n")
+	utils.AssertEqual(t, runCommand(t, dataFilePath, "query", "list"), "projects: work\n")
+	err = reminder.RunCommand(dataFilePath, []string{"query", "delete", "urgent"}, &out)
+	utils.AssertEqual(t, err != nil, true)
+}
+
+func TestRunCommandDoctor(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	utils.AssertEqual(t, runCommand(t, dataFilePath, "doctor"), "No problems found.\n")
+	// two priorities cannot be set on a note
+	var out bytes.Buffer
+	err := reminder.RunCommand(dataFilePath, []string{"note", "add", "--text", "pay bills", "--tag", "priority-urgent", "--tag", "priority-low"}, &out)
+	utils.AssertEqual(t, errors.Is(err, model.ErrorTagGroupRule), true)
+	// but, such a note may be there from earlier
+	reminderData, _ := model.ReadDataFile(dataFilePath, true)
+	reminderData.SetTagGroupRules(model.TagGroupRules{"priority": model.TagGroupRule_Multi})
+	note, _ := reminderData.NewNoteRegistration([]int{reminderData.TagFromSlug("priority-urgent").Id, reminderData.TagFromSlug("priority-low").Id}, "pay bills")
+	output := runCommand(t, dataFilePath, "doctor")
+	utils.AssertEqual(t, strings.HasPrefix(output, note.Id+" pay bills: only one of the tags"), true)
+	utils.AssertEqual(t, strings.HasSuffix(output, "Found 1 problems; run with --fix to fix them.\n"), true)
+	var result struct {
+		Problems []map[string]interface{} `json:"problems"`
+		Fixed    bool                     `json:"fixed"`
+	}
+	output = runCommand(t, dataFilePath, "doctor", "--fix", "--json")
+	utils.AssertEqual(t, json.Unmarshal([]byte(output), &result), nil)
+	utils.AssertEqual(t, result.Fixed, true)
+	utils.AssertEqual(t, result.Problems[0]["kind"], "tag_group")
+	output = runCommand(t, dataFilePath, "note", "list", "--json")
+	utils.AssertEqual(t, strings.Contains(output, `"priority-low"`), true)
+	utils.AssertEqual(t, strings.Contains(output, `"priority-urgent"`), false)
+	utils.AssertEqual(t, runCommand(t, dataFilePath, "doctor"), "No problems found.\n")
+}
+
+func TestRunCommandMigrate(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	id := strings.TrimSpace(runCommand(t, dataFilePath, "note", "add", "--text", "pay bills", "--tag", "current"))
+	_ = runCommand(t, dataFilePath, "note", "comment", id, "--text", "paid the electricity bill")
+	// migrate to sqlite
+	output := runCommand(t, dataFilePath, "migrate", "--to", "sqlite")
+	utils.AssertEqual(t, strings.HasPrefix(output, "Migrated 1 notes and 7 tags to \"temp_test_dir/mydata.db\"."), true)
+	jsonData, _ := model.ReadDataFile(dataFilePath, true)
+	sqliteData, _ := model.ReadDataFile("temp_test_dir/mydata.db", true)
+	utils.AssertEqual(t, sqliteData.User, jsonData.User)
+	utils.AssertEqual(t, sqliteData.Tags, jsonData.Tags)
+	utils.AssertEqual(t, sqliteData.Notes, jsonData.Notes)
+	// the migrated data file can be used right away
+	output = runCommand(t, "temp_test_dir/mydata.db", "note", "list", "--tag", "current")
+	utils.AssertEqual(t, strings.HasPrefix(output, id), true)
+	// migrating again, or to same backend fails
+	var out bytes.Buffer
+	utils.AssertEqual(t, reminder.RunCommand(dataFilePath, []string{"migrate", "--to", "sqlite"}, &out) != nil, true)
+	utils.AssertEqual(t, reminder.RunCommand(dataFilePath, []string{"migrate", "--to", "json"}, &out) != nil, true)
+	// migrate back to json
+	output = runCommand(t, "temp_test_dir/mydata.db", "migrate", "--to", "json", "--out", "temp_test_dir/restored.json", "--json")
+	var result map[string]interface{}
+	utils.AssertEqual(t, json.Unmarshal([]byte(output), &result), nil)
+	utils.AssertEqual(t, result["notes"], float64(1))
+	restoredData, _ := model.ReadDataFile("temp_test_dir/restored.json", true)
+	utils.AssertEqual(t, restoredData.Notes, jsonData.Notes)
+}
+
+func TestRunCommandDaemon(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	defer func(ct func() time.Time) { utils.CurrentTime = ct }(utils.CurrentTime)
+	utils.CurrentTime = func() time.Time { return time.Date(2030, 5, 10, 9, 0, 0, 0, time.UTC) }
+	id1 := strings.TrimSpace(runCommand(t, dataFilePath, "note", "add", "--text", "pay bills", "--tag", "current", "--due", "12-05-2030"))
+	_ = runCommand(t, dataFilePath, "note", "add", "--text", "renew passport", "--tag", "current", "--due", "12-06-2030")
+	// the daemon works while the data file is locked by an interactive session
+	lock, err := model.LockDataFile(dataFilePath)
+	utils.AssertEqual(t, err, nil)
+	output := runCommand(t, dataFilePath, "daemon", "--once", "--notifier", "bell")
+	utils.AssertEqual(t, output, "\apay bills: Due on 12-May-30\n"+"Notified "+id1+": pay bills (Due on 12-May-30)\n")
+	utils.AssertEqual(t, lock.Release(), nil)
+	// an already notified note isn't notified again
+	utils.AssertEqual(t, runCommand(t, dataFilePath, "daemon", "--once", "--notifier", "bell"), "")
+	state, err := notify.LoadState(dataFilePath + reminder.NotifiedFileSuffix)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, len(state.Notified), 1)
+	// a note marked done is forgotten
+	_ = runCommand(t, dataFilePath, "note", "done", id1)
+	utils.AssertEqual(t, runCommand(t, dataFilePath, "daemon", "--once", "--notifier", "bell"), "")
+	state, _ = notify.LoadState(dataFilePath + reminder.NotifiedFileSuffix)
+	utils.AssertEqual(t, len(state.Notified), 0)
+	// invalid options fail
+	var out bytes.Buffer
+	utils.AssertEqual(t, reminder.RunCommand(dataFilePath, []string{"daemon", "--once", "--notifier", "command"}, &out) != nil, true)
+	utils.AssertEqual(t, reminder.RunCommand(dataFilePath, []string{"daemon", "--once", "--interval", "0s"}, &out) != nil, true)
+	// the ids back-filled to the notes persisted without ids are persisted when the daemon starts
+	removeNoteIds(t, dataFilePath)
+	_ = runCommand(t, dataFilePath, "daemon", "--once", "--notifier", "bell")
+	utils.AssertEqual(t, notesWithoutIds(t, dataFilePath), 0)
+}
+
+func TestRunCommandDigest(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	defer func(ct func() time.Time) { utils.CurrentTime = ct }(utils.CurrentTime)
+	utils.CurrentTime = func() time.Time { return time.Date(2030, 5, 10, 9, 0, 0, 0, time.UTC) }
+	_ = runCommand(t, dataFilePath, "note", "add", "--text", "pay bills", "--tag", "current", "--due", "12-05-2030")
+	// the user of a new data file has no email id
+	var out bytes.Buffer
+	err := reminder.RunCommand(dataFilePath, []string{"digest", "--dry-run"}, &out)
+	utils.AssertEqual(t, err.Error(), "The user has no email id; pass the recipient with --to")
+	// dry run writes the email
+	output := runCommand(t, dataFilePath, "digest", "--dry-run", "--to", "jane@example.com")
+	utils.AssertEqual(t, strings.Contains(output, "Subject: Reminder digest for 10-May-30: 1 due, 0 overdue\r\n"), true)
+	utils.AssertEqual(t, strings.Contains(output, "  - pay bills (due 12-May-30)"), true)
+	utils.AssertEqual(t, strings.Contains(output, "Content-Type: text/html; charset=utf-8"), true)
+	// the digest is sent through the SMTP server
+	server, err := mailertest.NewServer()
+	utils.AssertEqual(t, err, nil)
+	defer server.Close()
+	output = runCommand(t, dataFilePath, "digest", "--to", "jane@example.com", "--view", "look-ahead", "--smtp-host", server.Host, "--smtp-port", fmt.Sprint(server.Port))
+	utils.AssertEqual(t, output, "Sent the digest (1 due, 0 overdue) to jane@example.com\n")
+	mails := server.Mails()
+	utils.AssertEqual(t, len(mails), 1)
+	utils.AssertEqual(t, mails[0].To, []string{"jane@example.com"})
+	utils.AssertEqual(t, strings.Contains(mails[0].Data, "Look Ahead"), true)
+	// unknown views fail
+	err = reminder.RunCommand(dataFilePath, []string{"digest", "--dry-run", "--to", "jane@example.com", "--view", "someday"}, &out)
+	utils.AssertEqual(t, strings.HasPrefix(err.Error(), "Unknown view"), true)
+}
+
+func TestRunCommandExportICS(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	id := strings.TrimSpace(runCommand(t, dataFilePath, "note", "add", "--text", "team sync", "--tag", "current", "--due", "12-05-2030 09:30 Europe/London", "--repeat", "weekly/2:mo,th"))
+	_ = runCommand(t, dataFilePath, "note", "add", "--text", "learn go", "--tag", "current")
+	// only the notes with due date are exported
+	output := runCommand(t, dataFilePath, "export", "ics")
+	utils.AssertEqual(t, strings.HasPrefix(output, "BEGIN:VCALENDAR\r\n"), true)
+	utils.AssertEqual(t, strings.Count(output, "BEGIN:VEVENT"), 1)
+	utils.AssertEqual(t, strings.Contains(output, "UID:"+id+"@reminder\r\n"), true)
+	utils.AssertEqual(t, strings.Contains(output, "DTSTART;TZID=Europe/London:20300512T093000\r\n"), true)
+	utils.AssertEqual(t, strings.Contains(output, "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH\r\n"), true)
+	// as to-dos, to a file
+	output = runCommand(t, dataFilePath, "export", "ics", "--todo", "--out", "temp_test_dir/reminder.ics")
+	utils.AssertEqual(t, output, "Exported the notes to \"temp_test_dir/reminder.ics\".\n")
+	content, _ := os.ReadFile("temp_test_dir/reminder.ics")
+	utils.AssertEqual(t, strings.Count(string(content), "BEGIN:VTODO"), 1)
+	// the live feed reflects the latest data, even while the data file is locked
+	server := httptest.NewServer(reminder.ICSFeedHandler(dataFilePath, ical.KindEvent))
+	defer server.Close()
+	lock, err := model.LockDataFile(dataFilePath)
+	utils.AssertEqual(t, err, nil)
+	response, err := http.Get(server.URL)
+	utils.AssertEqual(t, err, nil)
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	utils.AssertEqual(t, response.StatusCode, http.StatusOK)
+	utils.AssertEqual(t, response.Header.Get("Content-Type"), ical.ContentType)
+	utils.AssertEqual(t, string(body), runCommand(t, dataFilePath, "export", "ics"))
+	utils.AssertEqual(t, lock.Release(), nil)
+	// unchanged feed isn't sent again
+	request, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	request.Header.Set("If-None-Match", response.Header.Get("ETag"))
+	response, err = http.DefaultClient.Do(request)
+	utils.AssertEqual(t, err, nil)
+	response.Body.Close()
+	utils.AssertEqual(t, response.StatusCode, http.StatusNotModified)
+	// even later on
+	defer func(ct func() time.Time) { utils.CurrentTime = ct }(utils.CurrentTime)
+	utils.CurrentTime = func() time.Time { return time.Now().Add(time.Hour) }
+	response, err = http.DefaultClient.Do(request)
+	utils.AssertEqual(t, err, nil)
+	response.Body.Close()
+	utils.AssertEqual(t, response.StatusCode, http.StatusNotModified)
+	_ = runCommand(t, dataFilePath, "note", "add", "--text", "pay bills", "--tag", "current", "--due", "20-05-2030")
+	response, err = http.DefaultClient.Do(request)
+	utils.AssertEqual(t, err, nil)
+	body, _ = io.ReadAll(response.Body)
+	response.Body.Close()
+	utils.AssertEqual(t, response.StatusCode, http.StatusOK)
+	utils.AssertEqual(t, strings.Count(string(body), "BEGIN:VEVENT"), 2)
+	utils.AssertEqual(t, strings.Contains(string(body), "DTSTART;VALUE=DATE:20300520\r\n"), true)
+}
+
+func TestRunCommandImportICS(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	_ = runCommand(t, dataFilePath, "note", "add", "--text", "team sync", "--tag", "current", "--due", "12-05-2030 09:30 Europe/London")
+	// notes exported by the app itself aren't imported again
+	_ = runCommand(t, dataFilePath, "export", "ics", "--out", "temp_test_dir/reminder.ics")
+	output := runCommand(t, dataFilePath, "import", "ics", "temp_test_dir/reminder.ics")
+	utils.AssertEqual(t, output, "Imported 0 notes (1 skipped).\n")
+	// entries of other calendars are imported once
+	content := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"UID:x1@example.com",
+		"SUMMARY:pay rent",
+		"DTSTART;VALUE=DATE:20300501",
+		"RRULE:FREQ=MONTHLY",
+		"CATEGORIES:Home",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:x2@example.com",
+		"SUMMARY:standup",
+		"DTSTART:20300512T090000Z",
+		"RRULE:FREQ=DAILY;COUNT=5",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+	_ = os.WriteFile("temp_test_dir/other.ics", []byte(content), 0644)
+	output = runCommand(t, dataFilePath, "import", "ics", "--json", "temp_test_dir/other.ics")
+	var result struct {
+		Imported []string
+		Tags     []string
+		Skipped  int
+		Warnings []string
+	}
+	utils.AssertEqual(t, json.Unmarshal([]byte(output), &result), nil)
+	utils.AssertEqual(t, len(result.Imported), 2)
+	utils.AssertEqual(t, result.Tags, []string{"home"})
+	utils.AssertEqual(t, result.Skipped, 0)
+	utils.AssertEqual(t, len(result.Warnings), 1)
+	output = runCommand(t, dataFilePath, "note", "list", "--tag", "home")
+	utils.AssertEqual(t, strings.Contains(output, "pay rent"), true)
+	output = runCommand(t, dataFilePath, "import", "ics", "temp_test_dir/other.ics")
+	utils.AssertEqual(t, output, "Imported 0 notes (2 skipped).\n")
+	// invalid file
+	var out bytes.Buffer
+	err := reminder.RunCommand(dataFilePath, []string{"import", "ics", "temp_test_dir/mydata.json"}, &out)
+	utils.AssertEqual(t, strings.HasPrefix(err.Error(), "Unable to parse \"temp_test_dir/mydata.json\""), true)
+}
+
+func TestAPIHandler(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	_ = runCommand(t, dataFilePath, "tag", "list")
+	server := httptest.NewServer(reminder.APIHandler(dataFilePath, "secret"))
+	defer server.Close()
+	// request makes an API request, and returns the response along with its decoded body
+	request := func(method string, endpoint string, body string, headers ...string) (*http.Response, interface{}) {
+		t.Helper()
+		req, _ := http.NewRequest(method, server.URL+reminder.APIPrefix+endpoint, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		for index := 0; index < len(headers); index += 2 {
+			req.Header.Set(headers[index], headers[index+1])
+		}
+		response, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request %s %s failed with error: %v", method, endpoint, err)
+		}
+		defer response.Body.Close()
+		var decoded interface{}
+		_ = json.NewDecoder(response.Body).Decode(&decoded)
+		return response, decoded
+	}
+	// the token is required, except for the OpenAPI document
+	response, err := http.Get(server.URL + reminder.APIPrefix + "notes")
+	utils.AssertEqual(t, err, nil)
+	response.Body.Close()
+	utils.AssertEqual(t, response.StatusCode, http.StatusUnauthorized)
+	response, err = http.Get(server.URL + reminder.APIPrefix + "openapi.json")
+	utils.AssertEqual(t, err, nil)
+	var document map[string]interface{}
+	utils.AssertEqual(t, json.NewDecoder(response.Body).Decode(&document), nil)
+	response.Body.Close()
+	utils.AssertEqual(t, document["openapi"], "3.0.3")
+	// create a tag and notes
+	response, body := request(http.MethodPost, "tags", `{"slug": "home", "group": "area"}`)
+	utils.AssertEqual(t, response.StatusCode, http.StatusCreated)
+	utils.AssertEqual(t, body.(map[string]interface{})["slug"], "home")
+	response, body = request(http.MethodPost, "notes", `{"text": "pay bills", "tags": ["home"], "due": "12-05-2030"}`)
+	utils.AssertEqual(t, response.StatusCode, http.StatusCreated)
+	id := body.(map[string]interface{})["id"].(string)
+	utils.AssertEqual(t, response.Header.Get("Location"), reminder.APIPrefix+"notes/"+id)
+	etag := response.Header.Get("ETag")
+	utils.AssertEqual(t, etag != "", true)
+	response, _ = request(http.MethodPost, "notes", `{"text": "call mom", "repeat": "daily"}`)
+	utils.AssertEqual(t, response.StatusCode, http.StatusBadRequest)
+	response, _ = request(http.MethodPost, "notes", `{"text": "call mom", "frequency": "daily"}`)
+	utils.AssertEqual(t, response.StatusCode, http.StatusBadRequest)
+	response, body = request(http.MethodGet, "notes?tag=home", "")
+	utils.AssertEqual(t, response.StatusCode, http.StatusOK)
+	utils.AssertEqual(t, len(body.([]interface{})), 1)
+	response, body = request(http.MethodGet, "notes?query=home+AND+NOT+current", "")
+	utils.AssertEqual(t, response.StatusCode, http.StatusOK)
+	utils.AssertEqual(t, len(body.([]interface{})), 1)
+	response, _ = request(http.MethodGet, "notes?query=home+AND", "")
+	utils.AssertEqual(t, response.StatusCode, http.StatusBadRequest)
+	response, _ = request(http.MethodDelete, "notes", "")
+	utils.AssertEqual(t, response.StatusCode, http.StatusMethodNotAllowed)
+	utils.AssertEqual(t, response.Header.Get("Allow"), "GET, POST")
+	// unchanged note isn't sent again
+	response, _ = request(http.MethodGet, "notes/"+id[:8], "", "If-None-Match", etag)
+	utils.AssertEqual(t, response.StatusCode, http.StatusNotModified)
+	// update the note; the fields are updated at once, even if the clock ticks in the meantime
+	currentTime := utils.CurrentTime
+	clock := time.Now()
+	utils.CurrentTime = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	response, body = request(http.MethodPatch, "notes/"+id, `{"text": "pay the bills", "is_main": true, "due": ""}`, "If-Match", etag)
+	utils.CurrentTime = currentTime
+	utils.AssertEqual(t, response.StatusCode, http.StatusOK)
+	patchedData, _ := model.ReadDataFile(dataFilePath, true)
+	history := patchedData.FindNoteById(id).History
+	utils.AssertEqual(t, len(history), 3)
+	for _, revision := range history {
+		utils.AssertEqual(t, revision.At, history[0].At)
+	}
+	utils.AssertEqual(t, body.(map[string]interface{})["text"], "pay the bills")
+	utils.AssertEqual(t, body.(map[string]interface{})["is_main"], true)
+	utils.AssertEqual(t, body.(map[string]interface{})["complete_by"], nil)
+	utils.AssertEqual(t, response.Header.Get("ETag") != etag, true)
+	// invalid fields leave the note unchanged
+	response, _ = request(http.MethodPatch, "notes/"+id, `{"text": "pay rent", "repeat": "weekly"}`)
+	utils.AssertEqual(t, response.StatusCode, http.StatusBadRequest)
+	// the stale entity tag is rejected
+	response, _ = request(http.MethodPost, "notes/"+id+"/comments", `{"text": "paid"}`, "If-Match", etag)
+	utils.AssertEqual(t, response.StatusCode, http.StatusPreconditionFailed)
+	etag = response.Header.Get("ETag")
+	response, _ = request(http.MethodPost, "notes/"+id+"/comments", `{"text": "paid"}`, "If-Match", etag)
+	utils.AssertEqual(t, response.StatusCode, http.StatusOK)
+	response, _ = request(http.MethodPut, "notes/"+id+"/status", `{"status": "finished"}`)
+	utils.AssertEqual(t, response.StatusCode, http.StatusBadRequest)
+	response, body = request(http.MethodPut, "notes/"+id+"/status", `{"status": "done"}`)
+	utils.AssertEqual(t, response.StatusCode, http.StatusOK)
+	utils.AssertEqual(t, body.(map[string]interface{})["status"], "done")
+	reminderData, _ := model.ReadDataFile(dataFilePath, true)
+	note := reminderData.FindNoteById(id)
+	utils.AssertEqual(t, note.Text, "pay the bills")
+	utils.AssertEqual(t, note.Comments[0].Text, "paid")
+	utils.AssertEqual(t, note.Status, model.NoteStatus_Done)
+	// move the note to the trash
+	response, body = request(http.MethodDelete, "notes/"+id, "")
+	utils.AssertEqual(t, response.StatusCode, http.StatusOK)
+	utils.AssertEqual(t, body.(map[string]interface{})["text"], "pay the bills")
+	response, _ = request(http.MethodGet, "notes/"+id, "")
+	utils.AssertEqual(t, response.StatusCode, http.StatusNotFound)
+	// views
+	response, _ = request(http.MethodPost, "notes", `{"text": "renew passport", "due": "+3d"}`)
+	utils.AssertEqual(t, response.StatusCode, http.StatusCreated)
+	response, body = request(http.MethodGet, "views/approaching", "")
+	utils.AssertEqual(t, response.StatusCode, http.StatusOK)
+	utils.AssertEqual(t, len(body.([]interface{})), 1)
+	utils.AssertEqual(t, body.([]interface{})[0].(map[string]interface{})["text"], "renew passport")
+	utils.AssertEqual(t, body.([]interface{})[0].(map[string]interface{})["overdue"], false)
+	response, _ = request(http.MethodGet, "views/someday", "")
+	utils.AssertEqual(t, response.StatusCode, http.StatusNotFound)
+	// changes fail while the data file is locked by another session, but reads still work
+	lock, err := model.LockDataFile(dataFilePath)
+	utils.AssertEqual(t, err, nil)
+	response, _ = request(http.MethodPost, "tags", `{"slug": "travel", "group": "area"}`)
+	utils.AssertEqual(t, response.StatusCode, http.StatusLocked)
+	response, _ = request(http.MethodGet, "tags", "")
+	utils.AssertEqual(t, response.StatusCode, http.StatusOK)
+	utils.AssertEqual(t, lock.Release(), nil)
+	// the ids back-filled to the notes persisted without ids are persisted before serving the requests
+	removeNoteIds(t, dataFilePath)
+	utils.AssertEqual(t, notesWithoutIds(t, dataFilePath), 1)
+	_ = reminder.APIHandler(dataFilePath, "secret")
+	utils.AssertEqual(t, notesWithoutIds(t, dataFilePath), 0)
+}
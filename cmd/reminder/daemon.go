@@ -0,0 +1,133 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/goyalmunish/reminder/internal/model"
+	"github.com/goyalmunish/reminder/pkg/notify"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+// NotifiedFileSuffix is the suffix of the (sidecar) file, next to the data file, which tracks the notified notes.
+const NotifiedFileSuffix = ".notified.json"
+
+// notifyOptions returns the notification settings from the app config (or the default ones).
+func notifyOptions() *notify.Options {
+	if config != nil && config.Notify != nil {
+		return config.Notify
+	}
+	return notify.DefaultOptions()
+}
+
+// daemonCommand periodically notifies the notes approaching their due date (as in "Approaching Due Date").
+// It only reads the data file (without taking the lock on it), so it can run alongside an interactive session;
+// the notified notes are tracked in a separate state file, so that a note is notified only once per occurrence.
+func daemonCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	options := notifyOptions()
+	fs, _ := newFlagSet("daemon")
+	interval := fs.String("interval", options.Interval, "how often the notes are checked, such as 30s or 5m")
+	var notifierNames stringsFlag
+	fs.Var(&notifierNames, "notifier", "notifier to use: desktop, bell, or command (can be passed multiple times)")
+	hook := fs.String("command", options.Command, "shell command run by the command notifier")
+	stateFile := fs.String("state-file", options.StateFile, "file which tracks the notified notes")
+	once := fs.Bool("once", false, "check the notes just once, and exit")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("Unexpected arguments %q", strings.Join(positional, " "))
+	}
+	period, err := time.ParseDuration(*interval)
+	if err != nil {
+		return fmt.Errorf("Invalid interval %q: %w", *interval, err)
+	}
+	if period <= 0 {
+		return fmt.Errorf("Invalid interval %q; it must be positive", *interval)
+	}
+	if len(notifierNames) == 0 {
+		notifierNames = options.Notifiers
+	}
+	notifier, err := notify.New(&notify.Options{Notifiers: notifierNames, Command: *hook}, out)
+	if err != nil {
+		return err
+	}
+	if *stateFile == "" {
+		*stateFile = utils.TryConvertTildaBasedPath(rd.DataFile) + NotifiedFileSuffix
+	}
+	state, err := notify.LoadState(*stateFile)
+	if err != nil {
+		return fmt.Errorf("Unable to read the state file %q: %w", *stateFile, err)
+	}
+	// the data migrated while being read (such as back-filled ids of notes) is persisted at the start, as
+	// otherwise it would be migrated afresh on every check; the notified notes are tracked by their ids
+	if rd.Migrated() {
+		rd = persistMigration(rd.DataFile, rd)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	for {
+		err := notifyApproachingNotes(rd, notifier, state, utils.CurrentUnixTimestamp(), out)
+		if *once {
+			return err
+		}
+		// keep the daemon running, but log the error
+		utils.LogError(err)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(period):
+		}
+		// re-read the data file (without locking it), as it may have been updated in the meantime;
+		// the data file is always written atomically, so it is never read half-written
+		reminderData, err := model.ReadDataFile(rd.DataFile, true)
+		if err != nil {
+			utils.LogError(err)
+			continue
+		}
+		rd = reminderData
+	}
+}
+
+// notifyApproachingNotes notifies the notes approaching their due date at given timestamp, which are not yet notified.
+// Each occurrence of a recurring note is notified separately.
+func notifyApproachingNotes(rd *model.ReminderData, notifier notify.Notifier, state *notify.State, at int64, out io.Writer) error {
+	var errs []error
+	occurrences := rd.ApproachingOccurrences("default", at)
+	keys := make([]string, 0, len(occurrences))
+	for _, occurrence := range occurrences {
+		note := occurrence.Note
+		key := fmt.Sprintf("%s@%d", note.Id, occurrence.DueAt)
+		keys = append(keys, key)
+		if state.Has(key) {
+			continue
+		}
+		notification := notify.Notification{
+			Id:      note.Id,
+			Title:   note.Text,
+			Message: fmt.Sprintf("Due on %s", note.DueText(occurrence.DueAt)),
+			DueAt:   time.Unix(occurrence.DueAt, 0).In(note.DueLocation()),
+		}
+		if err := notifier.Notify(notification); err != nil {
+			// try again on next check
+			errs = append(errs, err)
+			continue
+		}
+		state.Mark(key, at)
+		fmt.Fprintf(out, "Notified %s: %s (%s)\n", note.Id, notification.Title, notification.Message)
+	}
+	// forget the notes which are no longer approaching their due date (such as the ones marked done)
+	state.Retain(keys)
+	if err := state.Save(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,104 @@
+package reminder
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/goyalmunish/reminder/internal/model"
+	"github.com/goyalmunish/reminder/pkg/mailer"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+// mailOptions returns the mail settings from the app config (or the default ones).
+func mailOptions() *mailer.Options {
+	if config != nil && config.Mail != nil {
+		return config.Mail
+	}
+	return mailer.DefaultOptions()
+}
+
+// digestTemplate returns content of the template file at given path, or the default template if path is empty.
+func digestTemplate(filePath string, defaultTemplate string) (string, error) {
+	if filePath == "" {
+		return defaultTemplate, nil
+	}
+	byteValue, err := os.ReadFile(utils.TryConvertTildaBasedPath(filePath))
+	if err != nil {
+		return "", fmt.Errorf("Unable to read the template: %w", err)
+	}
+	return string(byteValue), nil
+}
+
+// digestCommand emails digest of the pending notes (from the views of the Main Menu) to the user.
+// With --dry-run, the email (in MIME format) is written to the output instead of being sent.
+func digestCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	options := *mailOptions()
+	fs, _ := newFlagSet("digest")
+	var views stringsFlag
+	fs.Var(&views, "view", fmt.Sprintf("view to include: %s (can be passed multiple times)", strings.Join(model.DigestViews, ", ")))
+	to := fs.String("to", "", "recipient of the digest (by default, email id of the user)")
+	dryRun := fs.Bool("dry-run", false, "write the email to the output, instead of sending it")
+	fs.StringVar(&options.Host, "smtp-host", options.Host, "host of the SMTP server")
+	fs.IntVar(&options.Port, "smtp-port", options.Port, "port of the SMTP server")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("Unexpected arguments %q", strings.Join(positional, " "))
+	}
+	if len(views) == 0 {
+		views = stringsFlag{"approaching", "main"}
+	}
+	recipient := *to
+	if recipient == "" && rd.User != nil {
+		recipient = rd.User.EmailId
+	}
+	if recipient == "" {
+		return errors.New("The user has no email id; pass the recipient with --to")
+	}
+	sender := options.From
+	if sender == "" {
+		sender = recipient
+	}
+	// render the digest
+	digest, err := rd.Digest(views, utils.CurrentUnixTimestamp())
+	if err != nil {
+		return err
+	}
+	textTemplate, err := digestTemplate(options.TextTemplate, model.DefaultDigestTextTemplate)
+	if err != nil {
+		return err
+	}
+	htmlTemplate, err := digestTemplate(options.HTMLTemplate, model.DefaultDigestHTMLTemplate)
+	if err != nil {
+		return err
+	}
+	message := &mailer.Message{From: sender, To: recipient, Subject: digest.Subject()}
+	if message.Text, err = digest.RenderText(textTemplate); err != nil {
+		return fmt.Errorf("Unable to render the text template: %w", err)
+	}
+	if message.HTML, err = digest.RenderHTML(htmlTemplate); err != nil {
+		return fmt.Errorf("Unable to render the HTML template: %w", err)
+	}
+	// send (or print) the digest
+	if *dryRun {
+		if err := message.Validate(); err != nil {
+			return err
+		}
+		data, err := message.Bytes()
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(data)
+		return err
+	}
+	if err := mailer.Send(&options, message); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Sent the digest (%d due, %d overdue) to %s\n", digest.NumDue, digest.NumOverdue, recipient)
+	return nil
+}
@@ -0,0 +1,71 @@
+package reminder
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/goyalmunish/reminder/internal/model"
+)
+
+// tagGroupRules returns the rules of the tag groups: the built-in ones, overridden by the ones from the app config.
+func tagGroupRules() (model.TagGroupRules, error) {
+	configured, err := model.ParseTagGroupRules(appInfoOptions().TagGroups)
+	if err != nil {
+		return nil, err
+	}
+	rules := model.DefaultTagGroupRules()
+	for group, rule := range configured {
+		rules[group] = rule
+	}
+	return rules, nil
+}
+
+// problemView is the external (JSON) representation of a problem found in the data.
+type problemView struct {
+	NoteId  string            `json:"note_id"`
+	Text    string            `json:"text"`
+	Kind    model.ProblemKind `json:"kind"`
+	Message string            `json:"message"`
+}
+
+// doctorCommand reports the problems found in the data (such as notes breaking rules of the tag groups),
+// and fixes them with --fix.
+func doctorCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("doctor")
+	fix := fs.Bool("fix", false, "fix the problems")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("Unexpected arguments %q", strings.Join(positional, " "))
+	}
+	var problems []*model.Problem
+	if *fix {
+		if problems, err = rd.FixProblems(); err != nil {
+			return err
+		}
+	} else {
+		problems = rd.Diagnose()
+	}
+	if *asJSON {
+		views := make([]problemView, 0, len(problems))
+		for _, problem := range problems {
+			views = append(views, problemView{NoteId: problem.Note.Id, Text: problem.Note.Text, Kind: problem.Kind, Message: problem.Message})
+		}
+		return printJSON(out, map[string]interface{}{"problems": views, "fixed": *fix})
+	}
+	for _, problem := range problems {
+		fmt.Fprintf(out, "%s %s\n", problem.Note.Id, problem)
+	}
+	switch {
+	case len(problems) == 0:
+		fmt.Fprintln(out, "No problems found.")
+	case *fix:
+		fmt.Fprintf(out, "Fixed %d problems.\n", len(problems))
+	default:
+		fmt.Fprintf(out, "Found %d problems; run with --fix to fix them.\n", len(problems))
+	}
+	return nil
+}
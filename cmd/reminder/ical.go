@@ -0,0 +1,176 @@
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/goyalmunish/reminder/internal/model"
+	"github.com/goyalmunish/reminder/pkg/ical"
+	"github.com/goyalmunish/reminder/pkg/logger"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+// encodeICalendar returns iCalendar data of the pending notes with due date.
+func encodeICalendar(rd *model.ReminderData, kind string) ([]byte, error) {
+	iCalendar, err := rd.ICalendar(kind)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	if err := iCalendar.Encode(&buffer); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// ICSFeedHandler returns HTTP handler serving live iCalendar feed of the data file, which calendar apps can subscribe to.
+// The data file is read (without taking the lock on it) on each request; kind is ical.KindEvent or ical.KindTodo.
+func ICSFeedHandler(dataFile string, kind string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		reminderData, err := model.ReadDataFile(dataFile, true)
+		if err == nil {
+			var data []byte
+			if data, err = encodeICalendar(reminderData, kind); err == nil {
+				etag := fmt.Sprintf("%q", fmt.Sprintf("%x", sha256.Sum256(data))[:32])
+				w.Header().Set("ETag", etag)
+				w.Header().Set("Cache-Control", "no-cache")
+				if r.Header.Get("If-None-Match") == etag {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("Content-Type", ical.ContentType)
+				_, err = w.Write(data)
+				utils.LogError(err)
+				return
+			}
+		}
+		utils.LogError(err)
+		http.Error(w, "Unable to read the data file", http.StatusInternalServerError)
+	})
+}
+
+// exportICSCommand exports the pending notes with due date in iCalendar (.ics) format, or serves them as a live feed.
+func exportICSCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, _ := newFlagSet("export ics")
+	outFile := fs.String("out", "", "path of the .ics file (by default, the output is written to stdout)")
+	asTodo := fs.Bool("todo", false, "export the notes as to-dos (VTODO), instead of events (VEVENT)")
+	serve := fs.String("serve", "", "serve a live feed at this address (such as 127.0.0.1:8765), instead of exporting once")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("Unexpected arguments %q", strings.Join(positional, " "))
+	}
+	kind := ical.KindEvent
+	if *asTodo {
+		kind = ical.KindTodo
+	}
+	if *serve != "" {
+		return serveICSFeed(rd.DataFile, kind, *serve, out)
+	}
+	data, err := encodeICalendar(rd, kind)
+	if err != nil {
+		return err
+	}
+	if *outFile == "" {
+		_, err = out.Write(data)
+		return err
+	}
+	if err := utils.WriteFileAtomic(utils.TryConvertTildaBasedPath(*outFile), data, 0644, false); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Exported the notes to %q.\n", *outFile)
+	return nil
+}
+
+// importICSCommand imports events and to-dos of an iCalendar (.ics) file as notes.
+func importICSCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("import ics")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("Exactly one .ics file is required")
+	}
+	file, err := os.Open(utils.TryConvertTildaBasedPath(positional[0]))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	iCalendar, err := ical.Parse(file)
+	if err != nil {
+		return fmt.Errorf("Unable to parse %q: %w", positional[0], err)
+	}
+	result, err := rd.ImportICalendar(iCalendar)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		ids := make([]string, 0, len(result.Notes))
+		for _, note := range result.Notes {
+			ids = append(ids, note.Id)
+		}
+		return printJSON(out, map[string]interface{}{
+			"imported": ids,
+			"tags":     result.Tags.Slugs(),
+			"skipped":  result.Skipped,
+			"warnings": result.Warnings,
+		})
+	}
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(out, "Warning: %s\n", warning)
+	}
+	fmt.Fprintf(out, "Imported %d notes (%d skipped).\n", len(result.Notes), result.Skipped)
+	return nil
+}
+
+// serveICSFeed serves the live iCalendar feed of the data file at given address, until interrupted.
+func serveICSFeed(dataFile string, kind string, address string, out io.Writer) error {
+	mux := http.NewServeMux()
+	mux.Handle("/reminder.ics", ICSFeedHandler(dataFile, kind))
+	return listenAndServe(address, mux, func(addr net.Addr) {
+		fmt.Fprintf(out, "Serving the feed at http://%s/reminder.ics (press Ctrl-c to stop)\n", addr)
+		logger.Info(fmt.Sprintf("Serving iCalendar feed of %q at %v", dataFile, addr))
+	})
+}
+
+// listenAndServe serves HTTP requests with the handler at given address, until interrupted.
+// The announce function is called with the actual address, once the server is listening.
+func listenAndServe(address string, handler http.Handler, announce func(addr net.Addr)) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		utils.LogError(server.Shutdown(shutdownCtx))
+	}()
+	announce(listener.Addr())
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
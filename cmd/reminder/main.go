@@ -2,11 +2,18 @@
 Tool `reminder` is a command-line (terminal) based interactive app for organizing tasks with minimal efforts.
 
 Just run it as `go run ./cmd/reminder`
+
+It can also be run non-interactively (such as from shell scripts or cron jobs) by passing a command,
+for example `go run ./cmd/reminder note add --text "pay bills" --tag current --due 12-05`.
+Run `go run ./cmd/reminder --help` for list of all such commands.
 */
 package reminder
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"os"
 
 	"github.com/google/uuid"
 	"github.com/goyalmunish/reminder/internal/model"
@@ -22,7 +29,6 @@ func Run() error {
 	// initialization
 	var err error
 	var runID = uuid.New()
-	var startInteractiveProcess bool = true
 	// note: setting are loaded before logger is being setup; it will assume only default logrus settings
 	config, err = settings.LoadConfig()
 	if err != nil {
@@ -34,54 +40,54 @@ func Run() error {
 		"run_id": runID,
 	})
 
+	// parse global flags, and run the non-interactive command (if any is passed)
+	flags := flag.NewFlagSet("reminder", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(flags.Output(), CommandUsage()) }
+	flags.StringVar(&config.AppInfo.DataFile, "data-file", config.AppInfo.DataFile, "path of the data file")
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if flags.NArg() > 0 {
+		return RunCommand(config.AppInfo.DataFile, flags.Args(), os.Stdout)
+	}
+
 	// make sure DataFile exists
 	if err := model.MakeSureFileExists(config.AppInfo.DataFile, true); err != nil {
 		return err
 	}
 
+	// lock the data file for the whole session; a lock left behind by a crashed session is cleared automatically
+	lock, err := model.LockDataFile(config.AppInfo.DataFile)
+	if err != nil {
+		return err
+	}
+	// make sure lock is released while closing the app
+	defer func() {
+		utils.LogError(lock.Release())
+	}()
+
 	// read and parse the existing data
 	reminderData, err := model.ReadDataFile(config.AppInfo.DataFile, false)
 	if err != nil {
 		return err
 	}
-
-	// check if the data file is locked by another session
-	if reminderData.MutexLock {
-		fmt.Printf("WARNING! %s\n", model.ErrorMutexLockOn.Error())
-		reset, err := utils.AskBoolean("But, do you want to force reset the lock?")
-		if err != nil {
+	if reminderData.Migrated() {
+		if err := reminderData.UpdateDataFile("Persisting the migrated data!"); err != nil {
 			return err
 		}
-		if !reset {
-			// exit now without resetting the lock
-			return model.ErrorMutexLockOn
-		}
-		// proceed forward to reset the lock, but don't start the interactive process
-		startInteractiveProcess = false
-	}
-
-	// make sure lock is released and any uncommitted data is persisted
-	defer func() {
-		if !reminderData.MutexLock {
-			// prevent multiple runs of cleanup
-			return
-		}
-		// mutex lock is enable
-		reminderData.MutexLock = false
-		if err := reminderData.UpdateDataFile("Turning OFF the Mutex Lock, persisting the data, and closing the app!"); err != nil {
-			utils.LogError(err)
-		}
-	}()
-
-	// early exit if conditions are not met
-	if !startInteractiveProcess {
-		return model.ErrorInteractiveProcessSkipped
 	}
-
-	reminderData.MutexLock = true
-	if err := reminderData.UpdateDataFile("Turning ON the Mutex Lock!"); err != nil {
+	reminderData.SetIncludeArchived(config.AppInfo.IncludeArchived)
+	rules, err := tagGroupRules()
+	if err != nil {
 		return err
 	}
+	reminderData.SetTagGroupRules(rules)
+	if problems := reminderData.Diagnose(); len(problems) > 0 {
+		fmt.Printf("%v Found %d problems in the data; run `reminder doctor` for details.\n", utils.Symbols["warning"], len(problems))
+	}
 
 	// start the repeating interactive process
 	if err := RepeatInteractiveSession(reminderData); err != nil {
@@ -99,6 +105,9 @@ func RepeatInteractiveSession(reminderData *model.ReminderData) error {
 	// try automatic backup
 	_, err = reminderData.AutoBackup(24 * 60 * 60)
 	utils.LogError(err)
+	// purge the notes which have been in the trash for long
+	_, err = reminderData.AutoPurgeTrash(config.AppInfo.TrashRetentionDays)
+	utils.LogError(err)
 	// ask the main menu
 	fmt.Println("| =========================== MAIN MENU =========================== |")
 	fmt.Println("|     Use 'Ctrl-c' to jump one level up (towards the Main Menu)     |")
@@ -120,7 +129,8 @@ func RepeatInteractiveSession(reminderData *model.ReminderData) error {
 		fmt.Sprintf("%s %s", utils.Symbols["backup"], "Create Backup"),
 		fmt.Sprintf("%s %s", utils.Symbols["zzz"], "Suspended Notes"),
 		fmt.Sprintf("%s %s", utils.Symbols["telescope"], "Look Ahead"),
-		fmt.Sprintf("%s %s", utils.Symbols["refresh"], "Google Cloud Sync"),
+		fmt.Sprintf("%s %s", utils.Symbols["refresh"], "Calendar Sync"),
+		fmt.Sprintf("%s %s", utils.Symbols["trash"], "Trash"),
 		fmt.Sprintf("%s %s", utils.Symbols["pad"], "Display Data File")}, "Select Option")
 	// operate on main options
 	switch result {
@@ -138,8 +148,10 @@ func RepeatInteractiveSession(reminderData *model.ReminderData) error {
 		err = reminderData.PrintNotesAndAskOptions(model.Notes{}, "suspended_notes", -1, "default")
 	case fmt.Sprintf("%s %s", utils.Symbols["telescope"], "Look Ahead"):
 		err = reminderData.PrintNotesAndAskOptions(model.Notes{}, "pending_long_view_notes", -1, "due-date")
-	case fmt.Sprintf("%s %s", utils.Symbols["refresh"], "Google Cloud Sync"):
+	case fmt.Sprintf("%s %s", utils.Symbols["refresh"], "Calendar Sync"):
 		err = reminderData.SyncCalendar(config.Calendar)
+	case fmt.Sprintf("%s %s", utils.Symbols["trash"], "Trash"):
+		err = reminderData.ListTrash()
 	case fmt.Sprintf("%s %s", utils.Symbols["pad"], "Display Data File"):
 		err = reminderData.DisplayDataFile()
 	case fmt.Sprintf("%s %s %s", utils.Symbols["checkerdFlag"], "Exit", utils.Symbols["redFlag"]):
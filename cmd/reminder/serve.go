@@ -0,0 +1,578 @@
+package reminder
+
+import (
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/goyalmunish/reminder/internal/model"
+	"github.com/goyalmunish/reminder/pkg/logger"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+// APITokenEnv is the env variable holding the token of the REST API, used if it isn't passed with --token.
+const APITokenEnv = "REMINDER_API_TOKEN"
+
+// APIPrefix is the path prefix of all the endpoints of the REST API.
+const APIPrefix = "/api/v1/"
+
+// maxRequestBodySize is the maximum size (in bytes) of body of an API request.
+const maxRequestBodySize = 1 << 20
+
+// openAPIDocument is the OpenAPI description of the REST API, served at APIPrefix + "openapi.json".
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// An apiError represents an error along with the HTTP status to be responded with.
+type apiError struct {
+	status int
+	err    error
+}
+
+func (e *apiError) Error() string { return e.err.Error() }
+func (e *apiError) Unwrap() error { return e.err }
+
+// newAPIError returns an apiError with given status and error.
+func newAPIError(status int, err error) *apiError {
+	return &apiError{status: status, err: err}
+}
+
+// noteCreation is the body of the request creating a note.
+type noteCreation struct {
+	Text   string   `json:"text"`
+	Tags   []string `json:"tags"`
+	Due    string   `json:"due"`
+	Repeat string   `json:"repeat"`
+	IsMain bool     `json:"is_main"`
+}
+
+// notePatch is the body of the request updating a note; only the passed fields are updated,
+// and an empty summary, due or repeat clears it.
+type notePatch struct {
+	Text    *string   `json:"text"`
+	Summary *string   `json:"summary"`
+	Due     *string   `json:"due"`
+	Repeat  *string   `json:"repeat"`
+	Tags    *[]string `json:"tags"`
+	IsMain  *bool     `json:"is_main"`
+}
+
+// occurrenceView is the external (JSON) representation of a note in a view, along with its projected due date.
+type occurrenceView struct {
+	noteView
+	Due     string `json:"due,omitempty"`
+	Overdue bool   `json:"overdue"`
+}
+
+// apiViews maps views of the REST API to the ones accepted by ApproachingOccurrences.
+var apiViews = map[string]string{
+	"approaching": "default",
+	"look-ahead":  "long",
+}
+
+// apiServer serves the REST API of the data file.
+type apiServer struct {
+	dataFile string
+	token    string
+	mu       sync.Mutex // serializes the changes made through the API
+}
+
+// APIHandler returns HTTP handler serving the REST API (refer openapi.json) of the data file.
+// Every request (except for the OpenAPI document) must carry the token as "Authorization: Bearer TOKEN".
+// The data file is read (without taking the lock on it) on each request; a request which changes the data
+// takes the lock just for its duration, and fails with 423 (Locked) while another session holds it.
+func APIHandler(dataFile string, token string) http.Handler {
+	// the data migrated while being read (such as back-filled ids of notes) is persisted before serving any
+	// request, so that the data is served the same until it is changed
+	if reminderData, err := model.ReadDataFile(dataFile, true); err == nil && reminderData.Migrated() {
+		persistMigration(dataFile, reminderData)
+	}
+	server := &apiServer{dataFile: dataFile, token: token}
+	return http.HandlerFunc(server.serveHTTP)
+}
+
+// serveHTTP routes the request to its endpoint.
+func (s *apiServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	rest, found := strings.CutPrefix(r.URL.Path, APIPrefix)
+	if !found {
+		writeAPIError(w, newAPIError(http.StatusNotFound, errors.New("Not found")))
+		return
+	}
+	if rest == "openapi.json" {
+		err := dispatch(w, r, map[string]func() error{
+			http.MethodGet: func() error {
+				w.Header().Set("Content-Type", "application/json")
+				_, err := w.Write(openAPIDocument)
+				return err
+			},
+		})
+		writeAPIError(w, err)
+		return
+	}
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="reminder"`)
+		writeAPIError(w, newAPIError(http.StatusUnauthorized, errors.New("Missing or invalid API token")))
+		return
+	}
+	segments := strings.Split(strings.Trim(rest, "/"), "/")
+	var err error
+	switch {
+	case (len(segments) == 1) && (segments[0] == "notes"):
+		err = dispatch(w, r, map[string]func() error{
+			http.MethodGet:  func() error { return s.listNotes(w, r) },
+			http.MethodPost: func() error { return s.createNote(w, r) },
+		})
+	case (len(segments) == 2) && (segments[0] == "notes"):
+		err = dispatch(w, r, map[string]func() error{
+			http.MethodGet:    func() error { return s.getNote(w, r, segments[1]) },
+			http.MethodPatch:  func() error { return s.patchNote(w, r, segments[1]) },
+			http.MethodDelete: func() error { return s.trashNote(w, r, segments[1]) },
+		})
+	case (len(segments) == 3) && (segments[0] == "notes") && (segments[2] == "comments"):
+		err = dispatch(w, r, map[string]func() error{
+			http.MethodPost: func() error { return s.addComment(w, r, segments[1]) },
+		})
+	case (len(segments) == 3) && (segments[0] == "notes") && (segments[2] == "status"):
+		err = dispatch(w, r, map[string]func() error{
+			http.MethodPut: func() error { return s.updateStatus(w, r, segments[1]) },
+		})
+	case (len(segments) == 1) && (segments[0] == "tags"):
+		err = dispatch(w, r, map[string]func() error{
+			http.MethodGet:  func() error { return s.listTags(w) },
+			http.MethodPost: func() error { return s.createTag(w, r) },
+		})
+	case (len(segments) == 2) && (segments[0] == "views"):
+		err = dispatch(w, r, map[string]func() error{
+			http.MethodGet: func() error { return s.listView(w, segments[1]) },
+		})
+	default:
+		err = newAPIError(http.StatusNotFound, errors.New("Not found"))
+	}
+	writeAPIError(w, err)
+}
+
+// authorized tells if the request carries the token of the API.
+func (s *apiServer) authorized(r *http.Request) bool {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || (s.token == "") {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(s.token)) == 1
+}
+
+// dispatch calls the handler for method of the request, and responds with 405 (Method Not Allowed) if there is none.
+func dispatch(w http.ResponseWriter, r *http.Request, handlers map[string]func() error) error {
+	if handler, ok := handlers[r.Method]; ok {
+		return handler()
+	}
+	methods := make([]string, 0, len(handlers))
+	for method := range handlers {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	return newAPIError(http.StatusMethodNotAllowed, errors.New("Method not allowed"))
+}
+
+// read reads the data file, without taking the lock on it.
+func (s *apiServer) read() (*model.ReminderData, error) {
+	return model.ReadDataFile(s.dataFile, true)
+}
+
+// update reads the data file and passes it to the change function, while holding the lock on the data file.
+func (s *apiServer) update(change func(rd *model.ReminderData) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lock, err := model.LockDataFile(s.dataFile)
+	if errors.Is(err, model.ErrorDataFileLocked) {
+		return newAPIError(http.StatusLocked, err)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		utils.LogError(lock.Release())
+	}()
+	reminderData, err := model.ReadDataFile(s.dataFile, true)
+	if err != nil {
+		return err
+	}
+	// there is no one to ask about conflicting changes
+	reminderData.SetConflictResolver(model.RejectConflicts)
+	rules, err := tagGroupRules()
+	if err != nil {
+		return err
+	}
+	reminderData.SetTagGroupRules(rules)
+	return change(reminderData)
+}
+
+// listNotes responds with the notes with given status (and tag, or tag query).
+func (s *apiServer) listNotes(w http.ResponseWriter, r *http.Request) error {
+	rd, err := s.read()
+	if err != nil {
+		return err
+	}
+	status := r.URL.Query().Get("status")
+	query := model.NoteQuery{Status: model.NoteStatus(status)}
+	switch status {
+	case "":
+		query.Status = model.NoteStatus_Pending
+	case "all":
+		query.Status = ""
+	}
+	if (r.URL.Query().Get("tag") != "") && (r.URL.Query().Get("query") != "") {
+		return newAPIError(http.StatusBadRequest, errors.New("Pass either a tag or a tag query"))
+	}
+	if tagSlug := r.URL.Query().Get("tag"); tagSlug != "" {
+		tag, err := findTag(rd, tagSlug)
+		if err != nil {
+			return newAPIError(http.StatusBadRequest, err)
+		}
+		query.TagExpr = model.TagSlugExpr(tag.Slug, rd.Tags)
+	}
+	if tagQuery := r.URL.Query().Get("query"); tagQuery != "" {
+		expr, err := tagExprFromText(rd, tagQuery)
+		if err != nil {
+			return newAPIError(http.StatusBadRequest, err)
+		}
+		query.TagExpr = expr
+	}
+	notes, err := rd.QueryNotes(query)
+	if err != nil {
+		return err
+	}
+	sort.Sort(notes)
+	views := make([]noteView, 0, len(notes))
+	for _, note := range notes {
+		views = append(views, newNoteView(rd, note))
+	}
+	return writeAPIJSON(w, http.StatusOK, views)
+}
+
+// createNote creates a new note.
+func (s *apiServer) createNote(w http.ResponseWriter, r *http.Request) error {
+	var body noteCreation
+	if err := decodeAPIRequest(w, r, &body); err != nil {
+		return err
+	}
+	return s.update(func(rd *model.ReminderData) error {
+		note, err := addNote(rd, body.Text, body.Tags, body.Due, body.Repeat, body.IsMain)
+		if err != nil {
+			return newAPIError(http.StatusBadRequest, err)
+		}
+		w.Header().Set("Location", APIPrefix+"notes/"+url.PathEscape(note.Id))
+		return writeNote(w, http.StatusCreated, rd, note)
+	})
+}
+
+// getNote responds with the note, unless it matches the If-None-Match header.
+func (s *apiServer) getNote(w http.ResponseWriter, r *http.Request, id string) error {
+	rd, err := s.read()
+	if err != nil {
+		return err
+	}
+	note, err := findNote(rd, id)
+	if err != nil {
+		return newAPIError(http.StatusNotFound, err)
+	}
+	if etag := noteETag(note); matchesETag(r.Header.Get("If-None-Match"), etag) {
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	return writeNote(w, http.StatusOK, rd, note)
+}
+
+// patchNote updates the passed fields of the note.
+func (s *apiServer) patchNote(w http.ResponseWriter, r *http.Request, id string) error {
+	var body notePatch
+	if err := decodeAPIRequest(w, r, &body); err != nil {
+		return err
+	}
+	// empty values clear the respective fields
+	for _, field := range []*string{body.Summary, body.Due, body.Repeat} {
+		if (field != nil) && (strings.TrimSpace(*field) == "") {
+			*field = "nil"
+		}
+	}
+	return s.updateNote(w, r, id, func(rd *model.ReminderData, note *model.Note) error {
+		// validate all the fields before updating any of them
+		if (body.Text != nil) && (strings.TrimSpace(*body.Text) == "") {
+			return errors.New("Note's text is empty")
+		}
+		if (body.Due != nil) && (*body.Due != "nil") {
+			if err := utils.ValidateDateString()(*body.Due); err != nil {
+				return err
+			}
+		}
+		if body.Repeat != nil {
+			recurrence, err := model.ParseRecurrence(*body.Repeat)
+			if err != nil {
+				return err
+			}
+			dueCleared := (body.Due != nil) && (*body.Due == "nil")
+			if (recurrence != nil) && (dueCleared || ((note.CompleteBy == 0) && (body.Due == nil))) {
+				return errors.New("Note's due date is required for recurrence")
+			}
+		}
+		var tagIDs []int
+		if body.Tags != nil {
+			var err error
+			if tagIDs, err = tagIdsFromSlugs(rd, *body.Tags); err != nil {
+				return err
+			}
+			if err := rd.CheckTagGroups(tagIDs); err != nil {
+				return err
+			}
+		}
+		// update the fields, as a single change of the note
+		return rd.EditNote(note, func() error {
+			if body.Text != nil {
+				if err := note.UpdateText(*body.Text); err != nil {
+					return err
+				}
+			}
+			if body.Summary != nil {
+				if err := note.UpdateSummary(*body.Summary); err != nil {
+					return err
+				}
+			}
+			if body.Due != nil {
+				if err := note.UpdateCompleteBy(*body.Due); err != nil {
+					return err
+				}
+			}
+			if body.Repeat != nil {
+				if err := note.UpdateRecurrence(*body.Repeat); err != nil {
+					return err
+				}
+			}
+			if body.Tags != nil {
+				if err := note.UpdateTags(tagIDs, rd.Tags, rd.TagGroupRules()); err != nil {
+					return err
+				}
+			}
+			if (body.IsMain != nil) && (*body.IsMain != note.IsMain) {
+				return note.ToggleMainFlag()
+			}
+			return nil
+		})
+	})
+}
+
+// trashNote moves the note to the trash.
+func (s *apiServer) trashNote(w http.ResponseWriter, r *http.Request, id string) error {
+	return s.updateNote(w, r, id, func(rd *model.ReminderData, note *model.Note) error {
+		return rd.TrashNote(note)
+	})
+}
+
+// addComment adds a comment to the note.
+func (s *apiServer) addComment(w http.ResponseWriter, r *http.Request, id string) error {
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := decodeAPIRequest(w, r, &body); err != nil {
+		return err
+	}
+	return s.updateNote(w, r, id, func(rd *model.ReminderData, note *model.Note) error {
+		return rd.AddNoteComment(note, body.Text)
+	})
+}
+
+// updateStatus updates status of the note.
+func (s *apiServer) updateStatus(w http.ResponseWriter, r *http.Request, id string) error {
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := decodeAPIRequest(w, r, &body); err != nil {
+		return err
+	}
+	status := model.NoteStatus(body.Status)
+	switch status {
+	case model.NoteStatus_Pending, model.NoteStatus_Suspended, model.NoteStatus_Done:
+	default:
+		return newAPIError(http.StatusBadRequest, fmt.Errorf("Unknown status %q; use pending, suspended or done", body.Status))
+	}
+	return s.updateNote(w, r, id, func(rd *model.ReminderData, note *model.Note) error {
+		return rd.UpdateNoteStatus(note, status)
+	})
+}
+
+// updateNote applies the change to the note (provided it matches the If-Match header), and responds with the note.
+// An error returned by the change function is responded with 400 (Bad Request).
+func (s *apiServer) updateNote(w http.ResponseWriter, r *http.Request, id string, change func(rd *model.ReminderData, note *model.Note) error) error {
+	return s.update(func(rd *model.ReminderData) error {
+		note, err := findNote(rd, id)
+		if err != nil {
+			return newAPIError(http.StatusNotFound, err)
+		}
+		if ifMatch := r.Header.Get("If-Match"); (ifMatch != "") && !matchesETag(ifMatch, noteETag(note)) {
+			w.Header().Set("ETag", noteETag(note))
+			return newAPIError(http.StatusPreconditionFailed, fmt.Errorf("The note %s has changed since it was fetched", note.Id))
+		}
+		if err := change(rd, note); err != nil {
+			return newAPIError(http.StatusBadRequest, err)
+		}
+		return writeNote(w, http.StatusOK, rd, note)
+	})
+}
+
+// listTags responds with all the tags.
+func (s *apiServer) listTags(w http.ResponseWriter) error {
+	rd, err := s.read()
+	if err != nil {
+		return err
+	}
+	rd.SortedTagSlugs()
+	views := make([]tagView, 0, len(rd.Tags))
+	for _, tag := range rd.Tags {
+		views = append(views, tagView{Id: tag.Id, Slug: tag.Slug, Group: tag.Group})
+	}
+	return writeAPIJSON(w, http.StatusOK, views)
+}
+
+// createTag creates a new tag.
+func (s *apiServer) createTag(w http.ResponseWriter, r *http.Request) error {
+	var body tagView
+	if err := decodeAPIRequest(w, r, &body); err != nil {
+		return err
+	}
+	if strings.TrimSpace(body.Slug) == "" || strings.TrimSpace(body.Group) == "" {
+		return newAPIError(http.StatusBadRequest, errors.New("Both slug and group are required"))
+	}
+	return s.update(func(rd *model.ReminderData) error {
+		tagID, err := rd.NewTagRegistration(body.Slug, body.Group)
+		if err != nil {
+			return newAPIError(http.StatusBadRequest, err)
+		}
+		tag := rd.Tags.FromIds([]int{tagID})[0]
+		return writeAPIJSON(w, http.StatusCreated, tagView{Id: tag.Id, Slug: tag.Slug, Group: tag.Group})
+	})
+}
+
+// listView responds with the pending notes of the view (as in the Main Menu), sorted by their projected due date.
+func (s *apiServer) listView(w http.ResponseWriter, view string) error {
+	window, ok := apiViews[view]
+	if !ok {
+		return newAPIError(http.StatusNotFound, fmt.Errorf("Unknown view %q; use approaching or look-ahead", view))
+	}
+	rd, err := s.read()
+	if err != nil {
+		return err
+	}
+	at := utils.CurrentUnixTimestamp()
+	occurrences := rd.ApproachingOccurrences(window, at)
+	sort.Stable(occurrences)
+	views := make([]occurrenceView, 0, len(occurrences))
+	for _, occurrence := range occurrences {
+		item := occurrenceView{noteView: newNoteView(rd, occurrence.Note)}
+		if occurrence.DueAt > 0 {
+			item.Due = occurrence.Note.DueText(occurrence.DueAt)
+			item.Overdue = occurrence.DueAt < at
+		}
+		views = append(views, item)
+	}
+	return writeAPIJSON(w, http.StatusOK, views)
+}
+
+// noteETag returns entity tag of the note, which changes whenever the note is updated.
+// As UpdatedAt is in seconds, the number of revisions of the note tells apart updates made within the same second.
+func noteETag(note *model.Note) string {
+	return fmt.Sprintf(`"%d-%d"`, note.UpdatedAt, len(note.History))
+}
+
+// matchesETag tells if the (If-Match or If-None-Match) header lists the entity tag, or is "*".
+func matchesETag(header string, etag string) bool {
+	for _, value := range strings.Split(header, ",") {
+		value = strings.TrimSpace(value)
+		if (value == "*") || (value == etag) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeAPIRequest decodes the JSON body of the request into value.
+func decodeAPIRequest(w http.ResponseWriter, r *http.Request, value interface{}) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil {
+		return newAPIError(http.StatusBadRequest, fmt.Errorf("Invalid request body: %w", err))
+	}
+	return nil
+}
+
+// writeNote responds with the note along with its entity tag.
+func writeNote(w http.ResponseWriter, status int, rd *model.ReminderData, note *model.Note) error {
+	w.Header().Set("ETag", noteETag(note))
+	return writeAPIJSON(w, status, newNoteView(rd, note))
+}
+
+// writeAPIJSON responds with value as JSON.
+func writeAPIJSON(w http.ResponseWriter, status int, value interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return printJSON(w, value)
+}
+
+// writeAPIError responds with the error (if any) as JSON; errors other than apiError are logged,
+// and responded with 500 (Internal Server Error).
+func writeAPIError(w http.ResponseWriter, err error) {
+	if err == nil {
+		return
+	}
+	status := http.StatusInternalServerError
+	var apiErr *apiError
+	switch {
+	case errors.Is(err, model.ErrorUnresolvedConflict):
+		status = http.StatusConflict
+	case errors.As(err, &apiErr):
+		status = apiErr.status
+	}
+	message := err.Error()
+	if status == http.StatusInternalServerError {
+		utils.LogError(err)
+		message = "Unable to process the request"
+	}
+	utils.LogError(writeAPIJSON(w, status, map[string]string{"error": message}))
+}
+
+// serveCommand serves the REST API of the data file, until interrupted.
+// It doesn't hold the lock on the data file; refer APIHandler.
+func serveCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, _ := newFlagSet("serve")
+	address := fs.String("address", "127.0.0.1:8766", "address to serve the API at")
+	token := fs.String("token", "", fmt.Sprintf("token the clients must send as \"Authorization: Bearer TOKEN\" (by default, %s env variable)", APITokenEnv))
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("Unexpected arguments %q", strings.Join(positional, " "))
+	}
+	if *token == "" {
+		*token = os.Getenv(APITokenEnv)
+	}
+	if strings.TrimSpace(*token) == "" {
+		return fmt.Errorf("The API token is empty; pass it with --token, or set %s env variable", APITokenEnv)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(APIPrefix, APIHandler(rd.DataFile, strings.TrimSpace(*token)))
+	return listenAndServe(*address, mux, func(addr net.Addr) {
+		fmt.Fprintf(out, "Serving the API at http://%s%s (press Ctrl-c to stop)\n", addr, APIPrefix)
+		logger.Info(fmt.Sprintf("Serving REST API of %q at %v", rd.DataFile, addr))
+	})
+}
@@ -0,0 +1,120 @@
+package reminder
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/goyalmunish/reminder/internal/model"
+)
+
+// printTag prints the tag.
+func printTag(tag *model.Tag, asJSON bool, out io.Writer) error {
+	if asJSON {
+		return printJSON(out, tagView{Id: tag.Id, Slug: tag.Slug, Group: tag.Group})
+	}
+	fmt.Fprintln(out, tag)
+	return nil
+}
+
+// printUpdatedNotes prints number of notes updated by merging or deleting the tag.
+func printUpdatedNotes(count int, asJSON bool, out io.Writer) error {
+	if asJSON {
+		return printJSON(out, map[string]interface{}{"updated_notes": count})
+	}
+	fmt.Fprintf(out, "Updated %d notes.\n", count)
+	return nil
+}
+
+// tagRenameCommand changes slug of the tag.
+func tagRenameCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("tag rename")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return errors.New("Pass the slug of the tag, and its new slug")
+	}
+	tag, err := findTag(rd, positional[0])
+	if err != nil {
+		return err
+	}
+	if err := rd.RenameTag(tag, positional[1]); err != nil {
+		return err
+	}
+	return printTag(tag, *asJSON, out)
+}
+
+// tagRegroupCommand changes group of the tag.
+func tagRegroupCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("tag regroup")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return errors.New("Pass the slug of the tag, and its new group (\"\" for no group)")
+	}
+	tag, err := findTag(rd, positional[0])
+	if err != nil {
+		return err
+	}
+	if err := rd.RegroupTag(tag, positional[1]); err != nil {
+		return err
+	}
+	return printTag(tag, *asJSON, out)
+}
+
+// tagMergeCommand merges the tag into another one.
+func tagMergeCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("tag merge")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return errors.New("Pass the slug of the tag, and of the tag to merge it into")
+	}
+	source, err := findTag(rd, positional[0])
+	if err != nil {
+		return err
+	}
+	target, err := findTag(rd, positional[1])
+	if err != nil {
+		return err
+	}
+	count, err := rd.MergeTags(source, target)
+	if err != nil {
+		return err
+	}
+	return printUpdatedNotes(count, *asJSON, out)
+}
+
+// tagDeleteCommand deletes the tag, detaching its notes from it (or reassigning them to another tag).
+func tagDeleteCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("tag delete")
+	reassign := fs.String("reassign", "", "slug of the tag to reassign the notes to (instead of detaching them)")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("Pass exactly one tag slug")
+	}
+	tag, err := findTag(rd, positional[0])
+	if err != nil {
+		return err
+	}
+	var reassignTo *model.Tag
+	if *reassign != "" {
+		if reassignTo, err = findTag(rd, *reassign); err != nil {
+			return err
+		}
+	}
+	count, err := rd.DeleteTag(tag, reassignTo)
+	if err != nil {
+		return err
+	}
+	return printUpdatedNotes(count, *asJSON, out)
+}
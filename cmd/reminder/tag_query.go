@@ -0,0 +1,81 @@
+package reminder
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/goyalmunish/reminder/internal/model"
+)
+
+// tagQueryView is the external (JSON) representation of a saved tag query.
+type tagQueryView struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+}
+
+// tagExprFromText returns the expression of the saved tag query with given name, or else parses the text
+// as an expression of tags.
+func tagExprFromText(rd *model.ReminderData, text string) (model.TagExpr, error) {
+	if tagQuery := rd.TagQueries.FromName(text); tagQuery != nil {
+		return rd.TagQueryExpr(tagQuery)
+	}
+	return model.ParseTagExpr(text, rd.Tags)
+}
+
+// querySaveCommand saves a tag query (replacing any existing query with the same name).
+func querySaveCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("query save")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return errors.New("Pass the name of the query, and the query (such as \"current AND NOT waiting\")")
+	}
+	tagQuery, err := rd.SaveTagQuery(positional[0], positional[1])
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(out, tagQueryView{Name: tagQuery.Name, Query: tagQuery.Query})
+	}
+	fmt.Fprintln(out, tagQuery)
+	return nil
+}
+
+// queryListCommand lists the saved tag queries.
+func queryListCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("query list")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *asJSON {
+		views := make([]tagQueryView, 0, len(rd.TagQueries))
+		for _, tagQuery := range rd.TagQueries {
+			views = append(views, tagQueryView{Name: tagQuery.Name, Query: tagQuery.Query})
+		}
+		return printJSON(out, views)
+	}
+	for _, tagQuery := range rd.TagQueries {
+		fmt.Fprintln(out, tagQuery)
+	}
+	return nil
+}
+
+// queryDeleteCommand deletes the saved tag query.
+func queryDeleteCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, _ := newFlagSet("query delete")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("Pass exactly one query name")
+	}
+	if err := rd.DeleteTagQuery(positional[0]); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Deleted the tag query %q.\n", positional[0])
+	return nil
+}
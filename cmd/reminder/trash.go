@@ -0,0 +1,146 @@
+package reminder
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/goyalmunish/reminder/internal/appinfo"
+	"github.com/goyalmunish/reminder/internal/model"
+)
+
+// appInfoOptions returns the app settings from the app config (or the default ones).
+func appInfoOptions() *appinfo.Options {
+	if config != nil && config.AppInfo != nil {
+		return config.AppInfo
+	}
+	return appinfo.DefaultOptions()
+}
+
+// noteTrashCommand moves the note to the trash.
+func noteTrashCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("note trash")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("Pass exactly one note id")
+	}
+	note, err := findNote(rd, positional[0])
+	if err != nil {
+		return err
+	}
+	if err := rd.TrashNote(note); err != nil {
+		return err
+	}
+	return printNotes(rd, model.Notes{note}, *asJSON, out)
+}
+
+// noteRestoreCommand restores the note from the trash.
+func noteRestoreCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("note restore")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("Pass exactly one note id")
+	}
+	note, err := findNoteIn(rd.Trash, positional[0])
+	if err != nil {
+		return err
+	}
+	if err := rd.RestoreNote(note); err != nil {
+		return err
+	}
+	return printNotes(rd, model.Notes{note}, *asJSON, out)
+}
+
+// notePurgeCommand permanently deletes the note from the trash.
+func notePurgeCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, _ := newFlagSet("note purge")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("Pass exactly one note id")
+	}
+	note, err := findNoteIn(rd.Trash, positional[0])
+	if err != nil {
+		return fmt.Errorf("%w; only a note in the trash can be purged", err)
+	}
+	if err := rd.PurgeNote(note); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Deleted the note %q permanently.\n", note.Text)
+	return nil
+}
+
+// trashListCommand lists the notes in the trash.
+func trashListCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("trash list")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	notes := append(model.Notes{}, rd.Trash...)
+	sort.Sort(notes)
+	return printNotes(rd, notes, *asJSON, out)
+}
+
+// trashPurgeCommand permanently deletes the notes which have been in the trash for long (or all of them).
+func trashPurgeCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("trash purge")
+	olderThan := fs.Int("older-than", appInfoOptions().TrashRetentionDays, "purge the notes which have been in the trash for these many days (0 keeps all of them)")
+	all := fs.Bool("all", false, "purge all the notes in the trash")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("Unexpected arguments %q", strings.Join(positional, " "))
+	}
+	var purged model.Notes
+	if *all {
+		purged, err = rd.PurgeTrash(0)
+	} else {
+		purged, err = rd.AutoPurgeTrash(*olderThan)
+	}
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		ids := make([]string, 0, len(purged))
+		for _, note := range purged {
+			ids = append(ids, note.Id)
+		}
+		return printJSON(out, map[string]interface{}{"purged": ids, "remaining": len(rd.Trash)})
+	}
+	fmt.Fprintf(out, "Purged %d notes from the trash (%d remaining).\n", len(purged), len(rd.Trash))
+	return nil
+}
+
+// archiveCommand moves the done notes not updated for long to the yearly archive files.
+func archiveCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, asJSON := newFlagSet("archive")
+	olderThan := fs.Int("older-than", appInfoOptions().ArchiveAfterDays, "archive the done notes not updated for these many days")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("Unexpected arguments %q", strings.Join(positional, " "))
+	}
+	archived, err := rd.ArchiveNotes(*olderThan)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printNotes(rd, archived, true, out)
+	}
+	fmt.Fprintf(out, "Archived %d notes.\n", len(archived))
+	return nil
+}
@@ -0,0 +1,23 @@
+package reminder
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/goyalmunish/reminder/internal/model"
+	"github.com/goyalmunish/reminder/internal/tui"
+)
+
+// tuiCommand runs the full-screen (multi-pane) terminal UI, holding the lock on the data file until it is quit.
+func tuiCommand(rd *model.ReminderData, args []string, out io.Writer) error {
+	fs, _ := newFlagSet("tui")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return fmt.Errorf("Unexpected arguments %q", strings.Join(positional, " "))
+	}
+	return tui.New(rd).Run()
+}
@@ -2,11 +2,22 @@ package appinfo
 
 type Options struct {
 	DataFile string `json:"data_file" yaml:"data_file" mapstructure:"data_file"`
+	// TrashRetentionDays is the number of days after which notes in the trash are purged (0 keeps them forever)
+	TrashRetentionDays int `json:"trash_retention_days" yaml:"trash_retention_days" mapstructure:"trash_retention_days"`
+	// ArchiveAfterDays is the number of days (since their last update) after which done notes are archived
+	ArchiveAfterDays int `json:"archive_after_days" yaml:"archive_after_days" mapstructure:"archive_after_days"`
+	// IncludeArchived tells if the archived notes are included in stats, search, and the views
+	IncludeArchived bool `json:"include_archived" yaml:"include_archived" mapstructure:"include_archived"`
+	// TagGroups are the rules ("single" or "multi") of the tag groups, by group name, on top of the built-in ones
+	TagGroups map[string]string `json:"tag_groups" yaml:"tag_groups" mapstructure:"tag_groups"`
 }
 
 func DefaultOptions() *Options {
 	dataFilePath := "~/reminder/data.json"
 	return &Options{
-		DataFile: dataFilePath,
+		DataFile:           dataFilePath,
+		TrashRetentionDays: 30,
+		ArchiveAfterDays:   365,
+		TagGroups:          map[string]string{},
 	}
 }
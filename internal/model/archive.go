@@ -0,0 +1,183 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+/*
+An Archive represents the notes archived for a year, which are kept in a (JSON) file next to the data file.
+
+The archived notes are read-only, and are left out everywhere unless asked for (refer SetIncludeArchived).
+*/
+type Archive struct {
+	Year  int   `json:"year"`
+	Notes Notes `json:"notes"`
+	BaseStruct
+}
+
+// ArchiveFilePath returns path of the archive file of the data file for given year, such as
+// "~/reminder/data_archive_2024.json" for "~/reminder/data.json" (archive files are JSON even for SQLite data files).
+func ArchiveFilePath(dataFilePath string, year int) string {
+	dataFilePath = utils.TryConvertTildaBasedPath(dataFilePath)
+	return fmt.Sprintf("%s_archive_%04d.json", strings.TrimSuffix(dataFilePath, path.Ext(dataFilePath)), year)
+}
+
+// archiveFilePaths returns paths of all the archive files of the data file, in order of their years.
+func archiveFilePaths(dataFilePath string) ([]string, error) {
+	dataFilePath = utils.TryConvertTildaBasedPath(dataFilePath)
+	pattern := strings.TrimSuffix(dataFilePath, path.Ext(dataFilePath)) + "_archive_[0-9][0-9][0-9][0-9].json"
+	filePaths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(filePaths)
+	return filePaths, nil
+}
+
+// ReadArchive reads the archive file; a missing file is read as an empty archive.
+func ReadArchive(filePath string) (*Archive, error) {
+	archive := &Archive{Notes: Notes{}}
+	byteValue, err := os.ReadFile(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return archive, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(byteValue, archive); err != nil {
+		return nil, fmt.Errorf("Unable to parse the archive file %q: %w", filePath, err)
+	}
+	return archive, nil
+}
+
+// write writes the archive to the file.
+func (archive *Archive) write(filePath string) error {
+	currentTime := utils.CurrentUnixTimestamp()
+	if archive.CreatedAt == 0 {
+		archive.CreatedAt = currentTime
+	}
+	archive.UpdatedAt = currentTime
+	byteValue, err := json.MarshalIndent(archive, "", "    ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(filePath, byteValue, 0644, false)
+}
+
+// ArchiveNotes moves the done notes which haven't been updated for at least given number of days to the yearly
+// archive files (as per the year in which they were last updated). It returns the archived notes.
+func (rd *ReminderData) ArchiveNotes(days int) (Notes, error) {
+	if days < 0 {
+		return nil, errors.New("Number of days cannot be negative")
+	}
+	currentTime := utils.CurrentUnixTimestamp()
+	cutoff := currentTime - int64(days)*24*60*60
+	byYear := make(map[int]Notes)
+	var years []int
+	var archived Notes
+	kept := Notes{}
+	for _, note := range rd.Notes {
+		if (note.Status != NoteStatus_Done) || (note.UpdatedAt > cutoff) {
+			kept = append(kept, note)
+			continue
+		}
+		// the note is left untouched until it is written to the archive file
+		archivedNote := *note
+		archivedNote.ArchivedAt = currentTime
+		year := utils.UnixTimestampToTime(note.UpdatedAt).Year()
+		if _, ok := byYear[year]; !ok {
+			years = append(years, year)
+		}
+		byYear[year] = append(byYear[year], &archivedNote)
+		archived = append(archived, &archivedNote)
+	}
+	if len(archived) == 0 {
+		return archived, nil
+	}
+	// write the archive files before removing the notes from the data file, so that no note is lost midway
+	sort.Ints(years)
+	for _, year := range years {
+		filePath := ArchiveFilePath(rd.DataFile, year)
+		archive, err := ReadArchive(filePath)
+		if err != nil {
+			return nil, err
+		}
+		archive.Year = year
+		for _, note := range byYear[year] {
+			// a note archived again (such as when the data file was not updated last time) replaces the older copy
+			archive.Notes = append(archive.Notes.WithoutId(note.Id), note)
+		}
+		if err := archive.write(filePath); err != nil {
+			return nil, err
+		}
+	}
+	rd.Notes = kept
+	rd.archived = nil
+	return archived, rd.UpdateDataFile(fmt.Sprintf("Archived %d notes.", len(archived)))
+}
+
+// ArchivedNotes returns the notes of all the archive files of the data file.
+// The notes are read once, and then reused for rest of the session.
+func (rd *ReminderData) ArchivedNotes() (Notes, error) {
+	if rd.archived != nil {
+		return rd.archived, nil
+	}
+	filePaths, err := archiveFilePaths(rd.DataFile)
+	if err != nil {
+		return nil, err
+	}
+	archived := Notes{}
+	for _, filePath := range filePaths {
+		archive, err := ReadArchive(filePath)
+		if err != nil {
+			return nil, err
+		}
+		archived = append(archived, archive.Notes...)
+	}
+	rd.archived = archived
+	return archived, nil
+}
+
+// SetIncludeArchived sets whether the archived notes are included in Stats, SearchNotes and the views
+// which aren't limited to pending notes (refer AllNotes).
+func (rd *ReminderData) SetIncludeArchived(include bool) {
+	rd.includeArchived = include
+}
+
+// IncludesArchived tells whether the archived notes are included (refer SetIncludeArchived).
+func (rd *ReminderData) IncludesArchived() bool {
+	return rd.includeArchived
+}
+
+// IncludedArchivedNotes returns the archived notes if they are to be included (refer SetIncludeArchived), or else nil.
+// An error in reading the archive files is logged, and then the archived notes are left out.
+func (rd *ReminderData) IncludedArchivedNotes() Notes {
+	if !rd.includeArchived {
+		return nil
+	}
+	archived, err := rd.ArchivedNotes()
+	if err != nil {
+		utils.LogError(err)
+		return nil
+	}
+	return archived
+}
+
+// AllNotes returns all the notes, followed by the archived ones if they are to be included (refer SetIncludeArchived).
+func (rd *ReminderData) AllNotes() Notes {
+	archived := rd.IncludedArchivedNotes()
+	if len(archived) == 0 {
+		return rd.Notes
+	}
+	return append(append(Notes{}, rd.Notes...), archived...)
+}
@@ -0,0 +1,77 @@
+package model_test
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/goyalmunish/reminder/internal/model"
+	utils "github.com/goyalmunish/reminder/pkg/utils"
+)
+
+func TestArchiveFilePath(t *testing.T) {
+	utils.AssertEqual(t, model.ArchiveFilePath("temp_test_dir/mydata.json", 2024), "temp_test_dir/mydata_archive_2024.json")
+	utils.AssertEqual(t, model.ArchiveFilePath("temp_test_dir/mydata.db", 2024), "temp_test_dir/mydata_archive_2024.json")
+}
+
+func TestArchiveNotes(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	defer func(ct func() time.Time) { utils.CurrentTime = ct }(utils.CurrentTime)
+	currentTime := time.Date(2028, 12, 20, 9, 0, 0, 0, time.UTC)
+	utils.CurrentTime = func() time.Time { return currentTime }
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, false)
+	// notes done in different years, and a pending one
+	bills, _ := reminderData.NewNoteRegistration([]int{}, "pay bills")
+	_ = reminderData.UpdateNoteStatus(bills, model.NoteStatus_Done)
+	currentTime = time.Date(2029, 3, 1, 9, 0, 0, 0, time.UTC)
+	bank, _ := reminderData.NewNoteRegistration([]int{}, "call bank")
+	_ = reminderData.UpdateNoteStatus(bank, model.NoteStatus_Done)
+	learn, _ := reminderData.NewNoteRegistration([]int{}, "learn go")
+	currentTime = time.Date(2030, 5, 10, 9, 0, 0, 0, time.UTC)
+	_, err := reminderData.ArchiveNotes(-1)
+	utils.AssertEqual(t, err != nil, true)
+	// nothing is archived if no done note is old enough
+	archived, err := reminderData.ArchiveNotes(800)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, len(archived), 0)
+	// archive the done notes
+	archived, err = reminderData.ArchiveNotes(365)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, len(archived), 2)
+	utils.AssertEqual(t, archived[0].ArchivedAt, utils.CurrentUnixTimestamp())
+	utils.AssertEqual(t, reminderData.Notes, model.Notes{learn})
+	// the notes are written to the yearly archive files
+	archive, err := model.ReadArchive(model.ArchiveFilePath(dataFilePath, 2028))
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, archive.Year, 2028)
+	utils.AssertEqual(t, len(archive.Notes), 1)
+	utils.AssertEqual(t, archive.Notes[0].Text, "pay bills")
+	archive, _ = model.ReadArchive(model.ArchiveFilePath(dataFilePath, 2029))
+	utils.AssertEqual(t, len(archive.Notes), 1)
+	utils.AssertEqual(t, archive.Notes[0].Text, "call bank")
+	archive, err = model.ReadArchive(model.ArchiveFilePath(dataFilePath, 2030))
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, len(archive.Notes), 0)
+	// the archived notes are left out unless asked for
+	reminderDataRe, _ := model.ReadDataFile(dataFilePath, false)
+	utils.AssertEqual(t, len(reminderDataRe.AllNotes()), 1)
+	stats, _ := reminderDataRe.Stats()
+	utils.AssertEqual(t, strings.Contains(stats, "Done Notes:      0\n"), true)
+	utils.AssertEqual(t, strings.Contains(stats, "Archived Notes"), false)
+	reminderDataRe.SetIncludeArchived(true)
+	utils.AssertEqual(t, len(reminderDataRe.AllNotes()), 3)
+	stats, _ = reminderDataRe.Stats()
+	utils.AssertEqual(t, strings.Contains(stats, "Pending Notes:   1/3\n"), true)
+	utils.AssertEqual(t, strings.Contains(stats, "Done Notes:      2\n"), true)
+	utils.AssertEqual(t, strings.Contains(stats, "Archived Notes:  2\n"), true)
+	// the archived notes cannot be changed
+	archivedNote := reminderDataRe.IncludedArchivedNotes()[0]
+	err = reminderDataRe.UpdateNoteStatus(archivedNote, model.NoteStatus_Pending)
+	utils.AssertEqual(t, errors.Is(err, model.ErrorNoteArchived), true)
+}
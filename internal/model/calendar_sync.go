@@ -0,0 +1,337 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/goyalmunish/reminder/pkg/calendar"
+	"github.com/goyalmunish/reminder/pkg/logger"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+/*
+A CalendarLink represents the calendar event of a note, as of the last sync.
+*/
+type CalendarLink struct {
+	EventId string `json:"event_id"`
+	Hash    string `json:"hash"`             // hash of the event (refer calendar.Event.Hash) as last synced
+	Version string `json:"version"`          // version of the event as last synced, as reported by the calendar
+	Start   string `json:"start,omitempty"`  // start of the event (refer calendar.Event.StartText) as last synced
+	DueAt   int64  `json:"due_at,omitempty"` // due date (or time) of the note as last synced
+}
+
+/*
+A CalendarConflict represents a note and its event which were both changed since the last sync.
+The note is kept as it is, and its event is updated as per the note.
+*/
+type CalendarConflict struct {
+	Note   *Note
+	Reason string
+}
+
+// String provides basic string representation of the conflict.
+func (conflict *CalendarConflict) String() string {
+	return fmt.Sprintf("%q: %s", conflict.Note.Text, conflict.Reason)
+}
+
+/*
+A CalendarSync represents outcome of syncing the notes with the calendar.
+*/
+type CalendarSync struct {
+	Pulled    int // number of notes updated as per their events
+	Inserted  int
+	Updated   int
+	Deleted   int
+	Unchanged int
+	Conflicts []*CalendarConflict
+}
+
+// String provides a summary of the sync.
+func (result *CalendarSync) String() string {
+	return fmt.Sprintf("%d pulled, %d inserted, %d updated, %d deleted, %d unchanged, %d conflicts",
+		result.Pulled, result.Inserted, result.Updated, result.Deleted, result.Unchanged, len(result.Conflicts))
+}
+
+// SyncCalendarEvents syncs pending notes (with due date) with the calendar, incrementally.
+//
+// Each note is linked to its event (by event id). First, the changes made in the calendar are pulled: an event
+// moved to another date (or time) updates due date of its note, and a deleted event suspends (or completes,
+// as per OnDelete option) its note. If the note was changed in the app as well since the last sync, it is
+// reported as a conflict, and the note is kept as it is.
+// Then, the notes are pushed: only the events of new notes are inserted, the events of changed notes (as per
+// hash of the event) are updated, and the events of the notes which are no longer pending (or have no due date)
+// are deleted. Other details of the events (such as the ones edited in the calendar) are left as they are.
+// The events registered by the app (with the title prefix) but not linked to any note, such as the ones from
+// earlier versions of the app, are deleted.
+// The sync carries on past the failing events, and the links of the synced ones are saved to the data file.
+func (rd *ReminderData) SyncCalendarEvents(provider calendar.Provider, calOptions *calendar.Options) (*CalendarSync, error) {
+	if err := calOptions.Validate(); err != nil {
+		return nil, err
+	}
+	dryMode := calOptions.DryMode
+	logger.Info("Fetch all the events registered by reminder app.")
+	currentTime := utils.CurrentTime()
+	reminderEvents, err := provider.List(calendar.TitlePrefix, currentTime.AddDate(-2, 0, 0), currentTime.AddDate(5, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+	timeZone := reminderEvents.TimeZone
+	result := &CalendarSync{}
+	var errs []error
+	changed := false
+	// pull the changes made in the calendar
+	listedEvents := make(map[string]*calendar.Event)
+	for _, event := range reminderEvents.Items {
+		listedEvents[event.Id] = event
+	}
+	repeatAnnuallyTagId, repeatMonthlyTagId := rd.RepeatTagIds()
+	repeatTagIDs := rd.TagIdsForGroup(RepeatTagGroup)
+	for _, note := range rd.Notes.WithStatus(NoteStatus_Pending) {
+		link := note.CalendarLink
+		if link == nil || note.CompleteBy == 0 {
+			continue
+		}
+		event := listedEvents[link.EventId]
+		if event == nil {
+			// the event is deleted, or is outside the listed period
+			event, err = provider.Get(link.EventId)
+			if err != nil && !calendar.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("Couldn't get the Calendar event %q of the note %q: %w", link.EventId, note.Text, err))
+				continue
+			}
+		}
+		if event == nil {
+			noteChanged, err := rd.noteChangedSinceSync(note, timeZone, repeatAnnuallyTagId, repeatMonthlyTagId)
+			if err != nil {
+				return nil, err
+			}
+			if noteChanged {
+				result.Conflicts = append(result.Conflicts, &CalendarConflict{Note: note, Reason: "the event was deleted in the calendar, but the note was changed in the app; the event is added again"})
+				if !dryMode {
+					note.CalendarLink = nil
+					changed = true
+				}
+				continue
+			}
+			result.Pulled++
+			status := NoteStatus_Suspended
+			if calOptions.OnDelete == calendar.OnDeleteDone && note.EffectiveRecurrence(repeatAnnuallyTagId, repeatMonthlyTagId) == nil {
+				status = NoteStatus_Done
+			}
+			fmt.Printf("  - The event of the note %q was deleted in the calendar; marking the note as %s\n", note.Text, status)
+			if dryMode {
+				logger.Warn("Dry mode is enabled; skipping update of the note.")
+				continue
+			}
+			note.CalendarLink = nil
+			changed = true
+			if err := rd.mutateNote(note, func() error {
+				return note.UpdateStatus(status, repeatTagIDs)
+			}); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if event.Version == link.Version {
+			continue
+		}
+		start := event.StartText()
+		if link.Start == "" || start == link.Start {
+			// the event was changed otherwise (or was synced before its start was tracked)
+			if !dryMode {
+				link.Version, link.Start, link.DueAt = event.Version, start, note.CompleteBy
+				changed = true
+			}
+			continue
+		}
+		dueAt, err := eventDueAt(event, note)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Couldn't read start of the Calendar event %q of the note %q: %w", link.EventId, note.Text, err))
+			continue
+		}
+		if note.CompleteBy != link.DueAt {
+			result.Conflicts = append(result.Conflicts, &CalendarConflict{Note: note, Reason: fmt.Sprintf("the event was moved to %s in the calendar, but the due date of the note was changed to %s in the app; the event is moved back", note.DueText(dueAt), note.DueText(note.CompleteBy))})
+			continue
+		}
+		result.Pulled++
+		fmt.Printf("  - The event of the note %q was moved in the calendar; updating the due date to %s\n", note.Text, note.DueText(dueAt))
+		if dryMode {
+			logger.Warn("Dry mode is enabled; skipping update of the note.")
+			continue
+		}
+		if err := rd.mutateNote(note, func() error {
+			note.CompleteBy = dueAt
+			note.UpdatedAt = utils.CurrentUnixTimestamp()
+			return nil
+		}); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		// the event is updated as per the updated note (such as its description)
+		link.Version, link.Start, link.DueAt = event.Version, start, dueAt
+		changed = true
+	}
+	// the events of pending notes with due date
+	events, err := rd.CalendarEvents(timeZone)
+	if err != nil {
+		return nil, err
+	}
+	wantedEvents := make(map[string]*calendar.Event)
+	for _, event := range events {
+		wantedEvents[event.NoteId] = event
+	}
+	// match the existing events with the notes
+	linkedNotes := make(map[string]*Note)
+	for _, note := range rd.Notes {
+		if note.CalendarLink != nil {
+			linkedNotes[note.CalendarLink.EventId] = note
+		}
+	}
+	var orphanEvents []*calendar.Event
+	for _, event := range reminderEvents.Items {
+		if linkedNotes[event.Id] != nil {
+			continue
+		}
+		// adopt the event of a note which isn't linked to it
+		if note := rd.FindNoteById(event.NoteId); note != nil && note.CalendarLink == nil && wantedEvents[note.Id] != nil {
+			logger.Info(fmt.Sprintf("Linking the event %q to its note.", event.Id))
+			if !dryMode {
+				// without hash, the event gets updated
+				note.CalendarLink = &CalendarLink{EventId: event.Id, Version: event.Version}
+				changed = true
+			}
+			linkedNotes[event.Id] = note
+			continue
+		}
+		orphanEvents = append(orphanEvents, event)
+	}
+	// insert, update, or delete the events of the notes
+	for _, note := range rd.Notes {
+		event := wantedEvents[note.Id]
+		link := note.CalendarLink
+		if event == nil && link == nil {
+			continue
+		}
+		var hash string
+		if event != nil {
+			if hash, err = event.Hash(); err != nil {
+				return nil, err
+			}
+		}
+		switch {
+		case link == nil:
+			result.Inserted++
+			fmt.Printf("  - Inserting the event %q\n", event)
+			if dryMode {
+				logger.Warn("Dry mode is enabled; skipping insertion of the event.")
+				continue
+			}
+			inserted, err := provider.Insert(event)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("Couldn't insert the Calendar event of the note %q: %w", note.Text, err))
+				continue
+			}
+			note.CalendarLink = newCalendarLink(inserted, hash, note)
+			changed = true
+		case event == nil:
+			result.Deleted++
+			fmt.Printf("  - Deleting the event %q of the note %q\n", link.EventId, note.Text)
+			if dryMode {
+				logger.Warn("Dry mode is enabled; skipping deletion of the event.")
+				continue
+			}
+			if err := provider.Delete(link.EventId); err != nil && !calendar.IsNotFound(err) {
+				errs = append(errs, fmt.Errorf("Couldn't delete the Calendar event %q of the note %q: %w", link.EventId, note.Text, err))
+				continue
+			}
+			note.CalendarLink = nil
+			changed = true
+		case link.Hash == hash:
+			result.Unchanged++
+		default:
+			result.Updated++
+			fmt.Printf("  - Updating the event %q\n", event)
+			if dryMode {
+				logger.Warn("Dry mode is enabled; skipping update of the event.")
+				continue
+			}
+			event.Id = link.EventId
+			updated, err := provider.Update(event)
+			if calendar.IsNotFound(err) {
+				// the event is gone (such as deleted in the calendar meanwhile), so it is inserted again
+				updated, err = provider.Insert(event)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("Couldn't update the Calendar event %q of the note %q: %w", link.EventId, note.Text, err))
+				continue
+			}
+			note.CalendarLink = newCalendarLink(updated, hash, note)
+			changed = true
+		}
+	}
+	// delete the events not linked to any note
+	for _, event := range orphanEvents {
+		result.Deleted++
+		fmt.Printf("  - Deleting the unlinked event %q\n", event)
+		if dryMode {
+			logger.Warn("Dry mode is enabled; skipping deletion of the event.")
+			continue
+		}
+		if err := provider.Delete(event.Id); err != nil && !calendar.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("Couldn't delete the Calendar event %q: %w", event.Id, err))
+		}
+	}
+	if changed {
+		if err := rd.UpdateDataFile(fmt.Sprintf("Synced the notes with the calendar (%v).", result)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// newCalendarLink returns link of the note to its event, as just synced.
+func newCalendarLink(event *calendar.Event, hash string, note *Note) *CalendarLink {
+	return &CalendarLink{EventId: event.Id, Hash: hash, Version: event.Version, Start: event.StartText(), DueAt: note.CompleteBy}
+}
+
+// noteChangedSinceSync tells if the note was changed since it was last synced to its event.
+func (rd *ReminderData) noteChangedSinceSync(note *Note, timeZone string, repeatAnnuallyTagId int, repeatMonthlyTagId int) (bool, error) {
+	event, err := note.CalendarEvent(repeatAnnuallyTagId, repeatMonthlyTagId, timeZone, rd)
+	if err != nil {
+		return false, err
+	}
+	hash, err := event.Hash()
+	if err != nil {
+		return false, err
+	}
+	return hash != note.CalendarLink.Hash, nil
+}
+
+// eventDueAt returns the due date (or time) of the note as per start of its (moved) event.
+// The due date (as last synced) is moved by as much as the event was moved since the last sync (by whole days,
+// for a note without due time), except for an all-day event, whose date is taken as it is.
+func eventDueAt(event *calendar.Event, note *Note) (int64, error) {
+	if event.Start.IsZero() {
+		return 0, errors.New("Event has no start")
+	}
+	if event.AllDay {
+		date := event.Start
+		if note.HasDueTime {
+			// keep the time of the day
+			due := time.Unix(note.CalendarLink.DueAt, 0).In(note.DueLocation())
+			return time.Date(date.Year(), date.Month(), date.Day(), due.Hour(), due.Minute(), 0, 0, note.DueLocation()).Unix(), nil
+		}
+		return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC).Unix(), nil
+	}
+	syncedStart, err := time.Parse(time.RFC3339, note.CalendarLink.Start)
+	if err != nil {
+		return 0, fmt.Errorf("Unknown start of the event as last synced: %w", err)
+	}
+	moved := event.Start.Sub(syncedStart)
+	if !note.HasDueTime {
+		moved = time.Duration(math.Round(moved.Hours()/24)) * 24 * time.Hour
+	}
+	return note.CalendarLink.DueAt + int64(moved.Seconds()), nil
+}
@@ -0,0 +1,245 @@
+package model_test
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/goyalmunish/reminder/internal/model"
+	"github.com/goyalmunish/reminder/pkg/calendar"
+	"github.com/goyalmunish/reminder/pkg/calendar/calendartest"
+	utils "github.com/goyalmunish/reminder/pkg/utils"
+)
+
+func TestSyncCalendarEvents(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, true)
+	call, _ := reminderData.NewNoteRegistration([]int{0}, "call bank")
+	_ = call.UpdateCompleteBy("12-05-2030 14:30 Europe/London")
+	rent, _ := reminderData.NewNoteRegistration([]int{0}, "pay rent")
+	_ = rent.UpdateCompleteBy("01-05-2030")
+	_ = rent.UpdateRecurrence("monthly")
+	_, _ = reminderData.NewNoteRegistration([]int{0}, "learn go")
+	calOptions, dryOptions := calendar.DefaultOptions(), calendar.DefaultOptions()
+	dryOptions.DryMode = true
+	events := calendartest.NewProvider()
+	legacy := events.Put(&calendar.Event{Title: "[reminder] pay rent"})
+	dentist := events.Put(&calendar.Event{Title: "dentist"})
+	// first sync inserts the events of the notes with due date, and deletes the unlinked ones
+	result, err := reminderData.SyncCalendarEvents(events, calOptions)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, result.String(), "0 pulled, 2 inserted, 0 updated, 1 deleted, 0 unchanged, 0 conflicts")
+	utils.AssertEqual(t, events.Event(legacy.Id) == nil, true)
+	utils.AssertEqual(t, events.Event(dentist.Id) != nil, true)
+	callEvent := events.Event(call.CalendarLink.EventId)
+	utils.AssertEqual(t, callEvent.Title, "[reminder] call bank")
+	utils.AssertEqual(t, callEvent.Start.Format(time.RFC3339), "2030-05-12T14:30:00+01:00")
+	utils.AssertEqual(t, events.Event(rent.CalendarLink.EventId).Recurrence, []string{"RRULE:FREQ=MONTHLY"})
+	// the links are saved
+	reminderDataRe, _ := model.ReadDataFile(dataFilePath, true)
+	utils.AssertEqual(t, reminderDataRe.FindNoteById(call.Id).CalendarLink, call.CalendarLink)
+	// nothing is changed when the notes aren't changed
+	events.Calls = nil
+	result, err = reminderData.SyncCalendarEvents(events, calOptions)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, result.String(), "0 pulled, 0 inserted, 0 updated, 0 deleted, 2 unchanged, 0 conflicts")
+	utils.AssertEqual(t, len(events.Calls), 0)
+	// changed notes are updated
+	call.Text = "call the bank"
+	rent.Recurrence = nil
+	result, err = reminderData.SyncCalendarEvents(events, calOptions)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, result.String(), "0 pulled, 0 inserted, 2 updated, 0 deleted, 0 unchanged, 0 conflicts")
+	callEvent = events.Event(call.CalendarLink.EventId)
+	utils.AssertEqual(t, callEvent.Title, "[reminder] call the bank")
+	utils.AssertEqual(t, len(events.Event(rent.CalendarLink.EventId).Recurrence), 0)
+	// an event deleted in the calendar is inserted again, if its note is changed too
+	events.Remove(rent.CalendarLink.EventId)
+	rent.Text = "pay the rent"
+	events.Calls = nil
+	result, err = reminderData.SyncCalendarEvents(events, calOptions)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, result.String(), "0 pulled, 1 inserted, 0 updated, 0 deleted, 1 unchanged, 1 conflicts")
+	utils.AssertEqual(t, result.Conflicts[0].Note, rent)
+	utils.AssertEqual(t, len(events.Calls), 1)
+	utils.AssertEqual(t, events.Event(rent.CalendarLink.EventId).Title, "[reminder] pay the rent")
+	// an event not linked to its note (such as after an interrupted sync) is linked again, instead of duplicated
+	rentEventId := rent.CalendarLink.EventId
+	rent.CalendarLink = nil
+	events.Calls = nil
+	result, err = reminderData.SyncCalendarEvents(events, calOptions)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, result.String(), "0 pulled, 0 inserted, 1 updated, 0 deleted, 1 unchanged, 0 conflicts")
+	utils.AssertEqual(t, events.Calls, []string{"update " + rentEventId})
+	utils.AssertEqual(t, rent.CalendarLink.EventId, rentEventId)
+	// the event of a note which is no longer pending is deleted
+	call.Status = model.NoteStatus_Done
+	callEventId := call.CalendarLink.EventId
+	result, err = reminderData.SyncCalendarEvents(events, calOptions)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, result.String(), "0 pulled, 0 inserted, 0 updated, 1 deleted, 1 unchanged, 0 conflicts")
+	utils.AssertEqual(t, events.Event(callEventId) == nil, true)
+	utils.AssertEqual(t, call.CalendarLink == nil, true)
+	// nothing is changed in dry mode
+	call.Status = model.NoteStatus_Pending
+	events.Calls = nil
+	result, err = reminderData.SyncCalendarEvents(events, dryOptions)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, result.String(), "0 pulled, 1 inserted, 0 updated, 0 deleted, 1 unchanged, 0 conflicts")
+	utils.AssertEqual(t, len(events.Calls), 0)
+	utils.AssertEqual(t, call.CalendarLink == nil, true)
+	utils.AssertEqual(t, len(events.Items()), 2)
+}
+
+func TestSyncCalendarEventsPull(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, true)
+	call, _ := reminderData.NewNoteRegistration([]int{0}, "call bank")
+	_ = call.UpdateCompleteBy("12-05-2030 14:30 Europe/London")
+	rent, _ := reminderData.NewNoteRegistration([]int{0}, "pay rent")
+	_ = rent.UpdateCompleteBy("01-05-2030")
+	_ = rent.UpdateRecurrence("monthly")
+	bills, _ := reminderData.NewNoteRegistration([]int{0}, "pay bills")
+	_ = bills.UpdateCompleteBy("20-05-2030")
+	calOptions := calendar.DefaultOptions()
+	events := calendartest.NewProvider()
+	events.TimeZone = "Asia/Kolkata"
+	_, err := reminderData.SyncCalendarEvents(events, calOptions)
+	utils.AssertEqual(t, err, nil)
+	// an event moved in the calendar moves its note
+	callEvent := events.Event(call.CalendarLink.EventId)
+	london, _ := time.LoadLocation("Europe/London")
+	callEvent.Start = time.Date(2030, 5, 14, 9, 0, 0, 0, london)
+	events.Put(callEvent)
+	rentEvent := events.Event(rent.CalendarLink.EventId)
+	rentEvent.Start = rentEvent.Start.Add(2 * 24 * time.Hour)
+	events.Put(rentEvent)
+	// an event edited otherwise is left as it is
+	billsEvent := events.Event(bills.CalendarLink.EventId)
+	billsEvent.Description = "at the bank"
+	events.Put(billsEvent)
+	result, err := reminderData.SyncCalendarEvents(events, calOptions)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, result.String(), "2 pulled, 0 inserted, 2 updated, 0 deleted, 1 unchanged, 0 conflicts")
+	utils.AssertEqual(t, call.DueText(call.CompleteBy), "14-May-30 09:00")
+	utils.AssertEqual(t, rent.CompleteBy, date(2030, 5, 3))
+	utils.AssertEqual(t, rent.Recurrence.String(), "monthly")
+	utils.AssertEqual(t, rent.History[len(rent.History)-1].Field, "complete_by")
+	utils.AssertEqual(t, events.Event(call.CalendarLink.EventId).Start.Equal(callEvent.Start), true)
+	utils.AssertEqual(t, events.Event(bills.CalendarLink.EventId).Description, "at the bank")
+	// the pulled changes are saved
+	reminderDataRe, _ := model.ReadDataFile(dataFilePath, true)
+	utils.AssertEqual(t, reminderDataRe.FindNoteById(rent.Id).CompleteBy, date(2030, 5, 3))
+	// a note changed in both the places is reported as conflict, and the note is kept
+	billsEvent = events.Event(bills.CalendarLink.EventId)
+	billsEvent.Start = billsEvent.Start.Add(5 * 24 * time.Hour)
+	events.Put(billsEvent)
+	_ = bills.UpdateCompleteBy("22-05-2030")
+	result, err = reminderData.SyncCalendarEvents(events, calOptions)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, result.String(), "0 pulled, 0 inserted, 1 updated, 0 deleted, 2 unchanged, 1 conflicts")
+	utils.AssertEqual(t, result.Conflicts[0].String(), `"pay bills": the event was moved to 25-May-30 in the calendar, but the due date of the note was changed to 22-May-30 in the app; the event is moved back`)
+	utils.AssertEqual(t, bills.CompleteBy, date(2030, 5, 22))
+	wantEvent, _ := bills.CalendarEvent(-1, -1, "Asia/Kolkata", TestTagger{})
+	utils.AssertEqual(t, events.Event(bills.CalendarLink.EventId).StartText(), wantEvent.StartText())
+	// a note whose event is deleted in the calendar is suspended (or done, as per the options)
+	calOptions.OnDelete = calendar.OnDeleteDone
+	events.Remove(rent.CalendarLink.EventId)
+	events.Remove(bills.CalendarLink.EventId)
+	result, err = reminderData.SyncCalendarEvents(events, calOptions)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, result.String(), "2 pulled, 0 inserted, 0 updated, 0 deleted, 1 unchanged, 0 conflicts")
+	utils.AssertEqual(t, bills.Status, model.NoteStatus_Done)
+	utils.AssertEqual(t, bills.CalendarLink == nil, true)
+	// a recurring note can't be done
+	utils.AssertEqual(t, rent.Status, model.NoteStatus_Suspended)
+	utils.AssertEqual(t, len(events.Items()), 1)
+	// invalid options
+	calOptions.OnDelete = "archive"
+	_, err = reminderData.SyncCalendarEvents(events, calOptions)
+	utils.AssertEqual(t, err.Error(), `Invalid on_delete "archive" of the calendar; it should be "suspend" or "done"`)
+}
+
+func TestSyncCalendarEventsTrashedNote(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, true)
+	call, _ := reminderData.NewNoteRegistration([]int{0}, "call bank")
+	_ = call.UpdateCompleteBy("12-05-2030")
+	calOptions := calendar.DefaultOptions()
+	calOptions.OnDelete = calendar.OnDeleteDone
+	events := calendartest.NewProvider()
+	_, err := reminderData.SyncCalendarEvents(events, calOptions)
+	utils.AssertEqual(t, err, nil)
+	// the event of a trashed note is deleted
+	callEventId := call.CalendarLink.EventId
+	_ = reminderData.TrashNote(call)
+	result, err := reminderData.SyncCalendarEvents(events, calOptions)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, result.String(), "0 pulled, 0 inserted, 0 updated, 1 deleted, 0 unchanged, 0 conflicts")
+	utils.AssertEqual(t, events.Event(callEventId) == nil, true)
+	// a restored note gets its event again, rather than being taken as deleted in the calendar
+	_ = reminderData.RestoreNote(call)
+	result, err = reminderData.SyncCalendarEvents(events, calOptions)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, result.String(), "0 pulled, 1 inserted, 0 updated, 0 deleted, 0 unchanged, 0 conflicts")
+	utils.AssertEqual(t, call.Status, model.NoteStatus_Pending)
+	utils.AssertEqual(t, events.Event(call.CalendarLink.EventId).Title, "[reminder] call bank")
+	// a note restored before the sync is linked to its event again
+	_ = reminderData.TrashNote(call)
+	_ = reminderData.RestoreNote(call)
+	result, err = reminderData.SyncCalendarEvents(events, calOptions)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, result.String(), "0 pulled, 0 inserted, 1 updated, 0 deleted, 0 unchanged, 0 conflicts")
+	utils.AssertEqual(t, len(events.Items()), 1)
+}
+
+func TestSyncCalendarEventsCalDAV(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, true)
+	call, _ := reminderData.NewNoteRegistration([]int{0}, "call bank")
+	_ = call.UpdateCompleteBy("12-05-2030 14:30 Europe/London")
+	rent, _ := reminderData.NewNoteRegistration([]int{0}, "pay rent")
+	_ = rent.UpdateCompleteBy("01-05-2030")
+	_ = rent.UpdateRecurrence("monthly")
+	server := calendartest.NewCalDAVServer("/calendars/me/work/")
+	defer server.Close()
+	calOptions := calendar.DefaultOptions()
+	calOptions.Provider, calOptions.CalendarId = calendar.ProviderCalDAV, server.URL()
+	provider, err := calendar.NewProvider(calOptions)
+	utils.AssertEqual(t, err, nil)
+	result, err := reminderData.SyncCalendarEvents(provider, calOptions)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, result.String(), "0 pulled, 2 inserted, 0 updated, 0 deleted, 0 unchanged, 0 conflicts")
+	utils.AssertEqual(t, len(server.Paths()), 2)
+	utils.AssertEqual(t, call.CalendarLink.EventId, "/calendars/me/work/"+call.Id+".ics")
+	result, err = reminderData.SyncCalendarEvents(provider, calOptions)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, result.String(), "0 pulled, 0 inserted, 0 updated, 0 deleted, 2 unchanged, 0 conflicts")
+	// an event moved by another client moves its note
+	data := server.Resource(call.CalendarLink.EventId)
+	server.PutResource(call.CalendarLink.EventId, strings.Replace(data, "20300512T143000", "20300513T090000", 1))
+	result, err = reminderData.SyncCalendarEvents(provider, calOptions)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, result.String(), "1 pulled, 0 inserted, 1 updated, 0 deleted, 1 unchanged, 0 conflicts")
+	utils.AssertEqual(t, call.DueText(call.CompleteBy), "13-May-30 09:00")
+	// a note whose event is deleted by another client is suspended
+	_ = provider.Delete(rent.CalendarLink.EventId)
+	result, err = reminderData.SyncCalendarEvents(provider, calOptions)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, result.String(), "1 pulled, 0 inserted, 0 updated, 0 deleted, 1 unchanged, 0 conflicts")
+	utils.AssertEqual(t, rent.Status, model.NoteStatus_Suspended)
+}
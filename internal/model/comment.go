@@ -15,6 +15,7 @@ A comment belongs to a particular note,
 whereas a note can have multiple comments
 */
 type Comment struct {
+	Id   string `json:"id"` // stable unique identifier (UUID) of the comment
 	Text string `json:"text"`
 	BaseStruct
 }
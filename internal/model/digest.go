@@ -0,0 +1,187 @@
+package model
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+// DigestViews are the views (as in the Main Menu) which can be included in a digest, in their display order.
+var DigestViews = []string{"approaching", "look-ahead", "main"}
+
+// digestViewTitles are the titles of the views of a digest.
+var digestViewTitles = map[string]string{
+	"approaching": "Approaching Due Date",
+	"look-ahead":  "Look Ahead",
+	"main":        "Main Notes",
+}
+
+/*
+A Digest represents summary of pending notes (from one or more views) to be sent to the user.
+*/
+type Digest struct {
+	User        User
+	GeneratedAt time.Time
+	Sections    []DigestSection
+	NumDue      int // number of (distinct) notes due within the digest's period, but not overdue
+	NumOverdue  int // number of (distinct) notes with passed due date
+}
+
+/*
+A DigestSection represents a view of a digest, with its notes grouped by tag.
+*/
+type DigestSection struct {
+	Title  string
+	Groups []DigestGroup
+}
+
+/*
+A DigestGroup represents notes of a section under a tag, sorted by their due date.
+*/
+type DigestGroup struct {
+	Tag   string
+	Items []DigestItem
+}
+
+/*
+A DigestItem represents a note (with its projected due date) in a digest.
+*/
+type DigestItem struct {
+	Id      string
+	Text    string
+	Tags    []string
+	Due     string // empty if the note has no due date
+	Repeat  string // empty if the note is not recurring
+	Overdue bool
+	dueAt   int64
+}
+
+// Digest builds digest of the pending notes from given views (refer DigestViews), as seen at given timestamp.
+func (rd *ReminderData) Digest(views []string, at int64) (*Digest, error) {
+	digest := &Digest{GeneratedAt: utils.UnixTimestampToTime(at)}
+	if rd.User != nil {
+		digest.User = *rd.User
+	}
+	repeatAnnuallyTagId, repeatMonthlyTagId := rd.RepeatTagIds()
+	due, overdue := map[string]bool{}, map[string]bool{}
+	for _, view := range views {
+		var occurrences Occurrences
+		switch view {
+		case "approaching":
+			occurrences = rd.ApproachingOccurrences("default", at)
+		case "look-ahead":
+			occurrences = rd.ApproachingOccurrences("long", at)
+		case "main":
+			occurrences = rd.NoteOccurrences(rd.Notes.OnlyMain().WithStatus(NoteStatus_Pending), "default", at)
+		default:
+			return nil, fmt.Errorf("Unknown view %q; use one of %s", view, strings.Join(DigestViews, ", "))
+		}
+		// group the notes by their (first) tag
+		groups := map[string][]DigestItem{}
+		for _, occurrence := range occurrences {
+			note := occurrence.Note
+			item := DigestItem{Id: note.Id, Text: note.Text, Tags: rd.TagsFromIds(note.TagIds), dueAt: occurrence.DueAt}
+			if occurrence.DueAt > 0 {
+				item.Due = note.DueText(occurrence.DueAt)
+				item.Overdue = occurrence.DueAt < at
+				if item.Overdue {
+					overdue[note.Id] = true
+				} else if occurrence.IsActive(at) {
+					due[note.Id] = true
+				}
+			}
+			if recurrence := note.EffectiveRecurrence(repeatAnnuallyTagId, repeatMonthlyTagId); recurrence != nil {
+				item.Repeat = recurrence.String()
+			}
+			tag := "untagged"
+			if len(item.Tags) > 0 {
+				tag = item.Tags[0]
+			}
+			groups[tag] = append(groups[tag], item)
+		}
+		section := DigestSection{Title: digestViewTitles[view]}
+		for tag, items := range groups {
+			// notes without due date go last
+			sort.SliceStable(items, func(i, j int) bool {
+				if (items[i].dueAt == 0) != (items[j].dueAt == 0) {
+					return items[j].dueAt == 0
+				}
+				return items[i].dueAt < items[j].dueAt
+			})
+			section.Groups = append(section.Groups, DigestGroup{Tag: tag, Items: items})
+		}
+		sort.Slice(section.Groups, func(i, j int) bool { return section.Groups[i].Tag < section.Groups[j].Tag })
+		digest.Sections = append(digest.Sections, section)
+	}
+	digest.NumDue, digest.NumOverdue = len(due), len(overdue)
+	return digest, nil
+}
+
+// Subject returns subject line of the digest.
+func (digest *Digest) Subject() string {
+	return fmt.Sprintf("Reminder digest for %s: %d due, %d overdue", digest.GeneratedAt.Format("02-Jan-06"), digest.NumDue, digest.NumOverdue)
+}
+
+// DefaultDigestTextTemplate is the built-in plain-text template of a digest.
+const DefaultDigestTextTemplate = `Hi {{if .User.Name}}{{.User.Name}}{{else}}there{{end}},
+
+Here is your reminder digest ({{.NumDue}} due, {{.NumOverdue}} overdue).
+{{range .Sections}}
+== {{.Title}} ==
+{{range .Groups}}
+#{{.Tag}}
+{{range .Items}}  - {{.Text}}{{if .Due}} (due {{.Due}}{{if .Overdue}}, OVERDUE{{end}}){{end}}{{if .Repeat}} [repeats {{.Repeat}}]{{end}}
+{{end}}{{else}}
+  Nothing here.
+{{end}}{{end}}
+Generated at {{.GeneratedAt.Format "Mon, 02 Jan 2006 15:04 MST"}}.
+`
+
+// DefaultDigestHTMLTemplate is the built-in HTML template of a digest.
+const DefaultDigestHTMLTemplate = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif;">
+<p>Hi {{if .User.Name}}{{.User.Name}}{{else}}there{{end}},</p>
+<p>Here is your reminder digest ({{.NumDue}} due, {{.NumOverdue}} overdue).</p>
+{{range .Sections}}<h2>{{.Title}}</h2>
+{{range .Groups}}<h3>#{{.Tag}}</h3>
+<ul>
+{{range .Items}}<li>{{.Text}}{{if .Due}} <em{{if .Overdue}} style="color: #c00;"{{end}}>(due {{.Due}}{{if .Overdue}}, overdue{{end}})</em>{{end}}{{if .Repeat}} <small>[repeats {{.Repeat}}]</small>{{end}}</li>
+{{end}}</ul>
+{{else}}<p>Nothing here.</p>
+{{end}}{{end}}<p><small>Generated at {{.GeneratedAt.Format "Mon, 02 Jan 2006 15:04 MST"}}.</small></p>
+</body>
+</html>
+`
+
+// RenderText renders the digest with given text template (refer DefaultDigestTextTemplate).
+func (digest *Digest) RenderText(templateText string) (string, error) {
+	tmpl, err := texttemplate.New("digest").Parse(templateText)
+	if err != nil {
+		return "", err
+	}
+	var result strings.Builder
+	if err := tmpl.Execute(&result, digest); err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// RenderHTML renders the digest with given HTML template (refer DefaultDigestHTMLTemplate).
+// The notes are escaped as per their context in the template.
+func (digest *Digest) RenderHTML(templateText string) (string, error) {
+	tmpl, err := htmltemplate.New("digest").Parse(templateText)
+	if err != nil {
+		return "", err
+	}
+	var result strings.Builder
+	if err := tmpl.Execute(&result, digest); err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
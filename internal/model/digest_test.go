@@ -0,0 +1,97 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/goyalmunish/reminder/internal/model"
+	utils "github.com/goyalmunish/reminder/pkg/utils"
+)
+
+func TestDigest(t *testing.T) {
+	utils.Location = utils.UTCLocation()
+	at := time.Date(2024, 2, 6, 10, 0, 0, 0, time.UTC).Unix()
+	recurrence, _ := model.ParseRecurrence("monthly")
+	reminderData := &model.ReminderData{
+		User: &model.User{Name: "Jane", EmailId: "jane@example.com"},
+		Tags: model.Tags{{Id: 0, Slug: "current"}, {Id: 1, Slug: "bills"}},
+		Notes: model.Notes{
+			{Id: "a", Text: "pay rent", Status: model.NoteStatus_Pending, TagIds: []int{1}, CompleteBy: date(2023, 11, 5), Recurrence: recurrence},
+			{Id: "b", Text: "file <taxes>", Status: model.NoteStatus_Pending, TagIds: []int{0}, CompleteBy: date(2024, 2, 1), IsMain: true},
+			{Id: "c", Text: "call mom", Status: model.NoteStatus_Pending, TagIds: []int{0}, CompleteBy: date(2024, 2, 10)},
+			{Id: "d", Text: "learn go", Status: model.NoteStatus_Pending, IsMain: true},
+			{Id: "e", Text: "renew passport", Status: model.NoteStatus_Pending, TagIds: []int{0}, CompleteBy: date(2024, 8, 1)},
+			{Id: "f", Text: "done already", Status: model.NoteStatus_Done, TagIds: []int{0}, CompleteBy: date(2024, 2, 7)},
+		},
+	}
+	digest, err := reminderData.Digest([]string{"approaching", "main"}, at)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, digest.User, *reminderData.User)
+	utils.AssertEqual(t, digest.NumDue, 1)
+	utils.AssertEqual(t, digest.NumOverdue, 2)
+	utils.AssertEqual(t, digest.Subject(), "Reminder digest for 06-Feb-24: 1 due, 2 overdue")
+	utils.AssertEqual(t, len(digest.Sections), 2)
+	// notes are grouped by tag, and sorted by due date
+	approaching := digest.Sections[0]
+	utils.AssertEqual(t, approaching.Title, "Approaching Due Date")
+	utils.AssertEqual(t, len(approaching.Groups), 2)
+	utils.AssertEqual(t, approaching.Groups[0].Tag, "bills")
+	utils.AssertEqual(t, approaching.Groups[0].Items[0].Due, "05-Feb-24")
+	utils.AssertEqual(t, approaching.Groups[0].Items[0].Repeat, "monthly")
+	utils.AssertEqual(t, approaching.Groups[1].Tag, "current")
+	utils.AssertEqual(t, len(approaching.Groups[1].Items), 2)
+	utils.AssertEqual(t, approaching.Groups[1].Items[0].Text, "file <taxes>")
+	utils.AssertEqual(t, approaching.Groups[1].Items[0].Overdue, true)
+	utils.AssertEqual(t, approaching.Groups[1].Items[1].Text, "call mom")
+	// notes without tag and due date are listed too (at the end)
+	main := digest.Sections[1]
+	utils.AssertEqual(t, main.Title, "Main Notes")
+	utils.AssertEqual(t, main.Groups[1].Tag, "untagged")
+	utils.AssertEqual(t, main.Groups[1].Items[0].Due, "")
+	// the look-ahead view includes notes due much later
+	digest, _ = reminderData.Digest([]string{"look-ahead"}, at)
+	utils.AssertEqual(t, len(digest.Sections[0].Groups[1].Items), 3)
+	// unknown views are reported
+	_, err = reminderData.Digest([]string{"someday"}, at)
+	utils.AssertEqual(t, strings.HasPrefix(err.Error(), "Unknown view"), true)
+}
+
+func TestDigestRender(t *testing.T) {
+	utils.Location = utils.UTCLocation()
+	at := time.Date(2024, 2, 6, 10, 0, 0, 0, time.UTC).Unix()
+	reminderData := &model.ReminderData{
+		User:  &model.User{Name: "Jane"},
+		Tags:  model.Tags{{Id: 0, Slug: "current"}},
+		Notes: model.Notes{{Id: "b", Text: "file <taxes>", Status: model.NoteStatus_Pending, TagIds: []int{0}, CompleteBy: date(2024, 2, 1)}},
+	}
+	digest, _ := reminderData.Digest([]string{"approaching", "main"}, at)
+	text, err := digest.RenderText(model.DefaultDigestTextTemplate)
+	utils.AssertEqual(t, err, nil)
+	want := `Hi Jane,
+
+Here is your reminder digest (0 due, 1 overdue).
+
+== Approaching Due Date ==
+
+#current
+  - file <taxes> (due 01-Feb-24, OVERDUE)
+
+== Main Notes ==
+
+  Nothing here.
+
+Generated at Tue, 06 Feb 2024 10:00 UTC.
+`
+	utils.AssertEqual(t, text, want)
+	// the html is escaped
+	html, err := digest.RenderHTML(model.DefaultDigestHTMLTemplate)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, strings.Contains(html, "<li>file &lt;taxes&gt; <em style=\"color: #c00;\">(due 01-Feb-24, overdue)</em></li>"), true)
+	// custom templates
+	text, err = digest.RenderText("{{range .Sections}}{{.Title}};{{end}}")
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, text, "Approaching Due Date;Main Notes;")
+	_, err = digest.RenderHTML("{{.Unknown}}")
+	utils.AssertEqual(t, err != nil, true)
+}
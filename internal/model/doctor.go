@@ -0,0 +1,108 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+// ProblemKind is the kind of an inconsistency found in the data.
+type ProblemKind string
+
+const (
+	ProblemKind_UnknownTag ProblemKind = "unknown_tag" // a note refers to a tag which doesn't exist
+	ProblemKind_TagGroup   ProblemKind = "tag_group"   // a note has more than one tag of a single-choice group
+)
+
+/*
+A Problem represents an inconsistency found in a note (refer Diagnose), which can be fixed with FixProblems.
+*/
+type Problem struct {
+	Note    *Note
+	Kind    ProblemKind
+	Message string
+}
+
+// String provides basic string representation of a problem.
+func (problem *Problem) String() string {
+	return fmt.Sprintf("%v: %v", problem.Note.Text, problem.Message)
+}
+
+// Diagnose returns the problems found in the notes (including the ones in the trash), in order of the notes.
+// The archived notes are left out, as they are read-only.
+func (rd *ReminderData) Diagnose() []*Problem {
+	var problems []*Problem
+	rules := rd.TagGroupRules()
+	for _, notes := range []Notes{rd.Notes, rd.Trash} {
+		for _, note := range notes {
+			if unknownTagIDs := rd.unknownTagIds(note.TagIds); len(unknownTagIDs) > 0 {
+				problems = append(problems, &Problem{
+					Note:    note,
+					Kind:    ProblemKind_UnknownTag,
+					Message: fmt.Sprintf("refers to tags %v which don't exist", unknownTagIDs),
+				})
+			}
+			for _, text := range violationTexts(rd.Tags.GroupViolations(note.TagIds, rules)) {
+				problems = append(problems, &Problem{Note: note, Kind: ProblemKind_TagGroup, Message: text})
+			}
+		}
+	}
+	return problems
+}
+
+// FixProblems fixes the problems found by Diagnose, and returns them. The unknown tags are removed from the notes,
+// and of the tags of a single-choice group, a note keeps only the one added last (that is, the last one of its tags).
+// The changes are recorded in history of the notes (but cannot be undone, as with setNoteTagIds).
+func (rd *ReminderData) FixProblems() ([]*Problem, error) {
+	problems := rd.Diagnose()
+	if len(problems) == 0 {
+		return problems, nil
+	}
+	rules := rd.TagGroupRules()
+	fixed := make(map[*Note]bool)
+	for _, problem := range problems {
+		note := problem.Note
+		if fixed[note] {
+			continue
+		}
+		fixed[note] = true
+		unknownTagIDs := rd.unknownTagIds(note.TagIds)
+		seenGroups := make(map[string]bool)
+		var tagIDs []int
+		// go through the tags in reverse order, so that the last tag of a group is kept
+		for index := len(note.TagIds) - 1; index >= 0; index-- {
+			tagID := note.TagIds[index]
+			if utils.IsMemberOfSlice(tagID, unknownTagIDs) {
+				continue
+			}
+			group := rd.Tags.FromIds([]int{tagID})[0].Group
+			if rules.Rule(group) == TagGroupRule_Single {
+				if seenGroups[group] {
+					continue
+				}
+				seenGroups[group] = true
+			}
+			tagIDs = append([]int{tagID}, tagIDs...)
+		}
+		if tagIDs == nil {
+			tagIDs = []int{}
+		}
+		if err := rd.setNoteTagIds(note, tagIDs); err != nil {
+			return nil, err
+		}
+	}
+	return problems, rd.UpdateDataFile(fmt.Sprintf("Fixed %d problems in %d notes.", len(problems), len(fixed)))
+}
+
+// unknownTagIds returns the tagIDs which aren't ids of any of the tags, in sorted order.
+func (rd *ReminderData) unknownTagIds(tagIDs []int) []int {
+	var unknownTagIDs []int
+	for _, tagID := range tagIDs {
+		if (len(rd.Tags.FromIds([]int{tagID})) == 0) && !utils.IsMemberOfSlice(tagID, unknownTagIDs) {
+			unknownTagIDs = append(unknownTagIDs, tagID)
+		}
+	}
+	sort.Ints(unknownTagIDs)
+	return unknownTagIDs
+}
@@ -0,0 +1,52 @@
+package model_test
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	model "github.com/goyalmunish/reminder/internal/model"
+	utils "github.com/goyalmunish/reminder/pkg/utils"
+)
+
+func TestDiagnoseAndFixProblems(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, false)
+	current := reminderData.TagFromSlug("current").Id
+	urgent := reminderData.TagFromSlug("priority-urgent").Id
+	medium := reminderData.TagFromSlug("priority-medium").Id
+	low := reminderData.TagFromSlug("priority-low").Id
+	// notes added while a note could have more than one priority
+	reminderData.SetTagGroupRules(model.TagGroupRules{"priority": model.TagGroupRule_Multi})
+	bills, _ := reminderData.NewNoteRegistration([]int{urgent, current, low}, "pay bills")
+	bank, _ := reminderData.NewNoteRegistration([]int{current, 42}, "call bank")
+	learn, _ := reminderData.NewNoteRegistration([]int{medium, urgent}, "learn go")
+	_ = reminderData.TrashNote(learn)
+	utils.AssertEqual(t, len(reminderData.Diagnose()), 1)
+	// the problems are found as per the current rules
+	reminderData.SetTagGroupRules(nil)
+	problems := reminderData.Diagnose()
+	utils.AssertEqual(t, len(problems), 3)
+	utils.AssertEqual(t, problems[0].Note, bills)
+	utils.AssertEqual(t, problems[0].Kind, model.ProblemKind_TagGroup)
+	utils.AssertEqual(t, problems[1].String(), "call bank: refers to tags [42] which don't exist")
+	utils.AssertEqual(t, problems[2].Note, learn)
+	// fix the problems (the tag added last is kept)
+	fixed, err := reminderData.FixProblems()
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, len(fixed), 3)
+	utils.AssertEqual(t, bills.TagIds, []int{current, low})
+	utils.AssertEqual(t, bank.TagIds, []int{current})
+	utils.AssertEqual(t, learn.TagIds, []int{urgent})
+	utils.AssertEqual(t, bills.History[len(bills.History)-1].Field, "tag_ids")
+	utils.AssertEqual(t, len(reminderData.Diagnose()), 0)
+	// the fixes are saved
+	reminderDataRe, _ := model.ReadDataFile(dataFilePath, false)
+	utils.AssertEqual(t, len(reminderDataRe.Diagnose()), 0)
+	fixed, err = reminderDataRe.FixProblems()
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, len(fixed), 0)
+}
@@ -3,7 +3,18 @@ package model
 import "errors"
 
 var (
-	ErrorConflictFile              = errors.New("Created _CONFLICT file")
-	ErrorMutexLockOn               = errors.New("Mutex Lock is ON; there is already a session running!")
-	ErrorInteractiveProcessSkipped = errors.New("Skipped running the interactive process. Try again!")
+	ErrorConflictFile   = errors.New("Created _CONFLICT file")
+	ErrorDataFileLocked = errors.New("Data file is locked; there is already a session running!")
+	// ErrorUnresolvedConflict is returned by a resolver which doesn't resolve merge conflicts
+	ErrorUnresolvedConflict = errors.New("Unresolved merge conflict")
+	// ErrorNothingToUndo is returned when there is no change of the note to undo (or redo)
+	ErrorNothingToUndo = errors.New("Nothing to undo")
+	// ErrorNoteTrashed is returned while changing a note which is in the trash
+	ErrorNoteTrashed = errors.New("Note is in the trash; restore it first")
+	// ErrorNoteArchived is returned while changing an archived note (which is read-only)
+	ErrorNoteArchived = errors.New("Note is archived, and so it cannot be changed")
+	// ErrorTagProtected is returned while changing (or deleting) a tag which is looked up by its slug
+	ErrorTagProtected = errors.New("Tag is used by the app itself, and so it cannot be changed")
+	// ErrorTagGroupRule is returned while setting tags of a note which don't follow the rules of their groups
+	ErrorTagGroupRule = errors.New("Tags don't follow the rules of their groups")
 )
@@ -1,7 +1,6 @@
 package model
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io/fs"
@@ -11,6 +10,8 @@ import (
 	"strings"
 	"unicode"
 
+	"github.com/google/uuid"
+	"github.com/goyalmunish/reminder/pkg/lockfile"
 	"github.com/goyalmunish/reminder/pkg/logger"
 	"github.com/goyalmunish/reminder/pkg/utils"
 	"github.com/rivo/tview"
@@ -71,6 +72,7 @@ func NewNote(tagIDs []int, useText string) (*Note, error) {
 	var noteText string
 	var err error
 	note := &Note{
+		Id:         uuid.NewString(),
 		Comments:   Comments{},
 		Status:     NoteStatus_Pending,
 		CompleteBy: 0,
@@ -207,6 +209,17 @@ func MakeSureFileExists(dataFilePath string, askUserInput bool) error {
 	return nil
 }
 
+// LockDataFile function acquires the (OS-level) lock on the data file, so that only one session can
+// operate on it at a time. The lock is held through a sidecar lock file, and must be released once done.
+// It returns ErrorDataFileLocked (along with details of the lock holder) if another session holds the lock.
+func LockDataFile(dataFilePath string) (*lockfile.Lock, error) {
+	lock, err := lockfile.Acquire(utils.TryConvertTildaBasedPath(dataFilePath) + LockFileSuffix)
+	if errors.Is(err, lockfile.ErrLocked) {
+		return nil, fmt.Errorf("%w (%v)", ErrorDataFileLocked, err)
+	}
+	return lock, err
+}
+
 // BlankReminder function creates blank ReminderData object.
 func BlankReminder(askUserInput bool, dataFilePath string) (*ReminderData, error) {
 	var name string
@@ -265,22 +278,15 @@ func BlankReminder(askUserInput bool, dataFilePath string) (*ReminderData, error
 	return reminderData, nil
 }
 
-// ReadDataFile function reads data file as instance of `ReminderData`
+// ReadDataFile function reads data file as instance of `ReminderData`.
+// The data file is read through its store, which depends on the extension of the file (see NewStore).
 func ReadDataFile(dataFilePath string, silentMode bool) (*ReminderData, error) {
-	var reminderData ReminderData
-	// read byte data from file
-	byteValue, err := os.ReadFile(utils.TryConvertTildaBasedPath(dataFilePath))
-	if err != nil {
-		return nil, err
-	}
-	// parse json data
-	err = json.Unmarshal(byteValue, &reminderData)
+	reminderData, err := NewStore(dataFilePath).Load()
 	if err != nil {
 		return nil, err
 	}
 	if !silentMode {
 		logger.Info(fmt.Sprintf("Read contents of %q into ReminderData.", dataFilePath))
 	}
-	// close the file
-	return &reminderData, nil
+	return reminderData, nil
 }
@@ -5,6 +5,7 @@ import (
 	"io/fs"
 	"os"
 	"path"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -16,7 +17,9 @@ func TestNewNote(t *testing.T) {
 	tagIDs := []int{1, 3, 5}
 	dummyText := "a random note text"
 	note, _ := model.NewNote(tagIDs, dummyText)
+	utils.AssertEqual(t, note.Id != "", true)
 	want := &model.Note{
+		Id:         note.Id,
 		Text:       dummyText,
 		TagIds:     tagIDs,
 		Status:     note.Status,
@@ -82,3 +85,75 @@ func TestReadDataFile(t *testing.T) {
 	reminderData, _ := model.ReadDataFile(dataFilePath, false)
 	utils.AssertEqual(t, reminderData.UpdatedAt > 0, true)
 }
+
+func TestReadDataFileBackfillsIds(t *testing.T) {
+	dataFilePath := path.Join("..", "..", "test", "test_data_file.json")
+	reminderData, err := model.ReadDataFile(dataFilePath, true)
+	utils.AssertEqual(t, err, nil)
+	// every note gets a unique id even though the file doesn't have any
+	seen := make(map[string]bool)
+	for _, note := range reminderData.Notes {
+		utils.AssertEqual(t, note.Id != "", true)
+		utils.AssertEqual(t, seen[note.Id], false)
+		seen[note.Id] = true
+	}
+	utils.AssertEqual(t, len(seen), len(reminderData.Notes))
+}
+
+func TestReadDataFileRestoresFromBackup(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	// create the file (each write keeps previous version as backup)
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, false)
+	utils.AssertEqual(t, len(reminderData.Tags), 7)
+	_, err := os.Stat(dataFilePath + utils.BackupFileSuffix)
+	utils.AssertEqual(t, err, nil)
+	// simulate a crash which left the data file truncated
+	_ = os.WriteFile(dataFilePath, []byte(`{"user": {"name": "Test`), 0755)
+	// case 1: data is read from the backup, leaving the data file as it is (as the lock may not be held)
+	reminderData, err = model.ReadDataFile(dataFilePath, false)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, reminderData.DataFile, dataFilePath)
+	utils.AssertEqual(t, len(reminderData.Tags), 7)
+	utils.AssertEqual(t, reminderData.Migrated(), true)
+	byteValue, _ := os.ReadFile(dataFilePath)
+	utils.AssertEqual(t, string(byteValue), `{"user": {"name": "Test`)
+	matches, _ := filepath.Glob(dataFilePath + "_CORRUPT_*")
+	utils.AssertEqual(t, len(matches), 0)
+	// case 2: the data file is restored on save, and the corrupt file is kept aside
+	utils.AssertEqual(t, reminderData.UpdateDataFile(""), nil)
+	matches, _ = filepath.Glob(dataFilePath + "_CORRUPT_*")
+	utils.AssertEqual(t, len(matches), 1)
+	reminderData, err = model.ReadDataFile(dataFilePath, false)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, reminderData.Migrated(), false)
+	backup, _ := model.ReadDataFile(dataFilePath+utils.BackupFileSuffix, false)
+	utils.AssertEqual(t, len(backup.Tags), 7)
+	// case 3: corrupt data file without usable backup is an error
+	_ = os.Remove(dataFilePath + utils.BackupFileSuffix)
+	_ = os.WriteFile(dataFilePath, []byte(`{"user": {"name": "Test`), 0755)
+	_, err = model.ReadDataFile(dataFilePath, false)
+	utils.AssertEqual(t, err != nil, true)
+}
+
+func TestLockDataFile(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	// case 1: the lock is acquired through a sidecar lock file
+	lock, err := model.LockDataFile(dataFilePath)
+	utils.AssertEqual(t, err, nil)
+	_, err = os.Stat(dataFilePath + model.LockFileSuffix)
+	utils.AssertEqual(t, err, nil)
+	// case 2: another session cannot acquire the lock
+	_, err = model.LockDataFile(dataFilePath)
+	utils.AssertEqual(t, errors.Is(err, model.ErrorDataFileLocked), true)
+	// case 3: the lock can be acquired again once released
+	utils.AssertEqual(t, lock.Release(), nil)
+	lock, err = model.LockDataFile(dataFilePath)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, lock.Release(), nil)
+}
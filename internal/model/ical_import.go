@@ -0,0 +1,180 @@
+package model
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/goyalmunish/reminder/pkg/ical"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+// ImportedTagGroup is the group of the tags created while importing notes.
+const ImportedTagGroup = "imported"
+
+/*
+An ICalImport represents outcome of importing an iCalendar.
+*/
+type ICalImport struct {
+	Notes    Notes    // the imported notes
+	Tags     Tags     // the tags created for the imported notes
+	Skipped  int      // number of entries skipped, such as the ones imported earlier
+	Warnings []string // entries which were skipped (or imported partially), with the reason
+}
+
+// ImportICalendar imports events and to-dos of the iCalendar as notes, and saves them to the data file.
+// A note gets SUMMARY of its entry as text, DESCRIPTION as summary, DUE (or DTSTART) as due date,
+// RRULE as recurrence (with a yearly or monthly rule also mapped to the repeat tag), and CATEGORIES as tags
+// (which are created under "imported" group, if they don't exist).
+// An entry whose UID matches an existing note (including the ones exported by the app itself) is skipped,
+// so that importing the same file again doesn't create duplicates; an entry without UID is matched by
+// its SUMMARY, DTSTART and RRULE instead (refer importKey).
+func (rd *ReminderData) ImportICalendar(iCalendar *ical.Calendar) (*ICalImport, error) {
+	result := &ICalImport{}
+	repeatAnnuallyTagId, repeatMonthlyTagId := rd.RepeatTagIds()
+	seenUIDs := map[string]bool{}
+	for _, note := range rd.Notes {
+		seenUIDs[note.Id+"@reminder"] = true
+		if note.SourceUID != "" {
+			seenUIDs[note.SourceUID] = true
+		}
+	}
+	for _, component := range iCalendar.Components {
+		label := fmt.Sprintf("%s %q", component.Kind, component.Summary)
+		key := importKey(component)
+		switch {
+		case seenUIDs[key]:
+			result.Skipped++
+			continue
+		case strings.TrimSpace(component.Summary) == "":
+			result.Skipped++
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Skipped %s with UID %q: it has no summary", component.Kind, component.UID))
+			continue
+		case component.Status == "CANCELLED":
+			result.Skipped++
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Skipped %s: it is cancelled", label))
+			continue
+		}
+		seenUIDs[key] = true
+		tagIDs, err := rd.importTags(component.Categories, label, result)
+		if err != nil {
+			return nil, err
+		}
+		note, err := NewNote(tagIDs, component.Summary)
+		if err != nil {
+			return nil, err
+		}
+		note.Summary = strings.TrimSpace(component.Description)
+		note.SourceUID = key
+		if component.Status == "COMPLETED" {
+			note.Status = NoteStatus_Done
+		}
+		if !component.Start.IsZero() {
+			note.CompleteBy = component.Start.Unix()
+			if !component.AllDay {
+				note.HasDueTime = true
+				note.TimeZone = component.TimeZone
+				if note.TimeZone == "" {
+					note.TimeZone = "UTC"
+					if component.Start.Location() == time.Local {
+						// a floating time
+						note.TimeZone = utils.LocalZoneName()
+					}
+				}
+			}
+		}
+		if component.RRule != "" {
+			recurrence, err := ParseRRule(component.RRule)
+			switch {
+			case err != nil:
+				result.Warnings = append(result.Warnings, fmt.Sprintf("Imported %s without its recurrence: %v", label, err))
+			case note.CompleteBy == 0:
+				result.Warnings = append(result.Warnings, fmt.Sprintf("Imported %s without its recurrence: it has no start date", label))
+			default:
+				note.Recurrence = recurrence
+				// plain yearly and monthly recurrences are also marked with their repeat tags
+				repeatTagId := -1
+				switch recurrence.String() {
+				case string(RecurrenceFrequency_Yearly):
+					repeatTagId = repeatAnnuallyTagId
+				case string(RecurrenceFrequency_Monthly):
+					repeatTagId = repeatMonthlyTagId
+				}
+				if repeatTagId >= 0 && !utils.IsMemberOfSlice(repeatTagId, note.TagIds) {
+					note.TagIds = append(note.TagIds, repeatTagId)
+				}
+			}
+		}
+		rd.Notes = append(rd.Notes, note)
+		result.Notes = append(result.Notes, note)
+	}
+	if len(result.Notes) == 0 && len(result.Tags) == 0 {
+		return result, nil
+	}
+	msg := fmt.Sprintf("Imported %d notes (and created %d tags).", len(result.Notes), len(result.Tags))
+	if err := rd.UpdateDataFile(msg); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// importKey returns the key which identifies the imported entry (and is kept as SourceUID of its note).
+// It is UID of the entry; for an entry without UID, it is derived from its SUMMARY, DTSTART and RRULE.
+func importKey(component *ical.Component) string {
+	if component.UID != "" {
+		return component.UID
+	}
+	var start string
+	if !component.Start.IsZero() {
+		start = component.Start.UTC().Format(time.RFC3339)
+	}
+	hash := sha256.Sum256([]byte(strings.Join([]string{component.Summary, start, component.RRule}, "\n")))
+	return fmt.Sprintf("sha256:%x", hash)
+}
+
+// importTags returns ids of the tags with given categories (as slugs; refer importSlug), creating the missing tags.
+// A category which doesn't make a valid slug, or whose tag cannot be used along with the earlier ones (as per rules
+// of the tag groups), is left out.
+func (rd *ReminderData) importTags(categories []string, label string, result *ICalImport) ([]int, error) {
+	tagIDs := make([]int, 0, len(categories))
+	for _, category := range categories {
+		slug := importSlug(category)
+		tag := rd.TagFromSlug(slug)
+		if tag == nil {
+			if err := checkTagSlug(slug); err != nil {
+				result.Warnings = append(result.Warnings, fmt.Sprintf("Imported %s without its category %q: %v", label, category, err))
+				continue
+			}
+			var err error
+			tag, err = NewTag(rd.nextPossibleTagId(), slug, ImportedTagGroup)
+			if err != nil {
+				return nil, err
+			}
+			rd.Tags = append(rd.Tags, tag)
+			result.Tags = append(result.Tags, tag)
+		}
+		if utils.IsMemberOfSlice(tag.Id, tagIDs) {
+			continue
+		}
+		if err := rd.CheckTagGroups(append(tagIDs, tag.Id)); err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("Imported %s without its category %q: %v", label, category, err))
+			continue
+		}
+		tagIDs = append(tagIDs, tag.Id)
+	}
+	return tagIDs, nil
+}
+
+// importSlug returns slug of the tag of the category, such as "work/project-x" for "Work / Project (X)".
+// The parentheses (which group a tag query) and the empty levels are left out.
+func importSlug(category string) string {
+	category = strings.NewReplacer("(", " ", ")", " ").Replace(category)
+	var levels []string
+	for _, level := range strings.Split(category, TagLevelSeparator) {
+		if level = strings.Join(strings.Fields(level), "-"); level != "" {
+			levels = append(levels, level)
+		}
+	}
+	return strings.ToLower(strings.Join(levels, TagLevelSeparator))
+}
@@ -0,0 +1,111 @@
+package model_test
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/goyalmunish/reminder/internal/model"
+	"github.com/goyalmunish/reminder/pkg/ical"
+	utils "github.com/goyalmunish/reminder/pkg/utils"
+)
+
+func TestImportICalendar(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, true)
+	repeatAnnuallyTagId, repeatMonthlyTagId := reminderData.RepeatTagIds()
+	existing, _ := reminderData.NewNoteRegistration([]int{0}, "exported earlier")
+	tagsCount := len(reminderData.Tags)
+	london, _ := time.LoadLocation("Europe/London")
+	calendar := &ical.Calendar{Components: []*ical.Component{
+		{Kind: ical.KindEvent, UID: "a@example.com", Summary: "pay rent", Description: "to landlord",
+			Start: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), AllDay: true, RRule: "RRULE:FREQ=MONTHLY",
+			Categories: []string{"Home Bills", "current"}},
+		{Kind: ical.KindEvent, UID: "b@example.com", Summary: "mom's birthday",
+			Start: time.Date(2026, 8, 3, 0, 0, 0, 0, time.UTC), AllDay: true, RRule: "RRULE:FREQ=YEARLY"},
+		{Kind: ical.KindTodo, UID: "c@example.com", Summary: "call bank", Status: "COMPLETED",
+			Start: time.Date(2026, 5, 12, 14, 30, 0, 0, london), TimeZone: "Europe/London", Categories: []string{"home bills"}},
+		{Kind: ical.KindEvent, UID: "d@example.com", Summary: "standup",
+			Start: time.Date(2026, 5, 12, 9, 0, 0, 0, time.UTC), RRule: "RRULE:FREQ=DAILY;COUNT=5"},
+		{Kind: ical.KindEvent, UID: "e@example.com", Summary: "cancelled", Status: "CANCELLED"},
+		{Kind: ical.KindEvent, UID: "f@example.com"},
+		{Kind: ical.KindEvent, UID: existing.Id + "@reminder", Summary: "exported earlier"},
+		{Kind: ical.KindEvent, UID: "a@example.com", Summary: "pay rent"},
+		{Kind: ical.KindEvent, Summary: "water plants", Start: time.Date(2026, 5, 2, 0, 0, 0, 0, time.UTC), AllDay: true},
+		{Kind: ical.KindEvent, Summary: "water plants", Start: time.Date(2026, 5, 2, 0, 0, 0, 0, time.UTC), AllDay: true},
+		{Kind: ical.KindEvent, Summary: "water plants", Start: time.Date(2026, 5, 9, 0, 0, 0, 0, time.UTC), AllDay: true},
+	}}
+	result, err := reminderData.ImportICalendar(calendar)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, len(result.Notes), 6)
+	utils.AssertEqual(t, result.Skipped, 5)
+	utils.AssertEqual(t, len(result.Warnings), 3)
+	utils.AssertEqual(t, strings.Contains(result.Warnings[0], `"standup" without its recurrence`), true)
+	// a new tag is created for the new category
+	utils.AssertEqual(t, len(result.Tags), 1)
+	utils.AssertEqual(t, result.Tags[0].Slug, "home-bills")
+	utils.AssertEqual(t, result.Tags[0].Group, model.ImportedTagGroup)
+	utils.AssertEqual(t, len(reminderData.Tags), tagsCount+1)
+	homeBillsTagId := result.Tags[0].Id
+	currentTagId := reminderData.TagFromSlug("current").Id
+	rent := result.Notes[0]
+	utils.AssertEqual(t, rent.Text, "pay rent")
+	utils.AssertEqual(t, rent.Summary, "to landlord")
+	utils.AssertEqual(t, rent.SourceUID, "a@example.com")
+	utils.AssertEqual(t, rent.CompleteBy, date(2026, 5, 1))
+	utils.AssertEqual(t, rent.HasDueTime, false)
+	utils.AssertEqual(t, rent.Recurrence.String(), "monthly")
+	utils.AssertEqual(t, rent.TagIds, []int{homeBillsTagId, currentTagId, repeatMonthlyTagId})
+	birthday := result.Notes[1]
+	utils.AssertEqual(t, birthday.TagIds, []int{repeatAnnuallyTagId})
+	call := result.Notes[2]
+	utils.AssertEqual(t, call.Status, model.NoteStatus_Done)
+	utils.AssertEqual(t, call.HasDueTime, true)
+	utils.AssertEqual(t, call.TimeZone, "Europe/London")
+	utils.AssertEqual(t, call.TagIds, []int{homeBillsTagId})
+	utils.AssertEqual(t, result.Notes[3].Recurrence == nil, true)
+	// entries without UID are told apart by their content
+	utils.AssertEqual(t, result.Notes[4].Text, "water plants")
+	utils.AssertEqual(t, strings.HasPrefix(result.Notes[4].SourceUID, "sha256:"), true)
+	utils.AssertEqual(t, result.Notes[4].SourceUID != result.Notes[5].SourceUID, true)
+	// the imported notes are saved
+	reminderDataRe, _ := model.ReadDataFile(dataFilePath, true)
+	utils.AssertEqual(t, len(reminderDataRe.Notes), 7)
+	// importing again creates no duplicates
+	result, err = reminderDataRe.ImportICalendar(calendar)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, len(result.Notes), 0)
+	utils.AssertEqual(t, len(result.Tags), 0)
+	utils.AssertEqual(t, result.Skipped, 11)
+	utils.AssertEqual(t, len(reminderDataRe.Notes), 7)
+}
+
+func TestImportICalendarCategories(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, true)
+	calendar := &ical.Calendar{Components: []*ical.Component{
+		{Kind: ical.KindEvent, UID: "a@example.com", Summary: "review design",
+			Categories: []string{"Work / Project (X)", "AND", "//", " "}},
+	}}
+	result, err := reminderData.ImportICalendar(calendar)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, len(result.Notes), 1)
+	// the categories are made into valid slugs, or else are left out
+	utils.AssertEqual(t, len(result.Tags), 1)
+	utils.AssertEqual(t, result.Tags[0].Slug, "work/project-x")
+	utils.AssertEqual(t, result.Notes[0].TagIds, []int{result.Tags[0].Id})
+	utils.AssertEqual(t, len(result.Warnings), 3)
+	utils.AssertEqual(t, strings.Contains(result.Warnings[0], `without its category "AND"`), true)
+	// the tag can be referred to in a tag query
+	expr, err := model.ParseTagExpr("work/project-x and not current", reminderData.Tags)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, len(reminderData.Notes.Query(model.NoteQuery{TagExpr: expr})), 1)
+}
@@ -0,0 +1,445 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/goyalmunish/reminder/pkg/logger"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+/*
+A MergeConflict represents a field which is changed differently by current session (mine)
+and by another session which updated the data file in the meantime (theirs).
+*/
+type MergeConflict struct {
+	Kind   string      // "user", "note", "tag" or "tag query"
+	Label  string      // human readable label of the conflicting object, such as text of a note
+	Field  string      // name of the conflicting field
+	Base   interface{} // value as it was when loaded by current session
+	Theirs interface{} // value in the data file
+	Mine   interface{} // value in current session
+}
+
+// String provides basic string representation of a merge conflict.
+func (c *MergeConflict) String() string {
+	return fmt.Sprintf("%s %q, field %q: base <%v>, theirs <%v>, mine <%v>", c.Kind, c.Label, c.Field, c.Base, c.Theirs, c.Mine)
+}
+
+// A ConflictResolver decides how to resolve a merge conflict; it returns true to keep the value of
+// current session (mine), and false to keep the value of the data file (theirs).
+type ConflictResolver func(conflict *MergeConflict) (bool, error)
+
+// AskConflictResolution is a ConflictResolver which asks the user to resolve the conflict.
+func AskConflictResolution(conflict *MergeConflict) (bool, error) {
+	fmt.Printf("%v Conflicting changes for %s %q (field %q):\n", utils.Symbols["warning"], conflict.Kind, conflict.Label, conflict.Field)
+	fmt.Printf("  - original:                 %v\n", conflict.Base)
+	fmt.Printf("  - in data file (theirs):    %v\n", conflict.Theirs)
+	fmt.Printf("  - in this session (mine):   %v\n", conflict.Mine)
+	index, _, err := utils.AskOption([]string{
+		fmt.Sprintf("%v %v", utils.Symbols["upArrow"], "Keep mine"),
+		fmt.Sprintf("%v %v", utils.Symbols["downArrow"], "Keep theirs"),
+	}, "Resolve Conflict: ")
+	if err != nil {
+		return false, err
+	}
+	return index == 0, nil
+}
+
+// RejectConflicts is a ConflictResolver which doesn't resolve any conflict.
+// It is useful for non-interactive sessions.
+func RejectConflicts(conflict *MergeConflict) (bool, error) {
+	return false, fmt.Errorf("%w: %v", ErrorUnresolvedConflict, conflict)
+}
+
+// cloneReminderData returns a deep copy of the (persistable part of) reminder data.
+func cloneReminderData(rd *ReminderData) (*ReminderData, error) {
+	byteValue, err := json.Marshal(rd)
+	if err != nil {
+		return nil, err
+	}
+	var clone ReminderData
+	err = json.Unmarshal(byteValue, &clone)
+	return &clone, err
+}
+
+// mergePersisted merges the changes of another session, if the persisted data was updated since
+// current session loaded (or last saved) it.
+func (rd *ReminderData) mergePersisted(persisted *ReminderData) error {
+	if persisted == nil || persisted.UpdatedAt == rd.UpdatedAt {
+		return nil
+	}
+	if rd.base == nil {
+		return ErrorUnresolvedConflict
+	}
+	logger.Warn("It seems another instance of the application updated the data file; merging its changes.")
+	return Merge(rd.base, persisted, rd, rd.resolver)
+}
+
+// saveConflictFile saves the data, which couldn't be merged with the data file, to a (JSON) _CONFLICT_
+// file next to the data file instead, and returns ErrorConflictFile.
+func (rd *ReminderData) saveConflictFile(mergeErr error) error {
+	newFilePath := fmt.Sprintf("%s_CONFLICT_%d", rd.DataFile, utils.CurrentUnixTimestamp())
+	logger.Error(fmt.Sprintf("Unable to merge changes made by another instance of the application (%v); the data will instead be saved to confict file %q.", mergeErr, newFilePath))
+	rd.DataFile = newFilePath
+	rd.store = &JSONStore{DataFile: newFilePath}
+	if err := rd.store.Save(rd); err != nil {
+		return err
+	}
+	return ErrorConflictFile
+}
+
+// Merge performs three-way merge of the reminder data into mine.
+// The base is the state loaded by current session, theirs is the current state of the data file, and mine is
+// the state of current session. Changes which don't overlap are merged automatically, whereas for a field which
+// is changed differently on both sides, the resolver is asked which value to keep.
+// The merge is done on a copy of mine, and so mine is left as it is if the merge fails (such as for an unresolved
+// conflict). Otherwise, the objects of mine are updated in-place, so that any references to them remain valid.
+func Merge(base *ReminderData, theirs *ReminderData, mine *ReminderData, resolve ConflictResolver) error {
+	if resolve == nil {
+		resolve = AskConflictResolution
+	}
+	merged, err := cloneReminderData(mine)
+	if err != nil {
+		return err
+	}
+	// the copies (which are in same order as their originals) mapped to their originals
+	notes := originalsOf(merged.Notes, mine.Notes)
+	trash := originalsOf(merged.Trash, mine.Trash)
+	tags := originalsOf(merged.Tags, mine.Tags)
+	tagQueries := originalsOf(merged.TagQueries, mine.TagQueries)
+	if err := merge(base, theirs, merged, resolve); err != nil {
+		return err
+	}
+	if (mine.User != nil) && (merged.User != nil) {
+		*mine.User = *merged.User
+	} else {
+		mine.User = merged.User
+	}
+	mine.LastBackupAt = merged.LastBackupAt
+	mine.NextTagId = merged.NextTagId
+	mine.Notes = adopt(merged.Notes, notes)
+	mine.Trash = adopt(merged.Trash, trash)
+	mine.Tags = adopt(merged.Tags, tags)
+	mine.TagQueries = adopt(merged.TagQueries, tagQueries)
+	return nil
+}
+
+// originalsOf maps each of the copies to its original object (at same index in originals).
+func originalsOf[T any](copies []*T, originals []*T) map[*T]*T {
+	result := make(map[*T]*T, len(copies))
+	for index, object := range copies {
+		result[object] = originals[index]
+	}
+	return result
+}
+
+// adopt returns the merged objects, with the ones having an original object copied into that object.
+func adopt[T any](merged []*T, originals map[*T]*T) []*T {
+	if merged == nil {
+		return nil
+	}
+	result := make([]*T, 0, len(merged))
+	for _, object := range merged {
+		if original, ok := originals[object]; ok {
+			*original = *object
+			object = original
+		}
+		result = append(result, object)
+	}
+	return result
+}
+
+// merge performs the three-way merge (refer Merge) in-place into mine.
+func merge(base *ReminderData, theirs *ReminderData, mine *ReminderData, resolve ConflictResolver) error {
+	// user
+	if base.User != nil && theirs.User != nil && mine.User != nil {
+		label := mine.User.Name
+		if err := mergeFields(base.User, theirs.User, mine.User, nil, "user", label, resolve); err != nil {
+			return err
+		}
+	} else if mine.User == nil {
+		mine.User = theirs.User
+	}
+	if theirs.LastBackupAt > mine.LastBackupAt {
+		mine.LastBackupAt = theirs.LastBackupAt
+	}
+	if theirs.NextTagId > mine.NextTagId {
+		mine.NextTagId = theirs.NextTagId
+	}
+	// tags (before notes, as tag ids of notes may need to be updated)
+	if err := mergeTags(base, theirs, mine, resolve); err != nil {
+		return err
+	}
+	if err := mergeTagQueries(base.TagQueries, theirs.TagQueries, mine, resolve); err != nil {
+		return err
+	}
+	var err error
+	if mine.Notes, err = mergeNotes(base.Notes, theirs.Notes, mine.Notes, resolve); err != nil {
+		return err
+	}
+	if mine.Trash, err = mergeNotes(base.Trash, theirs.Trash, mine.Trash, resolve); err != nil {
+		return err
+	}
+	// a note moved to (or restored from) the trash by one side is present in both the merged lists
+	mine.dedupeTrash()
+	return nil
+}
+
+// mergeTags merges tags of theirs into mine.
+// A new tag of mine with same id as another new tag of theirs is assigned a new id (in the notes and the
+// trashed notes of mine as well).
+func mergeTags(base *ReminderData, theirs *ReminderData, mine *ReminderData, resolve ConflictResolver) error {
+	baseTags := make(map[int]*Tag)
+	for _, tag := range base.Tags {
+		baseTags[tag.Id] = tag
+	}
+	theirTags := make(map[int]*Tag)
+	for _, tag := range theirs.Tags {
+		theirTags[tag.Id] = tag
+	}
+	// find next free tag id across both the sides
+	nextTagId := mine.NextTagId
+	for _, tags := range []Tags{theirs.Tags, mine.Tags} {
+		for _, tag := range tags {
+			if tag.Id >= nextTagId {
+				nextTagId = tag.Id + 1
+			}
+		}
+	}
+	var merged Tags
+	mineTagIds := make(map[int]bool)
+	for _, tag := range mine.Tags {
+		baseTag, inBase := baseTags[tag.Id]
+		theirTag, inTheirs := theirTags[tag.Id]
+		switch {
+		case inBase && inTheirs:
+			if err := mergeFields(baseTag, theirTag, tag, nil, "tag", tag.Slug, resolve); err != nil {
+				return err
+			}
+		case inBase && !inTheirs:
+			// deleted by them; keep it only if it is changed by current session
+			if reflect.DeepEqual(baseTag, tag) {
+				continue
+			}
+			logger.Warn(fmt.Sprintf("Keeping tag %q which is deleted in the data file but updated in this session.", tag.Slug))
+		case !inBase && inTheirs:
+			// both sessions added a tag with the same id
+			if theirTag.Slug != tag.Slug {
+				oldTagId := tag.Id
+				tag.Id = nextTagId
+				nextTagId++
+				logger.Warn(fmt.Sprintf("Changing id of new tag %q from %d to %d as the id is already taken in the data file.", tag.Slug, oldTagId, tag.Id))
+				for _, notes := range []Notes{mine.Notes, mine.Trash} {
+					for _, note := range notes {
+						for index, tagID := range note.TagIds {
+							if tagID == oldTagId {
+								note.TagIds[index] = tag.Id
+							}
+						}
+					}
+				}
+			}
+		}
+		mineTagIds[tag.Id] = true
+		merged = append(merged, tag)
+	}
+	for _, theirTag := range theirs.Tags {
+		if mineTagIds[theirTag.Id] {
+			continue
+		}
+		baseTag, inBase := baseTags[theirTag.Id]
+		if inBase && reflect.DeepEqual(baseTag, theirTag) {
+			// deleted by current session, and not changed by them
+			continue
+		}
+		// added (or changed) by them
+		merged = append(merged, theirTag)
+	}
+	mine.Tags = merged
+	return nil
+}
+
+// mergeTagQueries merges saved tag queries of theirs into mine (matching them by their names).
+func mergeTagQueries(base TagQueries, theirs TagQueries, mine *ReminderData, resolve ConflictResolver) error {
+	var merged TagQueries
+	for _, tagQuery := range mine.TagQueries {
+		baseQuery := base.FromName(tagQuery.Name)
+		theirQuery := theirs.FromName(tagQuery.Name)
+		switch {
+		case theirQuery != nil:
+			if baseQuery == nil {
+				// added by both sessions; merge them without any common ancestor
+				baseQuery = &TagQuery{Name: tagQuery.Name}
+			}
+			if err := mergeFields(baseQuery, theirQuery, tagQuery, nil, "tag query", tagQuery.Name, resolve); err != nil {
+				return err
+			}
+		case baseQuery != nil:
+			// deleted by them; keep it only if it is changed by current session
+			if reflect.DeepEqual(baseQuery, tagQuery) {
+				continue
+			}
+			logger.Warn(fmt.Sprintf("Keeping tag query %q which is deleted in the data file but updated in this session.", tagQuery.Name))
+		}
+		merged = append(merged, tagQuery)
+	}
+	for _, theirQuery := range theirs {
+		if mine.TagQueries.FromName(theirQuery.Name) != nil {
+			continue
+		}
+		if baseQuery := base.FromName(theirQuery.Name); (baseQuery != nil) && reflect.DeepEqual(baseQuery, theirQuery) {
+			// deleted by current session, and not changed by them
+			continue
+		}
+		// added (or changed) by them
+		merged = append(merged, theirQuery)
+	}
+	mine.TagQueries = merged
+	return nil
+}
+
+// mergeNotes merges notes of theirs into mine (matching them by their ids), and returns the merged notes.
+func mergeNotes(base Notes, theirs Notes, mine Notes, resolve ConflictResolver) (Notes, error) {
+	baseNotes := make(map[string]*Note)
+	for _, note := range base {
+		baseNotes[note.Id] = note
+	}
+	theirNotes := make(map[string]*Note)
+	for _, note := range theirs {
+		theirNotes[note.Id] = note
+	}
+	var merged Notes
+	mineNoteIds := make(map[string]bool)
+	for _, note := range mine {
+		mineNoteIds[note.Id] = true
+		baseNote, inBase := baseNotes[note.Id]
+		theirNote, inTheirs := theirNotes[note.Id]
+		switch {
+		case inTheirs:
+			if !inBase {
+				// (unlikely) added by both sessions; merge them without any common ancestor
+				baseNote = &Note{Id: note.Id}
+			}
+			if err := mergeNote(baseNote, theirNote, note, resolve); err != nil {
+				return nil, err
+			}
+		case inBase:
+			// deleted by them; keep it only if it is changed by current session
+			if note.UpdatedAt == baseNote.UpdatedAt {
+				continue
+			}
+			logger.Warn(fmt.Sprintf("Keeping note %q which is deleted in the data file but updated in this session.", note.Text))
+		}
+		merged = append(merged, note)
+	}
+	for _, theirNote := range theirs {
+		if mineNoteIds[theirNote.Id] {
+			continue
+		}
+		baseNote, inBase := baseNotes[theirNote.Id]
+		if inBase && theirNote.UpdatedAt == baseNote.UpdatedAt {
+			// deleted by current session, and not changed by them
+			continue
+		}
+		// added (or changed) by them
+		merged = append(merged, theirNote)
+	}
+	return merged, nil
+}
+
+// mergeNote merges a note of theirs into the note of mine.
+// Comments (and history) are merged as union of comments (and revisions) from both the sides.
+func mergeNote(base *Note, theirs *Note, mine *Note, resolve ConflictResolver) error {
+	skip := []string{"Id", "Comments", "History", "BaseStruct"}
+	if err := mergeFields(base, theirs, mine, skip, "note", mine.Text, resolve); err != nil {
+		return err
+	}
+	mine.Comments = mergeComments(base.Comments, theirs.Comments, mine.Comments)
+	mine.History = mergeRevisions(base.History, theirs.History, mine.History)
+	if theirs.UpdatedAt > mine.UpdatedAt {
+		mine.UpdatedAt = theirs.UpdatedAt
+	}
+	return nil
+}
+
+// mergeComments returns union of comments of theirs and mine (matched by their ids),
+// leaving out the ones deleted on any of the sides.
+func mergeComments(base Comments, theirs Comments, mine Comments) Comments {
+	inBase := make(map[string]bool)
+	for _, comment := range base {
+		inBase[comment.Id] = true
+	}
+	inTheirs := make(map[string]bool)
+	for _, comment := range theirs {
+		inTheirs[comment.Id] = true
+	}
+	inMine := make(map[string]bool)
+	merged := Comments{}
+	for _, comment := range mine {
+		inMine[comment.Id] = true
+		if inBase[comment.Id] && !inTheirs[comment.Id] {
+			continue
+		}
+		merged = append(merged, comment)
+	}
+	for _, comment := range theirs {
+		if inMine[comment.Id] || inBase[comment.Id] {
+			continue
+		}
+		merged = append(merged, comment)
+	}
+	// keep the comments in chronological order
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].CreatedAt < merged[j].CreatedAt })
+	return merged
+}
+
+// mergeFields performs three-way merge of each of the exported fields (except the skipped ones)
+// of the struct pointed by mine. All of base, theirs and mine must be pointers to same struct type.
+func mergeFields(base interface{}, theirs interface{}, mine interface{}, skip []string, kind string, label string, resolve ConflictResolver) error {
+	baseValue := reflect.ValueOf(base).Elem()
+	theirValue := reflect.ValueOf(theirs).Elem()
+	mineValue := reflect.ValueOf(mine).Elem()
+	structType := mineValue.Type()
+	for index := 0; index < structType.NumField(); index++ {
+		field := structType.Field(index)
+		if !field.IsExported() || utils.IsMemberOfSlice(field.Name, skip) {
+			continue
+		}
+		baseField := baseValue.Field(index).Interface()
+		theirField := theirValue.Field(index).Interface()
+		mineField := mineValue.Field(index).Interface()
+		switch {
+		case reflect.DeepEqual(theirField, mineField):
+			// same on both sides
+		case reflect.DeepEqual(baseField, mineField):
+			// changed only by them
+			mineValue.Field(index).Set(theirValue.Field(index))
+		case reflect.DeepEqual(baseField, theirField):
+			// changed only by current session
+		default:
+			conflict := &MergeConflict{Kind: kind, Label: label, Field: fieldName(field), Base: baseField, Theirs: theirField, Mine: mineField}
+			keepMine, err := resolve(conflict)
+			if err != nil {
+				return err
+			}
+			if !keepMine {
+				mineValue.Field(index).Set(theirValue.Field(index))
+			}
+			logger.Info(fmt.Sprintf("Resolved the conflict (keeping mine: %v): %v", keepMine, conflict))
+		}
+	}
+	return nil
+}
+
+// fieldName returns json name of the struct field (falling back to its go name).
+func fieldName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
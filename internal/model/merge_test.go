@@ -0,0 +1,239 @@
+package model_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	model "github.com/goyalmunish/reminder/internal/model"
+	utils "github.com/goyalmunish/reminder/pkg/utils"
+)
+
+// mergeTestData returns reminder data with a tag and a note (with a comment).
+func mergeTestData() *model.ReminderData {
+	return &model.ReminderData{
+		User: &model.User{Name: "Test User", EmailId: "user@test.com"},
+		Tags: model.Tags{&model.Tag{Id: 0, Slug: "current", Group: ""}},
+		Notes: model.Notes{&model.Note{
+			Id:       "note-1",
+			Text:     "original text",
+			Summary:  "original summary",
+			Status:   model.NoteStatus_Pending,
+			TagIds:   []int{0},
+			Comments: model.Comments{&model.Comment{Id: "comment-1", Text: "first comment", BaseStruct: model.BaseStruct{CreatedAt: 100}}},
+			BaseStruct: model.BaseStruct{
+				CreatedAt: 100,
+				UpdatedAt: 100,
+			},
+		}},
+	}
+}
+
+func TestMergeNonOverlappingChanges(t *testing.T) {
+	base, theirs, mine := mergeTestData(), mergeTestData(), mergeTestData()
+	mineNote := mine.Notes[0]
+	// they change the text, add a comment and a note
+	theirs.Notes[0].Text = "their text"
+	theirs.Notes[0].UpdatedAt = 200
+	theirs.Notes[0].Comments = append(theirs.Notes[0].Comments, &model.Comment{Id: "comment-2", Text: "their comment", BaseStruct: model.BaseStruct{CreatedAt: 200}})
+	theirs.Notes = append(theirs.Notes, &model.Note{Id: "note-2", Text: "their note"})
+	// current session changes the summary, and adds a comment
+	mine.Notes[0].Summary = "my summary"
+	mine.Notes[0].UpdatedAt = 150
+	mine.Notes[0].Comments = append(mine.Notes[0].Comments, &model.Comment{Id: "comment-3", Text: "my comment", BaseStruct: model.BaseStruct{CreatedAt: 150}})
+	resolve := func(conflict *model.MergeConflict) (bool, error) {
+		t.Errorf("Unexpected conflict: %v", conflict)
+		return true, nil
+	}
+	err := model.Merge(base, theirs, mine, resolve)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, len(mine.Notes), 2)
+	// existing note is updated in-place
+	utils.AssertEqual(t, mine.Notes[0] == mineNote, true)
+	utils.AssertEqual(t, mineNote.Text, "their text")
+	utils.AssertEqual(t, mineNote.Summary, "my summary")
+	utils.AssertEqual(t, mineNote.UpdatedAt, int64(200))
+	utils.AssertEqual(t, mineNote.Comments.Strings(), model.Comments{
+		&model.Comment{Text: "first comment", BaseStruct: model.BaseStruct{CreatedAt: 100}},
+		&model.Comment{Text: "my comment", BaseStruct: model.BaseStruct{CreatedAt: 150}},
+		&model.Comment{Text: "their comment", BaseStruct: model.BaseStruct{CreatedAt: 200}},
+	}.Strings())
+	utils.AssertEqual(t, mine.Notes[1].Text, "their note")
+}
+
+func TestMergeConflictingChanges(t *testing.T) {
+	for _, keepMine := range []bool{true, false} {
+		base, theirs, mine := mergeTestData(), mergeTestData(), mergeTestData()
+		theirs.Notes[0].Text = "their text"
+		theirs.Notes[0].Status = model.NoteStatus_Done
+		mine.Notes[0].Text = "my text"
+		var conflicts []*model.MergeConflict
+		resolve := func(conflict *model.MergeConflict) (bool, error) {
+			conflicts = append(conflicts, conflict)
+			return keepMine, nil
+		}
+		err := model.Merge(base, theirs, mine, resolve)
+		utils.AssertEqual(t, err, nil)
+		// only the text is a real conflict
+		utils.AssertEqual(t, len(conflicts), 1)
+		utils.AssertEqual(t, conflicts[0].Field, "text")
+		utils.AssertEqual(t, conflicts[0].Base, "original text")
+		utils.AssertEqual(t, conflicts[0].Theirs, "their text")
+		utils.AssertEqual(t, conflicts[0].Mine, "my text")
+		if keepMine {
+			utils.AssertEqual(t, mine.Notes[0].Text, "my text")
+		} else {
+			utils.AssertEqual(t, mine.Notes[0].Text, "their text")
+		}
+		utils.AssertEqual(t, mine.Notes[0].Status, model.NoteStatus_Done)
+	}
+	// unresolved conflict
+	base, theirs, mine := mergeTestData(), mergeTestData(), mergeTestData()
+	theirs.Tags[0].Group = "their-group"
+	mine.Tags[0].Group = "my-group"
+	err := model.Merge(base, theirs, mine, model.RejectConflicts)
+	utils.AssertEqual(t, errors.Is(err, model.ErrorUnresolvedConflict), true)
+	// mine is left as it is, even for the changes merged before the unresolved conflict
+	base, theirs, mine = mergeTestData(), mergeTestData(), mergeTestData()
+	theirs.User.Name = "Their Name"
+	theirs.Tags = append(theirs.Tags, &model.Tag{Id: 1, Slug: "their-tag"})
+	theirs.Notes[0].Summary = "their summary"
+	theirs.Notes[0].Text = "their text"
+	mine.Notes[0].Text = "my text"
+	err = model.Merge(base, theirs, mine, model.RejectConflicts)
+	utils.AssertEqual(t, errors.Is(err, model.ErrorUnresolvedConflict), true)
+	utils.AssertEqual(t, mine.User.Name, "Test User")
+	utils.AssertEqual(t, len(mine.Tags), 1)
+	utils.AssertEqual(t, mine.Notes[0].Summary, "original summary")
+	utils.AssertEqual(t, mine.Notes[0].Text, "my text")
+}
+
+func TestMergeNoteHistory(t *testing.T) {
+	base, theirs, mine := mergeTestData(), mergeTestData(), mergeTestData()
+	baseRevision := model.Revision{Field: "text", OldValue: `""`, NewValue: `"original text"`, At: 50}
+	for _, rd := range []*model.ReminderData{base, theirs, mine} {
+		revision := baseRevision
+		rd.Notes[0].History = model.Revisions{&revision}
+	}
+	theirs.Notes[0].History = append(theirs.Notes[0].History, &model.Revision{Field: "status", OldValue: `"pending"`, NewValue: `"done"`, At: 200})
+	mine.Notes[0].History = append(mine.Notes[0].History, &model.Revision{Field: "summary", OldValue: `""`, NewValue: `"my summary"`, At: 150})
+	err := model.Merge(base, theirs, mine, model.RejectConflicts)
+	utils.AssertEqual(t, err, nil)
+	fields := []string{}
+	for _, revision := range mine.Notes[0].History {
+		fields = append(fields, revision.Field)
+	}
+	utils.AssertEqual(t, fields, []string{"text", "summary", "status"})
+}
+
+func TestMergeNewTagsWithSameId(t *testing.T) {
+	base, theirs, mine := mergeTestData(), mergeTestData(), mergeTestData()
+	theirs.Tags = append(theirs.Tags, &model.Tag{Id: 1, Slug: "their-tag"})
+	mine.Tags = append(mine.Tags, &model.Tag{Id: 1, Slug: "my-tag"}, &model.Tag{Id: 2, Slug: "another-tag"})
+	mine.Notes[0].TagIds = []int{0, 1}
+	mine.Trash = model.Notes{&model.Note{Id: "note-2", Text: "trashed note", Status: model.NoteStatus_Pending, TagIds: []int{1, 2}}}
+	err := model.Merge(base, theirs, mine, model.RejectConflicts)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, mine.TagFromSlug("their-tag").Id, 1)
+	utils.AssertEqual(t, mine.TagFromSlug("my-tag").Id, 3)
+	utils.AssertEqual(t, mine.TagFromSlug("another-tag").Id, 2)
+	utils.AssertEqual(t, mine.Notes[0].TagIds, []int{0, 3})
+	// the trashed notes refer to the new id as well
+	utils.AssertEqual(t, len(mine.Trash), 1)
+	utils.AssertEqual(t, mine.Trash[0].TagIds, []int{3, 2})
+}
+
+func TestMergeTagQueries(t *testing.T) {
+	base, theirs, mine := mergeTestData(), mergeTestData(), mergeTestData()
+	for _, rd := range []*model.ReminderData{base, theirs, mine} {
+		rd.TagQueries = model.TagQueries{
+			&model.TagQuery{Name: "changed", Query: "current"},
+			&model.TagQuery{Name: "deleted", Query: "current"},
+		}
+	}
+	// they change a query and add one, and current session deletes a query and adds one
+	theirs.TagQueries[0].Query = "NOT current"
+	theirs.TagQueries = append(theirs.TagQueries, &model.TagQuery{Name: "theirs", Query: "current"})
+	mine.TagQueries = model.TagQueries{mine.TagQueries[0], &model.TagQuery{Name: "mine", Query: "current"}}
+	err := model.Merge(base, theirs, mine, model.RejectConflicts)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, mine.TagQueries.Names(), []string{"changed", "mine", "theirs"})
+	utils.AssertEqual(t, mine.TagQueries.FromName("changed").Query, "NOT current")
+	// conflicting changes of a query are resolved
+	base, theirs, mine = mergeTestData(), mergeTestData(), mergeTestData()
+	base.TagQueries = model.TagQueries{&model.TagQuery{Name: "query", Query: "current"}}
+	theirs.TagQueries = model.TagQueries{&model.TagQuery{Name: "query", Query: "NOT current"}}
+	mine.TagQueries = model.TagQueries{&model.TagQuery{Name: "query", Query: "current OR current"}}
+	err = model.Merge(base, theirs, mine, model.RejectConflicts)
+	utils.AssertEqual(t, errors.Is(err, model.ErrorUnresolvedConflict), true)
+}
+
+func TestMergeDeletedNotes(t *testing.T) {
+	base, theirs, mine := mergeTestData(), mergeTestData(), mergeTestData()
+	// note deleted by them, but unchanged in current session is dropped
+	theirs.Notes = model.Notes{}
+	err := model.Merge(base, theirs, mine, model.RejectConflicts)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, len(mine.Notes), 0)
+	// note deleted by them, but changed in current session is kept
+	base, theirs, mine = mergeTestData(), mergeTestData(), mergeTestData()
+	theirs.Notes = model.Notes{}
+	mine.Notes[0].Text = "my text"
+	mine.Notes[0].UpdatedAt = 200
+	err = model.Merge(base, theirs, mine, model.RejectConflicts)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, len(mine.Notes), 1)
+}
+
+func TestUpdateDataFileMergesConcurrentChanges(t *testing.T) {
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll("temp_test_dir")
+	// make the clock tick a second on every reading, so that each write gets a distinct timestamp
+	defer func(currentTime func() time.Time) { utils.CurrentTime = currentTime }(utils.CurrentTime)
+	clock := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+	utils.CurrentTime = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	for _, dataFilePath := range []string{"temp_test_dir/mydata.json", "temp_test_dir/mydata.db"} {
+		_ = model.MakeSureFileExists(dataFilePath, false)
+		setup, _ := model.ReadDataFile(dataFilePath, true)
+		_, _ = setup.NewNoteRegistration([]int{}, "shared note")
+		// two sessions load the same data
+		sessionA, _ := model.ReadDataFile(dataFilePath, true)
+		sessionB, _ := model.ReadDataFile(dataFilePath, true)
+		// session A adds a note, and session B updates the shared note
+		_, err := sessionA.NewNoteRegistration([]int{}, "note from A")
+		utils.AssertEqual(t, err, nil)
+		sessionB.SetConflictResolver(model.RejectConflicts)
+		err = sessionB.UpdateNoteText(sessionB.Notes[0], "shared note updated by B")
+		utils.AssertEqual(t, err, nil)
+		// no conflict file is written, and changes from both sessions are kept
+		conflictFiles, _ := filepath.Glob(dataFilePath + "_CONFLICT_*")
+		utils.AssertEqual(t, len(conflictFiles), 0)
+		reminderData, _ := model.ReadDataFile(dataFilePath, true)
+		utils.AssertEqual(t, len(reminderData.Notes), 2)
+		utils.AssertEqual(t, reminderData.Notes[0].Text, "shared note updated by B")
+		utils.AssertEqual(t, reminderData.Notes[1].Text, "note from A")
+		// conflicting change which cannot be resolved is saved to a conflict file
+		sessionA.SetConflictResolver(func(conflict *model.MergeConflict) (bool, error) { return true, nil })
+		err = sessionA.UpdateNoteText(sessionA.Notes[0], "shared note updated by A")
+		utils.AssertEqual(t, err, nil)
+		err = sessionB.UpdateNoteText(sessionB.Notes[0], "shared note updated by B again")
+		utils.AssertEqual(t, err, model.ErrorConflictFile)
+		conflictFiles, _ = filepath.Glob(dataFilePath + "_CONFLICT_*")
+		utils.AssertEqual(t, len(conflictFiles), 1)
+		// the base of a session follows its own saves, so that they aren't taken as changes of another session
+		sessionC, _ := model.ReadDataFile(dataFilePath, true)
+		sessionC.SetConflictResolver(model.RejectConflicts)
+		utils.AssertEqual(t, sessionC.UpdateNoteText(sessionC.Notes[1], "note from A, edited by C"), nil)
+		sessionD, _ := model.ReadDataFile(dataFilePath, true)
+		utils.AssertEqual(t, sessionD.UpdateNoteStatus(sessionD.Notes[1], model.NoteStatus_Done), nil)
+		utils.AssertEqual(t, sessionC.UpdateNoteText(sessionC.Notes[1], "note from A, edited by C again"), nil)
+		reminderData, _ = model.ReadDataFile(dataFilePath, true)
+		utils.AssertEqual(t, reminderData.Notes[1].Text, "note from A, edited by C again")
+		utils.AssertEqual(t, reminderData.Notes[1].Status, model.NoteStatus_Done)
+	}
+}
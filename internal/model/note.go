@@ -1,15 +1,17 @@
 package model
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/goyalmunish/reminder/pkg/calendar"
+	"github.com/goyalmunish/reminder/pkg/ical"
 	"github.com/goyalmunish/reminder/pkg/logger"
 	"github.com/goyalmunish/reminder/pkg/utils"
-	gc "google.golang.org/api/calendar/v3"
 )
 
 /*
@@ -19,17 +21,26 @@ A note can be main or incidental.
 A note can be multiple tags, and a tag can be assocaited with mutiple notes.
 */
 type Note struct {
+	Id       string   `json:"id"` // stable unique identifier (UUID) of the note
 	Text     string   `json:"text"`
 	Comments Comments `json:"comments"`
 	Summary  string   `json:"summary"`
 	// Status can be "pending", "done", or "suspended".
 	// The "pending" status is special, and notes marked with it show up everywhere, whereas
 	// the nodes marked with other status show up only under "Search" or their dedicated menu.
-	Status      NoteStatus `json:"status"`
-	TagIds      []int      `json:"tag_ids"`
-	IsMain      bool       `json:"is_main"`
-	CompleteBy  int64      `json:"complete_by"`
-	tempDueDate int64
+	Status     NoteStatus  `json:"status"`
+	TagIds     []int       `json:"tag_ids"`
+	IsMain     bool        `json:"is_main"`
+	CompleteBy int64       `json:"complete_by"`
+	HasDueTime bool        `json:"has_due_time,omitempty"` // if set, CompleteBy is the exact due time (instead of just due date)
+	TimeZone   string      `json:"time_zone,omitempty"`    // IANA time zone of the due time
+	Recurrence *Recurrence `json:"recurrence,omitempty"`   // repetition of the note, starting from its due date
+	History    Revisions   `json:"history,omitempty"`      // log of changes made to the note
+	SourceUID  string      `json:"source_uid,omitempty"`   // UID (or a key derived from content, without UID) of the calendar entry the note was imported from
+	// CalendarLink is the calendar event of the note, as of the last sync
+	CalendarLink *CalendarLink `json:"calendar_event,omitempty"`
+	TrashedAt    int64         `json:"trashed_at,omitempty"`  // when the note was moved to the trash (refer ReminderData.Trash)
+	ArchivedAt   int64         `json:"archived_at,omitempty"` // when the note was moved to an archive file
 	BaseStruct
 }
 
@@ -45,6 +56,48 @@ const (
 	NoteStatus_Done NoteStatus = "done"
 )
 
+// EnsureIds assigns an id to the note and to each of its comments that doesn't have one yet
+// (such as the ones from older data files). It returns true if any id was assigned.
+func (note *Note) EnsureIds() bool {
+	return note.ensureIds(0)
+}
+
+// ensureIds assigns the missing ids (refer legacyId); the ordinal tells apart the notes with same text
+// and creation time.
+func (note *Note) ensureIds(ordinal int) bool {
+	assigned := false
+	if note.Id == "" {
+		note.Id = legacyId("note", note.CreatedAt, note.Text, ordinal)
+		assigned = true
+	}
+	for index, comment := range note.Comments {
+		if comment.Id == "" {
+			comment.Id = legacyId("comment", note.Id, index, comment.CreatedAt, comment.Text)
+			assigned = true
+		}
+	}
+	return assigned
+}
+
+// legacyId returns the id (a name-based UUID) for an object persisted before ids were introduced.
+// The id is derived from the object itself, so that the same object gets the same id each time the
+// data is read, even if the back-filled ids could not be persisted in the meantime.
+func legacyId(parts ...interface{}) string {
+	name, _ := json.Marshal(parts)
+	return uuid.NewSHA1(uuid.NameSpaceOID, name).String()
+}
+
+// editable returns an error if the note cannot be changed, that is, if it is archived or in the trash.
+func (note *Note) editable() error {
+	if note.ArchivedAt > 0 {
+		return ErrorNoteArchived
+	}
+	if note.TrashedAt > 0 {
+		return ErrorNoteTrashed
+	}
+	return nil
+}
+
 // Type returns type of the note: main or incidental.
 func (note *Note) Type() string {
 	if note.IsMain {
@@ -65,7 +118,7 @@ func (note *Note) Strings() ([]string, error) {
 	strs = append(strs, printNoteField("Status", note.Status))
 	strs = append(strs, printNoteField("Tags", note.TagIds))
 	strs = append(strs, printNoteField("IsMain", note.IsMain))
-	strs = append(strs, printNoteField("CompleteBy", utils.UnixTimestampToLongTimeStr(note.CompleteBy)))
+	strs = append(strs, printNoteField("CompleteBy", note.dueLongText()))
 	strs = append(strs, printNoteField("CreatedAt", utils.UnixTimestampToLongTimeStr(note.CreatedAt)))
 	strs = append(strs, printNoteField("UpdatedAt", utils.UnixTimestampToLongTimeStr(note.UpdatedAt)))
 	return strs, nil
@@ -97,6 +150,31 @@ func (note *Note) ExternalText(reminderData *ReminderData) (string, error) {
 	return strings.Join(strs, ""), nil
 }
 
+// listText returns display text of the note, as shown in a list of notes, with given due date.
+// Refer Notes.ExternalTexts for details.
+func (note *Note) listText(maxStrLen int, repeatAnnuallyTagId int, repeatMonthlyTagId int, dueAt int64) string {
+	columns := note.ListColumns(maxStrLen, repeatAnnuallyTagId, repeatMonthlyTagId, dueAt)
+	return fmt.Sprintf("%*v {R: %s, C:%s, S:%v, D:%v}", -maxStrLen, columns[0], columns[1], columns[2], columns[3], columns[4])
+}
+
+// ListColumns returns the columns of the note, as shown in a list of notes, with given due date:
+// the text (truncated to maxStrLen, if positive), and then R, C, S and D (refer Notes.ExternalTexts).
+func (note *Note) ListColumns(maxStrLen int, repeatAnnuallyTagId int, repeatMonthlyTagId int, dueAt int64) []string {
+	noteText := note.Text
+	if maxStrLen > 0 {
+		if len(noteText) > maxStrLen {
+			noteText = fmt.Sprintf("%v%v", noteText[0:(maxStrLen-3)], "...")
+		}
+	}
+	return []string{
+		noteText,
+		note.RepeatType(repeatAnnuallyTagId, repeatMonthlyTagId),
+		fmt.Sprintf("%02d", len(note.Comments)),
+		strings.ToUpper(string(note.Status)[0:1]),
+		note.DueText(dueAt),
+	}
+}
+
 // SafeExtText prints a note with its tags slugs, but only the safe components.
 // This is used as final external reprensentation for display of a single note to external services like Google Calendar.
 func (note *Note) SafeExtText(tagger Tagger) (string, error) {
@@ -144,7 +222,7 @@ func (note *Note) AddComment(text string) error {
 	if len(strings.TrimSpace(text)) == 0 {
 		return errors.New("Note's comment text is empty")
 	}
-	comment := &Comment{Text: text, BaseStruct: BaseStruct{CreatedAt: utils.CurrentUnixTimestamp()}}
+	comment := &Comment{Id: uuid.NewString(), Text: text, BaseStruct: BaseStruct{CreatedAt: utils.CurrentUnixTimestamp()}}
 	note.Comments = append(note.Comments, comment)
 	defer logger.Info(fmt.Sprintln("Added the comment."))
 	// update the UpdatedAt as well
@@ -153,7 +231,11 @@ func (note *Note) AddComment(text string) error {
 }
 
 // UpdateTags updates note's tags.
-func (note *Note) UpdateTags(tagIDs []int) error {
+// The tags (as looked up from tags) must follow the rules of their groups.
+func (note *Note) UpdateTags(tagIDs []int, tags Tags, rules TagGroupRules) error {
+	if err := tags.CheckGroupRules(tagIDs, rules); err != nil {
+		return err
+	}
 	note.TagIds = tagIDs
 	defer logger.Info(fmt.Sprintln("Updated the note with tags."))
 	// update the UpdatedAt as well
@@ -161,11 +243,13 @@ func (note *Note) UpdateTags(tagIDs []int) error {
 	return nil
 }
 
-// UpdateStatus updates note's status ("done"/"pending").
-// Status of a note tag with repeat tag cannot be mared as "done".
+// UpdateStatus updates note's status ("done"/"pending"/"suspended").
+// A recurring note can be marked as "done" or "suspended", which ends its series. A note tagged with repeat tag
+// is recurring once its recurrence is set from the tag (which is done while reading the data file); until then,
+// it cannot be marked as "done".
 func (note *Note) UpdateStatus(status NoteStatus, repeatTagIDs []int) error {
 	noteIDsWithRepeat := utils.GetCommonMembersOfSlices(note.TagIds, repeatTagIDs)
-	if len(noteIDsWithRepeat) != 0 {
+	if status == NoteStatus_Done && len(noteIDsWithRepeat) != 0 && note.Recurrence == nil {
 		return errors.New("Note is part of a \"repeat\" group")
 	}
 	if note.Status == status {
@@ -212,8 +296,11 @@ func (note *Note) UpdateSummary(text string) error {
 	return nil
 }
 
-// UpdateCompleteBy updates note's due date.
-// The input is of the form DD-MM-YYYY or just DD-MM (with implicity value for year; either current or next).
+// UpdateCompleteBy updates note's due date, and optionally its due time.
+// The input is of the form DD-MM-YYYY or just DD-MM (with implicity value for year; either current or next),
+// or a natural language phrase (such as "tomorrow" or "in 3 weeks"; refer utils.ParseDate),
+// optionally followed by due time HH:MM and then IANA time zone (such as "12-05-2026 14:30 Europe/London").
+// For a due time without time zone, the existing time zone of the note (or else the local one) is used.
 // If input is "nil", the existing due date is cleared.
 func (note *Note) UpdateCompleteBy(text string) error {
 	// handle edge-case of empty text
@@ -223,39 +310,127 @@ func (note *Note) UpdateCompleteBy(text string) error {
 	// happy path
 	if text == "nil" {
 		note.CompleteBy = 0
+		note.HasDueTime = false
 		defer logger.Info(fmt.Sprintln("Cleared the due date from the note."))
 	} else {
-		format := "2-1-2006"
-		// set current year as year if year part is missing
-		timeSplit := strings.Split(text, "-")
-		if len(timeSplit) == 2 {
-			year, err := utils.YearForDueDateDDMM(text)
+		// resolve the date (which can also be a phrase like "next friday") to DD-MM-YYYY
+		normalized, err := utils.NormalizeDueDate(text)
+		if err != nil {
+			return err
+		}
+		fields := strings.Fields(normalized)
+		dateText := fields[0]
+		if len(fields) == 1 {
+			// parse and set the date
+			// note: this time value that date/month/year in 00:00:00 GMT+0000
+			timeValue, _ := time.Parse("2-1-2006", dateText)
+			note.CompleteBy = int64(timeValue.Unix())
+			note.HasDueTime = false
+			defer logger.Info(fmt.Sprintln("Updated the note with new due date."))
+		} else {
+			timeZone := note.TimeZone
+			if len(fields) == 3 {
+				timeZone = fields[2]
+			}
+			if timeZone == "" {
+				timeZone = utils.LocalZoneName()
+			}
+			location, err := time.LoadLocation(timeZone)
+			if err != nil {
+				return fmt.Errorf("Invalid time zone %q: %w", timeZone, err)
+			}
+			timeValue, err := time.ParseInLocation("2-1-2006 15:04", fmt.Sprintf("%s %s", dateText, fields[1]), location)
 			if err != nil {
-				return err
+				return fmt.Errorf("Invalid due time %q: %w", fields[1], err)
 			}
-			text = fmt.Sprintf("%s-%d", text, year)
+			note.CompleteBy = timeValue.Unix()
+			note.HasDueTime = true
+			note.TimeZone = timeZone
+			defer logger.Info(fmt.Sprintln("Updated the note with new due date and time."))
 		}
-		// parse and set the date
-		// note: this time value that date/month/year in 00:00:00 GMT+0000
-		timeValue, _ := time.Parse(format, text)
-		note.CompleteBy = int64(timeValue.Unix())
-		defer logger.Info(fmt.Sprintln("Updated the note with new due date."))
 	}
 	// update the UpdatedAt as well
 	note.UpdatedAt = utils.CurrentUnixTimestamp()
 	return nil
 }
 
-// RepeatType return - (Not-repeat), A (Annual-Repeat), or M (Monthly-Repeat) string
+// DueLocation returns location of time zone of the note (UTC, if it is not set or is invalid).
+func (note *Note) DueLocation() *time.Location {
+	if note.TimeZone != "" {
+		if location, err := time.LoadLocation(note.TimeZone); err == nil {
+			return location
+		}
+	}
+	return time.UTC
+}
+
+// dueStart returns due date (with due time, if any) of the note, as start of its recurrence.
+// Without due time, it is the due date at 00:00:00 GMT+0000.
+func (note *Note) dueStart() time.Time {
+	if note.HasDueTime {
+		return time.Unix(note.CompleteBy, 0).In(note.DueLocation())
+	}
+	return dateOf(time.Unix(note.CompleteBy, 0))
+}
+
+// DueText returns short display text of (an occurrence of) the due date of the note.
+// For a note with due time, the time is shown in time zone of the note.
+func (note *Note) DueText(dueAt int64) string {
+	if note.HasDueTime && (dueAt > 0) {
+		return time.Unix(dueAt, 0).In(note.DueLocation()).Format("02-Jan-06 15:04")
+	}
+	return utils.UnixTimestampToShortTimeStr(dueAt)
+}
+
+// dueLongText returns long display text of the due date of the note.
+func (note *Note) dueLongText() string {
+	if note.HasDueTime && (note.CompleteBy > 0) {
+		return fmt.Sprintf("%v (%v)", time.Unix(note.CompleteBy, 0).In(note.DueLocation()).Format(time.RFC850), note.TimeZone)
+	}
+	return utils.UnixTimestampToLongTimeStr(note.CompleteBy)
+}
+
+// UpdateRecurrence updates note's recurrence.
+// The input is of the form accepted by ParseRecurrence (such as "weekly:mo,we" or "monthly:last").
+// If input is "nil", the existing recurrence is cleared.
+func (note *Note) UpdateRecurrence(text string) error {
+	recurrence, err := ParseRecurrence(text)
+	if err != nil {
+		return err
+	}
+	if (recurrence != nil) && (note.CompleteBy == 0) {
+		return errors.New("Note's due date is required for recurrence")
+	}
+	// happy path
+	note.Recurrence = recurrence
+	if recurrence == nil {
+		defer logger.Info(fmt.Sprintln("Cleared the recurrence from the note."))
+	} else {
+		defer logger.Info(fmt.Sprintln("Updated the recurrence."))
+	}
+	// update the UpdatedAt as well
+	note.UpdatedAt = utils.CurrentUnixTimestamp()
+	return nil
+}
+
+// EffectiveRecurrence returns recurrence of the note.
+// For a note without recurrence, the one corresponding to its "repeat-annually" or "repeat-monthly" tag
+// is returned (if any).
+func (note *Note) EffectiveRecurrence(repeatAnnuallyTagId int, repeatMonthlyTagId int) *Recurrence {
+	if note.Recurrence != nil {
+		return note.Recurrence
+	}
+	return recurrenceFromRepeatTags(note.TagIds, repeatAnnuallyTagId, repeatMonthlyTagId)
+}
+
+// RepeatType return - (Not-repeat), A (Annual-Repeat), M (Monthly-Repeat), W (Weekly-Repeat), or D (Daily-Repeat) string
 // representing repeat-type of the note
 func (note *Note) RepeatType(repeatAnnuallyTagId int, repeatMonthlyTagId int) string {
-	repeat := "-" // non-repeat
-	if utils.IsMemberOfSlice(repeatAnnuallyTagId, note.TagIds) {
-		repeat = "A"
-	} else if utils.IsMemberOfSlice(repeatMonthlyTagId, note.TagIds) {
-		repeat = "M"
+	recurrence := note.EffectiveRecurrence(repeatAnnuallyTagId, repeatMonthlyTagId)
+	if recurrence == nil {
+		return "-" // non-repeat
 	}
-	return repeat
+	return recurrence.RepeatType()
 }
 
 // ToggleMainFlag toggles note's main flag.
@@ -267,70 +442,87 @@ func (note *Note) ToggleMainFlag() error {
 	return nil
 }
 
-// GoogleCalendarEvent converts a note to Google Calendar Event.
-func (note *Note) GoogleCalendarEvent(repeatAnnuallyTagId int, repeatMonthlyTagId int, timezoneIANA string, tagger Tagger) (*gc.Event, error) {
+// CalendarEvent converts a note to calendar event.
+// A note with due time is at its due time (in time zone of the note), whereas a note without due time is at
+// 10 AM in the given time zone (of the calendar), for notification purpose.
+func (note *Note) CalendarEvent(repeatAnnuallyTagId int, repeatMonthlyTagId int, timezoneIANA string, tagger Tagger) (*calendar.Event, error) {
 	// basic information
 	title := note.Text
 	start := utils.UnixTimestampToTime(note.CompleteBy) // this is the original time in 00:00:00 GMT+0000
-	offset, err := utils.GetZoneFromLocation(timezoneIANA)
-	if err != nil {
-		return nil, fmt.Errorf("Couldn't calculate offset for timezone %q; %w", timezoneIANA, err)
+	if note.HasDueTime {
+		// the event is at the due time, in time zone of the note
+		timezoneIANA = note.TimeZone
+		start = time.Unix(note.CompleteBy, 0).In(note.DueLocation())
+	} else {
+		offset, err := utils.GetZoneFromLocation(timezoneIANA)
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't calculate offset for timezone %q; %w", timezoneIANA, err)
+		}
+		start = start.Add(offset)          // adjusting the start to local time for notification purpose
+		start = start.Add(-14 * time.Hour) // set notification for 10 AM of given timezoneIANA
 	}
-	start = start.Add(offset)          // adjusting the start to local time for notification purpose
-	start = start.Add(-14 * time.Hour) // set notification for 10 AM of given timezoneIANA
-	repeatType := note.RepeatType(repeatAnnuallyTagId, repeatMonthlyTagId)
+	repeat := note.EffectiveRecurrence(repeatAnnuallyTagId, repeatMonthlyTagId)
 	description, err := note.SafeExtText(tagger)
 	if err != nil {
 		return nil, err
 	}
 
-	// lego the information
-	var recurrence []string
-	title = fmt.Sprintf("%s%s", calendar.TitlePrefix, title)
-	startRFC3339 := &gc.EventDateTime{
-		DateTime: start.Format(time.RFC3339),
-		TimeZone: timezoneIANA,
+	// construct the event
+	event := &calendar.Event{
+		// the note is identified even if the event isn't linked to it (such as after an interrupted sync)
+		NoteId:      note.Id,
+		Title:       fmt.Sprintf("%s%s", calendar.TitlePrefix, title),
+		Description: description,
+		Start:       start,
+		End:         start.Add(time.Duration(30 * time.Minute)), // keeping the event for duration of only 30 mins
+		TimeZone:    timezoneIANA,
 	}
-	endRFC3339 := &gc.EventDateTime{
-		DateTime: start.Add(time.Duration(30 * time.Minute)).Format(time.RFC3339), // keeping the event for duration of only 30 mins
-		TimeZone: timezoneIANA,
+	if repeat != nil {
+		event.Recurrence = []string{repeat.RRule()}
 	}
-	source := &gc.EventSource{
-		Title: "reminder",
-		Url:   "https://github.com/goyalmunish/reminder",
+	return event, nil
+}
+
+// ICalComponent returns the iCalendar event (or to-do, with kind as ical.KindTodo) of the note.
+// Like its Google Calendar event, a note with due time is at the due time (in time zone of the note), and a recurring
+// note repeats as per its recurrence (starting from its due date); a note without due time is an all-day entry.
+func (note *Note) ICalComponent(repeatAnnuallyTagId int, repeatMonthlyTagId int, kind string, tagger Tagger) (*ical.Component, error) {
+	if note.CompleteBy == 0 {
+		return nil, errors.New("Note has no due date")
 	}
-	rem := &gc.EventReminders{
-		Overrides:  []*gc.EventReminder{},
-		UseDefault: true,
+	description, err := note.SafeExtText(tagger)
+	if err != nil {
+		return nil, err
 	}
-	// Refer https://developers.google.com/calendar/api/concepts/events-calendars
-	switch repeatType {
-	case "-":
-		recurrence = []string{}
-	case "A":
-		recurrence = []string{"RRULE:FREQ=YEARLY"}
-	case "M":
-		recurrence = []string{"RRULE:FREQ=MONTHLY"}
+	component := &ical.Component{
+		Kind:        kind,
+		UID:         note.Id + "@reminder",
+		Summary:     note.Text,
+		Description: description,
+		Categories:  tagger.TagsFromIds(note.TagIds),
+		Status:      "CONFIRMED",
+		// the stamp is taken from the note (rather than the current time), so that unchanged notes are
+		// always encoded the same (such as for the ETag of the live feed)
+		Stamp: time.Unix(note.CreatedAt, 0),
 	}
-
-	// construct the event
-	event := &gc.Event{
-		// ICalUID
-		// Id
-		// Created
-		// Updated
-		Summary:      title,
-		Description:  description,
-		Start:        startRFC3339,
-		End:          endRFC3339,
-		Recurrence:   recurrence,
-		ColorId:      "10", // "Basil" color
-		Reminders:    rem,
-		EventType:    "default",
-		Source:       source,
-		Status:       "confirmed",
-		Transparency: "transparent",
-		Visibility:   "default",
+	if kind == ical.KindTodo {
+		component.Status = "NEEDS-ACTION"
 	}
-	return event, nil
+	if note.UpdatedAt > 0 {
+		component.Stamp = time.Unix(note.UpdatedAt, 0)
+		component.LastModified = component.Stamp
+	}
+	if note.HasDueTime {
+		component.Start = time.Unix(note.CompleteBy, 0).In(note.DueLocation())
+		component.End = component.Start.Add(30 * time.Minute) // keeping the event for duration of only 30 mins
+		component.TimeZone = note.TimeZone
+	} else {
+		component.AllDay = true
+		component.Start = time.Unix(note.CompleteBy, 0).UTC() // this is the original time in 00:00:00 GMT+0000
+		component.End = component.Start.AddDate(0, 0, 1)
+	}
+	if repeat := note.EffectiveRecurrence(repeatAnnuallyTagId, repeatMonthlyTagId); repeat != nil {
+		component.RRule = repeat.RRule()
+	}
+	return component, nil
 }
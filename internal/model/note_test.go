@@ -4,10 +4,12 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	model "github.com/goyalmunish/reminder/internal/model"
+	"github.com/goyalmunish/reminder/pkg/calendar"
+	"github.com/goyalmunish/reminder/pkg/ical"
 	"github.com/goyalmunish/reminder/pkg/utils"
-	gc "google.golang.org/api/calendar/v3"
 )
 
 func TestNoteStrings(t *testing.T) {
@@ -127,6 +129,7 @@ func TestNoteAddComment(t *testing.T) {
 	utils.AssertEqual(t, err, nil)
 	utils.AssertEqual(t, len(note1.Comments), 1)
 	utils.AssertEqual(t, strings.Contains(note1.Comments[0].Text, "test comment 1"), true)
+	utils.AssertEqual(t, note1.Comments[0].Id != "", true)
 	// case 2
 	err = note1.AddComment("test comment 2")
 	utils.AssertEqual(t, err, nil)
@@ -139,20 +142,67 @@ func TestNoteAddComment(t *testing.T) {
 	utils.AssertEqual(t, strings.Contains(note1.Comments[1].Text, "test comment 2"), true)
 }
 
+func TestNoteEnsureIds(t *testing.T) {
+	comments := model.Comments{&model.Comment{Text: "c1"}, &model.Comment{Id: "existing-comment-id", Text: "c2"}}
+	note := model.Note{Text: "a note without id", Comments: comments}
+	// case 1: missing ids are assigned
+	utils.AssertEqual(t, note.EnsureIds(), true)
+	utils.AssertEqual(t, note.Id != "", true)
+	utils.AssertEqual(t, note.Comments[0].Id != "", true)
+	utils.AssertEqual(t, note.Comments[1].Id, "existing-comment-id")
+	// case 2: existing ids are kept as they are
+	noteId := note.Id
+	utils.AssertEqual(t, note.EnsureIds(), false)
+	utils.AssertEqual(t, note.Id, noteId)
+	// case 3: same note read again gets same ids
+	sameNote := model.Note{Text: "a note without id", Comments: model.Comments{&model.Comment{Text: "c1"}}}
+	utils.AssertEqual(t, sameNote.EnsureIds(), true)
+	utils.AssertEqual(t, sameNote.Id, noteId)
+	utils.AssertEqual(t, sameNote.Comments[0].Id, note.Comments[0].Id)
+}
+
+func TestNotesEnsureIds(t *testing.T) {
+	newNotes := func() model.Notes {
+		return model.Notes{
+			&model.Note{Text: "pay bills", BaseStruct: model.BaseStruct{CreatedAt: 100}},
+			&model.Note{Text: "pay bills", BaseStruct: model.BaseStruct{CreatedAt: 100}},
+			&model.Note{Id: "existing-note-id", Text: "call bank"},
+		}
+	}
+	notes := newNotes()
+	utils.AssertEqual(t, notes.EnsureIds(), 2)
+	// notes with same text and creation time get different ids
+	utils.AssertEqual(t, notes[0].Id != notes[1].Id, true)
+	utils.AssertEqual(t, notes[2].Id, "existing-note-id")
+	// same notes read again get same ids
+	notesRe := newNotes()
+	_ = notesRe.EnsureIds()
+	utils.AssertEqual(t, notesRe[0].Id, notes[0].Id)
+	utils.AssertEqual(t, notesRe[1].Id, notes[1].Id)
+}
+
 func TestNoteUpdateTags(t *testing.T) {
 	// create notes
 	note1 := model.Note{Text: "original text", Status: model.NoteStatus_Pending, TagIds: []int{1, 4}, BaseStruct: model.BaseStruct{UpdatedAt: 1600000001}}
 	// update TagIds
 	// case 1
 	tagIds := []int{2, 5}
-	err := note1.UpdateTags(tagIds)
+	err := note1.UpdateTags(tagIds, nil, nil)
 	utils.AssertEqual(t, err, nil)
 	utils.AssertEqual(t, note1.TagIds, tagIds)
 	// case 2
 	tagIds = []int{}
-	err = note1.UpdateTags(tagIds)
+	err = note1.UpdateTags(tagIds, nil, nil)
 	utils.AssertEqual(t, err, nil)
 	utils.AssertEqual(t, note1.TagIds, tagIds)
+	// case 3: tags of a single-choice group
+	tags := model.BasicTags()
+	err = note1.UpdateTags([]int{0, 1, 3}, tags, model.DefaultTagGroupRules())
+	utils.AssertEqual(t, errors.Is(err, model.ErrorTagGroupRule), true)
+	utils.AssertEqual(t, note1.TagIds, tagIds)
+	err = note1.UpdateTags([]int{0, 1, 3}, tags, model.TagGroupRules{"priority": model.TagGroupRule_Multi})
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, note1.TagIds, []int{0, 1, 3})
 }
 
 func TestNoteUpdateStatus(t *testing.T) {
@@ -171,6 +221,15 @@ func TestNoteUpdateStatus(t *testing.T) {
 	err = note1.UpdateStatus(model.NoteStatus_Pending, []int{5, 6, 7})
 	utils.AssertEqual(t, err, nil)
 	utils.AssertEqual(t, note1.Status, model.NoteStatus_Pending)
+	// case 4: a note with repeat tag can still be suspended
+	err = note1.UpdateStatus(model.NoteStatus_Suspended, []int{1, 2, 3})
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, note1.Status, model.NoteStatus_Suspended)
+	// case 5: a note with repeat tag can be marked as done once its recurrence is set, which ends its series
+	note1.Recurrence = &model.Recurrence{Frequency: model.RecurrenceFrequency_Yearly}
+	err = note1.UpdateStatus(model.NoteStatus_Done, []int{1, 2, 3})
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, note1.Status, model.NoteStatus_Done)
 }
 
 func TestNoteUpdateText(t *testing.T) {
@@ -218,6 +277,52 @@ func TestNoteUpdateCompleteBy(t *testing.T) {
 	err = note1.UpdateCompleteBy("nil")
 	utils.AssertEqual(t, err, nil)
 	utils.AssertEqual(t, note1.CompleteBy, 0)
+	// case 4: with due time in given time zone
+	err = note1.UpdateCompleteBy("12-05-2026 14:30 Australia/Melbourne")
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, note1.CompleteBy, 1778560200) // Tue May 12 2026 04:30:00 GMT+0000
+	utils.AssertEqual(t, note1.HasDueTime, true)
+	utils.AssertEqual(t, note1.TimeZone, "Australia/Melbourne")
+	utils.AssertEqual(t, note1.DueText(note1.CompleteBy), "12-May-26 14:30")
+	// case 5: with due time in existing time zone of the note
+	err = note1.UpdateCompleteBy("12-07-2026 9:00")
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, note1.CompleteBy, 1783810800) // Sat Jul 11 2026 23:00:00 GMT+0000
+	utils.AssertEqual(t, note1.TimeZone, "Australia/Melbourne")
+	// case 6: invalid time zone
+	err = note1.UpdateCompleteBy("12-07-2026 9:00 Mars/Olympus")
+	utils.AssertEqual(t, strings.Contains(err.Error(), "Invalid time zone"), true)
+	utils.AssertEqual(t, note1.CompleteBy, 1783810800)
+	// case 7: back to only due date
+	err = note1.UpdateCompleteBy("31-12-2022")
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, note1.CompleteBy, 1672444800)
+	utils.AssertEqual(t, note1.HasDueTime, false)
+	// case 8: natural language due date (relative to current time)
+	defer func(ct func() time.Time) { utils.CurrentTime = ct }(utils.CurrentTime)
+	utils.CurrentTime = func() time.Time { return time.Date(2026, 11, 3, 18, 30, 0, 0, time.UTC) }
+	err = note1.UpdateCompleteBy("next friday")
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, note1.CompleteBy, 1793923200) // Fri Nov 06 2026 00:00:00 GMT+0000
+	err = note1.UpdateCompleteBy("in 3 weeks at 14:30 UTC")
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, note1.CompleteBy, 1795530600) // Tue Nov 24 2026 14:30:00 GMT+0000
+	utils.AssertEqual(t, note1.HasDueTime, true)
+	// case 9: unknown phrase
+	err = note1.UpdateCompleteBy("someday")
+	utils.AssertEqual(t, err != nil, true)
+	utils.AssertEqual(t, note1.CompleteBy, 1795530600)
+}
+
+func TestCalendarEventWithDueTime(t *testing.T) {
+	note := model.Note{Text: "dentist", Status: model.NoteStatus_Pending, TagIds: []int{1}}
+	utils.AssertEqual(t, note.UpdateCompleteBy("12-05-2026 14:30 Europe/London"), nil)
+	event, err := note.CalendarEvent(-1, -1, "Australia/Melbourne", TestTagger{})
+	utils.AssertEqual(t, err, nil)
+	// the event is at the due time in time zone of the note (instead of 10 AM in the given time zone)
+	utils.AssertEqual(t, event.Start.Format(time.RFC3339), "2026-05-12T14:30:00+01:00")
+	utils.AssertEqual(t, event.TimeZone, "Europe/London")
+	utils.AssertEqual(t, event.End.Format(time.RFC3339), "2026-05-12T15:00:00+01:00")
 }
 
 func TestNoteRepeatType(t *testing.T) {
@@ -250,7 +355,7 @@ func TestNoteToggleMainFlag(t *testing.T) {
 	utils.AssertEqual(t, originalPriority != note1.IsMain, true)
 }
 
-func TestGoogleCalendarEvent(t *testing.T) {
+func TestCalendarEvent(t *testing.T) {
 	tagger := TestTagger{}
 	var tests = []struct {
 		name          string // has to be string
@@ -259,7 +364,7 @@ func TestGoogleCalendarEvent(t *testing.T) {
 		inputRMTID    int
 		inputTimezone string
 		inputTagger   model.Tagger
-		want          *gc.Event
+		want          *calendar.Event
 		wantErr       error
 		wantedErr     bool
 	}{
@@ -270,21 +375,58 @@ func TestGoogleCalendarEvent(t *testing.T) {
 			inputRMTID:    3,
 			inputTimezone: "Australia/Melbourne",
 			inputTagger:   tagger,
-			want: &gc.Event{
-				Summary: "[reminder] original text",
+			want: &calendar.Event{
+				Title: "[reminder] original text",
 			},
 			wantedErr: false,
 		},
 	}
 	for position, subtest := range tests {
 		t.Run(subtest.name, func(t *testing.T) {
-			got, err := subtest.note.GoogleCalendarEvent(subtest.inputRATID, subtest.inputRMTID, subtest.inputTimezone, tagger)
+			got, err := subtest.note.CalendarEvent(subtest.inputRATID, subtest.inputRMTID, subtest.inputTimezone, tagger)
 			if (err != nil) != subtest.wantedErr {
-				t.Fatalf("GoogleCalendarEvent case %q (position=%d) with input <%+v> returns error <%v>; wantError <%v>", subtest.name, position, subtest.note, err, subtest.wantErr)
+				t.Fatalf("CalendarEvent case %q (position=%d) with input <%+v> returns error <%v>; wantError <%v>", subtest.name, position, subtest.note, err, subtest.wantErr)
 			}
-			if got.Summary != subtest.want.Summary {
-				t.Errorf("GoogleCalendarEvent case %q (position=%d) with input <%+v> returns <%+v>; want <%+v>", subtest.name, position, subtest.note, got, subtest.want)
+			if got.Title != subtest.want.Title {
+				t.Errorf("CalendarEvent case %q (position=%d) with input <%+v> returns <%+v>; want <%+v>", subtest.name, position, subtest.note, got, subtest.want)
 			}
 		})
 	}
 }
+
+func TestNoteICalComponent(t *testing.T) {
+	tagger := TestTagger{}
+	// a note without due date has no calendar entry
+	note := &model.Note{Id: "abc", Text: "learn go", Status: model.NoteStatus_Pending}
+	_, err := note.ICalComponent(-1, -1, ical.KindEvent, tagger)
+	utils.AssertEqual(t, err.Error(), "Note has no due date")
+	// a note without due time is an all-day entry, which repeats as per its recurrence
+	recurrence, _ := model.ParseRecurrence("monthly:last")
+	note = &model.Note{Id: "abc", Text: "pay rent", Status: model.NoteStatus_Pending, TagIds: []int{1}, CompleteBy: 1778544000, Recurrence: recurrence, BaseStruct: model.BaseStruct{UpdatedAt: 1778000000}}
+	component, err := note.ICalComponent(-1, -1, ical.KindEvent, tagger)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, component.UID, "abc@reminder")
+	utils.AssertEqual(t, component.AllDay, true)
+	utils.AssertEqual(t, component.Start, time.Date(2026, 5, 12, 0, 0, 0, 0, time.UTC))
+	utils.AssertEqual(t, component.End, time.Date(2026, 5, 13, 0, 0, 0, 0, time.UTC))
+	utils.AssertEqual(t, component.RRule, "RRULE:FREQ=MONTHLY;BYMONTHDAY=-1")
+	utils.AssertEqual(t, component.Categories, []string{"0-1"})
+	utils.AssertEqual(t, component.Stamp.Unix(), int64(1778000000))
+	utils.AssertEqual(t, component.Status, "CONFIRMED")
+	// a note with due time is at its due time, in its time zone
+	note = &model.Note{Id: "abc", Text: "call", Status: model.NoteStatus_Pending}
+	utils.AssertEqual(t, note.UpdateCompleteBy("12-05-2026 14:30 Europe/London"), nil)
+	component, err = note.ICalComponent(-1, -1, ical.KindTodo, tagger)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, component.Kind, ical.KindTodo)
+	utils.AssertEqual(t, component.AllDay, false)
+	utils.AssertEqual(t, component.TimeZone, "Europe/London")
+	utils.AssertEqual(t, component.Start.Format(time.RFC3339), "2026-05-12T14:30:00+01:00")
+	utils.AssertEqual(t, component.RRule, "")
+	utils.AssertEqual(t, component.Status, "NEEDS-ACTION")
+	// the stamp of a note which was never updated is its creation time (irrespective of current time)
+	note = &model.Note{Id: "abc", Text: "call", Status: model.NoteStatus_Pending, CompleteBy: 1778544000, BaseStruct: model.BaseStruct{CreatedAt: 1777000000}}
+	component, _ = note.ICalComponent(-1, -1, ical.KindEvent, tagger)
+	utils.AssertEqual(t, component.Stamp.Unix(), int64(1777000000))
+	utils.AssertEqual(t, component.LastModified.IsZero(), true)
+}
@@ -1,9 +1,6 @@
 package model
 
 import (
-	"fmt"
-	"strings"
-
 	"github.com/goyalmunish/reminder/pkg/utils"
 )
 
@@ -27,25 +24,71 @@ func (notes Notes) ExternalTexts(maxStrLen int, repeatAnnuallyTagId int, repeatM
 	// assuming there are at least (on average) 100s of notes
 	allTexts := make([]string, 0, 100)
 	for _, note := range notes {
-		noteText := note.Text
-		if maxStrLen > 0 {
-			if len(noteText) > maxStrLen {
-				noteText = fmt.Sprintf("%v%v", noteText[0:(maxStrLen-3)], "...")
+		allTexts = append(allTexts, note.listText(maxStrLen, repeatAnnuallyTagId, repeatMonthlyTagId, note.CompleteBy))
+	}
+	return allTexts
+}
+
+// EnsureIds makes sure that each of the notes (and their comments) has an id.
+// It returns number of notes for which any id was assigned.
+func (notes Notes) EnsureIds() int {
+	taken := make(map[string]bool)
+	for _, note := range notes {
+		taken[note.Id] = true
+	}
+	count := 0
+	for _, note := range notes {
+		ordinal := 0
+		if note.Id == "" {
+			for taken[legacyId("note", note.CreatedAt, note.Text, ordinal)] {
+				ordinal++
 			}
 		}
-		noteText = fmt.Sprintf(
-			"%*v {R: %s, C:%02d, S:%v, D:%v}", -maxStrLen, noteText,
-			note.RepeatType(repeatAnnuallyTagId, repeatMonthlyTagId), len(note.Comments), strings.ToUpper(string(note.Status)[0:1]), utils.UnixTimestampToShortTimeStr(note.CompleteBy))
-		allTexts = append(allTexts, noteText)
+		if note.ensureIds(ordinal) {
+			count++
+		}
+		taken[note.Id] = true
 	}
-	return allTexts
+	return count
 }
 
-// PopulateTempDueDate popultes tempDueDate field of note from its CompleteBy field.
-func (notes Notes) PopulateTempDueDate() {
+// WithId returns the note with given id.
+// It returns nil if no such note is found.
+func (notes Notes) WithId(id string) *Note {
 	for _, note := range notes {
-		note.tempDueDate = note.CompleteBy
+		if note.Id == id {
+			return note
+		}
 	}
+	return nil
+}
+
+// Query filters-in notes matching the query.
+// It returns empty Notes if no matching Note is found.
+func (notes Notes) Query(query NoteQuery) Notes {
+	var result Notes
+	for _, note := range notes {
+		if query.Status != "" && note.Status != query.Status {
+			continue
+		}
+		if query.OnlyMain && !note.IsMain {
+			continue
+		}
+		if query.DueBefore > 0 && (note.CompleteBy == 0 || note.CompleteBy > query.DueBefore) {
+			continue
+		}
+		hasAllTags := true
+		for _, tagID := range query.TagIds {
+			if !utils.IsMemberOfSlice(tagID, note.TagIds) {
+				hasAllTags = false
+				break
+			}
+		}
+		if hasAllTags && (query.TagExpr == nil || query.TagExpr.Matches(note.TagIds)) {
+			result = append(result, note)
+		}
+	}
+	return result
 }
 
 // WithStatus filters-in notes with given status (such as "pending" status).
@@ -96,3 +139,47 @@ func (notes Notes) WithTagIdAndStatus(tagID int, status NoteStatus) Notes {
 	}
 	return result
 }
+
+// WithTagId filters-in the notes (of any status) with given tagID.
+func (notes Notes) WithTagId(tagID int) Notes {
+	var result Notes
+	for _, note := range notes {
+		if utils.IsMemberOfSlice(tagID, note.TagIds) {
+			result = append(result, note)
+		}
+	}
+	return result
+}
+
+// WithAnyTagId filters-in the notes (of any status) having any of given tagIDs.
+func (notes Notes) WithAnyTagId(tagIDs []int) Notes {
+	var result Notes
+	for _, note := range notes {
+		if hasAnyTagId(note, tagIDs) {
+			result = append(result, note)
+		}
+	}
+	return result
+}
+
+// hasAnyTagId tells if the note has any of given tagIDs.
+func hasAnyTagId(note *Note, tagIDs []int) bool {
+	for _, tagID := range tagIDs {
+		if utils.IsMemberOfSlice(tagID, note.TagIds) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithoutId returns the notes except the one with given id.
+// It returns empty Notes if no other note is there.
+func (notes Notes) WithoutId(id string) Notes {
+	result := Notes{}
+	for _, note := range notes {
+		if note.Id != id {
+			result = append(result, note)
+		}
+	}
+	return result
+}
@@ -3,10 +3,11 @@ package model
 /*
 A NotesByDueDate is a slice of Note objects.
 
-By default it is sorted by its CreatedAt field.
+By default it is sorted by its CompleteBy field.
+To sort by projected due dates (of recurring notes), use Occurrences instead.
 */
 type NotesByDueDate []*Note
 
 func (c NotesByDueDate) Len() int           { return len(c) }
 func (c NotesByDueDate) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
-func (c NotesByDueDate) Less(i, j int) bool { return c[i].tempDueDate < c[j].tempDueDate }
+func (c NotesByDueDate) Less(i, j int) bool { return c[i].CompleteBy < c[j].CompleteBy }
@@ -14,7 +14,6 @@ func TestNotesByDueDate(t *testing.T) {
 	notes = append(notes, &model.Note{Text: "2", Status: model.NoteStatus_Pending, BaseStruct: model.BaseStruct{UpdatedAt: 1600000004}, CompleteBy: 1800000004})
 	notes = append(notes, &model.Note{Text: "3", Status: model.NoteStatus_Done, BaseStruct: model.BaseStruct{UpdatedAt: 1600000003}, CompleteBy: 1800000002})
 	notes = append(notes, &model.Note{Text: "4", Status: model.NoteStatus_Done, BaseStruct: model.BaseStruct{UpdatedAt: 1600000002}, CompleteBy: 1800000001})
-	model.Notes(notes).PopulateTempDueDate()
 	sort.Sort(model.NotesByDueDate(notes))
 	var gotTexts []string
 	for _, value := range notes {
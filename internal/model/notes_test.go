@@ -140,3 +140,17 @@ func TestNotesWithTagIdAndStatus(t *testing.T) {
 	// case 6
 	utils.AssertEqual(t, notes.WithTagIdAndStatus(1, model.NoteStatus_Suspended), []*model.Note{&note6})
 }
+
+func TestNotesWithId(t *testing.T) {
+	var notes model.Notes
+	// case 1 (no notes)
+	utils.AssertEqual(t, notes.WithId("id-1") == nil, true)
+	// add some notes
+	note1 := model.Note{Id: "id-1", Text: "big fat cat"}
+	note2 := model.Note{Id: "id-2", Text: "cute brown dog"}
+	notes = append(notes, &note1, &note2)
+	// case 2 (existing id)
+	utils.AssertEqual(t, notes.WithId("id-2"), &note2)
+	// case 3 (non-existing id)
+	utils.AssertEqual(t, notes.WithId("id-3") == nil, true)
+}
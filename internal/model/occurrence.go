@@ -0,0 +1,83 @@
+package model
+
+/*
+An Occurrence represents projection of due date of a note as seen at a point in time.
+
+For a non-recurring note, it is the due date of the note, whereas for a recurring note it is
+its occurrence matching the point in time (the recent one, if it is still within its display window,
+otherwise the next one).
+The window is the period during which the note is displayed as approaching its due date.
+An occurrence is a value computed from the note; it never modifies the note.
+*/
+type Occurrence struct {
+	Note        *Note
+	DueAt       int64 // 0 if the note has no due date
+	WindowStart int64
+	WindowEnd   int64 // 0 if the window is open-ended (that is, until the note is marked done)
+}
+
+/*
+An Occurrences is a slice of Occurrence objects.
+
+By default it is sorted by its DueAt field.
+*/
+type Occurrences []*Occurrence
+
+func (c Occurrences) Len() int           { return len(c) }
+func (c Occurrences) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c Occurrences) Less(i, j int) bool { return c[i].DueAt < c[j].DueAt }
+
+// ProjectOccurrence projects due date of the note (with given effective recurrence, if any) as seen at given timestamp.
+// It accepts view as an argument with "default" or "long" as acceptable values; the view affects only the window.
+func ProjectOccurrence(note *Note, recurrence *Recurrence, view string, at int64) *Occurrence {
+	occurrence := &Occurrence{Note: note}
+	if note.CompleteBy == 0 {
+		return occurrence
+	}
+	daysSecs := int64(24 * 60 * 60)
+	if recurrence == nil {
+		// start showing non-recurring notes 7 days in advance from their due date, and until they are marked done
+		occurrence.DueAt = note.CompleteBy
+		occurrence.WindowStart = note.CompleteBy - 7*daysSecs
+		if view == "long" {
+			occurrence.WindowStart = note.CompleteBy - 365*daysSecs
+		}
+		return occurrence
+	}
+	// note: for a recurring note without due time, we accept only date
+	// so, even if there is a time element recorded the the timestamp, we ignore it
+	occurrence.DueAt = recurrence.currentOccurrence(note.dueStart(), at)
+	daysBefore, daysAfter := recurrence.displayWindow(view)
+	occurrence.WindowStart = occurrence.DueAt - daysBefore*daysSecs
+	occurrence.WindowEnd = occurrence.DueAt + daysAfter*daysSecs
+	return occurrence
+}
+
+// IsActive determines if the timestamp falls within the window of the occurrence.
+func (occurrence *Occurrence) IsActive(at int64) bool {
+	if occurrence.DueAt == 0 {
+		return false
+	}
+	return (at >= occurrence.WindowStart) && ((occurrence.WindowEnd == 0) || (at <= occurrence.WindowEnd))
+}
+
+// Notes returns the notes of the occurrences (in the same order).
+func (occurrences Occurrences) Notes() Notes {
+	notes := make(Notes, 0, len(occurrences))
+	for _, occurrence := range occurrences {
+		notes = append(notes, occurrence.Note)
+	}
+	return notes
+}
+
+// ExternalTexts returns display text of list of occurrences, with due date of each note
+// shown as its projected due date.
+// Refer Notes.ExternalTexts for details.
+func (occurrences Occurrences) ExternalTexts(maxStrLen int, repeatAnnuallyTagId int, repeatMonthlyTagId int) []string {
+	// assuming there are at least (on average) 100s of notes
+	allTexts := make([]string, 0, 100)
+	for _, occurrence := range occurrences {
+		allTexts = append(allTexts, occurrence.Note.listText(maxStrLen, repeatAnnuallyTagId, repeatMonthlyTagId, occurrence.DueAt))
+	}
+	return allTexts
+}
@@ -0,0 +1,116 @@
+package model_test
+
+import (
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	model "github.com/goyalmunish/reminder/internal/model"
+	utils "github.com/goyalmunish/reminder/pkg/utils"
+)
+
+func TestProjectOccurrence(t *testing.T) {
+	at := date(2024, 2, 6) + 10*3600
+	daysSecs := int64(24 * 3600)
+	// a note without due date has no occurrence
+	note := &model.Note{Text: "no due date", Status: model.NoteStatus_Pending}
+	occurrence := model.ProjectOccurrence(note, nil, "default", at)
+	utils.AssertEqual(t, *occurrence, model.Occurrence{Note: note})
+	utils.AssertEqual(t, occurrence.IsActive(at), false)
+	// a non-recurring note is shown from 7 days before its due date, until it is marked as done
+	note = &model.Note{Text: "one-off", Status: model.NoteStatus_Pending, CompleteBy: date(2024, 2, 10)}
+	occurrence = model.ProjectOccurrence(note, nil, "default", at)
+	utils.AssertEqual(t, *occurrence, model.Occurrence{Note: note, DueAt: date(2024, 2, 10), WindowStart: date(2024, 2, 3)})
+	utils.AssertEqual(t, occurrence.IsActive(at), true)
+	utils.AssertEqual(t, occurrence.IsActive(date(2030, 1, 1)), true)
+	occurrence = model.ProjectOccurrence(note, nil, "long", at)
+	utils.AssertEqual(t, occurrence.WindowStart, date(2024, 2, 10)-365*daysSecs)
+	// a recurring note is projected to its matching occurrence, without modifying the note
+	recurrence, _ := model.ParseRecurrence("monthly")
+	note = &model.Note{Text: "monthly", Status: model.NoteStatus_Pending, CompleteBy: date(2023, 11, 5), Recurrence: recurrence}
+	occurrence = model.ProjectOccurrence(note, recurrence, "default", at)
+	utils.AssertEqual(t, *occurrence, model.Occurrence{Note: note, DueAt: date(2024, 2, 5), WindowStart: date(2024, 2, 4), WindowEnd: date(2024, 2, 8)})
+	utils.AssertEqual(t, occurrence.IsActive(at), true)
+	utils.AssertEqual(t, occurrence.IsActive(date(2024, 2, 9)), false)
+	occurrence = model.ProjectOccurrence(note, recurrence, "default", date(2024, 2, 9))
+	utils.AssertEqual(t, occurrence.DueAt, date(2024, 3, 5))
+	utils.AssertEqual(t, note.CompleteBy, date(2023, 11, 5))
+}
+
+func TestProjectOccurrenceWithDueTime(t *testing.T) {
+	location, _ := time.LoadLocation("America/New_York")
+	// a weekly recurring note at 18:00 in New York (across change of daylight saving time)
+	recurrence, _ := model.ParseRecurrence("weekly")
+	note := &model.Note{Text: "weekly review", Status: model.NoteStatus_Pending, Recurrence: recurrence}
+	utils.AssertEqual(t, note.UpdateCompleteBy("01-03-2024 18:00 America/New_York"), nil)
+	at := time.Date(2024, 3, 15, 12, 0, 0, 0, location).Unix()
+	occurrence := model.ProjectOccurrence(note, recurrence, "default", at)
+	utils.AssertEqual(t, occurrence.DueAt, time.Date(2024, 3, 15, 18, 0, 0, 0, location).Unix())
+	utils.AssertEqual(t, occurrence.IsActive(at), true)
+	// the window is relative to the due time
+	utils.AssertEqual(t, occurrence.IsActive(time.Date(2024, 3, 14, 17, 0, 0, 0, location).Unix()), false)
+	utils.AssertEqual(t, occurrence.IsActive(time.Date(2024, 3, 14, 19, 0, 0, 0, location).Unix()), true)
+	// once the due time has passed (and beyond its window), the next occurrence is projected
+	at = time.Date(2024, 3, 17, 19, 0, 0, 0, location).Unix()
+	occurrence = model.ProjectOccurrence(note, recurrence, "default", at)
+	utils.AssertEqual(t, occurrence.DueAt, time.Date(2024, 3, 22, 18, 0, 0, 0, location).Unix())
+	// a non-recurring note is due at its due time
+	note = &model.Note{Text: "call", Status: model.NoteStatus_Pending}
+	utils.AssertEqual(t, note.UpdateCompleteBy("20-03-2024 09:15 America/New_York"), nil)
+	occurrence = model.ProjectOccurrence(note, nil, "default", at)
+	utils.AssertEqual(t, occurrence.DueAt, time.Date(2024, 3, 20, 9, 15, 0, 0, location).Unix())
+	utils.AssertEqual(t, occurrence.IsActive(time.Date(2024, 3, 13, 9, 0, 0, 0, location).Unix()), false)
+	utils.AssertEqual(t, occurrence.IsActive(time.Date(2024, 3, 13, 9, 30, 0, 0, location).Unix()), true)
+}
+
+func TestOccurrencesSorting(t *testing.T) {
+	occurrences := model.Occurrences{
+		{Note: &model.Note{Text: "1"}, DueAt: 1800000003},
+		{Note: &model.Note{Text: "2"}, DueAt: 1800000001},
+		{Note: &model.Note{Text: "3"}, DueAt: 1800000002},
+	}
+	sort.Sort(occurrences)
+	var gotTexts []string
+	for _, note := range occurrences.Notes() {
+		gotTexts = append(gotTexts, note.Text)
+	}
+	utils.AssertEqual(t, gotTexts, []string{"2", "3", "1"})
+}
+
+func TestApproachingOccurrencesConcurrently(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, true)
+	_, repeatMonthlyTagId := reminderData.RepeatTagIds()
+	reminderData.Notes = model.Notes{
+		{Text: "pay rent", Status: model.NoteStatus_Pending, TagIds: []int{repeatMonthlyTagId}, CompleteBy: date(2023, 11, 5)},
+		{Text: "one-off", Status: model.NoteStatus_Pending, CompleteBy: date(2024, 2, 10)},
+	}
+	at := time.Date(2024, 2, 6, 10, 0, 0, 0, time.UTC).Unix()
+	// several views (at different times) can be projected at once
+	var wg sync.WaitGroup
+	for index := 0; index < 10; index++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			view := "default"
+			if index%2 == 1 {
+				view = "long"
+			}
+			_ = reminderData.ApproachingOccurrences(view, at+int64(index)*24*3600)
+		}(index)
+	}
+	wg.Wait()
+	occurrences := reminderData.ApproachingOccurrences("default", at)
+	utils.AssertEqual(t, len(occurrences), 2)
+	utils.AssertEqual(t, occurrences[0].DueAt, date(2024, 2, 5))
+	utils.AssertEqual(t, occurrences[1].DueAt, date(2024, 2, 10))
+	// the notes are left as they are
+	utils.AssertEqual(t, reminderData.Notes[0].CompleteBy, date(2023, 11, 5))
+	utils.AssertEqual(t, len(reminderData.ApproachingOccurrences("default", date(2024, 2, 20))), 1)
+}
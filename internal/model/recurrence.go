@@ -0,0 +1,344 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+type RecurrenceFrequency string
+
+const (
+	RecurrenceFrequency_Daily   RecurrenceFrequency = "daily"
+	RecurrenceFrequency_Weekly  RecurrenceFrequency = "weekly"
+	RecurrenceFrequency_Monthly RecurrenceFrequency = "monthly"
+	RecurrenceFrequency_Yearly  RecurrenceFrequency = "yearly"
+)
+
+// RecurrenceWeekdays are the weekday codes (as used by RRULE) accepted by a weekly recurrence.
+var RecurrenceWeekdays = []string{"MO", "TU", "WE", "TH", "FR", "SA", "SU"}
+
+/*
+A Recurrence represents repetition of a note, starting from its due date (CompleteBy).
+
+For a note with only due date, the occurrences are at 00:00:00 GMT+0000, whereas for a note with due time,
+the occurrences are at the same time of day in time zone of the note.
+A recurrence is represented in text (as accepted by ParseRecurrence) as:
+
+	<frequency>[/<interval>][:<weekdays>|last]
+
+For example, "daily", "daily/3", "weekly:mo,we", "weekly/2:fr", "monthly:last", and "yearly".
+*/
+type Recurrence struct {
+	Frequency RecurrenceFrequency `json:"frequency"`
+	// Interval is the N in every N days/weeks/months/years (0 means 1).
+	Interval int `json:"interval,omitempty"`
+	// Weekdays (such as "MO" and "WE") of a weekly recurrence; if empty, the weekday of the due date is used.
+	Weekdays []string `json:"weekdays,omitempty"`
+	// LastDayOfMonth makes a monthly recurrence to occur on the last day of each month (instead of day of the due date).
+	LastDayOfMonth bool `json:"last_day_of_month,omitempty"`
+}
+
+// ParseRecurrence parses the text representation of a recurrence.
+// If input is "nil", nil recurrence is returned (which clears existing recurrence).
+func ParseRecurrence(text string) (*Recurrence, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "" {
+		return nil, errors.New("Recurrence is empty")
+	}
+	if text == "nil" {
+		return nil, nil
+	}
+	recurrence := &Recurrence{}
+	rule, details, hasDetails := strings.Cut(text, ":")
+	frequency, interval, hasInterval := strings.Cut(rule, "/")
+	recurrence.Frequency = RecurrenceFrequency(frequency)
+	if hasInterval {
+		value, err := strconv.Atoi(interval)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid interval %q of the recurrence", interval)
+		}
+		recurrence.Interval = value
+	}
+	if hasDetails {
+		if recurrence.Frequency == RecurrenceFrequency_Monthly && details == "last" {
+			recurrence.LastDayOfMonth = true
+		} else {
+			for _, weekday := range strings.Split(details, ",") {
+				weekday = strings.TrimSpace(weekday)
+				if len(weekday) < 2 {
+					return nil, fmt.Errorf("Invalid weekday %q of the recurrence", weekday)
+				}
+				recurrence.Weekdays = append(recurrence.Weekdays, strings.ToUpper(weekday[:2]))
+			}
+		}
+	}
+	if err := recurrence.Validate(); err != nil {
+		return nil, err
+	}
+	return recurrence, nil
+}
+
+// Validate checks that the recurrence is well formed.
+func (recurrence *Recurrence) Validate() error {
+	if !utils.IsMemberOfSlice(recurrence.Frequency, []RecurrenceFrequency{RecurrenceFrequency_Daily, RecurrenceFrequency_Weekly, RecurrenceFrequency_Monthly, RecurrenceFrequency_Yearly}) {
+		return fmt.Errorf("Invalid frequency %q of the recurrence", recurrence.Frequency)
+	}
+	if recurrence.Interval < 0 {
+		return fmt.Errorf("Invalid interval %d of the recurrence", recurrence.Interval)
+	}
+	if len(recurrence.Weekdays) > 0 && recurrence.Frequency != RecurrenceFrequency_Weekly {
+		return errors.New("Weekdays are allowed only for weekly recurrence")
+	}
+	for _, weekday := range recurrence.Weekdays {
+		if !utils.IsMemberOfSlice(weekday, RecurrenceWeekdays) {
+			return fmt.Errorf("Invalid weekday %q of the recurrence", weekday)
+		}
+	}
+	if recurrence.LastDayOfMonth && recurrence.Frequency != RecurrenceFrequency_Monthly {
+		return errors.New("Last day of month is allowed only for monthly recurrence")
+	}
+	return nil
+}
+
+// String provides text representation of the recurrence (as accepted by ParseRecurrence).
+func (recurrence *Recurrence) String() string {
+	if recurrence == nil {
+		return "-"
+	}
+	text := string(recurrence.Frequency)
+	if recurrence.Interval > 1 {
+		text = fmt.Sprintf("%s/%d", text, recurrence.Interval)
+	}
+	if len(recurrence.Weekdays) > 0 {
+		text = fmt.Sprintf("%s:%s", text, strings.ToLower(strings.Join(recurrence.Weekdays, ",")))
+	}
+	if recurrence.LastDayOfMonth {
+		text = fmt.Sprintf("%s:last", text)
+	}
+	return text
+}
+
+// RepeatType returns single letter representing the frequency of the recurrence.
+func (recurrence *Recurrence) RepeatType() string {
+	switch recurrence.Frequency {
+	case RecurrenceFrequency_Daily:
+		return "D"
+	case RecurrenceFrequency_Weekly:
+		return "W"
+	case RecurrenceFrequency_Monthly:
+		return "M"
+	case RecurrenceFrequency_Yearly:
+		return "A"
+	}
+	return "-"
+}
+
+// RRule returns the RRULE (RFC 5545) of the recurrence, as used by calendar events.
+func (recurrence *Recurrence) RRule() string {
+	rule := fmt.Sprintf("RRULE:FREQ=%s", strings.ToUpper(string(recurrence.Frequency)))
+	if recurrence.Interval > 1 {
+		rule = fmt.Sprintf("%s;INTERVAL=%d", rule, recurrence.Interval)
+	}
+	if len(recurrence.Weekdays) > 0 {
+		rule = fmt.Sprintf("%s;BYDAY=%s", rule, strings.Join(recurrence.Weekdays, ","))
+	}
+	if recurrence.LastDayOfMonth {
+		rule = fmt.Sprintf("%s;BYMONTHDAY=-1", rule)
+	}
+	return rule
+}
+
+// ParseRRule parses an iCalendar (RFC 5545) recurrence rule, such as "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"
+// (the "RRULE:" prefix is optional). It is the reverse of RRule, and so it accepts only the rules which can be
+// represented by a Recurrence; for example, rules with COUNT or UNTIL are rejected.
+func ParseRRule(rule string) (*Recurrence, error) {
+	recurrence := &Recurrence{}
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:"), ";") {
+		name, value, _ := strings.Cut(part, "=")
+		name, value = strings.ToUpper(strings.TrimSpace(name)), strings.ToUpper(strings.TrimSpace(value))
+		switch {
+		case name == "FREQ":
+			recurrence.Frequency = RecurrenceFrequency(strings.ToLower(value))
+		case name == "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid interval %q of the recurrence rule", value)
+			}
+			recurrence.Interval = interval
+		case name == "BYDAY":
+			recurrence.Weekdays = strings.Split(value, ",")
+		case name == "BYMONTHDAY" && value == "-1":
+			recurrence.LastDayOfMonth = true
+		case name == "WKST" || name == "":
+			// the week start doesn't affect the supported rules
+		default:
+			return nil, fmt.Errorf("Unsupported part %q of the recurrence rule", part)
+		}
+	}
+	if recurrence.Interval == 1 {
+		recurrence.Interval = 0
+	}
+	if err := recurrence.Validate(); err != nil {
+		return nil, err
+	}
+	return recurrence, nil
+}
+
+// interval returns the effective interval of the recurrence.
+func (recurrence *Recurrence) interval() int {
+	if recurrence.Interval < 1 {
+		return 1
+	}
+	return recurrence.Interval
+}
+
+// Occurrences returns the last occurrence at or before the given timestamp, and the first occurrence after it,
+// of the recurrence starting at the date of the start timestamp (at 00:00:00 GMT+0000).
+// The previous occurrence is 0 if there is none (that is, if the timestamp is before the start).
+func (recurrence *Recurrence) Occurrences(start int64, timestamp int64) (int64, int64) {
+	return recurrence.occurrencesFrom(dateOf(utils.UnixTimestampToTime(start)), timestamp)
+}
+
+// occurrencesFrom is like Occurrences, but the recurrence starts at the start time; that is, the occurrences
+// are at the time of day of the start in its location.
+func (recurrence *Recurrence) occurrencesFrom(startDate time.Time, timestamp int64) (int64, int64) {
+	current := time.Unix(timestamp, 0).In(startDate.Location())
+	// find the period (of interval length) containing the timestamp, and consider its neighbours too
+	period := 0
+	switch recurrence.Frequency {
+	case RecurrenceFrequency_Daily:
+		period = int(current.Sub(startDate).Hours()/24) / recurrence.interval()
+	case RecurrenceFrequency_Weekly:
+		period = int(current.Sub(weekStartOf(startDate)).Hours()/(7*24)) / recurrence.interval()
+	case RecurrenceFrequency_Monthly:
+		period = (12*(current.Year()-startDate.Year()) + int(current.Month()-startDate.Month())) / recurrence.interval()
+	case RecurrenceFrequency_Yearly:
+		period = (current.Year() - startDate.Year()) / recurrence.interval()
+	}
+	period = max(period, 0)
+	var candidates []time.Time
+	for index := period - 1; index <= period+1; index++ {
+		if index < 0 {
+			continue
+		}
+		for _, occurrence := range recurrence.periodOccurrences(startDate, index) {
+			if !occurrence.Before(startDate) {
+				candidates = append(candidates, occurrence)
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+	var previous, next int64
+	for _, occurrence := range candidates {
+		if occurrence.Unix() <= timestamp {
+			previous = occurrence.Unix()
+		} else {
+			next = occurrence.Unix()
+			break
+		}
+	}
+	return previous, next
+}
+
+// periodOccurrences returns the occurrences within the period with given index (counted from the start date).
+func (recurrence *Recurrence) periodOccurrences(startDate time.Time, index int) []time.Time {
+	offset := index * recurrence.interval()
+	switch recurrence.Frequency {
+	case RecurrenceFrequency_Daily:
+		return []time.Time{startDate.AddDate(0, 0, offset)}
+	case RecurrenceFrequency_Weekly:
+		weekStart := weekStartOf(startDate).AddDate(0, 0, 7*offset)
+		weekdays := recurrence.Weekdays
+		if len(weekdays) == 0 {
+			weekdays = []string{weekdayCode(startDate.Weekday())}
+		}
+		var occurrences []time.Time
+		for _, weekday := range weekdays {
+			occurrences = append(occurrences, weekStart.AddDate(0, 0, slices.Index(RecurrenceWeekdays, weekday)))
+		}
+		return occurrences
+	case RecurrenceFrequency_Monthly:
+		monthStart := time.Date(startDate.Year(), startDate.Month()+time.Month(offset), 1, startDate.Hour(), startDate.Minute(), startDate.Second(), 0, startDate.Location())
+		day := startDate.Day()
+		if recurrence.LastDayOfMonth {
+			day = daysInMonth(monthStart)
+		}
+		return []time.Time{monthStart.AddDate(0, 0, min(day, daysInMonth(monthStart))-1)}
+	case RecurrenceFrequency_Yearly:
+		monthStart := time.Date(startDate.Year()+offset, startDate.Month(), 1, startDate.Hour(), startDate.Minute(), startDate.Second(), 0, startDate.Location())
+		return []time.Time{monthStart.AddDate(0, 0, min(startDate.Day(), daysInMonth(monthStart))-1)}
+	}
+	return nil
+}
+
+// displayWindow returns number of days before and after an occurrence during which the note is to be displayed
+// (as approaching due date).
+func (recurrence *Recurrence) displayWindow(view string) (int64, int64) {
+	var daysBefore, daysAfter, periodDays int64
+	switch recurrence.Frequency {
+	case RecurrenceFrequency_Daily:
+		daysBefore, daysAfter, periodDays = 0, 1, 1
+	case RecurrenceFrequency_Weekly:
+		daysBefore, daysAfter, periodDays = 1, 2, 7
+	case RecurrenceFrequency_Monthly:
+		daysBefore, daysAfter, periodDays = 1, 3, 31
+	case RecurrenceFrequency_Yearly:
+		daysBefore, daysAfter, periodDays = 3, 7, 365
+	}
+	if view == "long" {
+		daysBefore = periodDays * int64(recurrence.interval())
+	}
+	return daysBefore, daysAfter
+}
+
+// currentOccurrence returns the occurrence of the recurrence (starting at the start time) relevant at the timestamp;
+// that is, the previous occurrence as long as the timestamp is within its display window, otherwise the next one.
+func (recurrence *Recurrence) currentOccurrence(start time.Time, timestamp int64) int64 {
+	previous, next := recurrence.occurrencesFrom(start, timestamp)
+	// the days after an occurrence don't depend on the view
+	_, daysAfter := recurrence.displayWindow("default")
+	if (previous != 0) && ((timestamp <= previous+daysAfter*24*60*60) || (next == 0)) {
+		return previous
+	}
+	return next
+}
+
+// recurrenceFromRepeatTags returns recurrence corresponding to the "repeat-annually" and "repeat-monthly" tags
+// (used before notes had their own recurrence), or nil if the note has neither of them.
+func recurrenceFromRepeatTags(tagIDs []int, repeatAnnuallyTagId int, repeatMonthlyTagId int) *Recurrence {
+	if utils.IsMemberOfSlice(repeatAnnuallyTagId, tagIDs) {
+		return &Recurrence{Frequency: RecurrenceFrequency_Yearly}
+	}
+	if utils.IsMemberOfSlice(repeatMonthlyTagId, tagIDs) {
+		return &Recurrence{Frequency: RecurrenceFrequency_Monthly}
+	}
+	return nil
+}
+
+// dateOf returns the date part (at 00:00:00 GMT+0000) of the time.
+func dateOf(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// weekStartOf returns the Monday of the week of the date.
+func weekStartOf(date time.Time) time.Time {
+	return date.AddDate(0, 0, -slices.Index(RecurrenceWeekdays, weekdayCode(date.Weekday())))
+}
+
+// weekdayCode returns the RRULE code (such as "MO") of the weekday.
+func weekdayCode(weekday time.Weekday) string {
+	return RecurrenceWeekdays[(int(weekday)+6)%7]
+}
+
+// daysInMonth returns number of days in the month of the date.
+func daysInMonth(date time.Time) int {
+	return time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, date.Location()).Day()
+}
@@ -0,0 +1,197 @@
+package model_test
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	model "github.com/goyalmunish/reminder/internal/model"
+	utils "github.com/goyalmunish/reminder/pkg/utils"
+)
+
+// date returns unix timestamp of the date at 00:00:00 GMT+0000.
+func date(year int, month time.Month, day int) int64 {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix()
+}
+
+func TestParseRecurrence(t *testing.T) {
+	var tests = []struct {
+		text       string
+		wantString string
+		wantRRule  string
+		wantErr    bool
+	}{
+		{text: "daily", wantString: "daily", wantRRule: "RRULE:FREQ=DAILY"},
+		{text: "Daily/3", wantString: "daily/3", wantRRule: "RRULE:FREQ=DAILY;INTERVAL=3"},
+		{text: "weekly/2:mon,Thu", wantString: "weekly/2:mo,th", wantRRule: "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"},
+		{text: "monthly:last", wantString: "monthly:last", wantRRule: "RRULE:FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{text: "yearly/1", wantString: "yearly", wantRRule: "RRULE:FREQ=YEARLY"},
+		{text: "hourly", wantErr: true},
+		{text: "daily/x", wantErr: true},
+		{text: "weekly:xy", wantErr: true},
+		{text: "daily:mo", wantErr: true},
+		{text: "yearly:last", wantErr: true},
+		{text: "", wantErr: true},
+	}
+	for _, test := range tests {
+		recurrence, err := model.ParseRecurrence(test.text)
+		utils.AssertEqual(t, err != nil, test.wantErr)
+		if err == nil {
+			utils.AssertEqual(t, recurrence.String(), test.wantString)
+			utils.AssertEqual(t, recurrence.RRule(), test.wantRRule)
+		}
+	}
+	// "nil" clears the recurrence
+	recurrence, err := model.ParseRecurrence("nil")
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, recurrence == nil, true)
+}
+
+func TestRecurrenceOccurrences(t *testing.T) {
+	var tests = []struct {
+		name         string
+		rule         string
+		start        int64
+		timestamp    int64
+		wantPrevious int64
+		wantNext     int64
+	}{
+		{name: "every 3 days", rule: "daily/3", start: date(2024, 1, 31), timestamp: date(2024, 2, 5) + 3600,
+			wantPrevious: date(2024, 2, 3), wantNext: date(2024, 2, 6)},
+		{name: "weekly on chosen weekdays", rule: "weekly:mo,we", start: date(2024, 1, 31), timestamp: date(2024, 2, 2),
+			wantPrevious: date(2024, 1, 31), wantNext: date(2024, 2, 5)},
+		{name: "every 2 weeks", rule: "weekly/2:mo", start: date(2024, 1, 31), timestamp: date(2024, 2, 6),
+			wantPrevious: 0, wantNext: date(2024, 2, 12)},
+		{name: "monthly beyond end of month", rule: "monthly", start: date(2024, 1, 31), timestamp: date(2024, 2, 15),
+			wantPrevious: date(2024, 1, 31), wantNext: date(2024, 2, 29)},
+		{name: "last day of month", rule: "monthly:last", start: date(2024, 1, 15), timestamp: date(2024, 3, 1),
+			wantPrevious: date(2024, 2, 29), wantNext: date(2024, 3, 31)},
+		{name: "every 2 months", rule: "monthly/2", start: date(2024, 1, 10), timestamp: date(2024, 4, 1),
+			wantPrevious: date(2024, 3, 10), wantNext: date(2024, 5, 10)},
+		{name: "yearly on leap day", rule: "yearly", start: date(2024, 2, 29), timestamp: date(2025, 3, 1),
+			wantPrevious: date(2025, 2, 28), wantNext: date(2026, 2, 28)},
+		{name: "before the start", rule: "yearly", start: date(2030, 6, 15), timestamp: date(2024, 1, 1),
+			wantPrevious: 0, wantNext: date(2030, 6, 15)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recurrence, err := model.ParseRecurrence(test.rule)
+			utils.AssertEqual(t, err, nil)
+			previous, next := recurrence.Occurrences(test.start, test.timestamp)
+			utils.AssertEqual(t, previous, test.wantPrevious)
+			utils.AssertEqual(t, next, test.wantNext)
+		})
+	}
+}
+
+func TestNoteRecurrence(t *testing.T) {
+	repeatAnnuallyTagId := 4
+	repeatMonthlyTagId := 5
+	note := model.Note{Text: "team sync", Status: model.NoteStatus_Pending, TagIds: []int{0}}
+	// a recurrence starts from the due date of the note
+	utils.AssertEqual(t, note.UpdateRecurrence("weekly:mo") != nil, true)
+	utils.AssertEqual(t, note.UpdateCompleteBy("15-01-2024"), nil)
+	utils.AssertEqual(t, note.UpdateRecurrence("weekly/2:mo,th"), nil)
+	utils.AssertEqual(t, note.RepeatType(repeatAnnuallyTagId, repeatMonthlyTagId), "W")
+	event, err := note.CalendarEvent(repeatAnnuallyTagId, repeatMonthlyTagId, "Australia/Melbourne", TestTagger{})
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, event.Recurrence, []string{"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"})
+	// a recurring note can be marked as done, which ends its series
+	utils.AssertEqual(t, note.UpdateStatus(model.NoteStatus_Done, []int{repeatAnnuallyTagId, repeatMonthlyTagId}), nil)
+	utils.AssertEqual(t, note.UpdateStatus(model.NoteStatus_Pending, []int{repeatAnnuallyTagId, repeatMonthlyTagId}), nil)
+	// without a recurrence, the repeat tags are used
+	utils.AssertEqual(t, note.UpdateRecurrence("nil"), nil)
+	utils.AssertEqual(t, note.RepeatType(repeatAnnuallyTagId, repeatMonthlyTagId), "-")
+	note.TagIds = []int{repeatMonthlyTagId}
+	utils.AssertEqual(t, note.EffectiveRecurrence(repeatAnnuallyTagId, repeatMonthlyTagId).String(), "monthly")
+	event, _ = note.CalendarEvent(repeatAnnuallyTagId, repeatMonthlyTagId, "Australia/Melbourne", TestTagger{})
+	utils.AssertEqual(t, event.Recurrence, []string{"RRULE:FREQ=MONTHLY"})
+}
+
+func TestRecurringNotesApproachingDueDate(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, true)
+	// it's Tuesday
+	defer func(currentTime func() time.Time) { utils.CurrentTime = currentTime }(utils.CurrentTime)
+	utils.CurrentTime = func() time.Time {
+		return time.Date(2024, 2, 6, 10, 0, 0, 0, time.UTC)
+	}
+	recurrence := func(rule string) *model.Recurrence {
+		recurrence, _ := model.ParseRecurrence(rule)
+		return recurrence
+	}
+	reminderData.Notes = model.Notes{
+		{Text: "daily", Status: model.NoteStatus_Pending, CompleteBy: date(2024, 1, 1), Recurrence: recurrence("daily")},                    // 6th; expected
+		{Text: "every 2 days", Status: model.NoteStatus_Pending, CompleteBy: date(2024, 1, 1), Recurrence: recurrence("daily/2")},           // 6th; expected
+		{Text: "every 4 days", Status: model.NoteStatus_Pending, CompleteBy: date(2024, 1, 1), Recurrence: recurrence("daily/4")},           // 2nd and 6th; expected
+		{Text: "every 5 days", Status: model.NoteStatus_Pending, CompleteBy: date(2024, 1, 1), Recurrence: recurrence("daily/5")},           // 5th and 10th
+		{Text: "on wednesdays", Status: model.NoteStatus_Pending, CompleteBy: date(2024, 1, 3), Recurrence: recurrence("weekly")},           // 7th; expected
+		{Text: "on fridays", Status: model.NoteStatus_Pending, CompleteBy: date(2024, 1, 3), Recurrence: recurrence("weekly:fr")},           // 2nd and 9th
+		{Text: "on mondays", Status: model.NoteStatus_Pending, CompleteBy: date(2024, 1, 3), Recurrence: recurrence("weekly:mo,fr")},        // 5th; expected
+		{Text: "last day of month", Status: model.NoteStatus_Pending, CompleteBy: date(2024, 1, 3), Recurrence: recurrence("monthly:last")}, // 31st Jan and 29th Feb
+		{Text: "done daily", Status: model.NoteStatus_Done, CompleteBy: date(2024, 1, 1), Recurrence: recurrence("daily")},
+	}
+	var texts []string
+	for _, note := range reminderData.NotesApprachingDueDate("default") {
+		texts = append(texts, note.Text)
+	}
+	utils.AssertEqual(t, texts, []string{"daily", "every 2 days", "every 4 days", "on wednesdays", "on mondays"})
+	// the long view looks ahead a whole period
+	texts = nil
+	for _, note := range reminderData.NotesApprachingDueDate("long") {
+		texts = append(texts, note.Text)
+	}
+	utils.AssertEqual(t, texts, []string{"daily", "every 2 days", "every 4 days", "every 5 days", "on wednesdays", "on fridays", "on mondays", "last day of month"})
+}
+
+func TestMigrateRepeatTags(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, true)
+	repeatAnnuallyTagId, repeatMonthlyTagId := reminderData.RepeatTagIds()
+	// notes persisted with only the repeat tags
+	_, _ = reminderData.NewNoteRegistration([]int{repeatAnnuallyTagId}, "birthday")
+	_, _ = reminderData.NewNoteRegistration([]int{repeatMonthlyTagId}, "pay rent")
+	_, _ = reminderData.NewNoteRegistration([]int{0}, "one-off")
+	// on loading, the tags are migrated into recurrence (and the tags are kept)
+	reminderDataRe, _ := model.ReadDataFile(dataFilePath, true)
+	utils.AssertEqual(t, reminderDataRe.Notes[0].Recurrence, &model.Recurrence{Frequency: model.RecurrenceFrequency_Yearly})
+	utils.AssertEqual(t, reminderDataRe.Notes[0].TagIds, []int{repeatAnnuallyTagId})
+	utils.AssertEqual(t, reminderDataRe.Notes[1].Recurrence, &model.Recurrence{Frequency: model.RecurrenceFrequency_Monthly})
+	utils.AssertEqual(t, reminderDataRe.Notes[2].Recurrence == nil, true)
+	// a migrated note can end its series
+	utils.AssertEqual(t, reminderDataRe.UpdateNoteStatus(reminderDataRe.Notes[1], model.NoteStatus_Done), nil)
+	utils.AssertEqual(t, reminderDataRe.Notes[1].Status, model.NoteStatus_Done)
+}
+
+func TestParseRRule(t *testing.T) {
+	var tests = []struct {
+		rule       string
+		wantString string
+		wantErr    bool
+	}{
+		{rule: "RRULE:FREQ=DAILY", wantString: "daily"},
+		{rule: "FREQ=DAILY;INTERVAL=1", wantString: "daily"},
+		{rule: "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;WKST=SU", wantString: "weekly/2:mo,th"},
+		{rule: "RRULE:FREQ=MONTHLY;BYMONTHDAY=-1", wantString: "monthly:last"},
+		{rule: "RRULE:FREQ=YEARLY", wantString: "yearly"},
+		{rule: "RRULE:FREQ=MONTHLY;BYMONTHDAY=15", wantErr: true},
+		{rule: "RRULE:FREQ=WEEKLY;BYDAY=1MO", wantErr: true},
+		{rule: "RRULE:FREQ=DAILY;COUNT=5", wantErr: true},
+		{rule: "RRULE:FREQ=HOURLY", wantErr: true},
+		{rule: "RRULE:FREQ=DAILY;INTERVAL=x", wantErr: true},
+	}
+	for _, test := range tests {
+		recurrence, err := model.ParseRRule(test.rule)
+		utils.AssertEqual(t, err != nil, test.wantErr)
+		if err == nil {
+			utils.AssertEqual(t, recurrence.String(), test.wantString)
+		}
+	}
+}
@@ -11,27 +11,51 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/goyalmunish/reminder/pkg/calendar"
+	"github.com/goyalmunish/reminder/pkg/ical"
 	"github.com/goyalmunish/reminder/pkg/logger"
 	"github.com/goyalmunish/reminder/pkg/utils"
-	gc "google.golang.org/api/calendar/v3"
 )
 
 const EnableCalendar bool = true
 
+// LockFileSuffix is the suffix of the sidecar lock file of the data file.
+const LockFileSuffix = ".lock"
+
 /*
 A ReminderData represents the whole reminder data-structure.
 */
 type ReminderData struct {
-	User         *User  `json:"user"`
-	Notes        Notes  `json:"notes"`
-	Tags         Tags   `json:"tags"`
-	DataFile     string `json:"data_file"`
-	LastBackupAt int64  `json:"last_backup_at"`
-	MutexLock    bool   `json:"mutex_lock"`
+	User         *User      `json:"user"`
+	Notes        Notes      `json:"notes"`
+	Trash        Notes      `json:"trash,omitempty"` // notes moved to the trash, which can be restored until purged
+	Tags         Tags       `json:"tags"`
+	DataFile     string     `json:"data_file"`
+	LastBackupAt int64      `json:"last_backup_at"`
+	NextTagId    int        `json:"next_tag_id,omitempty"` // id for the next tag, which is past ids of all the deleted tags
+	TagQueries   TagQueries `json:"tag_queries,omitempty"` // saved queries of tags, which are listed along with the tags
 	BaseStruct
+	// migrated tells if the data was migrated (such as with back-filled ids) while being read
+	migrated bool
+	// readFromBackup tells if the data was read from the backup, as the data file is corrupt
+	readFromBackup bool
+	// base is the snapshot of the data as last read from (or written to) the data file,
+	// which is used to merge changes made to the data file by another session
+	base *ReminderData
+	// resolver resolves conflicting changes while merging; by default, the user is asked
+	resolver ConflictResolver
+	// store is the storage backend of the data file
+	store Store
+	// undoStack and redoStack are changes (made to notes) which can be undone and redone in current session
+	undoStack []noteChange
+	redoStack []noteChange
+	// includeArchived tells if the archived notes are included (refer SetIncludeArchived), and
+	// archived caches them once read from the archive files
+	includeArchived bool
+	archived        Notes
+	// tagGroupRules are the rules of the tag groups (refer SetTagGroupRules)
+	tagGroupRules TagGroupRules
 }
 
 // Tagger is interface representing ReminderData with TagsFromIds method.
@@ -39,91 +63,49 @@ type Tagger interface {
 	TagsFromIds(tagIDs []int) []string
 }
 
-// SyncCalendar syncs pending notes to Cloud Calendar.
+// SyncCalendar syncs pending notes with the calendar (as per the provider selected by the options).
+// Refer SyncCalendarEvents for details.
 func (rd *ReminderData) SyncCalendar(calOptions *calendar.Options) error {
-	lookAheadYears := 5
 	if !EnableCalendar {
-		logger.Warn("Google Calendar is disabled.")
+		logger.Warn("Calendar is disabled.")
 		return nil
 	}
 
-	// Get calendar service
-	logger.Info("Retrieve the Cloud Calendar Service.")
-	srv, err := calendar.GetCalendarService(calOptions)
-	if err != nil {
-		return fmt.Errorf("Unable to retrieve Calendar client: %w", err)
-	}
-
-	// Get list of all upcoming events, and display them
-	logger.Info("Fetch the list of all upcoming Calendar Events with each type of recurring event as single unit.")
-	existingEvents, eventDetails, timeZone, err := calendar.FetchUpcomingEventsAndDetails(srv, 0, lookAheadYears, "")
+	// Get calendar provider
+	logger.Info("Retrieve the Calendar Provider.")
+	provider, err := calendar.NewProvider(calOptions)
 	if err != nil {
 		return err
 	}
-	fmt.Println(eventDetails)
-	// Iterating through the Cloud Calendar Events
-	fmt.Printf("Listing upcoming calendar events (%v):\n", len(existingEvents))
-	fmt.Println("Note: It may take some time for Google Calendar read API to pick up the recently added events, or Calendar trash https://calendar.google.com/calendar/u/0/r/trash can also cause issues.")
-	if len(existingEvents) == 0 {
-		logger.Warn("No upcoming events found.")
-	} else {
-		for _, item := range existingEvents {
-			if item.Summary == "" {
-				continue
-			}
-			fmt.Printf("  - %v | %v\n", item.Summary, item.Recurrence)
-		}
-	}
-	fmt.Println() // just print a blank line
 
-	// Delete existing reminder events from Cloud Calendar
-	// Note: Your might like to check your Calendar trash
-	// https://calendar.google.com/calendar/u/0/r/trash and clear it from
-	// time to time. Otherwise, this obstructs with visibility of newly
-	// added event in the API call.
-	logger.Info("Fetch all the events registered by reminder app.")
-	reminderEvents, _, _, err := calendar.FetchUpcomingEventsAndDetails(srv, 2, lookAheadYears, calendar.TitlePrefix)
-	if err != nil {
-		return fmt.Errorf("Unable to retrieve the events: %w", err)
-	}
-	fmt.Printf("Listing (%v) and deleting the events registered by the reminder app:\n", len(reminderEvents))
-	err = calendar.DeleteEvents(srv, reminderEvents, calOptions.DryMode)
-	if err != nil {
-		return fmt.Errorf("Unable to delete the events: %w", err)
-	}
-
-	// Add events to Cloud Calendar
-	logger.Info("Fetching events to be Synced.")
-	newEvents, err := rd.GoogleCalendarEvents(timeZone)
-	if err != nil {
-		return err
-	}
-	waitFor := 10 * time.Second
-	fmt.Printf("\nAdding %v events (pending ones with due-date) to Google Calendar. Hit Ctrl-c if you don't want to do it at the moment. The process will wait for %v.\n", len(newEvents), waitFor)
-	fmt.Printf("waiting for %v...\n", waitFor)
-	time.Sleep(waitFor)
-	fmt.Println("Starting the event addition process.")
-	err = calendar.AddEvents(srv, newEvents, calOptions.DryMode)
-	if err != nil {
-		return err
+	fmt.Printf("Syncing the notes with the calendar %q:\n", calOptions.CalendarId)
+	result, err := rd.SyncCalendarEvents(provider, calOptions)
+	if result != nil {
+		for _, conflict := range result.Conflicts {
+			fmt.Printf("  - Conflict: %v\n", conflict)
+		}
+		fmt.Printf("Done with syncing the notes (%v).\n", result)
 	}
-	fmt.Println("Done with event addition process.")
-	return nil
+	return err
 }
 
-// GoogleCalendarEvents returns list of Google Calendar Events.
-func (rd *ReminderData) GoogleCalendarEvents(timezoneIANA string) ([]*gc.Event, error) {
-	logger.Info("Start: GoogleCalendarEvents")
-	defer logger.Info("End: GoogleCalendarEvents")
+// CalendarEvents returns calendar events of pending notes with due date.
+func (rd *ReminderData) CalendarEvents(timezoneIANA string) ([]*calendar.Event, error) {
+	logger.Info("Start: CalendarEvents")
+	defer logger.Info("End: CalendarEvents")
 	// get all pending notes
 	allNotes := rd.Notes
-	relevantNotes := allNotes.WithStatus(NoteStatus_Pending).WithCompleteBy()
+	occurrences := rd.NoteOccurrences(allNotes.WithStatus(NoteStatus_Pending), "default", utils.CurrentUnixTimestamp())
 	// construct Cloud Events
-	repeatAnnuallyTag := rd.TagFromSlug("repeat-annually")
-	repeatMonthlyTag := rd.TagFromSlug("repeat-monthly")
-	var events []*gc.Event
-	for _, note := range relevantNotes {
-		event, err := note.GoogleCalendarEvent(repeatAnnuallyTag.Id, repeatMonthlyTag.Id, timezoneIANA, rd)
+	// note: an event starts at the due date of the note (and not at its projected occurrence), as
+	// recurrence of the event is taken care of by the calendar itself
+	repeatAnnuallyTagId, repeatMonthlyTagId := rd.RepeatTagIds()
+	var events []*calendar.Event
+	for _, occurrence := range occurrences {
+		if occurrence.DueAt == 0 {
+			continue
+		}
+		event, err := occurrence.Note.CalendarEvent(repeatAnnuallyTagId, repeatMonthlyTagId, timezoneIANA, rd)
 		if err != nil {
 			return nil, err
 		}
@@ -132,23 +114,30 @@ func (rd *ReminderData) GoogleCalendarEvents(timezoneIANA string) ([]*gc.Event,
 	return events, nil
 }
 
+// ICalendar returns iCalendar of pending notes with due date, as events (or to-dos, with kind as ical.KindTodo).
+func (rd *ReminderData) ICalendar(kind string) (*ical.Calendar, error) {
+	iCalendar := &ical.Calendar{ProdId: "-//goyalmunish//reminder//EN", Name: "reminder"}
+	repeatAnnuallyTagId, repeatMonthlyTagId := rd.RepeatTagIds()
+	for _, note := range rd.Notes.WithStatus(NoteStatus_Pending) {
+		if note.CompleteBy == 0 {
+			continue
+		}
+		component, err := note.ICalComponent(repeatAnnuallyTagId, repeatMonthlyTagId, kind, rd)
+		if err != nil {
+			return nil, err
+		}
+		iCalendar.Components = append(iCalendar.Components, component)
+	}
+	return iCalendar, nil
+}
+
 // CreateDataFile creates data file with current state of `rd`.
 // The msg is any additional message to be printed.
 func (rd *ReminderData) CreateDataFile(msg string) error {
-	// update UpdatedAt field
-	// note that UpdatedAt of a whole ReminderData object is different
+	// note that CreatedAt (and UpdatedAt) of a whole ReminderData object is different
 	// from corresponding field of each note
-	currentTime := utils.CurrentUnixTimestamp()
-	rd.UpdatedAt = currentTime
-	rd.CreatedAt = currentTime
-	// marshal the data
-	byteValue, err := json.MarshalIndent(&rd, "", "    ")
-	if err != nil {
-		return err
-	}
-	// persist the byte data to file
-	err = os.WriteFile(rd.DataFile, byteValue, 0755)
-	if err != nil {
+	rd.CreatedAt = utils.CurrentUnixTimestamp()
+	if err := rd.storage().Save(rd); err != nil {
 		return err
 	}
 	if msg != "" {
@@ -161,55 +150,158 @@ func (rd *ReminderData) CreateDataFile(msg string) error {
 // UpdateDataFile updates data file with current state of `rd`.
 // The msg is any additional message to be printed.
 func (rd *ReminderData) UpdateDataFile(msg string) error {
-	var conflictError error
-	// if UpdatedAt timestamp of currently loaded data is not same as timestamp persisted on datafile
-	// some other process would have updated the data file, which can lead to data inconsistency
-	persistedData, err := ReadDataFile(rd.DataFile, true)
-	if err != nil {
+	err := rd.storage().Save(rd)
+	if err != nil && !errors.Is(err, ErrorConflictFile) {
 		return err
 	}
-	persistedTimestamp := persistedData.UpdatedAt
-	inMemoryTimestamp := rd.UpdatedAt
-	currentTimestamp := utils.CurrentUnixTimestamp()
-	logger.Info(fmt.Sprintf("In-memory timestamp: %v, Persisted timestamp: %v, Current Timestamp (being persisted): %v", inMemoryTimestamp, persistedTimestamp, currentTimestamp))
-	if persistedTimestamp != inMemoryTimestamp {
-		newFilePath := fmt.Sprintf("%s_CONFLICT_%d", rd.DataFile, currentTimestamp)
-		rd.DataFile = newFilePath
-		logger.Error(fmt.Sprintf("It seems another instance of the application updated the data file; the data will instead be saved to confict file %q.", newFilePath))
-		conflictError = ErrorConflictFile
-	}
-	// update UpdatedAt field
-	// note that UpdatedAt of a whole ReminderData object is different
-	// from corresponding field of each note
-	rd.UpdatedAt = currentTimestamp
-	// marshal the data
-	// Refer https://pkg.go.dev/encoding/json#MarshalIndent
-	// Note: String values encoded as JSON strings are coerced to valid
-	// UTF-8, replacing invalid bytes with Unicode replacement rune. So
-	// that the JSON will be safe to embed inside HTML <script> tags, the
-	// string is encoded using HTMLEscape.
-	// For example, a text such as `comment with < and "` will be written
-	// as `"comment with \u003c and \"` but it will read back same as the
-	// original string
-	byteValue, err := json.MarshalIndent(&rd, "", "    ")
+	if msg != "" {
+		logger.Info(msg)
+	}
+	return err
+}
+
+// mutateNote runs the mutation of the note, records the resulting changes in history of the note
+// (and on undo stack of the session), and persists the note.
+func (rd *ReminderData) mutateNote(note *Note, mutate func() error) error {
+	if err := note.editable(); err != nil {
+		return err
+	}
+	before, err := cloneNote(note)
 	if err != nil {
 		return err
 	}
-	// persist the byte data to file
-	err = os.WriteFile(rd.DataFile, byteValue, 0755)
+	if err := mutate(); err != nil {
+		return err
+	}
+	revisions, err := diffNote(before, note)
 	if err != nil {
 		return err
 	}
-	if msg != "" {
-		logger.Info(msg)
+	if len(revisions) > 0 {
+		note.History = append(note.History, revisions...)
+		rd.undoStack = append(rd.undoStack, noteChange{noteId: note.Id, revisions: revisions})
+		rd.redoStack = nil
 	}
-	logger.Info(fmt.Sprintf("Updated the data file %q at %v!", rd.DataFile, rd.UpdatedAt))
-	if conflictError != nil {
-		return conflictError
+	return rd.updateNote(note)
+}
+
+// UndoNoteChange undoes the last change of the note made in current session.
+// The undo itself is recorded in history of the note, and the change can be redone with RedoNoteChange.
+// It returns ErrorNothingToUndo if there is no change to undo.
+func (rd *ReminderData) UndoNoteChange(note *Note) error {
+	change, ok := popNoteChange(&rd.undoStack, note.Id)
+	if !ok {
+		return ErrorNothingToUndo
+	}
+	if err := rd.revertNoteChange(note, change); err != nil {
+		return err
 	}
+	rd.redoStack = append(rd.redoStack, change)
 	return nil
 }
 
+// RedoNoteChange redoes the last undone change of the note.
+// It returns ErrorNothingToUndo if there is no change to redo.
+func (rd *ReminderData) RedoNoteChange(note *Note) error {
+	change, ok := popNoteChange(&rd.redoStack, note.Id)
+	if !ok {
+		return ErrorNothingToUndo
+	}
+	// the inverse revisions (in reverse order) are reverted to redo the change
+	redoChange := noteChange{noteId: change.noteId}
+	for index := len(change.revisions) - 1; index >= 0; index-- {
+		redoChange.revisions = append(redoChange.revisions, change.revisions[index].inverse(0))
+	}
+	if err := rd.revertNoteChange(note, redoChange); err != nil {
+		return err
+	}
+	rd.undoStack = append(rd.undoStack, change)
+	return nil
+}
+
+// revertNoteChange applies inverse of the change to the note (recording it in history of the note),
+// and persists the note.
+func (rd *ReminderData) revertNoteChange(note *Note, change noteChange) error {
+	if err := note.editable(); err != nil {
+		return err
+	}
+	currentTime := utils.CurrentUnixTimestamp()
+	// revert the revisions in reverse order
+	for index := len(change.revisions) - 1; index >= 0; index-- {
+		revision := change.revisions[index].inverse(currentTime)
+		if err := note.applyRevision(revision); err != nil {
+			return err
+		}
+		note.History = append(note.History, revision)
+	}
+	note.UpdatedAt = currentTime
+	return rd.updateNote(note)
+}
+
+// popNoteChange removes (and returns) the last change of the note from the stack.
+func popNoteChange(stack *[]noteChange, noteId string) (noteChange, bool) {
+	for index := len(*stack) - 1; index >= 0; index-- {
+		change := (*stack)[index]
+		if change.noteId == noteId {
+			*stack = append((*stack)[:index], (*stack)[index+1:]...)
+			return change, true
+		}
+	}
+	return noteChange{}, false
+}
+
+// updateNote persists the (new or updated) note.
+func (rd *ReminderData) updateNote(note *Note) error {
+	return rd.storage().SaveNote(rd, note)
+}
+
+// storage returns the store of the data file.
+func (rd *ReminderData) storage() Store {
+	if rd.store == nil {
+		rd.store = NewStore(rd.DataFile)
+	}
+	return rd.store
+}
+
+// MigrateTo copies the whole data to another data file, which can be of another storage backend
+// (such as from JSON to SQLite). The other data file must not exist already.
+func (rd *ReminderData) MigrateTo(dataFilePath string) error {
+	dataFilePath = utils.TryConvertTildaBasedPath(dataFilePath)
+	if _, err := os.Stat(dataFilePath); err == nil {
+		return fmt.Errorf("The data file %q already exists", dataFilePath)
+	}
+	migratedData, err := cloneReminderData(rd)
+	if err != nil {
+		return err
+	}
+	migratedData.DataFile = dataFilePath
+	if err := migratedData.storage().Save(migratedData); err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("Migrated the data file %q to %q.", rd.DataFile, dataFilePath))
+	return nil
+}
+
+// QueryNotes returns the (persisted) notes matching the query.
+func (rd *ReminderData) QueryNotes(query NoteQuery) (Notes, error) {
+	return rd.storage().QueryNotes(query)
+}
+
+// SetConflictResolver sets the resolver for conflicting changes made by another session.
+func (rd *ReminderData) SetConflictResolver(resolve ConflictResolver) {
+	rd.resolver = resolve
+}
+
+// refreshBase takes snapshot of the data as it is persisted.
+func (rd *ReminderData) refreshBase() {
+	base, err := cloneReminderData(rd)
+	if err != nil {
+		utils.LogError(err)
+		base = nil
+	}
+	rd.base = base
+}
+
 // SortedTagSlugs sorts the tags in-place and return slugs.
 // Empty Tags is returned if there are no tags.
 func (rd *ReminderData) SortedTagSlugs() []string {
@@ -234,12 +326,24 @@ func (rd *ReminderData) TagIdsForGroup(group string) []int {
 	return rd.Tags.IdsForGroup(group)
 }
 
-// FindNotesByTagId gets all notes with given tagID and given status.
+// Migrated tells if the data was migrated while being read from the data file (or was read from its backup,
+// as the data file is corrupt), in which case it should be persisted to keep the migration.
+func (rd *ReminderData) Migrated() bool {
+	return rd.migrated
+}
+
+// FindNoteById gets the note with given id.
+// It returns nil if no such note is found.
+func (rd *ReminderData) FindNoteById(id string) *Note {
+	return rd.Notes.WithId(id)
+}
+
+// FindNotesByTagId gets all notes with given tagID (or any of its sub-tags) and given status.
 func (rd *ReminderData) FindNotesByTagId(tagID int, status NoteStatus) Notes {
-	return rd.Notes.WithTagIdAndStatus(tagID, status)
+	return rd.Notes.WithStatus(status).WithAnyTagId(rd.Tags.WithSubTagIds(tagID))
 }
 
-// FindNotesByTagSlug gets all notes with given tagSlug and given status.
+// FindNotesByTagSlug gets all notes with given tagSlug (or any of its sub-tags) and given status.
 func (rd *ReminderData) FindNotesByTagSlug(tagSlug string, status NoteStatus) Notes {
 	tag := rd.TagFromSlug(tagSlug)
 	// return empty Notes object for nil `tag`
@@ -249,68 +353,78 @@ func (rd *ReminderData) FindNotesByTagSlug(tagSlug string, status NoteStatus) No
 	return rd.FindNotesByTagId(tag.Id, status)
 }
 
-// UpdateNoteText updates note's text.
-func (rd *ReminderData) UpdateNoteText(note *Note, text string) error {
-	err := note.UpdateText(text)
+// EditNote applies the edit (such as several updates through the methods of Note) to the note at once:
+// the changes are recorded as a single change in history of the note (and on undo stack of the session),
+// and the note is persisted once. If the edit fails, the note is left as it was.
+func (rd *ReminderData) EditNote(note *Note, edit func() error) error {
+	before, err := cloneNote(note)
 	if err != nil {
 		return err
 	}
-	return rd.UpdateDataFile("")
+	return rd.mutateNote(note, func() error {
+		if err := edit(); err != nil {
+			*note = *before
+			return err
+		}
+		return nil
+	})
+}
+
+// UpdateNoteText updates note's text.
+func (rd *ReminderData) UpdateNoteText(note *Note, text string) error {
+	return rd.mutateNote(note, func() error {
+		return note.UpdateText(text)
+	})
 }
 
 // UpdateNoteSummary updates the note's summary.
 func (rd *ReminderData) UpdateNoteSummary(note *Note, text string) error {
-	err := note.UpdateSummary(text)
-	if err != nil {
-		return err
-	}
-	return rd.UpdateDataFile("")
+	return rd.mutateNote(note, func() error {
+		return note.UpdateSummary(text)
+	})
 }
 
 // UpdateNoteCompleteBy updates the note's due date (complete by).
 func (rd *ReminderData) UpdateNoteCompleteBy(note *Note, text string) error {
-	err := note.UpdateCompleteBy(text)
-	if err != nil {
-		return err
-	}
-	return rd.UpdateDataFile("")
+	return rd.mutateNote(note, func() error {
+		return note.UpdateCompleteBy(text)
+	})
+}
+
+// UpdateNoteRecurrence updates note's recurrence.
+func (rd *ReminderData) UpdateNoteRecurrence(note *Note, text string) error {
+	return rd.mutateNote(note, func() error {
+		return note.UpdateRecurrence(text)
+	})
 }
 
 // AddNoteComment adds note's comment.
 func (rd *ReminderData) AddNoteComment(note *Note, text string) error {
-	err := note.AddComment(text)
-	if err != nil {
-		return err
-	}
-	return rd.UpdateDataFile("")
+	return rd.mutateNote(note, func() error {
+		return note.AddComment(text)
+	})
 }
 
 // UpdateNoteTags updates note's tags.
 func (rd *ReminderData) UpdateNoteTags(note *Note, tagIDs []int) error {
-	err := note.UpdateTags(tagIDs)
-	if err != nil {
-		return err
-	}
-	return rd.UpdateDataFile("")
+	return rd.mutateNote(note, func() error {
+		return note.UpdateTags(tagIDs, rd.Tags, rd.TagGroupRules())
+	})
 }
 
 // UpdateNoteStatus updates note's status.
 func (rd *ReminderData) UpdateNoteStatus(note *Note, status NoteStatus) error {
-	repeatTagIDs := rd.TagIdsForGroup("repeat")
-	err := note.UpdateStatus(status, repeatTagIDs)
-	if err != nil {
-		return err
-	}
-	return rd.UpdateDataFile("")
+	repeatTagIDs := rd.TagIdsForGroup(RepeatTagGroup)
+	return rd.mutateNote(note, func() error {
+		return note.UpdateStatus(status, repeatTagIDs)
+	})
 }
 
 // ToggleNoteMainFlag toggles note's priority.
 func (rd *ReminderData) ToggleNoteMainFlag(note *Note) error {
-	err := note.ToggleMainFlag()
-	if err != nil {
-		return err
-	}
-	return rd.UpdateDataFile("")
+	return rd.mutateNote(note, func() error {
+		return note.ToggleMainFlag()
+	})
 }
 
 // RegisterBasicTags registers basic tags.
@@ -344,21 +458,51 @@ func (rd *ReminderData) ListTags() error {
 	for _, tagSlug := range rd.SortedTagSlugs() {
 		allTagSlugsWithEmoji = append(allTagSlugsWithEmoji, fmt.Sprintf("%v %v", tagSymbol(tagSlug), tagSlug))
 	}
+	// the saved tag queries are listed as virtual tags
+	numTags := len(allTagSlugsWithEmoji)
+	for _, tagQuery := range rd.TagQueries {
+		allTagSlugsWithEmoji = append(allTagSlugsWithEmoji, fmt.Sprintf("%v %v", utils.Symbols["search"], tagQuery))
+	}
+	numEntries := len(allTagSlugsWithEmoji)
 	// ask user to select a tag
-	tagIndex, _, err := utils.AskOption(append(allTagSlugsWithEmoji, fmt.Sprintf("%v %v", utils.Symbols["add"], "Add Tag")), "Select Tag: ")
+	tagIndex, _, err := utils.AskOption(append(allTagSlugsWithEmoji,
+		fmt.Sprintf("%v %v", utils.Symbols["add"], "Add Tag"),
+		fmt.Sprintf("%v %v", utils.Symbols["text"], "Edit Tag"),
+		fmt.Sprintf("%v %v", utils.Symbols["search"], "Add Tag Query")), "Select Tag: ")
 	if (err != nil) || (tagIndex == -1) {
 		// do nothing, just exit
 		return err
 	}
 	// check if user wants to add a new tag
-	if tagIndex == len(rd.SortedTagSlugs()) {
+	if tagIndex == numEntries {
 		// add new tag
-		_, err = rd.NewTagRegistration()
+		_, err = rd.NewTagRegistration("", "")
 		if err != nil {
 			return err
 		}
 		return nil
 	}
+	// check if user wants to edit a tag
+	if tagIndex == numEntries+1 {
+		return rd.EditTag()
+	}
+	// check if user wants to add a tag query
+	if tagIndex == numEntries+2 {
+		return rd.NewTagQueryRegistration()
+	}
+	// operate on the selected tag query
+	if tagIndex >= numTags {
+		var notes Notes
+		if notes, err = rd.FindNotesByTagQuery(rd.TagQueries[tagIndex-numTags], NoteStatus_Pending); err == nil {
+			err = rd.PrintNotesAndAskOptions(notes, "passed_notes", -1, "default")
+		}
+		if err != nil {
+			utils.LogError(err)
+			// go back to ListTags
+			return rd.ListTags()
+		}
+		return nil
+	}
 	// operate on the selected a tag, and display both main and non-main notes
 	tag := rd.Tags[tagIndex]
 	err = rd.PrintNotesAndAskOptions(Notes{}, "pending_tag_notes", tag.Id, "default")
@@ -373,12 +517,105 @@ func (rd *ReminderData) ListTags() error {
 	return nil
 }
 
-// SearchNotes searches throught all notes.
+// NewTagQueryRegistration prompts for name and expression of a tag query, and saves it.
+func (rd *ReminderData) NewTagQueryRegistration() error {
+	name, err := utils.GeneratePrompt("tag_query_name", "")
+	if err != nil {
+		return err
+	}
+	query, err := utils.GeneratePrompt("tag_query", "")
+	if err != nil {
+		return err
+	}
+	_, err = rd.SaveTagQuery(name, query)
+	return err
+}
+
+// EditTag prompts for a tag, and then renames, regroups, merges or deletes it.
+// Like utils.AskOptions, it prints any encountered error, and returns that error just for information.
+func (rd *ReminderData) EditTag() error {
+	tagIndex, _, err := utils.AskOption(rd.SortedTagSlugs(), "Select Tag to Edit: ")
+	if (err != nil) || (tagIndex == -1) {
+		return err
+	}
+	tag := rd.Tags[tagIndex]
+	if tag.Protected() {
+		err = fmt.Errorf("%w: %q", ErrorTagProtected, tag.Slug)
+		utils.LogError(err)
+		return err
+	}
+	numNotes := len(rd.Notes.WithTagId(tag.Id)) + len(rd.Trash.WithTagId(tag.Id))
+	_, tagOption, _ := utils.AskOption([]string{
+		fmt.Sprintf("%v %v", utils.Symbols["text"], "Rename"),
+		fmt.Sprintf("%v %v", utils.Symbols["tag"], "Change Group"),
+		fmt.Sprintf("%v %v", utils.Symbols["merge"], "Merge into another Tag"),
+		fmt.Sprintf("%v %v", utils.Symbols["trash"], "Delete"),
+		fmt.Sprintf("%v %v", utils.Symbols["noAction"], "Do nothing")},
+		fmt.Sprintf("Select Action (for tag %q of %v notes): ", tag.Slug, numNotes))
+	var count int
+	switch tagOption {
+	case fmt.Sprintf("%v %v", utils.Symbols["text"], "Rename"):
+		var slug string
+		if slug, err = utils.GeneratePrompt("tag_slug", tag.Slug); err == nil {
+			err = rd.RenameTag(tag, slug)
+		}
+	case fmt.Sprintf("%v %v", utils.Symbols["tag"], "Change Group"):
+		var group string
+		if group, err = utils.GeneratePrompt("tag_group", tag.Group); err == nil {
+			err = rd.RegroupTag(tag, group)
+		}
+	case fmt.Sprintf("%v %v", utils.Symbols["merge"], "Merge into another Tag"):
+		var target *Tag
+		if target, err = rd.askOtherTag(tag, "Merge into Tag: "); (err == nil) && (target != nil) {
+			count, err = rd.MergeTags(tag, target)
+			fmt.Printf("Updated %v notes.\n", count)
+		}
+	case fmt.Sprintf("%v %v", utils.Symbols["trash"], "Delete"):
+		_, deleteOption, _ := utils.AskOption([]string{
+			fmt.Sprintf("%v %v", utils.Symbols["error"], "Detach the notes from the tag"),
+			fmt.Sprintf("%v %v", utils.Symbols["merge"], "Reassign the notes to another tag"),
+			fmt.Sprintf("%v %v", utils.Symbols["noAction"], "Do nothing")},
+			"Select Action: ")
+		switch deleteOption {
+		case fmt.Sprintf("%v %v", utils.Symbols["error"], "Detach the notes from the tag"):
+			count, err = rd.DeleteTag(tag, nil)
+			fmt.Printf("Updated %v notes.\n", count)
+		case fmt.Sprintf("%v %v", utils.Symbols["merge"], "Reassign the notes to another tag"):
+			var target *Tag
+			if target, err = rd.askOtherTag(tag, "Reassign to Tag: "); (err == nil) && (target != nil) {
+				count, err = rd.DeleteTag(tag, target)
+				fmt.Printf("Updated %v notes.\n", count)
+			}
+		}
+	}
+	if err != nil {
+		utils.LogError(err)
+	}
+	return err
+}
+
+// askOtherTag prompts for a tag other than the given one (and the protected ones).
+// It returns nil if no tag is selected.
+func (rd *ReminderData) askOtherTag(tag *Tag, label string) (*Tag, error) {
+	var slugs []string
+	for _, slug := range rd.SortedTagSlugs() {
+		if (slug != tag.Slug) && !utils.IsMemberOfSlice(slug, ProtectedTagSlugs) {
+			slugs = append(slugs, slug)
+		}
+	}
+	_, slug, err := utils.AskOption(slugs, label)
+	if (err != nil) || (slug == "") {
+		return nil, err
+	}
+	return rd.TagFromSlug(slug), nil
+}
+
+// SearchNotes searches throught all notes (including the archived ones, if they are to be included).
 // Like utils.AskOptions, it prints any encountered error, and returns that error just for information.
 func (rd *ReminderData) SearchNotes() error {
 	// get texts of all notes
-	sort.Sort(rd.Notes)
-	allNotes := rd.Notes
+	allNotes := rd.AllNotes()
+	sort.Sort(allNotes)
 	// assuming the search shows 25 items in general
 	allTexts := make([]string, 0, 25)
 	for _, note := range allNotes {
@@ -419,88 +656,100 @@ func (rd *ReminderData) SearchNotes() error {
 }
 
 // NotesApprachingDueDate fetches all pending notes which are urgent.
-// It accepts view as an argument with "default" or "long" as acceptable values
-// Note: NotesApprachingDueDate is dangerous as it manipulates the due date (CompleteBy) date of repeating tags
-// which can cause persitence of manupulated dates, if the returned data is persisted.
+// It accepts view as an argument with "default" or "long" as acceptable values.
+// Refer ApproachingOccurrences for details.
 func (rd *ReminderData) NotesApprachingDueDate(view string) Notes {
-	allNotes := rd.Notes
-	pendingNotes := allNotes.WithStatus(NoteStatus_Pending)
-	// assuming there are at least 100 notes (on average)
-	currentNotes := make([]*Note, 0, 100)
-	repeatTagIDs := rd.TagIdsForGroup("repeat")
-	// populate tempDueDate
-	pendingNotes.PopulateTempDueDate()
-	// populating currentNotes
-	for _, note := range pendingNotes {
-		noteIDsWithRepeat := utils.GetCommonMembersOfSlices(note.TagIds, repeatTagIDs)
-		// first process notes WITHOUT tag with group "repeat"
-		// start showing such notes 7 days in advance from their due date, and until they are marked done
-		minDay := note.tempDueDate - 7*24*60*60
-		if view == "long" {
-			minDay = note.tempDueDate - 365*24*60*60
-		}
-		currentTimestamp := utils.CurrentUnixTimestamp()
-		if (len(noteIDsWithRepeat) == 0) && (note.tempDueDate != 0) && (currentTimestamp >= minDay) {
-			currentNotes = append(currentNotes, note)
-		}
-		// check notes with tag with group "repeat"
-		// start showing notes with "repeat-annually" 7 days in advance
-		// start showing notes with "repeat-monthly" 3 days in advance
-		// don't show such notes after their due date is past by 2 day
-		if (len(noteIDsWithRepeat) > 0) && (note.tempDueDate != 0) {
-			// check for repeat-annually tag
-			// note: for the tempDueDate date of the note, we accept only date
-			// so, even if there is a time element recorded the the timestamp,
-			// we ignore it
-			repeatAnnuallyTag := rd.TagFromSlug("repeat-annually")
-			repeatMonthlyTag := rd.TagFromSlug("repeat-monthly")
-			if (repeatAnnuallyTag != nil) && utils.IsMemberOfSlice(repeatAnnuallyTag.Id, note.TagIds) {
-				_, noteMonth, noteDay := utils.UnixTimestampToTime(note.tempDueDate).Date()
-				noteTimestampCurrent := utils.UnixTimestampForCorrespondingCurrentYear(int(noteMonth), noteDay)
-				noteTimestampPrevious := noteTimestampCurrent - 365*24*60*60
-				noteTimestampNext := noteTimestampCurrent + 365*24*60*60
-				daysBefore := int64(3) // days before to start showing the note
-				daysAfter := int64(7)  // days after until to show the note
-				if view == "long" {
-					daysBefore = int64(365)
-				}
-				shouldDisplay, matchingTimestamp := utils.MatchedTimestamp(noteTimestampCurrent, noteTimestampPrevious, noteTimestampNext, daysBefore, daysAfter)
-				// temporarity update note's timestamp
-				note.tempDueDate = matchingTimestamp
-				if shouldDisplay {
-					currentNotes = append(currentNotes, note)
-				}
-			}
-			// check for repeat-monthly tag
-			if (repeatMonthlyTag != nil) && utils.IsMemberOfSlice(repeatMonthlyTag.Id, note.TagIds) {
-				_, _, noteDay := utils.UnixTimestampToTime(note.tempDueDate).Date()
-				noteTimestampCurrent := utils.UnixTimestampForCorrespondingCurrentYearMonth(noteDay)
-				noteTimestampPrevious := noteTimestampCurrent - 30*24*60*60
-				noteTimestampNext := noteTimestampCurrent + 30*24*60*60
-				daysBefore := int64(1) // days beofre to start showing the note
-				daysAfter := int64(3)  // days after until to show the note
-				if view == "long" {
-					daysBefore = int64(31)
-				}
-				shouldDisplay, matchingTimestamp := utils.MatchedTimestamp(noteTimestampCurrent, noteTimestampPrevious, noteTimestampNext, daysBefore, daysAfter)
-				// temporarity update note's timestamp
-				note.tempDueDate = matchingTimestamp
-				if shouldDisplay {
-					currentNotes = append(currentNotes, note)
-				}
-			}
+	return rd.ApproachingOccurrences(view, utils.CurrentUnixTimestamp()).Notes()
+}
+
+// ApproachingOccurrences returns occurrences of all pending notes whose window includes given timestamp.
+// It accepts view as an argument with "default" or "long" as acceptable values.
+// The occurrences are in the order of the notes (that is, unsorted), and the notes are not modified.
+func (rd *ReminderData) ApproachingOccurrences(view string, at int64) Occurrences {
+	repeatTagIDs := rd.TagIdsForGroup(RepeatTagGroup)
+	repeatAnnuallyTagId, repeatMonthlyTagId := rd.RepeatTagIds()
+	var result Occurrences
+	for _, note := range rd.Notes.WithStatus(NoteStatus_Pending) {
+		recurrence := note.EffectiveRecurrence(repeatAnnuallyTagId, repeatMonthlyTagId)
+		// skip non-recurring notes with (any other) tag with group "repeat" (refer RepeatTagGroup)
+		if (recurrence == nil) && (len(utils.GetCommonMembersOfSlices(note.TagIds, repeatTagIDs)) > 0) {
+			continue
+		}
+		occurrence := ProjectOccurrence(note, recurrence, view, at)
+		if occurrence.IsActive(at) {
+			result = append(result, occurrence)
+		}
+	}
+	return result
+}
+
+// NoteOccurrences projects due dates of given notes as seen at given timestamp.
+// Refer ProjectOccurrence for details.
+func (rd *ReminderData) NoteOccurrences(notes Notes, view string, at int64) Occurrences {
+	repeatAnnuallyTagId, repeatMonthlyTagId := rd.RepeatTagIds()
+	occurrences := make(Occurrences, 0, len(notes))
+	for _, note := range notes {
+		recurrence := note.EffectiveRecurrence(repeatAnnuallyTagId, repeatMonthlyTagId)
+		occurrences = append(occurrences, ProjectOccurrence(note, recurrence, view, at))
+	}
+	return occurrences
+}
+
+// RepeatTagIds returns ids of "repeat-annually" and "repeat-monthly" tags (-1 for a missing tag).
+func (rd *ReminderData) RepeatTagIds() (int, int) {
+	repeatAnnuallyTagId, repeatMonthlyTagId := -1, -1
+	if tag := rd.TagFromSlug("repeat-annually"); tag != nil {
+		repeatAnnuallyTagId = tag.Id
+	}
+	if tag := rd.TagFromSlug("repeat-monthly"); tag != nil {
+		repeatMonthlyTagId = tag.Id
+	}
+	return repeatAnnuallyTagId, repeatMonthlyTagId
+}
+
+// migrate migrates the data just loaded from a data file (of any format) to its current shape,
+// and marks it as migrated (refer Migrated) if anything was changed.
+func (rd *ReminderData) migrate() {
+	// back-fill ids of notes (and their comments) persisted before ids were introduced
+	if count := rd.Notes.EnsureIds(); count > 0 {
+		rd.migrated = true
+		logger.Info(fmt.Sprintf("Assigned ids to %d notes.", count))
+	}
+	// set recurrence of notes persisted with only "repeat-annually" or "repeat-monthly" tag
+	if count := rd.migrateRepeatTags(); count > 0 {
+		rd.migrated = true
+		logger.Info(fmt.Sprintf("Set recurrence of %d notes from their repeat tags.", count))
+	}
+}
+
+// migrateRepeatTags sets recurrence of the notes with "repeat-annually" or "repeat-monthly" tag
+// (used before notes had their own recurrence). The tags are kept as they are.
+// It returns number of migrated notes.
+func (rd *ReminderData) migrateRepeatTags() int {
+	repeatAnnuallyTagId, repeatMonthlyTagId := rd.RepeatTagIds()
+	count := 0
+	for _, note := range rd.Notes {
+		if note.Recurrence != nil {
+			continue
+		}
+		if recurrence := recurrenceFromRepeatTags(note.TagIds, repeatAnnuallyTagId, repeatMonthlyTagId); recurrence != nil {
+			note.Recurrence = recurrence
+			count++
 		}
 	}
-	// return unsorted list
-	return currentNotes
+	return count
 }
 
 // NewTagRegistration registers a new tag.
-func (rd *ReminderData) NewTagRegistration() (int, error) {
+// Pass useSlug and/or useGroup to use given values instead of prompting user.
+func (rd *ReminderData) NewTagRegistration(useSlug string, useGroup string) (int, error) {
 	// collect and ask info about the tag
 	tagID := rd.nextPossibleTagId()
 
-	tag, err := NewTag(tagID, "", "")
+	tag, err := NewTag(tagID, useSlug, useGroup)
+	if err == nil {
+		err = checkTagSlug(tag.Slug)
+	}
 
 	// validate and save data
 	if err != nil {
@@ -512,10 +761,15 @@ func (rd *ReminderData) NewTagRegistration() (int, error) {
 }
 
 // nextPossibleTagId gets next possible tagID.
+// The ids of deleted tags aren't reused, as they may still be referred to (such as by history of the notes).
 func (rd *ReminderData) nextPossibleTagId() int {
-	allTags := rd.Tags
-	allTagsLen := len(allTags)
-	return allTagsLen
+	nextTagId := rd.NextTagId
+	for _, tag := range rd.Tags {
+		if tag.Id >= nextTagId {
+			nextTagId = tag.Id + 1
+		}
+	}
+	return nextTagId
 }
 
 // newTagAppend appends a new tag.
@@ -533,18 +787,22 @@ func (rd *ReminderData) newTagAppend(tag *Tag) error {
 	// go ahead and append
 	logger.Info(fmt.Sprintf("Added Tag: %v\n", *tag))
 	rd.Tags = append(rd.Tags, tag)
-	return rd.UpdateDataFile("")
+	return rd.storage().SaveTag(rd, tag)
 }
 
 // NewNoteRegistration registers new note.
+// Pass useText to use given text instead of prompting user.
 // The note is saved to the data file.
-func (rd *ReminderData) NewNoteRegistration(tagIDs []int) (*Note, error) {
+func (rd *ReminderData) NewNoteRegistration(tagIDs []int, useText string) (*Note, error) {
 	// collect info about the note
 	if tagIDs == nil {
 		// assuming each note with have on average 2 tags
 		tagIDs = make([]int, 0, 2)
 	}
-	note, err := NewNote(tagIDs, "")
+	if err := rd.CheckTagGroups(tagIDs); err != nil {
+		return nil, err
+	}
+	note, err := NewNote(tagIDs, useText)
 	// validate and save data
 	if err != nil {
 		return note, err
@@ -556,15 +814,25 @@ func (rd *ReminderData) NewNoteRegistration(tagIDs []int) (*Note, error) {
 	return note, nil
 }
 
+// AddNote adds the new note, which is built beforehand (such as with NewNote, and then with its due date and
+// recurrence set), so that the note is saved to the data file just once, along with all of its fields.
+func (rd *ReminderData) AddNote(note *Note) error {
+	if err := rd.CheckTagGroups(note.TagIds); err != nil {
+		return err
+	}
+	return rd.newNoteAppend(note)
+}
+
 // newNoteAppend appends a new note.
 // The note is saved to the data file.
 func (rd *ReminderData) newNoteAppend(note *Note) error {
 	logger.Info(fmt.Sprintf("Adding Note: %+v\n", *note))
 	rd.Notes = append(rd.Notes, note)
-	return rd.UpdateDataFile("")
+	return rd.updateNote(note)
 }
 
 // Stats returns current status.
+// The archived notes are counted as well if they are to be included (refer SetIncludeArchived).
 func (rd *ReminderData) Stats() (string, error) {
 	reportTemplate := `
 Stats of "{{.DataFile}}":
@@ -572,14 +840,23 @@ Stats of "{{.DataFile}}":
   - Pending Notes:   {{.Notes | numPending}}/{{.Notes | numAll}}
   - Suspended Notes: {{.Notes | numSuspended}}
   - Done Notes:      {{.Notes | numDone}}
-`
+{{if .Archived}}  - Archived Notes:  {{.Archived | len}}
+{{end}}{{if .Trash}}  - Trashed Notes:   {{.Trash | len}}
+{{end}}`
 	funcMap := template.FuncMap{
 		"numPending":   func(notes Notes) int { return len(notes.WithStatus(NoteStatus_Pending)) },
 		"numSuspended": func(notes Notes) int { return len(notes.WithStatus(NoteStatus_Suspended)) },
 		"numDone":      func(notes Notes) int { return len(notes.WithStatus(NoteStatus_Done)) },
 		"numAll":       func(notes Notes) int { return len(notes) },
 	}
-	return utils.TemplateResult(reportTemplate, funcMap, *rd)
+	data := struct {
+		DataFile string
+		Tags     Tags
+		Notes    Notes
+		Archived Notes
+		Trash    Notes
+	}{DataFile: rd.DataFile, Tags: rd.Tags, Notes: rd.AllNotes(), Archived: rd.IncludedArchivedNotes(), Trash: rd.Trash}
+	return utils.TemplateResult(reportTemplate, funcMap, data)
 }
 
 // CreateBackup creates timestamped backup.
@@ -619,6 +896,16 @@ func (rd *ReminderData) CreateBackup() (string, error) {
 // DisplayDataFile displays the data file.
 // Like utils.AskOptions, it prints any encountered error, and returns that error just for information.
 func (rd *ReminderData) DisplayDataFile() error {
+	if IsSQLiteDataFile(rd.DataFile) {
+		// the database is not human-readable, so print the data as JSON instead
+		fmt.Printf("Printing contents of %q (as JSON):\n", rd.DataFile)
+		byteValue, err := json.MarshalIndent(rd, "", "    ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(byteValue))
+		return nil
+	}
 	fmt.Printf("Printing contents (and if possible, its difference since last backup) of %q:\n", rd.DataFile)
 	ext := path.Ext(rd.DataFile)
 	lnFile := rd.DataFile[:len(rd.DataFile)-len(ext)] + "_backup_latest" + ext
@@ -667,7 +954,7 @@ func (rd *ReminderData) AskTagIds(tagIDs []int) []int {
 	// get tagID
 	if optionIndex == len(rd.SortedTagSlugs()) {
 		// add new tag
-		tagID, err = rd.NewTagRegistration()
+		tagID, err = rd.NewTagRegistration("", "")
 	} else {
 		// existing tag selected
 		tagID = rd.Tags[optionIndex].Id
@@ -675,7 +962,7 @@ func (rd *ReminderData) AskTagIds(tagIDs []int) []int {
 	}
 	// update tagIDs
 	if (err == nil) && (!utils.IsMemberOfSlice(tagID, tagIDs)) {
-		tagIDs = append(tagIDs, tagID)
+		tagIDs = append(rd.withoutSingleChoiceGroupTags(tagIDs, tagID), tagID)
 	}
 	// check with user if another tag is to be added
 	promptText, err := utils.GeneratePrompt("tag_another", "")
@@ -693,6 +980,24 @@ func (rd *ReminderData) AskTagIds(tagIDs []int) []int {
 	return tagIDs
 }
 
+// withoutSingleChoiceGroupTags returns the tagIDs except the ones which cannot be used along with the tag
+// (as they are of the same single-choice group), printing the tags that are replaced.
+func (rd *ReminderData) withoutSingleChoiceGroupTags(tagIDs []int, tagID int) []int {
+	tags := rd.Tags.FromIds([]int{tagID})
+	if (len(tags) == 0) || (rd.TagGroupRules().Rule(tags[0].Group) != TagGroupRule_Single) {
+		return tagIDs
+	}
+	kept := make([]int, 0, len(tagIDs))
+	for _, tag := range rd.Tags.FromIds(tagIDs) {
+		if tag.Group == tags[0].Group {
+			fmt.Printf("%v Replacing tag %q with %q, as group %q is single-choice\n", utils.Symbols["warning"], tag.Slug, tags[0].Slug, tag.Group)
+			continue
+		}
+		kept = append(kept, tag.Id)
+	}
+	return kept
+}
+
 // PrintNoteAndAskOptions prints note and display options.
 // Like utils.AskOptions, it prints any encountered error, but doesn't returns that error just for information.
 // It return string representing workflow direction.
@@ -706,10 +1011,15 @@ func (rd *ReminderData) PrintNoteAndAskOptions(note *Note) string {
 		fmt.Sprintf("%v %v", utils.Symbols["zzz"], "Mark as suspended"),
 		fmt.Sprintf("%v %v", utils.Symbols["downVote"], "Mark as pending"),
 		fmt.Sprintf("%v %v", utils.Symbols["calendar"], "Update due date"),
+		fmt.Sprintf("%v %v", utils.Symbols["refresh"], "Update recurrence"),
 		fmt.Sprintf("%v %v", utils.Symbols["tag"], "Update tags"),
 		fmt.Sprintf("%v %v", utils.Symbols["text"], "Update text"),
 		fmt.Sprintf("%v %v", utils.Symbols["glossary"], "Update summary"),
-		fmt.Sprintf("%v %v", utils.Symbols["hat"], "Toggle main/incidental")},
+		fmt.Sprintf("%v %v", utils.Symbols["hat"], "Toggle main/incidental"),
+		fmt.Sprintf("%v %v", utils.Symbols["undo"], "Undo last change"),
+		fmt.Sprintf("%v %v", utils.Symbols["redo"], "Redo last change"),
+		fmt.Sprintf("%v %v", utils.Symbols["history"], "Show history"),
+		fmt.Sprintf("%v %v", utils.Symbols["trash"], "Move to trash")},
 		"Select Action: ")
 	switch noteOption {
 	case fmt.Sprintf("%v %v", utils.Symbols["comment"], "Add comment"):
@@ -738,9 +1048,28 @@ func (rd *ReminderData) PrintNoteAndAskOptions(note *Note) string {
 	case fmt.Sprintf("%v %v", utils.Symbols["calendar"], "Update due date"):
 		promptText, err := utils.GeneratePrompt("note_completed_by", "")
 		utils.LogError(err)
+		if promptText != "nil" {
+			// show the resolved date (as the input can be a phrase like "next friday")
+			confirmed, err := utils.ConfirmDueDate(promptText)
+			utils.LogError(err)
+			if !confirmed {
+				fmt.Printf("%v Skipping updating the due date\n", utils.Symbols["warning"])
+				break
+			}
+		}
 		err = rd.UpdateNoteCompleteBy(note, promptText)
 		utils.LogError(err)
 		fmt.Print(note.ExternalText(rd))
+	case fmt.Sprintf("%v %v", utils.Symbols["refresh"], "Update recurrence"):
+		defaultText := ""
+		if note.Recurrence != nil {
+			defaultText = note.Recurrence.String()
+		}
+		promptText, err := utils.GeneratePrompt("note_recurrence", defaultText)
+		utils.LogError(err)
+		err = rd.UpdateNoteRecurrence(note, promptText)
+		utils.LogError(err)
+		fmt.Print(note.ExternalText(rd))
 	case fmt.Sprintf("%v %v", utils.Symbols["text"], "Update text"):
 		promptText, err := utils.GeneratePrompt("note_text", note.Text)
 		utils.LogError(err)
@@ -766,16 +1095,72 @@ func (rd *ReminderData) PrintNoteAndAskOptions(note *Note) string {
 		err := rd.ToggleNoteMainFlag(note)
 		utils.LogError(err)
 		fmt.Print(note.ExternalText(rd))
+	case fmt.Sprintf("%v %v", utils.Symbols["undo"], "Undo last change"):
+		err := rd.UndoNoteChange(note)
+		utils.LogError(err)
+		fmt.Print(note.ExternalText(rd))
+	case fmt.Sprintf("%v %v", utils.Symbols["redo"], "Redo last change"):
+		err := rd.RedoNoteChange(note)
+		utils.LogError(err)
+		fmt.Print(note.ExternalText(rd))
+	case fmt.Sprintf("%v %v", utils.Symbols["history"], "Show history"):
+		fmt.Printf("History of the note %q:\n", note.Text)
+		fmt.Print(note.HistoryText(rd))
+	case fmt.Sprintf("%v %v", utils.Symbols["trash"], "Move to trash"):
+		err := rd.TrashNote(note)
+		utils.LogError(err)
+		if err == nil {
+			fmt.Printf("Moved the note to the trash; it can be restored from %q of the main menu\n", "Trash")
+		}
 	}
 	return "stay"
 }
 
+// ListTrash prompts a list of the notes in the trash, and lets the user restore or permanently delete them.
+// Like utils.AskOptions, it prints any encountered error, and returns that error just for information.
+func (rd *ReminderData) ListTrash() error {
+	if len(rd.Trash) == 0 {
+		fmt.Println("The trash is empty.")
+		return nil
+	}
+	notes := append(Notes{}, rd.Trash...)
+	sort.Sort(notes)
+	repeatAnnuallyTagId, repeatMonthlyTagId := rd.RepeatTagIds()
+	width, err := utils.TerminalWidth()
+	if err != nil {
+		return err
+	}
+	texts := notes.ExternalTexts(width-50, repeatAnnuallyTagId, repeatMonthlyTagId)
+	noteIndex, _, err := utils.AskOption(texts, fmt.Sprintf("Select Note (%v in the trash): ", len(notes)))
+	if (err != nil) || (noteIndex == -1) {
+		return err
+	}
+	note := notes[noteIndex]
+	fmt.Print(note.ExternalText(rd))
+	_, noteOption, _ := utils.AskOption([]string{
+		fmt.Sprintf("%v %v", utils.Symbols["undo"], "Restore"),
+		fmt.Sprintf("%v %v", utils.Symbols["trash"], "Delete permanently"),
+		fmt.Sprintf("%v %v", utils.Symbols["noAction"], "Do nothing")},
+		"Select Action: ")
+	switch noteOption {
+	case fmt.Sprintf("%v %v", utils.Symbols["undo"], "Restore"):
+		err = rd.RestoreNote(note)
+	case fmt.Sprintf("%v %v", utils.Symbols["trash"], "Delete permanently"):
+		err = rd.PurgeNote(note)
+	}
+	if err != nil {
+		return err
+	}
+	// go back to the (remaining) notes in the trash
+	return rd.ListTrash()
+}
+
 // PrintNotesAndAskOptions (recursively) prints notes interactively.
 // In some cases, updated list notes will be fetched, so blank notes can be passed in those cases.
 // Unless notes are to be fetched, the passed `status` doesn't make sense, so in such cases it can be passed as "fake".
 // Like utils.AskOptions, it prints any encountered error, and returns that error just for information.
 // It accepts following values for `display_mode`:
-// - "done_notes": fetch only done notes
+// - "done_notes": fetch only done notes (including the archived ones, if they are to be included)
 // - "suspended_notes": fetch only suspended notes
 // - "pending_tag_notes": fetch pending notes with given tagID
 // - "pending_only_main_notes": fetch pending notes with IsMain set as true
@@ -788,7 +1173,7 @@ func (rd *ReminderData) PrintNotesAndAskOptions(notes Notes, display_mode string
 	case "done_notes":
 		// ignore the passed notes
 		// fetch all the done notes
-		notes = rd.Notes.WithStatus(NoteStatus_Done)
+		notes = rd.AllNotes().WithStatus(NoteStatus_Done)
 		fmt.Printf("A total of %v notes marked as 'done':\n", len(notes))
 	case "suspended_notes":
 		// ignore the passed notes
@@ -815,10 +1200,12 @@ func (rd *ReminderData) PrintNotesAndAskOptions(notes Notes, display_mode string
 		fmt.Println("Note: A note can be in 'pending', 'suspended' or 'done' status.")
 		fmt.Println("Note: Notes marked as 'pending' are special and they show up everywhere, whereas notes with other status only show up in 'Search' or under their dedicated menu.")
 		fmt.Println("Note: Following are the pending notes with due date:")
-		fmt.Println("      - within a week or already crossed (for non repeat-annually or repeat-monthly)")
-		fmt.Println("      - within 3 days for repeat-annually and a week post due date (ignoring its year)")
-		fmt.Println("      - within 1 day for repeat-monthly and 3 days post due date (ignoring its year and month)")
-		fmt.Println("Note: The process may automatically adjust CompleteBy (due-date) with MM-YYYY for monthly repeating notes and YYYY for yearly repeating ones. This is done as part of search algorithm, and it does not impacts on any visibility of those notes.")
+		fmt.Println("      - within a week or already crossed (for non-recurring notes)")
+		fmt.Println("      - within 3 days for yearly recurring (or repeat-annually) notes and a week post an occurrence")
+		fmt.Println("      - within 1 day for monthly recurring (or repeat-monthly) notes and 3 days post an occurrence")
+		fmt.Println("      - within 1 day for weekly recurring notes and 2 days post an occurrence")
+		fmt.Println("      - on the day of an occurrence for daily recurring notes")
+		fmt.Println("Note: For recurring notes, the due date shown is of their matching occurrence (their own due date is left as it is).")
 		notes = rd.NotesApprachingDueDate("default")
 	case "passed_notes":
 		// use passed notes
@@ -827,24 +1214,33 @@ func (rd *ReminderData) PrintNotesAndAskOptions(notes Notes, display_mode string
 		// otherwise the note will immediately disappear if the updated tags list doesn't include the original tag
 		fmt.Printf("Note: Using passed notes; the list will not be refreshed immediately!\n")
 		fmt.Printf("Note: You must not run multiple instances of the app on same data file!\n")
+		// except for the ones moved to the trash meanwhile
+		var remaining Notes
+		for _, note := range notes {
+			if note.TrashedAt == 0 {
+				remaining = append(remaining, note)
+			}
+		}
+		notes = remaining
 	default:
 		return errors.New("Error: Unreachable code")
 	}
 
-	// sort notes
-	switch sortBy {
-	case "due-date":
-		sort.Sort(NotesByDueDate(notes))
-	case "default":
+	// sort notes (by their projected due dates, if asked for)
+	if sortBy == "default" {
 		sort.Sort(Notes(notes))
 	}
-	repeatAnnuallyTag := rd.TagFromSlug("repeat-annually")
-	repeatMonthlyTag := rd.TagFromSlug("repeat-monthly")
+	occurrences := rd.NoteOccurrences(notes, "default", utils.CurrentUnixTimestamp())
+	if sortBy == "due-date" {
+		sort.Stable(occurrences)
+	}
+	notes = occurrences.Notes()
+	repeatAnnuallyTagId, repeatMonthlyTagId := rd.RepeatTagIds()
 	width, err := utils.TerminalWidth()
 	if err != nil {
 		return err
 	}
-	texts := notes.ExternalTexts(width-50, repeatAnnuallyTag.Id, repeatMonthlyTag.Id)
+	texts := occurrences.ExternalTexts(width-50, repeatAnnuallyTagId, repeatMonthlyTagId)
 
 	// ask user to select a note
 	promptText := ""
@@ -864,7 +1260,7 @@ func (rd *ReminderData) PrintNotesAndAskOptions(notes Notes, display_mode string
 		if tagID < 0 {
 			return errors.New("The passed tagID is invalid!")
 		}
-		note, err := rd.NewNoteRegistration([]int{tagID})
+		note, err := rd.NewNoteRegistration([]int{tagID}, "")
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,244 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+/*
+A Revision represents a change of a field of a note.
+
+The old and new values are kept as JSON encoded strings (same as they are persisted in the data file).
+For comments, a revision represents a single added (or removed) comment.
+*/
+type Revision struct {
+	Field    string `json:"field"`
+	OldValue string `json:"old_value"`
+	NewValue string `json:"new_value"`
+	At       int64  `json:"at"`
+}
+
+// Revisions is a slice of Revision objects (in chronological order).
+type Revisions []*Revision
+
+/*
+A noteChange represents a set of revisions of a note made by a single mutation,
+as tracked by undo and redo stacks of a session.
+*/
+type noteChange struct {
+	noteId    string
+	revisions Revisions
+}
+
+// String provides basic string representation of a revision.
+// The tagger is used for showing tag slugs instead of tag ids.
+func (revision *Revision) String(tagger Tagger) string {
+	return fmt.Sprintf("%v | %v: %v %v %v", utils.UnixTimestampToMediumTimeStr(revision.At), revision.Field,
+		revisionValueString(revision.Field, revision.OldValue, tagger), utils.Symbols["rightArrow"],
+		revisionValueString(revision.Field, revision.NewValue, tagger))
+}
+
+// Strings provides basic string representation (as a slice of strings) of revisions.
+func (revisions Revisions) Strings(tagger Tagger) []string {
+	strs := make([]string, 0, len(revisions))
+	for _, revision := range revisions {
+		strs = append(strs, revision.String(tagger))
+	}
+	return strs
+}
+
+// revisionValueString returns human readable representation of a value of the field.
+func revisionValueString(field string, value string, tagger Tagger) string {
+	if value == "" || value == "null" {
+		return "-"
+	}
+	switch field {
+	case "complete_by":
+		var timestamp int64
+		if err := json.Unmarshal([]byte(value), &timestamp); err == nil {
+			return utils.UnixTimestampToShortTimeStr(timestamp)
+		}
+	case "tag_ids":
+		var tagIDs []int
+		if err := json.Unmarshal([]byte(value), &tagIDs); err == nil && tagger != nil {
+			return fmt.Sprintf("%v", tagger.TagsFromIds(tagIDs))
+		}
+	case "recurrence":
+		var recurrence Recurrence
+		if err := json.Unmarshal([]byte(value), &recurrence); err == nil {
+			return recurrence.String()
+		}
+	case "comments":
+		var comment Comment
+		if err := json.Unmarshal([]byte(value), &comment); err == nil {
+			return fmt.Sprintf("%q", comment.Text)
+		}
+	}
+	return value
+}
+
+// trackedNoteFields are the fields of a note whose changes are recorded as revisions.
+// The calendar link is bookkeeping of the calendar sync, and not a change made by the user; similarly,
+// moving a note to the trash (or to an archive) is not undone along with the changes of the note.
+func trackedNoteFields() []reflect.StructField {
+	var fields []reflect.StructField
+	noteType := reflect.TypeOf(Note{})
+	for index := 0; index < noteType.NumField(); index++ {
+		field := noteType.Field(index)
+		if !field.IsExported() || utils.IsMemberOfSlice(field.Name, []string{"Id", "History", "CalendarLink", "TrashedAt", "ArchivedAt", "BaseStruct"}) {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// diffNote returns the revisions between the before and after states of a note.
+func diffNote(before *Note, after *Note) (Revisions, error) {
+	var revisions Revisions
+	currentTime := utils.CurrentUnixTimestamp()
+	beforeValue := reflect.ValueOf(before).Elem()
+	afterValue := reflect.ValueOf(after).Elem()
+	for _, field := range trackedNoteFields() {
+		oldValue := beforeValue.FieldByIndex(field.Index).Interface()
+		newValue := afterValue.FieldByIndex(field.Index).Interface()
+		if reflect.DeepEqual(oldValue, newValue) {
+			continue
+		}
+		if field.Name == "Comments" {
+			commentRevisions, err := diffComments(before.Comments, after.Comments, currentTime)
+			if err != nil {
+				return nil, err
+			}
+			revisions = append(revisions, commentRevisions...)
+			continue
+		}
+		oldJSON, err := json.Marshal(oldValue)
+		if err != nil {
+			return nil, err
+		}
+		newJSON, err := json.Marshal(newValue)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, &Revision{Field: fieldName(field), OldValue: string(oldJSON), NewValue: string(newJSON), At: currentTime})
+	}
+	return revisions, nil
+}
+
+// diffComments returns a revision for each of the added and removed comments.
+func diffComments(before Comments, after Comments, at int64) (Revisions, error) {
+	var revisions Revisions
+	ids := func(comments Comments) map[string]bool {
+		result := make(map[string]bool)
+		for _, comment := range comments {
+			result[comment.Id] = true
+		}
+		return result
+	}
+	beforeIds, afterIds := ids(before), ids(after)
+	for _, comment := range before {
+		if !afterIds[comment.Id] {
+			value, err := json.Marshal(comment)
+			if err != nil {
+				return nil, err
+			}
+			revisions = append(revisions, &Revision{Field: "comments", OldValue: string(value), NewValue: "null", At: at})
+		}
+	}
+	for _, comment := range after {
+		if !beforeIds[comment.Id] {
+			value, err := json.Marshal(comment)
+			if err != nil {
+				return nil, err
+			}
+			revisions = append(revisions, &Revision{Field: "comments", OldValue: "null", NewValue: string(value), At: at})
+		}
+	}
+	return revisions, nil
+}
+
+// inverse returns the revision which reverts the revision.
+func (revision *Revision) inverse(at int64) *Revision {
+	return &Revision{Field: revision.Field, OldValue: revision.NewValue, NewValue: revision.OldValue, At: at}
+}
+
+// applyRevision sets the field of the note to the new value of the revision.
+func (note *Note) applyRevision(revision *Revision) error {
+	if revision.Field == "comments" {
+		var oldComment, newComment *Comment
+		if err := json.Unmarshal([]byte(revision.OldValue), &oldComment); err != nil {
+			return err
+		}
+		if err := json.Unmarshal([]byte(revision.NewValue), &newComment); err != nil {
+			return err
+		}
+		if oldComment != nil {
+			var comments Comments
+			for _, comment := range note.Comments {
+				if comment.Id != oldComment.Id {
+					comments = append(comments, comment)
+				}
+			}
+			note.Comments = comments
+		}
+		if newComment != nil {
+			note.Comments = append(note.Comments, newComment)
+		}
+		return nil
+	}
+	noteValue := reflect.ValueOf(note).Elem()
+	for _, field := range trackedNoteFields() {
+		if fieldName(field) != revision.Field {
+			continue
+		}
+		fieldValue := noteValue.FieldByIndex(field.Index)
+		newValue := reflect.New(field.Type)
+		if err := json.Unmarshal([]byte(revision.NewValue), newValue.Interface()); err != nil {
+			return err
+		}
+		fieldValue.Set(newValue.Elem())
+		return nil
+	}
+	return fmt.Errorf("Unknown field %q of the note", revision.Field)
+}
+
+// cloneNote returns a deep copy of the note.
+func cloneNote(note *Note) (*Note, error) {
+	byteValue, err := json.Marshal(note)
+	if err != nil {
+		return nil, err
+	}
+	var clone Note
+	err = json.Unmarshal(byteValue, &clone)
+	return &clone, err
+}
+
+// mergeRevisions returns union of the revisions of theirs and mine.
+// As history of a note is only appended to, the revisions beyond the base are the new ones.
+func mergeRevisions(base Revisions, theirs Revisions, mine Revisions) Revisions {
+	merged := append(Revisions{}, mine...)
+	if len(theirs) > len(base) {
+		merged = append(merged, theirs[len(base):]...)
+	}
+	// keep the revisions in chronological order
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].At < merged[j].At })
+	return merged
+}
+
+// HistoryText returns display text of history (that is, the revisions) of the note.
+func (note *Note) HistoryText(tagger Tagger) string {
+	if len(note.History) == 0 {
+		return "No changes recorded yet\n"
+	}
+	var sb strings.Builder
+	for _, str := range note.History.Strings(tagger) {
+		sb.WriteString(fmt.Sprintf("  |  %v\n", str))
+	}
+	return sb.String()
+}
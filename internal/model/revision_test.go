@@ -0,0 +1,102 @@
+package model_test
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	model "github.com/goyalmunish/reminder/internal/model"
+	utils "github.com/goyalmunish/reminder/pkg/utils"
+)
+
+func TestNoteHistory(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, true)
+	note, _ := reminderData.NewNoteRegistration([]int{0}, "original text")
+	utils.AssertEqual(t, len(note.History), 0)
+	// each mutation is recorded with old and new values
+	utils.AssertEqual(t, reminderData.UpdateNoteText(note, "updated text"), nil)
+	utils.AssertEqual(t, reminderData.UpdateNoteTags(note, []int{0, 1}), nil)
+	utils.AssertEqual(t, reminderData.AddNoteComment(note, "a comment"), nil)
+	utils.AssertEqual(t, reminderData.UpdateNoteStatus(note, model.NoteStatus_Done), nil)
+	utils.AssertEqual(t, len(note.History), 4)
+	utils.AssertEqual(t, *note.History[0], model.Revision{Field: "text", OldValue: `"original text"`, NewValue: `"updated text"`, At: note.History[0].At})
+	utils.AssertEqual(t, note.History[1].Field, "tag_ids")
+	utils.AssertEqual(t, note.History[2].Field, "comments")
+	utils.AssertEqual(t, note.History[3].Field, "status")
+	// a mutation which doesn't change anything isn't recorded
+	utils.AssertEqual(t, reminderData.UpdateNoteTags(note, []int{0, 1}), nil)
+	utils.AssertEqual(t, len(note.History), 4)
+	// the history is displayed in human readable form, and is persisted
+	historyText := note.HistoryText(reminderData)
+	utils.AssertEqual(t, strings.Contains(historyText, `text: "original text"`), true)
+	utils.AssertEqual(t, strings.Contains(historyText, "tag_ids: [current]"), true)
+	utils.AssertEqual(t, strings.Contains(historyText, `comments: - `), true)
+	reminderDataRe, _ := model.ReadDataFile(dataFilePath, true)
+	utils.AssertEqual(t, reminderDataRe.FindNoteById(note.Id).History, note.History)
+}
+
+func TestUndoRedoNoteChange(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, true)
+	note, _ := reminderData.NewNoteRegistration([]int{0}, "original text")
+	otherNote, _ := reminderData.NewNoteRegistration([]int{0}, "other note")
+	// nothing to undo or redo yet
+	utils.AssertEqual(t, reminderData.UndoNoteChange(note), model.ErrorNothingToUndo)
+	utils.AssertEqual(t, reminderData.RedoNoteChange(note), model.ErrorNothingToUndo)
+	_ = reminderData.UpdateNoteText(note, "updated text")
+	_ = reminderData.AddNoteComment(note, "a comment")
+	_ = reminderData.UpdateNoteText(otherNote, "updated other note")
+	// undo changes of the note (in reverse order), without affecting the other note
+	utils.AssertEqual(t, reminderData.UndoNoteChange(note), nil)
+	utils.AssertEqual(t, len(note.Comments), 0)
+	utils.AssertEqual(t, reminderData.UndoNoteChange(note), nil)
+	utils.AssertEqual(t, note.Text, "original text")
+	utils.AssertEqual(t, reminderData.UndoNoteChange(note), model.ErrorNothingToUndo)
+	utils.AssertEqual(t, otherNote.Text, "updated other note")
+	// the undo is persisted and recorded in history
+	reminderDataRe, _ := model.ReadDataFile(dataFilePath, true)
+	utils.AssertEqual(t, reminderDataRe.FindNoteById(note.Id).Text, "original text")
+	utils.AssertEqual(t, len(note.History), 4)
+	// redo the changes
+	utils.AssertEqual(t, reminderData.RedoNoteChange(note), nil)
+	utils.AssertEqual(t, note.Text, "updated text")
+	utils.AssertEqual(t, reminderData.RedoNoteChange(note), nil)
+	utils.AssertEqual(t, note.Comments[0].Text, "a comment")
+	utils.AssertEqual(t, reminderData.RedoNoteChange(note), model.ErrorNothingToUndo)
+	// a new change clears the redo stack
+	_ = reminderData.UndoNoteChange(note)
+	_ = reminderData.UpdateNoteSummary(note, "a summary")
+	utils.AssertEqual(t, reminderData.RedoNoteChange(note), model.ErrorNothingToUndo)
+	utils.AssertEqual(t, reminderData.UndoNoteChange(note), nil)
+	utils.AssertEqual(t, note.Summary, "")
+	// several updates made at once are undone at once
+	err := reminderData.EditNote(note, func() error {
+		if err := note.UpdateText("edited text"); err != nil {
+			return err
+		}
+		return note.UpdateSummary("edited summary")
+	})
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, reminderData.UndoNoteChange(note), nil)
+	utils.AssertEqual(t, note.Text, "updated text")
+	utils.AssertEqual(t, note.Summary, "")
+	// a failed edit leaves the note as it was
+	historyCount := len(note.History)
+	err = reminderData.EditNote(note, func() error {
+		if err := note.UpdateText("edited text"); err != nil {
+			return err
+		}
+		return note.UpdateText(" ")
+	})
+	utils.AssertEqual(t, err != nil, true)
+	utils.AssertEqual(t, note.Text, "updated text")
+	utils.AssertEqual(t, len(note.History), historyCount)
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"path"
+	"strings"
+
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+/*
+A Store represents the storage backend of the reminder data.
+
+The whole data is loaded at once, but (depending on the backend) a single note or tag
+can be persisted without rewriting rest of the data.
+*/
+type Store interface {
+	// Load loads the whole reminder data.
+	Load() (*ReminderData, error)
+	// Save persists the whole reminder data.
+	Save(rd *ReminderData) error
+	// SaveNote persists a (new or updated) note of the reminder data.
+	SaveNote(rd *ReminderData, note *Note) error
+	// SaveTag persists a (new or updated) tag of the reminder data.
+	SaveTag(rd *ReminderData, tag *Tag) error
+	// QueryNotes returns the persisted notes matching the query.
+	QueryNotes(query NoteQuery) (Notes, error)
+}
+
+/*
+A NoteQuery represents filters for querying notes.
+Zero value of a field means that the notes aren't filtered by it.
+*/
+type NoteQuery struct {
+	Status    NoteStatus // only the notes with the status
+	TagIds    []int      // only the notes having all of the tags
+	TagExpr   TagExpr    // only the notes matching the expression of tags (refer ParseTagExpr)
+	DueBefore int64      // only the notes with due date (CompleteBy) on or before the timestamp
+	OnlyMain  bool       // only the main notes
+}
+
+// SQLiteExtensions are extensions of data file which is stored in SQLite database (instead of JSON).
+var SQLiteExtensions = []string{".db", ".sqlite", ".sqlite3"}
+
+// NewStore returns the store for the data file; the backend is chosen from extension of the file.
+func NewStore(dataFilePath string) Store {
+	dataFilePath = utils.TryConvertTildaBasedPath(dataFilePath)
+	if IsSQLiteDataFile(dataFilePath) {
+		return &SQLiteStore{DataFile: dataFilePath}
+	}
+	return &JSONStore{DataFile: dataFilePath}
+}
+
+// IsSQLiteDataFile tells if the data file is stored in SQLite database.
+func IsSQLiteDataFile(dataFilePath string) bool {
+	return utils.IsMemberOfSlice(strings.ToLower(path.Ext(dataFilePath)), SQLiteExtensions)
+}
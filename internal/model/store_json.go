@@ -0,0 +1,157 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/goyalmunish/reminder/pkg/logger"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+/*
+A JSONStore represents the reminder data stored as a (pretty-printed) JSON file.
+
+The whole file is rewritten on every save, so saving a single note or tag is same as saving the whole data.
+*/
+type JSONStore struct {
+	DataFile string
+}
+
+// Load reads the data file.
+// If the data file is corrupt (for example, because of a crash while it was being written), the data is read
+// from its backup (kept by the last write) instead. The data file itself is left as it is, as Load may be called
+// without holding the lock; rather, the data is marked as migrated (refer Migrated), and the next save restores
+// the data file, setting the corrupt file aside.
+func (s *JSONStore) Load() (*ReminderData, error) {
+	// read byte data from file
+	byteValue, err := os.ReadFile(s.DataFile)
+	if err != nil {
+		return nil, err
+	}
+	// parse json data
+	reminderData, err := parseReminderData(byteValue)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Unable to parse the data file %q: %v", s.DataFile, err))
+		reminderData, err = readBackupFile(s.DataFile, err)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// the data file is where it is read from, whatever path it has recorded
+	reminderData.DataFile = s.DataFile
+	reminderData.migrate()
+	reminderData.store = s
+	reminderData.refreshBase()
+	return reminderData, nil
+}
+
+// Save writes the whole data to the data file of the store.
+// If the data file was updated by another session in the meantime, its changes are merged first;
+// if they cannot be merged, the data is saved to a _CONFLICT_ file instead, and ErrorConflictFile is returned.
+func (s *JSONStore) Save(rd *ReminderData) error {
+	// if UpdatedAt timestamp of currently loaded data is not same as timestamp persisted on datafile
+	// some other process would have updated the data file, in which case its changes are merged
+	persistedData, err := (&JSONStore{DataFile: s.DataFile}).Load()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	currentTimestamp := utils.CurrentUnixTimestamp()
+	if persistedData != nil {
+		logger.Info(fmt.Sprintf("In-memory timestamp: %v, Persisted timestamp: %v, Current Timestamp (being persisted): %v", rd.UpdatedAt, persistedData.UpdatedAt, currentTimestamp))
+		if err := rd.mergePersisted(persistedData); err != nil {
+			return rd.saveConflictFile(err)
+		}
+		if persistedData.readFromBackup {
+			if err := setAsideCorruptFile(s.DataFile); err != nil {
+				return err
+			}
+		}
+	}
+	// update UpdatedAt field
+	// note that UpdatedAt of a whole ReminderData object is different
+	// from corresponding field of each note
+	rd.UpdatedAt = currentTimestamp
+	// marshal the data
+	// Refer https://pkg.go.dev/encoding/json#MarshalIndent
+	// Note: String values encoded as JSON strings are coerced to valid
+	// UTF-8, replacing invalid bytes with Unicode replacement rune. So
+	// that the JSON will be safe to embed inside HTML <script> tags, the
+	// string is encoded using HTMLEscape.
+	// For example, a text such as `comment with < and "` will be written
+	// as `"comment with \u003c and \"` but it will read back same as the
+	// original string
+	byteValue, err := json.MarshalIndent(&rd, "", "    ")
+	if err != nil {
+		return err
+	}
+	// persist the byte data to file (keeping the previous version as backup)
+	err = utils.WriteFileAtomic(s.DataFile, byteValue, 0755, true)
+	if err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("Updated the data file %q at %v!", s.DataFile, rd.UpdatedAt))
+	rd.refreshBase()
+	return nil
+}
+
+// SaveNote writes the whole data to its data file.
+func (s *JSONStore) SaveNote(rd *ReminderData, note *Note) error {
+	return s.Save(rd)
+}
+
+// SaveTag writes the whole data to its data file.
+func (s *JSONStore) SaveTag(rd *ReminderData, tag *Tag) error {
+	return s.Save(rd)
+}
+
+// QueryNotes reads the data file, and filters its notes.
+func (s *JSONStore) QueryNotes(query NoteQuery) (Notes, error) {
+	reminderData, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+	return reminderData.Notes.Query(query), nil
+}
+
+// parseReminderData function parses the byte data of a data file.
+func parseReminderData(byteValue []byte) (*ReminderData, error) {
+	var reminderData ReminderData
+	if err := json.Unmarshal(byteValue, &reminderData); err != nil {
+		return nil, err
+	}
+	return &reminderData, nil
+}
+
+// readBackupFile function reads the data from backup of the corrupt data file.
+// It returns the original parseErr if there is no usable backup.
+func readBackupFile(filePath string, parseErr error) (*ReminderData, error) {
+	backupFilePath := filePath + utils.BackupFileSuffix
+	byteValue, err := os.ReadFile(backupFilePath)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Unable to read the backup file %q: %v", backupFilePath, err))
+		return nil, parseErr
+	}
+	reminderData, err := parseReminderData(byteValue)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Unable to parse the backup file %q: %v", backupFilePath, err))
+		return nil, parseErr
+	}
+	reminderData.readFromBackup = true
+	reminderData.migrated = true
+	logger.Warn(fmt.Sprintf("Read the data file %q from its backup; it is restored on next save.", filePath))
+	return reminderData, nil
+}
+
+// setAsideCorruptFile function moves the corrupt data file aside with a _CORRUPT_ suffix, so that it is kept
+// (for inspection) while the data file is restored, and the backup is not overwritten with it.
+func setAsideCorruptFile(filePath string) error {
+	corruptFilePath := fmt.Sprintf("%s_CORRUPT_%d", filePath, utils.CurrentUnixTimestamp())
+	if err := os.Rename(filePath, corruptFilePath); err != nil {
+		return err
+	}
+	logger.Warn(fmt.Sprintf("Restored the data file %q from its backup; the corrupt file is kept at %q.", filePath, corruptFilePath))
+	return nil
+}
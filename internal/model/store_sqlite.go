@@ -0,0 +1,504 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/goyalmunish/reminder/pkg/logger"
+	"github.com/goyalmunish/reminder/pkg/utils"
+	_ "modernc.org/sqlite" // registers the (pure go) "sqlite" driver
+)
+
+/*
+A SQLiteStore represents the reminder data stored in an embedded SQLite database.
+
+Each note and tag is stored in its own row (as JSON, along with indexed columns which are used for
+querying), so that a single note or tag is saved without rewriting rest of the data.
+*/
+type SQLiteStore struct {
+	DataFile string
+}
+
+// sqliteSchema is the schema of the database; it is applied whenever the database is opened.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS meta (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS tags (
+	id   INTEGER PRIMARY KEY,
+	slug TEXT NOT NULL,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS notes (
+	id          TEXT PRIMARY KEY,
+	status      TEXT NOT NULL,
+	complete_by INTEGER NOT NULL DEFAULT 0,
+	is_main     INTEGER NOT NULL DEFAULT 0,
+	data        TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS trash (
+	id   TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS note_tags (
+	note_id TEXT NOT NULL,
+	tag_id  INTEGER NOT NULL,
+	PRIMARY KEY (note_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS notes_status ON notes (status);
+CREATE INDEX IF NOT EXISTS notes_complete_by ON notes (complete_by);
+CREATE INDEX IF NOT EXISTS note_tags_tag_id ON note_tags (tag_id);
+`
+
+// sqliteHeaderKey is the key of meta table row which stores the sqliteHeader.
+const sqliteHeaderKey = "reminder"
+
+// sqliteHeader is the part of the reminder data which is stored in the meta table.
+type sqliteHeader struct {
+	User         *User      `json:"user"`
+	LastBackupAt int64      `json:"last_backup_at"`
+	NextTagId    int        `json:"next_tag_id,omitempty"`
+	TagQueries   TagQueries `json:"tag_queries,omitempty"`
+	BaseStruct
+}
+
+// newSQLiteHeader returns the header of the reminder data.
+func newSQLiteHeader(rd *ReminderData) sqliteHeader {
+	return sqliteHeader{User: rd.User, LastBackupAt: rd.LastBackupAt, NextTagId: rd.NextTagId, TagQueries: rd.TagQueries, BaseStruct: rd.BaseStruct}
+}
+
+// applyTo sets the header of the reminder data.
+func (header *sqliteHeader) applyTo(rd *ReminderData) {
+	rd.User = header.User
+	rd.LastBackupAt = header.LastBackupAt
+	rd.NextTagId = header.NextTagId
+	rd.TagQueries = header.TagQueries
+	rd.BaseStruct = header.BaseStruct
+}
+
+// Load reads the whole data from the database.
+func (s *SQLiteStore) Load() (*ReminderData, error) {
+	// don't create a blank database as side effect of reading it
+	if _, err := os.Stat(s.DataFile); err != nil {
+		return nil, err
+	}
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	reminderData := &ReminderData{
+		Notes:    Notes{},
+		Tags:     Tags{},
+		DataFile: s.DataFile,
+		store:    s,
+	}
+	// header
+	header, err := readHeader(db)
+	if err != nil {
+		return nil, err
+	}
+	if header != nil {
+		header.applyTo(reminderData)
+	}
+	// tags
+	rows, err := db.Query("SELECT data FROM tags ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tag Tag
+		if err := scanJSON(rows, &tag); err != nil {
+			return nil, err
+		}
+		reminderData.Tags = append(reminderData.Tags, &tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// notes
+	notes, err := queryNotes(db, NoteQuery{})
+	if err != nil {
+		return nil, err
+	}
+	reminderData.Notes = append(reminderData.Notes, notes...)
+	// trash
+	trashRows, err := db.Query("SELECT data FROM trash ORDER BY rowid")
+	if err != nil {
+		return nil, err
+	}
+	defer trashRows.Close()
+	for trashRows.Next() {
+		var note Note
+		if err := scanJSON(trashRows, &note); err != nil {
+			return nil, err
+		}
+		reminderData.Trash = append(reminderData.Trash, &note)
+	}
+	if err := trashRows.Err(); err != nil {
+		return nil, err
+	}
+	reminderData.migrate()
+	reminderData.refreshBase()
+	return reminderData, nil
+}
+
+// Save writes the whole data to the database.
+// Notes and tags which are no longer part of the data are deleted.
+// If the database was updated by another session in the meantime, its changes are merged first;
+// if they cannot be merged, the data is saved to a _CONFLICT_ (JSON) file instead, and ErrorConflictFile is returned.
+func (s *SQLiteStore) Save(rd *ReminderData) error {
+	persistedData, err := s.Load()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := rd.mergePersisted(persistedData); err != nil {
+		return rd.saveConflictFile(err)
+	}
+	rd.UpdatedAt = utils.CurrentUnixTimestamp()
+	err = s.update(func(tx *sql.Tx) error {
+		if err := saveHeader(tx, rd); err != nil {
+			return err
+		}
+		tagIDs := make(map[string]bool)
+		for _, tag := range rd.Tags {
+			tagIDs[fmt.Sprint(tag.Id)] = true
+			if err := saveTag(tx, tag); err != nil {
+				return err
+			}
+		}
+		if err := deleteRowsExcept(tx, "tags", tagIDs); err != nil {
+			return err
+		}
+		noteIDs := make(map[string]bool)
+		for _, note := range rd.Notes {
+			noteIDs[note.Id] = true
+			if err := saveNote(tx, note); err != nil {
+				return err
+			}
+		}
+		if err := deleteRowsExcept(tx, "notes", noteIDs); err != nil {
+			return err
+		}
+		trashedNoteIDs := make(map[string]bool)
+		for _, note := range rd.Trash {
+			trashedNoteIDs[note.Id] = true
+			if err := saveTrashedNote(tx, note); err != nil {
+				return err
+			}
+		}
+		return deleteRowsExcept(tx, "trash", trashedNoteIDs)
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("Updated the data file %q at %v!", rd.DataFile, rd.UpdatedAt))
+	rd.refreshBase()
+	return nil
+}
+
+// SaveNote writes (only) the note to the database.
+func (s *SQLiteStore) SaveNote(rd *ReminderData, note *Note) error {
+	return s.saveRow(rd, func(tx *sql.Tx) error {
+		return saveNote(tx, note)
+	}, func(base *ReminderData) error {
+		clone, err := cloneNote(note)
+		if err != nil {
+			return err
+		}
+		if index := slices.IndexFunc(base.Notes, func(baseNote *Note) bool { return baseNote.Id == note.Id }); index >= 0 {
+			base.Notes[index] = clone
+		} else {
+			base.Notes = append(base.Notes, clone)
+		}
+		return nil
+	})
+}
+
+// SaveTag writes (only) the tag to the database.
+func (s *SQLiteStore) SaveTag(rd *ReminderData, tag *Tag) error {
+	return s.saveRow(rd, func(tx *sql.Tx) error {
+		return saveTag(tx, tag)
+	}, func(base *ReminderData) error {
+		clone := *tag
+		if index := slices.IndexFunc(base.Tags, func(baseTag *Tag) bool { return baseTag.Id == tag.Id }); index >= 0 {
+			base.Tags[index] = &clone
+		} else {
+			base.Tags = append(base.Tags, &clone)
+		}
+		return nil
+	})
+}
+
+// saveRow writes the header along with the row(s) written by save, and then updates them in the base of the
+// data (refer refreshBase) with refresh, rather than cloning the whole data again.
+// If the database was updated by another session in the meantime, the whole data is saved instead
+// (refer Save), so that changes of both the sessions are merged.
+func (s *SQLiteStore) saveRow(rd *ReminderData, save func(tx *sql.Tx) error, refresh func(base *ReminderData) error) error {
+	loadedAt := rd.UpdatedAt
+	outdated := false
+	err := s.update(func(tx *sql.Tx) error {
+		header, err := readHeader(tx)
+		if err != nil {
+			return err
+		}
+		if header != nil && header.UpdatedAt != loadedAt {
+			outdated = true
+			return nil
+		}
+		rd.UpdatedAt = utils.CurrentUnixTimestamp()
+		if err := saveHeader(tx, rd); err != nil {
+			return err
+		}
+		return save(tx)
+	})
+	if err != nil {
+		rd.UpdatedAt = loadedAt
+		return err
+	}
+	if outdated {
+		return s.Save(rd)
+	}
+	refreshBaseRow(rd, refresh)
+	return nil
+}
+
+// refreshBaseRow updates the header, and the row(s) with refresh, in the base of the data (refer refreshBase).
+// The whole base is refreshed instead if there is no base yet, or if it couldn't be updated.
+func refreshBaseRow(rd *ReminderData, refresh func(base *ReminderData) error) {
+	if rd.base == nil {
+		rd.refreshBase()
+		return
+	}
+	// the header is cloned (through JSON) too, so that the base shares nothing with the data
+	value, err := json.Marshal(newSQLiteHeader(rd))
+	var header sqliteHeader
+	if err == nil {
+		err = json.Unmarshal(value, &header)
+	}
+	if err == nil {
+		header.applyTo(rd.base)
+		err = refresh(rd.base)
+	}
+	if err != nil {
+		utils.LogError(err)
+		rd.refreshBase()
+	}
+}
+
+// QueryNotes queries the notes using indexes of the database.
+func (s *SQLiteStore) QueryNotes(query NoteQuery) (Notes, error) {
+	db, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return queryNotes(db, query)
+}
+
+// open opens the database (creating it, if required), and makes sure its schema is in place.
+func (s *SQLiteStore) open() (*sql.DB, error) {
+	if err := os.MkdirAll(path.Dir(s.DataFile), 0751); err != nil {
+		return nil, err
+	}
+	// the path is escaped, as the data file name may contain characters (such as "?" or "#") special to a URI
+	dsn := url.URL{Scheme: "file", OmitHost: true, Path: s.DataFile, RawQuery: "_pragma=busy_timeout(5000)"}
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("Unable to prepare the database %q: %w", s.DataFile, err)
+	}
+	return db, nil
+}
+
+// update runs fn within a transaction.
+func (s *SQLiteStore) update(fn func(tx *sql.Tx) error) error {
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// readHeader reads the header of the data; it returns nil if the header isn't written yet.
+func readHeader(db interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}) (*sqliteHeader, error) {
+	var value string
+	err := db.QueryRow("SELECT value FROM meta WHERE key = ?", sqliteHeaderKey).Scan(&value)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var header sqliteHeader
+	if err := json.Unmarshal([]byte(value), &header); err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+// saveHeader writes the header of the data.
+func saveHeader(tx *sql.Tx, rd *ReminderData) error {
+	value, err := json.Marshal(newSQLiteHeader(rd))
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO meta (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value", sqliteHeaderKey, string(value))
+	return err
+}
+
+// saveTag inserts or updates the tag.
+func saveTag(tx *sql.Tx, tag *Tag) error {
+	data, err := json.Marshal(tag)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO tags (id, slug, data) VALUES (?, ?, ?) ON CONFLICT (id) DO UPDATE SET slug = excluded.slug, data = excluded.data",
+		tag.Id, tag.Slug, string(data))
+	return err
+}
+
+// saveNote inserts or updates the note (along with its tag associations).
+func saveNote(tx *sql.Tx, note *Note) error {
+	data, err := json.Marshal(note)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO notes (id, status, complete_by, is_main, data) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET status = excluded.status, complete_by = excluded.complete_by, is_main = excluded.is_main, data = excluded.data`,
+		note.Id, string(note.Status), note.CompleteBy, note.IsMain, string(data))
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM note_tags WHERE note_id = ?", note.Id); err != nil {
+		return err
+	}
+	for _, tagID := range note.TagIds {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO note_tags (note_id, tag_id) VALUES (?, ?)", note.Id, tagID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveTrashedNote inserts or updates the note in the trash.
+func saveTrashedNote(tx *sql.Tx, note *Note) error {
+	data, err := json.Marshal(note)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT INTO trash (id, data) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET data = excluded.data", note.Id, string(data))
+	return err
+}
+
+// deleteRowsExcept deletes rows of the table ("notes", "trash", or "tags") whose ids are not among given ids.
+func deleteRowsExcept(tx *sql.Tx, table string, ids map[string]bool) error {
+	rows, err := tx.Query(fmt.Sprintf("SELECT id FROM %s", table))
+	if err != nil {
+		return err
+	}
+	var staleIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		if !ids[id] {
+			staleIDs = append(staleIDs, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, id := range staleIDs {
+		if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = ?", table), id); err != nil {
+			return err
+		}
+		if table == "notes" {
+			if _, err := tx.Exec("DELETE FROM note_tags WHERE note_id = ?", id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// queryNotes queries the notes matching the query (in order of their insertion).
+func queryNotes(db *sql.DB, query NoteQuery) (Notes, error) {
+	var conditions []string
+	var args []interface{}
+	if query.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, string(query.Status))
+	}
+	if query.OnlyMain {
+		conditions = append(conditions, "is_main = 1")
+	}
+	if query.DueBefore > 0 {
+		conditions = append(conditions, "complete_by > 0 AND complete_by <= ?")
+		args = append(args, query.DueBefore)
+	}
+	for _, tagID := range query.TagIds {
+		conditions = append(conditions, "id IN (SELECT note_id FROM note_tags WHERE tag_id = ?)")
+		args = append(args, tagID)
+	}
+	if query.TagExpr != nil {
+		condition, exprArgs := query.TagExpr.sql()
+		conditions = append(conditions, condition)
+		args = append(args, exprArgs...)
+	}
+	statement := "SELECT data FROM notes"
+	if len(conditions) > 0 {
+		statement += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	statement += " ORDER BY rowid"
+	rows, err := db.Query(statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var notes Notes
+	for rows.Next() {
+		var note Note
+		if err := scanJSON(rows, &note); err != nil {
+			return nil, err
+		}
+		notes = append(notes, &note)
+	}
+	return notes, rows.Err()
+}
+
+// scanJSON scans the (only) JSON column of the row into value.
+func scanJSON(rows *sql.Rows, value interface{}) error {
+	var data string
+	if err := rows.Scan(&data); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(data), value)
+}
@@ -0,0 +1,93 @@
+package model_test
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	model "github.com/goyalmunish/reminder/internal/model"
+	utils "github.com/goyalmunish/reminder/pkg/utils"
+)
+
+func TestNewStore(t *testing.T) {
+	_, isJSON := model.NewStore("temp_test_dir/mydata.json").(*model.JSONStore)
+	utils.AssertEqual(t, isJSON, true)
+	for _, dataFilePath := range []string{"temp_test_dir/mydata.db", "temp_test_dir/mydata.sqlite", "temp_test_dir/mydata.SQLITE3"} {
+		_, isSQLite := model.NewStore(dataFilePath).(*model.SQLiteStore)
+		utils.AssertEqual(t, isSQLite, true)
+	}
+}
+
+func TestStores(t *testing.T) {
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll("temp_test_dir")
+	// the file name of a database may contain characters which are special to a URI
+	for _, dataFilePath := range []string{"temp_test_dir/mydata.json", "temp_test_dir/mydata.db", "temp_test_dir/my data#1?.db"} {
+		_ = model.MakeSureFileExists(dataFilePath, false)
+		reminderData, err := model.ReadDataFile(dataFilePath, true)
+		utils.AssertEqual(t, err, nil)
+		utils.AssertEqual(t, len(reminderData.Tags), 7)
+		// save notes and tags
+		tagID, err := reminderData.NewTagRegistration("work", "area")
+		utils.AssertEqual(t, err, nil)
+		note1, _ := reminderData.NewNoteRegistration([]int{tagID}, "note 1")
+		note2, _ := reminderData.NewNoteRegistration([]int{0, tagID}, "note 2")
+		_, _ = reminderData.NewNoteRegistration([]int{0}, "note 3")
+		utils.AssertEqual(t, reminderData.UpdateNoteCompleteBy(note1, "15-06-2030"), nil)
+		utils.AssertEqual(t, reminderData.AddNoteComment(note1, "a comment"), nil)
+		utils.AssertEqual(t, reminderData.ToggleNoteMainFlag(note2), nil)
+		utils.AssertEqual(t, reminderData.UpdateNoteStatus(note2, model.NoteStatus_Done), nil)
+		// load the saved data
+		loadedData, err := model.ReadDataFile(dataFilePath, true)
+		utils.AssertEqual(t, err, nil)
+		utils.AssertEqual(t, loadedData.UpdatedAt, reminderData.UpdatedAt)
+		utils.AssertEqual(t, loadedData.Tags, reminderData.Tags)
+		utils.AssertEqual(t, loadedData.Notes, reminderData.Notes)
+		// query the notes
+		queryTexts := func(query model.NoteQuery) []string {
+			notes, err := reminderData.QueryNotes(query)
+			utils.AssertEqual(t, err, nil)
+			texts := []string{}
+			for _, note := range notes {
+				texts = append(texts, note.Text)
+			}
+			return texts
+		}
+		utils.AssertEqual(t, queryTexts(model.NoteQuery{}), []string{"note 1", "note 2", "note 3"})
+		utils.AssertEqual(t, queryTexts(model.NoteQuery{Status: model.NoteStatus_Pending}), []string{"note 1", "note 3"})
+		utils.AssertEqual(t, queryTexts(model.NoteQuery{TagIds: []int{tagID}}), []string{"note 1", "note 2"})
+		utils.AssertEqual(t, queryTexts(model.NoteQuery{TagIds: []int{0, tagID}}), []string{"note 2"})
+		utils.AssertEqual(t, queryTexts(model.NoteQuery{DueBefore: note1.CompleteBy}), []string{"note 1"})
+		utils.AssertEqual(t, queryTexts(model.NoteQuery{DueBefore: note1.CompleteBy - 1}), []string{})
+		utils.AssertEqual(t, queryTexts(model.NoteQuery{OnlyMain: true}), []string{"note 2"})
+		// notes and tags removed from the data are removed from the store too
+		reminderData.Notes = reminderData.Notes[1:]
+		reminderData.Tags = reminderData.Tags[:7]
+		utils.AssertEqual(t, reminderData.UpdateDataFile(""), nil)
+		loadedData, _ = model.ReadDataFile(dataFilePath, true)
+		utils.AssertEqual(t, len(loadedData.Notes), 2)
+		utils.AssertEqual(t, len(loadedData.Tags), 7)
+		utils.AssertEqual(t, queryTexts(model.NoteQuery{TagIds: []int{tagID}}), []string{"note 2"})
+	}
+}
+
+func TestJSONStoreSavesToItsDataFile(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll("temp_test_dir")
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	// a data file which was copied (or moved) from elsewhere records another path
+	byteValue, _ := os.ReadFile(dataFilePath)
+	byteValue = bytes.Replace(byteValue, []byte(`"data_file": "temp_test_dir/mydata.json"`), []byte(`"data_file": "temp_test_dir/elsewhere.json"`), 1)
+	_ = os.WriteFile(dataFilePath, byteValue, 0644)
+	reminderData, err := model.ReadDataFile(dataFilePath, true)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, reminderData.DataFile, dataFilePath)
+	// the changes are saved to the data file it was read from
+	_, err = reminderData.NewNoteRegistration([]int{}, "a note")
+	utils.AssertEqual(t, err, nil)
+	_, err = os.Stat("temp_test_dir/elsewhere.json")
+	utils.AssertEqual(t, os.IsNotExist(err), true)
+	reminderDataRe, _ := model.ReadDataFile(dataFilePath, true)
+	utils.AssertEqual(t, len(reminderDataRe.Notes), 1)
+}
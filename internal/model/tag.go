@@ -2,21 +2,63 @@ package model
 
 import (
 	"fmt"
+	"strings"
+
+	"github.com/goyalmunish/reminder/pkg/utils"
 )
 
+// RepeatTagGroup is the group of the tags which make their notes recurring (refer ProtectedTagSlugs).
+const RepeatTagGroup = "repeat"
+
+// ProtectedTagSlugs are slugs of the tags which are looked up by their slugs (such as for recurrence of the notes),
+// and so these tags cannot be renamed, regrouped, merged or deleted.
+var ProtectedTagSlugs = []string{"repeat-annually", "repeat-monthly"}
+
+// TagLevelSeparator separates levels of a nested tag's slug (such as "work/projectx/backend"); a tag is a sub-tag
+// of the tags whose slugs are prefixes of its slug (such as "work" and "work/projectx").
+const TagLevelSeparator = "/"
+
 /*
 A Tag represents classification of a note.
 
 A note can have multiple tags, and a tag can be associated with multiple notes.
+Tags can be nested (refer TagLevelSeparator), in which case a tag includes notes of its sub-tags.
 */
 type Tag struct {
 	Id    int    `json:"id"`    // internal int-based id of the tag
 	Slug  string `json:"slug"`  // client-facing string-based id for tag
-	Group string `json:"group"` // a note can be part of only one tag within a group
+	Group string `json:"group"` // a note can be part of only one tag within a group (unless it is multi-choice; refer TagGroupRules)
 	BaseStruct
 }
 
+// Protected tells if the tag is one of the tags looked up by their slugs (refer ProtectedTagSlugs).
+func (t Tag) Protected() bool {
+	return utils.IsMemberOfSlice(t.Slug, ProtectedTagSlugs)
+}
+
 // String provides basic string representation of a tag.
 func (t Tag) String() string {
 	return fmt.Sprintf("%v#%v#%v", t.Group, t.Slug, t.Id)
 }
+
+// IsUnder tells if the tag is the one with given slug, or is a sub-tag of it.
+func (t Tag) IsUnder(slug string) bool {
+	return (t.Slug == slug) || strings.HasPrefix(t.Slug, slug+TagLevelSeparator)
+}
+
+// checkTagSlug returns an error if the slug cannot be used for a new (or renamed) tag.
+// The slug must not have empty levels, and it must be usable as a term of a tag query (refer ParseTagExpr).
+func checkTagSlug(slug string) error {
+	for _, level := range strings.Split(slug, TagLevelSeparator) {
+		if level == "" {
+			return fmt.Errorf("Tag's slug %q has an empty level", slug)
+		}
+	}
+	if strings.ContainsAny(slug, " \t\n()") {
+		return fmt.Errorf("Tag's slug %q cannot have spaces or parentheses", slug)
+	}
+	if isTagExprKeyword(slug) {
+		return fmt.Errorf("Tag's slug %q is reserved", slug)
+	}
+	return nil
+}
@@ -0,0 +1,303 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/goyalmunish/reminder/pkg/logger"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+// RenameTag changes slug of the tag. The sub-tags of the tag are moved along (such as "work/projectx" to
+// "job/projectx" for renaming "work" to "job"), and the saved tag queries are updated accordingly.
+func (rd *ReminderData) RenameTag(tag *Tag, slug string) error {
+	if tag.Protected() {
+		return fmt.Errorf("%w: %q", ErrorTagProtected, tag.Slug)
+	}
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	if slug == "" {
+		return errors.New("Tag's slug is empty")
+	}
+	if err := checkTagSlug(slug); err != nil {
+		return err
+	}
+	if existingTag := rd.TagFromSlug(slug); existingTag != nil {
+		if existingTag == tag {
+			return errors.New("Desired slug is same as existing one")
+		}
+		return fmt.Errorf("Tag %q already exists; merge the tags instead", slug)
+	}
+	// the tag along with its sub-tags, and their new slugs
+	oldSlug := tag.Slug
+	renamed := make(map[*Tag]string)
+	for _, subTag := range rd.Tags.FromIds(rd.Tags.IdsUnder(oldSlug)) {
+		newSlug := slug + strings.TrimPrefix(subTag.Slug, oldSlug)
+		if subTag.Protected() || utils.IsMemberOfSlice(newSlug, ProtectedTagSlugs) {
+			return fmt.Errorf("The slug %q is reserved", newSlug)
+		}
+		if existingTag := rd.TagFromSlug(newSlug); (existingTag != nil) && !existingTag.IsUnder(oldSlug) {
+			return fmt.Errorf("Tag %q already exists; merge the tags instead", newSlug)
+		}
+		renamed[subTag] = newSlug
+	}
+	for subTag, newSlug := range renamed {
+		logger.Info(fmt.Sprintf("Renamed the tag %q to %q.\n", subTag.Slug, newSlug))
+		subTag.Slug = newSlug
+		subTag.UpdatedAt = utils.CurrentUnixTimestamp()
+	}
+	if !rd.renameSlugInTagQueries(oldSlug, slug, true) && (len(renamed) == 1) {
+		return rd.storage().SaveTag(rd, tag)
+	}
+	return rd.UpdateDataFile(fmt.Sprintf("Renamed the tag %q (and its %d sub-tags) to %q.", oldSlug, len(renamed)-1, slug))
+}
+
+// RegroupTag changes group of the tag (an empty group takes the tag out of its group).
+// Nothing is changed if any of the notes (including the ones in the trash) with the tag would break rule of the
+// new group, such as by having another tag of a single-choice group.
+func (rd *ReminderData) RegroupTag(tag *Tag, group string) error {
+	if tag.Protected() {
+		return fmt.Errorf("%w: %q", ErrorTagProtected, tag.Slug)
+	}
+	group = strings.ToLower(strings.TrimSpace(group))
+	if group == RepeatTagGroup {
+		// notes with a tag of the group are left out of the views unless they are recurring
+		return fmt.Errorf("The group %q is reserved", group)
+	}
+	if group == tag.Group {
+		return errors.New("Desired group is same as existing one")
+	}
+	// the tags as they would be after the change
+	regroupedTag := *tag
+	regroupedTag.Group = group
+	regrouped := make(Tags, 0, len(rd.Tags))
+	for _, other := range rd.Tags {
+		if other == tag {
+			other = &regroupedTag
+		}
+		regrouped = append(regrouped, other)
+	}
+	for _, notes := range []Notes{rd.Notes, rd.Trash} {
+		for _, note := range notes.WithTagId(tag.Id) {
+			if slugs, ok := regrouped.GroupViolations(note.TagIds, rd.TagGroupRules())[group]; ok {
+				violation := violationTexts(map[string][]string{group: slugs})[0]
+				return fmt.Errorf("%w: %s (for the note %q)", ErrorTagGroupRule, violation, note.Text)
+			}
+		}
+	}
+	logger.Info(fmt.Sprintf("Moved the tag %q from group %q to %q.\n", tag.Slug, tag.Group, group))
+	tag.Group = group
+	tag.UpdatedAt = utils.CurrentUnixTimestamp()
+	return rd.storage().SaveTag(rd, tag)
+}
+
+// MergeTags merges the source tag into the target tag: the notes (including the ones in the trash and the archived
+// ones) with the source tag get the target tag instead, and then the source tag is deleted. The sub-tags of the
+// source tag are moved under the target tag (such as "work/projectx" to "job/projectx" for merging "work" into
+// "job"), and the saved tag queries are updated accordingly. It returns number of updated notes.
+func (rd *ReminderData) MergeTags(source *Tag, target *Tag) (int, error) {
+	if source == target {
+		return 0, errors.New("Cannot merge a tag into itself")
+	}
+	for _, tag := range []*Tag{source, target} {
+		if tag.Protected() {
+			return 0, fmt.Errorf("%w: %q", ErrorTagProtected, tag.Slug)
+		}
+	}
+	if err := rd.checkTagExists(source, target); err != nil {
+		return 0, err
+	}
+	if target.IsUnder(source.Slug) {
+		return 0, errors.New("Cannot merge a tag into its own sub-tag")
+	}
+	// the sub-tags of the source tag, and their new slugs
+	moved := make(map[*Tag]string)
+	for _, subTag := range rd.Tags.FromIds(rd.Tags.IdsUnder(source.Slug)) {
+		if subTag == source {
+			continue
+		}
+		newSlug := target.Slug + strings.TrimPrefix(subTag.Slug, source.Slug)
+		if subTag.Protected() || utils.IsMemberOfSlice(newSlug, ProtectedTagSlugs) {
+			return 0, fmt.Errorf("The slug %q is reserved", newSlug)
+		}
+		if rd.TagFromSlug(newSlug) != nil {
+			return 0, fmt.Errorf("Tag %q already exists; merge the tag %q into it first", newSlug, subTag.Slug)
+		}
+		moved[subTag] = newSlug
+	}
+	count, err := rd.replaceNoteTagId(source.Id, target.Id)
+	if err != nil {
+		return 0, err
+	}
+	for subTag, newSlug := range moved {
+		logger.Info(fmt.Sprintf("Renamed the tag %q to %q.\n", subTag.Slug, newSlug))
+		subTag.Slug = newSlug
+		subTag.UpdatedAt = utils.CurrentUnixTimestamp()
+	}
+	rd.removeTag(source)
+	rd.renameSlugInTagQueries(source.Slug, target.Slug, true)
+	return count, rd.UpdateDataFile(fmt.Sprintf("Merged the tag %q (and its %d sub-tags) into %q (updated %d notes).", source.Slug, len(moved), target.Slug, count))
+}
+
+// DeleteTag deletes the tag. The notes (including the ones in the trash and the archived ones) with the tag get
+// the reassignTo tag instead, or else (for nil reassignTo) are just detached from the tag. It returns number of
+// updated notes. The sub-tags of the tag are moved under the reassignTo tag (refer MergeTags), or else are kept.
+func (rd *ReminderData) DeleteTag(tag *Tag, reassignTo *Tag) (int, error) {
+	if reassignTo != nil {
+		return rd.MergeTags(tag, reassignTo)
+	}
+	if tag.Protected() {
+		return 0, fmt.Errorf("%w: %q", ErrorTagProtected, tag.Slug)
+	}
+	if err := rd.checkTagExists(tag); err != nil {
+		return 0, err
+	}
+	count, err := rd.replaceNoteTagId(tag.Id, -1)
+	if err != nil {
+		return 0, err
+	}
+	rd.removeTag(tag)
+	return count, rd.UpdateDataFile(fmt.Sprintf("Deleted the tag %q (detached %d notes).", tag.Slug, count))
+}
+
+// removeTag removes the tag from the tags, making sure that its id isn't reused for a new tag.
+func (rd *ReminderData) removeTag(tag *Tag) {
+	rd.NextTagId = rd.nextPossibleTagId()
+	rd.Tags = rd.Tags.WithoutId(tag.Id)
+}
+
+// checkTagExists returns an error if any of the tags is not among the tags.
+func (rd *ReminderData) checkTagExists(tags ...*Tag) error {
+	for _, tag := range tags {
+		if rd.TagFromSlug(tag.Slug) != tag {
+			return fmt.Errorf("The tag %q is not among the tags", tag.Slug)
+		}
+	}
+	return nil
+}
+
+// replaceNoteTagId replaces the old tag id with the new one (or just removes it, for a negative new id) in the
+// notes, in the trash and in the archived notes. Nothing is changed if any of the notes (other than the archived
+// ones) would break rules of the tag groups. It returns number of updated notes.
+func (rd *ReminderData) replaceNoteTagId(oldTagId int, newTagId int) (int, error) {
+	replaced := make(map[*Note][]int)
+	for _, notes := range []Notes{rd.Notes, rd.Trash} {
+		for _, note := range notes {
+			if !utils.IsMemberOfSlice(oldTagId, note.TagIds) {
+				continue
+			}
+			tagIDs := replacedTagIds(note.TagIds, oldTagId, newTagId)
+			if err := rd.CheckTagGroups(tagIDs); err != nil {
+				return 0, fmt.Errorf("%w (for the note %q)", err, note.Text)
+			}
+			replaced[note] = tagIDs
+		}
+	}
+	// rewrite the archive files before changing the notes, so that no archived note is left with the old tag id
+	archivedCount, err := rd.replaceArchivedNoteTagId(oldTagId, newTagId)
+	if err != nil {
+		return 0, err
+	}
+	for note, tagIDs := range replaced {
+		if err := rd.setNoteTagIds(note, tagIDs); err != nil {
+			return 0, err
+		}
+	}
+	return len(replaced) + archivedCount, nil
+}
+
+// replaceArchivedNoteTagId replaces the old tag id with the new one (or just removes it, for a negative new id) in
+// the archived notes, rewriting the archive files as needed. The change is recorded in history of the notes, but the
+// rules of the tag groups are not checked (as the archived notes are read-only). It returns number of updated notes.
+func (rd *ReminderData) replaceArchivedNoteTagId(oldTagId int, newTagId int) (int, error) {
+	filePaths, err := archiveFilePaths(rd.DataFile)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, filePath := range filePaths {
+		archive, err := ReadArchive(filePath)
+		if err != nil {
+			return 0, err
+		}
+		changed := false
+		for _, note := range archive.Notes {
+			if !utils.IsMemberOfSlice(oldTagId, note.TagIds) {
+				continue
+			}
+			// the note keeps its UpdatedAt, as it tells the year of its archive file
+			before, err := cloneNote(note)
+			if err != nil {
+				return 0, err
+			}
+			note.TagIds = replacedTagIds(note.TagIds, oldTagId, newTagId)
+			revisions, err := diffNote(before, note)
+			if err != nil {
+				return 0, err
+			}
+			note.History = append(note.History, revisions...)
+			changed = true
+			count++
+		}
+		if !changed {
+			continue
+		}
+		if err := archive.write(filePath); err != nil {
+			return 0, err
+		}
+		rd.archived = nil
+	}
+	return count, nil
+}
+
+// replacedTagIds returns the tag ids with the old tag id replaced by the new one (or just removed, for a negative
+// new id), without any duplicates.
+func replacedTagIds(tagIDs []int, oldTagId int, newTagId int) []int {
+	replaced := make([]int, 0, len(tagIDs))
+	for _, tagID := range tagIDs {
+		if tagID == oldTagId {
+			tagID = newTagId
+		}
+		if (tagID >= 0) && !utils.IsMemberOfSlice(tagID, replaced) {
+			replaced = append(replaced, tagID)
+		}
+	}
+	return replaced
+}
+
+// setNoteTagIds sets tags of the note (which may be in the trash) without validating them. The change is recorded
+// in history of the note, but is not undoable; rather, the changes of tags made earlier in the session cannot be
+// undone anymore either, as they may refer to a tag which is no longer there.
+func (rd *ReminderData) setNoteTagIds(note *Note, tagIDs []int) error {
+	before, err := cloneNote(note)
+	if err != nil {
+		return err
+	}
+	note.TagIds = tagIDs
+	note.UpdatedAt = utils.CurrentUnixTimestamp()
+	revisions, err := diffNote(before, note)
+	if err != nil {
+		return err
+	}
+	note.History = append(note.History, revisions...)
+	rd.undoStack = withoutTagChanges(rd.undoStack)
+	rd.redoStack = withoutTagChanges(rd.redoStack)
+	return nil
+}
+
+// withoutTagChanges returns the changes (of an undo or redo stack) which don't change tags of the notes.
+func withoutTagChanges(changes []noteChange) []noteChange {
+	var kept []noteChange
+	for _, change := range changes {
+		changesTags := false
+		for _, revision := range change.revisions {
+			if revision.Field == "tag_ids" {
+				changesTags = true
+			}
+		}
+		if !changesTags {
+			kept = append(kept, change)
+		}
+	}
+	return kept
+}
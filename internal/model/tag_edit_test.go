@@ -0,0 +1,154 @@
+package model_test
+
+import (
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/goyalmunish/reminder/internal/model"
+	utils "github.com/goyalmunish/reminder/pkg/utils"
+)
+
+func TestRenameAndRegroupTag(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, false)
+	tag := reminderData.TagFromSlug("tips")
+	// rename the tag
+	utils.AssertEqual(t, reminderData.RenameTag(tag, " Hints "), nil)
+	utils.AssertEqual(t, tag.Slug, "hints")
+	utils.AssertEqual(t, reminderData.RenameTag(tag, "hints") != nil, true)
+	utils.AssertEqual(t, reminderData.RenameTag(tag, "current") != nil, true)
+	utils.AssertEqual(t, reminderData.RenameTag(tag, "repeat-monthly") != nil, true)
+	// regroup the tag
+	utils.AssertEqual(t, reminderData.RegroupTag(tag, "Learning"), nil)
+	utils.AssertEqual(t, tag.Group, "learning")
+	utils.AssertEqual(t, reminderData.RegroupTag(tag, "repeat") != nil, true)
+	// a tag cannot be moved into a single-choice group if a note (even in the trash) has another tag of the group
+	urgent := reminderData.TagFromSlug("priority-urgent")
+	note, _ := reminderData.NewNoteRegistration([]int{tag.Id, urgent.Id}, "read the docs")
+	_ = reminderData.TrashNote(note)
+	err := reminderData.RegroupTag(tag, urgent.Group)
+	utils.AssertEqual(t, errors.Is(err, model.ErrorTagGroupRule), true)
+	utils.AssertEqual(t, strings.Contains(err.Error(), `(for the note "read the docs")`), true)
+	utils.AssertEqual(t, tag.Group, "learning")
+	reminderData.SetTagGroupRules(model.TagGroupRules{urgent.Group: model.TagGroupRule_Multi})
+	utils.AssertEqual(t, reminderData.RegroupTag(tag, urgent.Group), nil)
+	utils.AssertEqual(t, reminderData.RegroupTag(tag, "learning"), nil)
+	// the tags looked up by their slugs are protected
+	repeatTag := reminderData.TagFromSlug("repeat-annually")
+	utils.AssertEqual(t, repeatTag.Protected(), true)
+	utils.AssertEqual(t, errors.Is(reminderData.RenameTag(repeatTag, "yearly"), model.ErrorTagProtected), true)
+	utils.AssertEqual(t, errors.Is(reminderData.RegroupTag(repeatTag, "yearly"), model.ErrorTagProtected), true)
+	// the changes are saved
+	reminderDataRe, _ := model.ReadDataFile(dataFilePath, false)
+	utils.AssertEqual(t, reminderDataRe.TagFromSlug("hints").Group, "learning")
+	utils.AssertEqual(t, reminderDataRe.TagFromSlug("tips") == nil, true)
+}
+
+func TestMergeAndDeleteTags(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, false)
+	// a note can have more than one priority in this test
+	reminderData.SetTagGroupRules(model.TagGroupRules{"priority": model.TagGroupRule_Multi})
+	current := reminderData.TagFromSlug("current")
+	urgent := reminderData.TagFromSlug("priority-urgent")
+	medium := reminderData.TagFromSlug("priority-medium")
+	bills, _ := reminderData.NewNoteRegistration([]int{current.Id, urgent.Id, medium.Id}, "pay bills")
+	bank, _ := reminderData.NewNoteRegistration([]int{medium.Id}, "call bank")
+	learn, _ := reminderData.NewNoteRegistration([]int{medium.Id}, "learn go")
+	_ = reminderData.TrashNote(learn)
+	// an earlier change of the tags cannot be undone after the merge
+	_ = reminderData.UpdateNoteTags(bank, []int{medium.Id, current.Id})
+	// merge a tag into another one
+	_, err := reminderData.MergeTags(medium, medium)
+	utils.AssertEqual(t, err != nil, true)
+	_, err = reminderData.MergeTags(medium, reminderData.TagFromSlug("repeat-monthly"))
+	utils.AssertEqual(t, errors.Is(err, model.ErrorTagProtected), true)
+	count, err := reminderData.MergeTags(medium, urgent)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, count, 3)
+	utils.AssertEqual(t, reminderData.TagFromSlug("priority-medium") == nil, true)
+	utils.AssertEqual(t, bills.TagIds, []int{current.Id, urgent.Id})
+	utils.AssertEqual(t, bank.TagIds, []int{urgent.Id, current.Id})
+	utils.AssertEqual(t, learn.TagIds, []int{urgent.Id})
+	utils.AssertEqual(t, bills.History[len(bills.History)-1].Field, "tag_ids")
+	utils.AssertEqual(t, errors.Is(reminderData.UndoNoteChange(bank), model.ErrorNothingToUndo), true)
+	// delete a tag, detaching its notes
+	_, err = reminderData.DeleteTag(reminderData.TagFromSlug("repeat-annually"), nil)
+	utils.AssertEqual(t, errors.Is(err, model.ErrorTagProtected), true)
+	count, err = reminderData.DeleteTag(current, nil)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, count, 2)
+	utils.AssertEqual(t, bills.TagIds, []int{urgent.Id})
+	_, err = reminderData.DeleteTag(current, nil)
+	utils.AssertEqual(t, err != nil, true)
+	// delete a tag, reassigning its notes to another tag
+	tips := reminderData.TagFromSlug("tips")
+	count, err = reminderData.DeleteTag(urgent, tips)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, count, 3)
+	utils.AssertEqual(t, bank.TagIds, []int{tips.Id})
+	// the changes are saved, and ids of the deleted tags aren't reused
+	reminderDataRe, _ := model.ReadDataFile(dataFilePath, false)
+	utils.AssertEqual(t, reminderDataRe.SortedTagSlugs(), []string{"priority-low", "repeat-annually", "repeat-monthly", "tips"})
+	utils.AssertEqual(t, reminderDataRe.FindNoteById(bills.Id).TagIds, []int{tips.Id})
+	utils.AssertEqual(t, reminderDataRe.Trash[0].TagIds, []int{tips.Id})
+	tagID, err := reminderDataRe.NewTagRegistration("home", "area")
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, tagID, 7)
+}
+
+func TestMergeTagsWithSubTagsAndArchivedNotes(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	defer func(ct func() time.Time) { utils.CurrentTime = ct }(utils.CurrentTime)
+	currentTime := time.Date(2028, 12, 20, 9, 0, 0, 0, time.UTC)
+	utils.CurrentTime = func() time.Time { return currentTime }
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, false)
+	workID, _ := reminderData.NewTagRegistration("work", "area")
+	projectID, _ := reminderData.NewTagRegistration("work/projectx", "project")
+	jobID, _ := reminderData.NewTagRegistration("job", "area")
+	work, project, job := reminderData.TagFromSlug("work"), reminderData.TagFromSlug("work/projectx"), reminderData.TagFromSlug("job")
+	// an archived note with the tags
+	report, _ := reminderData.NewNoteRegistration([]int{workID, projectID}, "send report")
+	_ = reminderData.UpdateNoteStatus(report, model.NoteStatus_Done)
+	currentTime = time.Date(2030, 5, 10, 9, 0, 0, 0, time.UTC)
+	_, _ = reminderData.ArchiveNotes(365)
+	// a tag cannot be merged into its own sub-tag, or if the target has the same sub-tag already
+	_, err := reminderData.MergeTags(work, project)
+	utils.AssertEqual(t, err != nil, true)
+	_, _ = reminderData.NewTagRegistration("job/projectx", "project")
+	_, err = reminderData.MergeTags(work, job)
+	utils.AssertEqual(t, err != nil, true)
+	utils.AssertEqual(t, project.Slug, "work/projectx")
+	_, _ = reminderData.DeleteTag(reminderData.TagFromSlug("job/projectx"), nil)
+	// merge the tag along with its sub-tags
+	count, err := reminderData.MergeTags(work, job)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, count, 1)
+	utils.AssertEqual(t, project.Slug, "job/projectx")
+	archive, _ := model.ReadArchive(model.ArchiveFilePath(dataFilePath, 2028))
+	utils.AssertEqual(t, archive.Notes[0].TagIds, []int{jobID, projectID})
+	utils.AssertEqual(t, archive.Notes[0].History[len(archive.Notes[0].History)-1].Field, "tag_ids")
+	// delete a tag, detaching the archived notes too
+	count, err = reminderData.DeleteTag(project, nil)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, count, 1)
+	archive, _ = model.ReadArchive(model.ArchiveFilePath(dataFilePath, 2028))
+	utils.AssertEqual(t, archive.Notes[0].TagIds, []int{jobID})
+	// the changes are saved
+	reminderDataRe, _ := model.ReadDataFile(dataFilePath, false)
+	utils.AssertEqual(t, reminderDataRe.TagFromSlug("work") == nil, true)
+	utils.AssertEqual(t, reminderDataRe.TagFromSlug("job/projectx") == nil, true)
+}
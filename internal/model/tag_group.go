@@ -0,0 +1,110 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TagGroupRule tells how many tags of a group a note can have.
+type TagGroupRule string
+
+const (
+	TagGroupRule_Single TagGroupRule = "single" // a note can have at most one tag of the group
+	TagGroupRule_Multi  TagGroupRule = "multi"  // a note can have any number of tags of the group
+)
+
+/*
+A TagGroupRules represents the rules of the tag groups, by group name.
+
+A group which isn't listed is single-choice (refer Tag), except that tags without a group are not limited.
+*/
+type TagGroupRules map[string]TagGroupRule
+
+// DefaultTagGroupRules returns the rules used unless set otherwise (refer SetTagGroupRules):
+// an entry imported from a calendar can have any number of categories.
+func DefaultTagGroupRules() TagGroupRules {
+	return TagGroupRules{ImportedTagGroup: TagGroupRule_Multi}
+}
+
+// ParseTagGroupRules parses the rules (such as {"area": "multi"}) as given in the config file.
+func ParseTagGroupRules(rules map[string]string) (TagGroupRules, error) {
+	parsed := TagGroupRules{}
+	for group, rule := range rules {
+		switch TagGroupRule(strings.ToLower(strings.TrimSpace(rule))) {
+		case TagGroupRule_Single:
+			parsed[strings.ToLower(group)] = TagGroupRule_Single
+		case TagGroupRule_Multi:
+			parsed[strings.ToLower(group)] = TagGroupRule_Multi
+		default:
+			return nil, fmt.Errorf("Unknown rule %q for tag group %q; use single or multi", rule, group)
+		}
+	}
+	return parsed, nil
+}
+
+// Rule returns the rule of the group.
+func (rules TagGroupRules) Rule(group string) TagGroupRule {
+	if group == "" {
+		return TagGroupRule_Multi
+	}
+	if rule, ok := rules[group]; ok {
+		return rule
+	}
+	return TagGroupRule_Single
+}
+
+// GroupViolations returns, for each single-choice group having more than one of the tags, the slugs of those
+// tags (in order of tagIDs). It returns an empty map if the tags follow the rules.
+func (tags Tags) GroupViolations(tagIDs []int, rules TagGroupRules) map[string][]string {
+	slugsByGroup := make(map[string][]string)
+	for _, tag := range tags.FromIds(tagIDs) {
+		if rules.Rule(tag.Group) == TagGroupRule_Single {
+			slugsByGroup[tag.Group] = append(slugsByGroup[tag.Group], tag.Slug)
+		}
+	}
+	violations := make(map[string][]string)
+	for group, slugs := range slugsByGroup {
+		if len(slugs) > 1 {
+			violations[group] = slugs
+		}
+	}
+	return violations
+}
+
+// CheckGroupRules returns ErrorTagGroupRule (along with the details) if the tags don't follow the rules.
+func (tags Tags) CheckGroupRules(tagIDs []int, rules TagGroupRules) error {
+	violations := tags.GroupViolations(tagIDs, rules)
+	if len(violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrorTagGroupRule, strings.Join(violationTexts(violations), "; "))
+}
+
+// violationTexts returns human readable representation of the violations, in order of the groups.
+func violationTexts(violations map[string][]string) []string {
+	texts := make([]string, 0, len(violations))
+	for group, slugs := range violations {
+		texts = append(texts, fmt.Sprintf("only one of the tags %q can be used, as group %q is single-choice", slugs, group))
+	}
+	sort.Strings(texts)
+	return texts
+}
+
+// SetTagGroupRules sets the rules of the tag groups, which are validated whenever tags of a note are set.
+func (rd *ReminderData) SetTagGroupRules(rules TagGroupRules) {
+	rd.tagGroupRules = rules
+}
+
+// TagGroupRules returns the rules of the tag groups (refer SetTagGroupRules and DefaultTagGroupRules).
+func (rd *ReminderData) TagGroupRules() TagGroupRules {
+	if rd.tagGroupRules == nil {
+		return DefaultTagGroupRules()
+	}
+	return rd.tagGroupRules
+}
+
+// CheckTagGroups returns an error if the tags don't follow the rules of their groups.
+func (rd *ReminderData) CheckTagGroups(tagIDs []int) error {
+	return rd.Tags.CheckGroupRules(tagIDs, rd.TagGroupRules())
+}
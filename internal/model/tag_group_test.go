@@ -0,0 +1,56 @@
+package model_test
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	model "github.com/goyalmunish/reminder/internal/model"
+	utils "github.com/goyalmunish/reminder/pkg/utils"
+)
+
+func TestTagGroupRules(t *testing.T) {
+	rules, err := model.ParseTagGroupRules(map[string]string{"Area": "Multi", "priority": "single"})
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, rules, model.TagGroupRules{"area": model.TagGroupRule_Multi, "priority": model.TagGroupRule_Single})
+	_, err = model.ParseTagGroupRules(map[string]string{"area": "many"})
+	utils.AssertEqual(t, err != nil, true)
+	// groups which aren't listed are single-choice, except for tags without a group
+	utils.AssertEqual(t, rules.Rule("area"), model.TagGroupRule_Multi)
+	utils.AssertEqual(t, rules.Rule("repeat"), model.TagGroupRule_Single)
+	utils.AssertEqual(t, rules.Rule(""), model.TagGroupRule_Multi)
+	utils.AssertEqual(t, model.DefaultTagGroupRules().Rule(model.ImportedTagGroup), model.TagGroupRule_Multi)
+	// violations
+	tags := model.BasicTags()
+	utils.AssertEqual(t, tags.GroupViolations([]int{0, 1, 3, 4, 5}, rules), map[string][]string{
+		"priority": {"priority-urgent", "priority-low"},
+		"repeat":   {"repeat-annually", "repeat-monthly"},
+	})
+	utils.AssertEqual(t, tags.CheckGroupRules([]int{0, 1, 4}, rules), nil)
+	err = tags.CheckGroupRules([]int{1, 2}, rules)
+	utils.AssertEqual(t, err.Error(), `Tags don't follow the rules of their groups: only one of the tags ["priority-urgent" "priority-medium"] can be used, as group "priority" is single-choice`)
+}
+
+func TestNewNoteRegistrationWithTagGroups(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, false)
+	urgent := reminderData.TagFromSlug("priority-urgent").Id
+	low := reminderData.TagFromSlug("priority-low").Id
+	// a new note cannot have two tags of a single-choice group
+	_, err := reminderData.NewNoteRegistration([]int{urgent, low}, "pay bills")
+	utils.AssertEqual(t, errors.Is(err, model.ErrorTagGroupRule), true)
+	utils.AssertEqual(t, len(reminderData.Notes), 0)
+	// and neither can an existing one
+	note, err := reminderData.NewNoteRegistration([]int{urgent}, "pay bills")
+	utils.AssertEqual(t, err, nil)
+	err = reminderData.UpdateNoteTags(note, []int{low, urgent})
+	utils.AssertEqual(t, errors.Is(err, model.ErrorTagGroupRule), true)
+	utils.AssertEqual(t, note.TagIds, []int{urgent})
+	// unless the group is multi-choice
+	reminderData.SetTagGroupRules(model.TagGroupRules{"priority": model.TagGroupRule_Multi})
+	utils.AssertEqual(t, reminderData.UpdateNoteTags(note, []int{low, urgent}), nil)
+}
@@ -0,0 +1,375 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/goyalmunish/reminder/pkg/logger"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+/*
+A TagExpr represents a parsed boolean expression of tags, such as "current AND priority-urgent AND NOT waiting"
+(refer ParseTagExpr).
+*/
+type TagExpr interface {
+	// Matches tells if a note with given tagIDs matches the expression.
+	Matches(tagIDs []int) bool
+	// String provides canonical representation of the expression, which can be parsed back.
+	String() string
+	// sql returns condition (on the notes table) and its arguments, for querying the matching notes.
+	sql() (string, []interface{})
+	// terms returns the slugs of the expression.
+	terms() []*tagTerm
+}
+
+// tagTerm is a slug of a TagExpr; it matches the notes having the tag or any of its sub-tags.
+type tagTerm struct {
+	slug   string
+	tagIds []int
+}
+
+func (term *tagTerm) Matches(tagIDs []int) bool {
+	for _, tagID := range term.tagIds {
+		if utils.IsMemberOfSlice(tagID, tagIDs) {
+			return true
+		}
+	}
+	return false
+}
+
+func (term *tagTerm) String() string { return term.slug }
+
+func (term *tagTerm) sql() (string, []interface{}) {
+	if len(term.tagIds) == 0 {
+		return "0", nil
+	}
+	args := make([]interface{}, 0, len(term.tagIds))
+	for _, tagID := range term.tagIds {
+		args = append(args, tagID)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+	return fmt.Sprintf("id IN (SELECT note_id FROM note_tags WHERE tag_id IN (%s))", placeholders), args
+}
+
+func (term *tagTerm) terms() []*tagTerm { return []*tagTerm{term} }
+
+// tagNot is negation of a TagExpr.
+type tagNot struct {
+	operand TagExpr
+}
+
+func (not *tagNot) Matches(tagIDs []int) bool { return !not.operand.Matches(tagIDs) }
+
+func (not *tagNot) String() string {
+	if _, ok := not.operand.(*tagBinary); ok {
+		return "NOT (" + not.operand.String() + ")"
+	}
+	return "NOT " + not.operand.String()
+}
+
+func (not *tagNot) sql() (string, []interface{}) {
+	condition, args := not.operand.sql()
+	return "NOT (" + condition + ")", args
+}
+
+func (not *tagNot) terms() []*tagTerm { return not.operand.terms() }
+
+// tagBinary is conjunction (AND) or disjunction (OR) of TagExpr operands.
+type tagBinary struct {
+	operator string
+	operands []TagExpr
+}
+
+func (binary *tagBinary) Matches(tagIDs []int) bool {
+	for _, operand := range binary.operands {
+		if operand.Matches(tagIDs) != (binary.operator == "AND") {
+			return binary.operator != "AND"
+		}
+	}
+	return binary.operator == "AND"
+}
+
+func (binary *tagBinary) String() string {
+	texts := make([]string, 0, len(binary.operands))
+	for _, operand := range binary.operands {
+		text := operand.String()
+		// AND binds tighter than OR
+		if other, ok := operand.(*tagBinary); ok && (other.operator == "OR") && (binary.operator == "AND") {
+			text = "(" + text + ")"
+		}
+		texts = append(texts, text)
+	}
+	return strings.Join(texts, " "+binary.operator+" ")
+}
+
+func (binary *tagBinary) sql() (string, []interface{}) {
+	conditions := make([]string, 0, len(binary.operands))
+	var args []interface{}
+	for _, operand := range binary.operands {
+		condition, operandArgs := operand.sql()
+		conditions = append(conditions, condition)
+		args = append(args, operandArgs...)
+	}
+	return "(" + strings.Join(conditions, " "+binary.operator+" ") + ")", args
+}
+
+func (binary *tagBinary) terms() []*tagTerm {
+	var terms []*tagTerm
+	for _, operand := range binary.operands {
+		terms = append(terms, operand.terms()...)
+	}
+	return terms
+}
+
+// isTagExprKeyword tells if the word is an operator of a TagExpr.
+func isTagExprKeyword(word string) bool {
+	return utils.IsMemberOfSlice(strings.ToUpper(word), []string{"AND", "OR", "NOT"})
+}
+
+// ParseTagExpr parses the boolean expression of tag slugs, such as "current AND priority-urgent AND NOT waiting".
+// The operators are NOT, AND and OR (in order of precedence, and case-insensitive), and parentheses can be used for
+// grouping. A slug matches the notes having the tag or any of its sub-tags; it is an error if there is no such tag.
+func ParseTagExpr(text string, tags Tags) (TagExpr, error) {
+	expr, err := parseTagExpr(text)
+	if err != nil {
+		return nil, err
+	}
+	for _, term := range expr.terms() {
+		term.tagIds = tags.IdsUnder(term.slug)
+		if len(term.tagIds) == 0 {
+			return nil, fmt.Errorf("Tag %q not found", term.slug)
+		}
+	}
+	return expr, nil
+}
+
+// TagSlugExpr returns the expression matching the notes having the tag with given slug or any of its sub-tags.
+// Unlike ParseTagExpr, the slug is taken as it is (and so it may have spaces, for example).
+func TagSlugExpr(slug string, tags Tags) TagExpr {
+	return &tagTerm{slug: slug, tagIds: tags.IdsUnder(slug)}
+}
+
+// parseTagExpr parses the expression, without resolving its slugs.
+func parseTagExpr(text string) (TagExpr, error) {
+	parser := &tagExprParser{tokens: strings.Fields(strings.NewReplacer("(", " ( ", ")", " ) ").Replace(text))}
+	if len(parser.tokens) == 0 {
+		return nil, errors.New("The tag query is empty")
+	}
+	expr, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if token, ok := parser.peek(); ok {
+		return nil, fmt.Errorf("Unexpected %q in the tag query %q", token, text)
+	}
+	return expr, nil
+}
+
+// tagExprParser is a recursive descent parser of TagExpr.
+type tagExprParser struct {
+	tokens []string
+	next   int
+}
+
+// peek returns the next token (as it is) without consuming it.
+func (p *tagExprParser) peek() (string, bool) {
+	if p.next >= len(p.tokens) {
+		return "", false
+	}
+	return p.tokens[p.next], true
+}
+
+// accept consumes the next token if it is the operator (or parenthesis).
+func (p *tagExprParser) accept(operator string) bool {
+	if token, ok := p.peek(); ok && strings.ToUpper(token) == operator {
+		p.next++
+		return true
+	}
+	return false
+}
+
+// parseBinary parses operands (parsed with parseOperand) joined with the operator.
+func (p *tagExprParser) parseBinary(operator string, parseOperand func() (TagExpr, error)) (TagExpr, error) {
+	operand, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	operands := []TagExpr{operand}
+	for p.accept(operator) {
+		if operand, err = parseOperand(); err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+	}
+	if len(operands) == 1 {
+		return operand, nil
+	}
+	return &tagBinary{operator: operator, operands: operands}, nil
+}
+
+func (p *tagExprParser) parseOr() (TagExpr, error) {
+	return p.parseBinary("OR", p.parseAnd)
+}
+
+func (p *tagExprParser) parseAnd() (TagExpr, error) {
+	return p.parseBinary("AND", p.parseNot)
+}
+
+func (p *tagExprParser) parseNot() (TagExpr, error) {
+	if p.accept("NOT") {
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &tagNot{operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *tagExprParser) parsePrimary() (TagExpr, error) {
+	token, ok := p.peek()
+	switch {
+	case !ok:
+		return nil, errors.New("Unexpected end of the tag query")
+	case token == "(":
+		p.next++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, errors.New("Missing closing parenthesis in the tag query")
+		}
+		return expr, nil
+	case token == ")" || isTagExprKeyword(token):
+		return nil, fmt.Errorf("Unexpected %q in the tag query", token)
+	}
+	p.next++
+	return &tagTerm{slug: strings.ToLower(token)}, nil
+}
+
+/*
+A TagQuery represents a saved boolean expression of tags (refer ParseTagExpr).
+
+The saved queries are listed along with the tags, as virtual tags.
+*/
+type TagQuery struct {
+	Name  string `json:"name"`  // name of the query, by which it is listed
+	Query string `json:"query"` // the expression, in its canonical form
+	BaseStruct
+}
+
+// String provides basic string representation of a tag query.
+func (tq TagQuery) String() string {
+	return fmt.Sprintf("%v: %v", tq.Name, tq.Query)
+}
+
+// A TagQueries is a slice of TagQuery objects.
+type TagQueries []*TagQuery
+
+// FromName fetches the query with given name.
+// It returns nil if there is no such query.
+func (queries TagQueries) FromName(name string) *TagQuery {
+	for _, tagQuery := range queries {
+		if tagQuery.Name == name {
+			return tagQuery
+		}
+	}
+	return nil
+}
+
+// Names returns names of the queries.
+func (queries TagQueries) Names() []string {
+	names := make([]string, 0, len(queries))
+	for _, tagQuery := range queries {
+		names = append(names, tagQuery.Name)
+	}
+	return names
+}
+
+// SaveTagQuery saves the query of tags with given name, replacing any existing query with that name.
+func (rd *ReminderData) SaveTagQuery(name string, query string) (*TagQuery, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("Name of the tag query is empty")
+	}
+	expr, err := ParseTagExpr(query, rd.Tags)
+	if err != nil {
+		return nil, err
+	}
+	currentTime := utils.CurrentUnixTimestamp()
+	tagQuery := rd.TagQueries.FromName(name)
+	if tagQuery == nil {
+		tagQuery = &TagQuery{Name: name, BaseStruct: BaseStruct{CreatedAt: currentTime}}
+		rd.TagQueries = append(rd.TagQueries, tagQuery)
+	}
+	tagQuery.Query = expr.String()
+	tagQuery.UpdatedAt = currentTime
+	return tagQuery, rd.UpdateDataFile(fmt.Sprintf("Saved the tag query %v.", tagQuery))
+}
+
+// DeleteTagQuery deletes the query of tags with given name.
+func (rd *ReminderData) DeleteTagQuery(name string) error {
+	tagQuery := rd.TagQueries.FromName(name)
+	if tagQuery == nil {
+		return fmt.Errorf("Tag query %q not found", name)
+	}
+	var remaining TagQueries
+	for _, other := range rd.TagQueries {
+		if other != tagQuery {
+			remaining = append(remaining, other)
+		}
+	}
+	rd.TagQueries = remaining
+	return rd.UpdateDataFile(fmt.Sprintf("Deleted the tag query %v.", tagQuery))
+}
+
+// TagQueryExpr returns the parsed expression of the saved query. A slug of a tag which has been deleted
+// meanwhile isn't an error; it just matches no note.
+func (rd *ReminderData) TagQueryExpr(tagQuery *TagQuery) (TagExpr, error) {
+	expr, err := parseTagExpr(tagQuery.Query)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid tag query %q: %w", tagQuery.Name, err)
+	}
+	for _, term := range expr.terms() {
+		term.tagIds = rd.Tags.IdsUnder(term.slug)
+	}
+	return expr, nil
+}
+
+// FindNotesByTagQuery gets all notes matching the saved query and with given status.
+func (rd *ReminderData) FindNotesByTagQuery(tagQuery *TagQuery, status NoteStatus) (Notes, error) {
+	expr, err := rd.TagQueryExpr(tagQuery)
+	if err != nil {
+		return nil, err
+	}
+	return rd.Notes.Query(NoteQuery{Status: status, TagExpr: expr}), nil
+}
+
+// renameSlugInTagQueries replaces the old slug (and, with subTags, the slugs of its sub-tags) with the new one
+// in the saved queries. It tells if any of the queries was changed.
+func (rd *ReminderData) renameSlugInTagQueries(oldSlug string, newSlug string, subTags bool) bool {
+	changed := false
+	for _, tagQuery := range rd.TagQueries {
+		expr, err := parseTagExpr(tagQuery.Query)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Skipping invalid tag query %q: %v", tagQuery.Name, err))
+			continue
+		}
+		for _, term := range expr.terms() {
+			if term.slug == oldSlug {
+				term.slug = newSlug
+			} else if subTags && strings.HasPrefix(term.slug, oldSlug+TagLevelSeparator) {
+				term.slug = newSlug + strings.TrimPrefix(term.slug, oldSlug)
+			}
+		}
+		if query := expr.String(); query != tagQuery.Query {
+			tagQuery.Query = query
+			tagQuery.UpdatedAt = utils.CurrentUnixTimestamp()
+			changed = true
+		}
+	}
+	return changed
+}
@@ -0,0 +1,132 @@
+package model_test
+
+import (
+	"os"
+	"testing"
+
+	model "github.com/goyalmunish/reminder/internal/model"
+	utils "github.com/goyalmunish/reminder/pkg/utils"
+)
+
+func TestParseTagExpr(t *testing.T) {
+	tags := model.Tags{
+		&model.Tag{Id: 0, Slug: "current"},
+		&model.Tag{Id: 1, Slug: "waiting"},
+		&model.Tag{Id: 2, Slug: "work"},
+		&model.Tag{Id: 3, Slug: "work/projectx"},
+		&model.Tag{Id: 4, Slug: "work/projectx/backend"},
+		&model.Tag{Id: 5, Slug: "workshop"},
+	}
+	// the expressions are represented in their canonical form
+	expr, err := model.ParseTagExpr("current and (WORK or workshop) And not Waiting", tags)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, expr.String(), "current AND (work OR workshop) AND NOT waiting")
+	expr, _ = model.ParseTagExpr("((current)) or work and not (waiting or workshop)", tags)
+	utils.AssertEqual(t, expr.String(), "current OR work AND NOT (waiting OR workshop)")
+	// AND binds tighter than OR
+	utils.AssertEqual(t, expr.Matches([]int{0, 1}), true)
+	utils.AssertEqual(t, expr.Matches([]int{2, 5}), false)
+	utils.AssertEqual(t, expr.Matches([]int{2}), true)
+	// a tag includes its sub-tags
+	expr, _ = model.ParseTagExpr("work/projectx", tags)
+	utils.AssertEqual(t, expr.Matches([]int{4}), true)
+	utils.AssertEqual(t, expr.Matches([]int{2}), false)
+	expr, _ = model.ParseTagExpr("work", tags)
+	utils.AssertEqual(t, expr.Matches([]int{4}), true)
+	utils.AssertEqual(t, expr.Matches([]int{5}), false)
+	// invalid expressions
+	for _, text := range []string{"", "current AND", "current waiting", "(current", "current)", "NOT", "AND current", "unknown"} {
+		_, err = model.ParseTagExpr(text, tags)
+		utils.AssertEqual(t, err != nil, true)
+	}
+}
+
+func TestTagHierarchy(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll("temp_test_dir")
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, false)
+	// slugs of new tags are validated
+	for _, slug := range []string{"/work", "work/", "work//projectx", "my work", "and"} {
+		_, err := reminderData.NewTagRegistration(slug, "area")
+		utils.AssertEqual(t, err != nil, true)
+	}
+	workID, _ := reminderData.NewTagRegistration("work", "area")
+	projectID, _ := reminderData.NewTagRegistration("work/projectx", "project")
+	backendID, _ := reminderData.NewTagRegistration("work/projectx/backend", "component")
+	workshopID, _ := reminderData.NewTagRegistration("workshop", "event")
+	utils.AssertEqual(t, reminderData.Tags.WithSubTagIds(workID), []int{workID, projectID, backendID})
+	bills, _ := reminderData.NewNoteRegistration([]int{workID}, "pay bills")
+	api, _ := reminderData.NewNoteRegistration([]int{backendID}, "write api")
+	_, _ = reminderData.NewNoteRegistration([]int{workshopID}, "attend workshop")
+	// selecting a parent tag includes notes of its sub-tags
+	utils.AssertEqual(t, reminderData.FindNotesByTagId(workID, model.NoteStatus_Pending), model.Notes{bills, api})
+	utils.AssertEqual(t, reminderData.FindNotesByTagSlug("work/projectx", model.NoteStatus_Pending), model.Notes{api})
+	utils.AssertEqual(t, reminderData.FindNotesByTagId(backendID, model.NoteStatus_Done), model.Notes(nil))
+	// renaming a tag moves its sub-tags along
+	workshop := reminderData.TagFromSlug("workshop")
+	utils.AssertEqual(t, reminderData.RenameTag(reminderData.TagFromSlug("work"), "workshop") != nil, true)
+	utils.AssertEqual(t, reminderData.RenameTag(workshop, "work/workshop"), nil)
+	utils.AssertEqual(t, reminderData.RenameTag(reminderData.TagFromSlug("work"), "job"), nil)
+	reminderDataRe, _ := model.ReadDataFile(dataFilePath, false)
+	utils.AssertEqual(t, reminderDataRe.SortedTagSlugs()[:4], []string{"current", "job", "job/projectx", "job/projectx/backend"})
+	utils.AssertEqual(t, reminderDataRe.TagFromSlug("job/workshop").Id, workshopID)
+}
+
+func TestTagQueries(t *testing.T) {
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll("temp_test_dir")
+	for _, dataFilePath := range []string{"temp_test_dir/mydata.json", "temp_test_dir/mydata.db"} {
+		_ = model.MakeSureFileExists(dataFilePath, false)
+		reminderData, _ := model.ReadDataFile(dataFilePath, false)
+		current := reminderData.TagFromSlug("current")
+		urgent := reminderData.TagFromSlug("priority-urgent")
+		waitingID, _ := reminderData.NewTagRegistration("waiting", "state")
+		backendID, _ := reminderData.NewTagRegistration("work/backend", "area")
+		bills, _ := reminderData.NewNoteRegistration([]int{current.Id, urgent.Id}, "pay bills")
+		_, _ = reminderData.NewNoteRegistration([]int{current.Id, urgent.Id, waitingID}, "call bank")
+		api, _ := reminderData.NewNoteRegistration([]int{backendID}, "write api")
+		// save the queries
+		_, err := reminderData.SaveTagQuery("urgent", "current AND unknown")
+		utils.AssertEqual(t, err != nil, true)
+		_, err = reminderData.SaveTagQuery(" ", "current")
+		utils.AssertEqual(t, err != nil, true)
+		tagQuery, err := reminderData.SaveTagQuery("urgent", "current and priority-urgent and not waiting")
+		utils.AssertEqual(t, err, nil)
+		utils.AssertEqual(t, tagQuery.Query, "current AND priority-urgent AND NOT waiting")
+		_, _ = reminderData.SaveTagQuery("work", "work OR tips")
+		notes, err := reminderData.FindNotesByTagQuery(tagQuery, model.NoteStatus_Pending)
+		utils.AssertEqual(t, err, nil)
+		utils.AssertEqual(t, notes, model.Notes{bills})
+		// the queries are saved, and can be used for querying the store
+		reminderDataRe, _ := model.ReadDataFile(dataFilePath, false)
+		utils.AssertEqual(t, reminderDataRe.TagQueries.Names(), []string{"urgent", "work"})
+		expr, err := reminderDataRe.TagQueryExpr(reminderDataRe.TagQueries.FromName("work"))
+		utils.AssertEqual(t, err, nil)
+		notes, err = reminderDataRe.QueryNotes(model.NoteQuery{TagExpr: expr})
+		utils.AssertEqual(t, err, nil)
+		utils.AssertEqual(t, len(notes), 1)
+		utils.AssertEqual(t, notes[0].Id, api.Id)
+		expr, _ = model.ParseTagExpr("current AND NOT (waiting OR work)", reminderDataRe.Tags)
+		notes, _ = reminderDataRe.QueryNotes(model.NoteQuery{Status: model.NoteStatus_Pending, TagExpr: expr})
+		utils.AssertEqual(t, len(notes), 1)
+		utils.AssertEqual(t, notes[0].Id, bills.Id)
+		// the queries follow the renamed and merged tags
+		utils.AssertEqual(t, reminderData.RenameTag(reminderData.TagFromSlug("waiting"), "on-hold"), nil)
+		utils.AssertEqual(t, reminderData.RenameTag(reminderData.TagFromSlug("work/backend"), "job/backend"), nil)
+		_, err = reminderData.MergeTags(reminderData.TagFromSlug("tips"), reminderData.TagFromSlug("current"))
+		utils.AssertEqual(t, err, nil)
+		utils.AssertEqual(t, reminderData.TagQueries.FromName("urgent").Query, "current AND priority-urgent AND NOT on-hold")
+		utils.AssertEqual(t, reminderData.TagQueries.FromName("work").Query, "work OR current")
+		// a query referring to a tag which no longer exists just doesn't match the tag
+		notes, err = reminderData.FindNotesByTagQuery(reminderData.TagQueries.FromName("work"), model.NoteStatus_Pending)
+		utils.AssertEqual(t, err, nil)
+		utils.AssertEqual(t, len(notes), 2)
+		// delete a query
+		utils.AssertEqual(t, reminderData.DeleteTagQuery("urgent"), nil)
+		utils.AssertEqual(t, reminderData.DeleteTagQuery("urgent") != nil, true)
+		reminderDataRe, _ = model.ReadDataFile(dataFilePath, false)
+		utils.AssertEqual(t, reminderDataRe.TagQueries.Names(), []string{"work"})
+	}
+}
@@ -57,3 +57,39 @@ func (tags Tags) IdsForGroup(group string) []int {
 	}
 	return tagIDs
 }
+
+// WithoutId returns the tags other than the one with given id.
+func (tags Tags) WithoutId(tagID int) Tags {
+	var filteredTags Tags
+	for _, tag := range tags {
+		if tag.Id != tagID {
+			filteredTags = append(filteredTags, tag)
+		}
+	}
+	return filteredTags
+}
+
+// IdsUnder returns ids of the tag with given slug and of its sub-tags (refer Tag.IsUnder).
+// It returns empty []int if there is no such tag.
+func (tags Tags) IdsUnder(slug string) []int {
+	var tagIDs []int
+	for _, tag := range tags {
+		if tag.IsUnder(slug) {
+			tagIDs = append(tagIDs, tag.Id)
+		}
+	}
+	return tagIDs
+}
+
+// WithSubTagIds returns given tagID followed by ids of sub-tags of that tag.
+func (tags Tags) WithSubTagIds(tagID int) []int {
+	tagIDs := []int{tagID}
+	for _, tag := range tags.FromIds([]int{tagID}) {
+		for _, subTagID := range tags.IdsUnder(tag.Slug) {
+			if subTagID != tagID {
+				tagIDs = append(tagIDs, subTagID)
+			}
+		}
+	}
+	return tagIDs
+}
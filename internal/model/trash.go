@@ -0,0 +1,103 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/goyalmunish/reminder/pkg/logger"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+// TrashNote moves the note to the trash, from where it can be restored until it is purged.
+// A note in the trash doesn't show up anywhere else, and cannot be changed.
+func (rd *ReminderData) TrashNote(note *Note) error {
+	if rd.FindNoteById(note.Id) != note {
+		return fmt.Errorf("The note %q is not among the notes", note.Text)
+	}
+	if err := note.editable(); err != nil {
+		return err
+	}
+	currentTime := utils.CurrentUnixTimestamp()
+	note.TrashedAt = currentTime
+	note.UpdatedAt = currentTime
+	rd.Notes = rd.Notes.WithoutId(note.Id)
+	rd.Trash = append(rd.Trash, note)
+	return rd.UpdateDataFile(fmt.Sprintf("Moved the note %q to the trash.", note.Text))
+}
+
+// RestoreNote moves the note back from the trash.
+// The note is unlinked from its calendar event, which is deleted by a calendar sync while the note is in the trash;
+// the next sync then adds the event again (or links it again, if it wasn't deleted yet).
+func (rd *ReminderData) RestoreNote(note *Note) error {
+	if rd.Trash.WithId(note.Id) != note {
+		return fmt.Errorf("The note %q is not in the trash", note.Text)
+	}
+	note.TrashedAt = 0
+	note.CalendarLink = nil
+	note.UpdatedAt = utils.CurrentUnixTimestamp()
+	rd.Trash = rd.Trash.WithoutId(note.Id)
+	rd.Notes = append(rd.Notes, note)
+	return rd.UpdateDataFile(fmt.Sprintf("Restored the note %q from the trash.", note.Text))
+}
+
+// PurgeNote permanently deletes the note, which must be in the trash.
+func (rd *ReminderData) PurgeNote(note *Note) error {
+	if rd.Trash.WithId(note.Id) != note {
+		return fmt.Errorf("The note %q is not in the trash", note.Text)
+	}
+	rd.Trash = rd.Trash.WithoutId(note.Id)
+	return rd.UpdateDataFile(fmt.Sprintf("Deleted the note %q permanently.", note.Text))
+}
+
+// PurgeTrash permanently deletes the notes which were moved to the trash at least given number of days ago
+// (or all of them, for 0 days). It returns the purged notes.
+func (rd *ReminderData) PurgeTrash(days int) (Notes, error) {
+	if days < 0 {
+		return nil, errors.New("Number of days cannot be negative")
+	}
+	cutoff := utils.CurrentUnixTimestamp() - int64(days)*24*60*60
+	var purged, kept Notes
+	for _, note := range rd.Trash {
+		if note.TrashedAt <= cutoff {
+			purged = append(purged, note)
+		} else {
+			kept = append(kept, note)
+		}
+	}
+	if len(purged) == 0 {
+		return purged, nil
+	}
+	rd.Trash = kept
+	return purged, rd.UpdateDataFile(fmt.Sprintf("Purged %d notes from the trash.", len(purged)))
+}
+
+// AutoPurgeTrash purges the notes which have been in the trash for more than retentionDays
+// (refer PurgeTrash); the trash is kept as it is for non-positive retentionDays.
+func (rd *ReminderData) AutoPurgeTrash(retentionDays int) (Notes, error) {
+	if retentionDays <= 0 {
+		logger.Info(fmt.Sprintln("Skipping automatic purge of the trash."))
+		return nil, nil
+	}
+	return rd.PurgeTrash(retentionDays)
+}
+
+// dedupeTrash makes sure that a note is either among the notes or in the trash (such as after merging
+// changes of another session which trashed or restored the note), keeping the one updated last.
+func (rd *ReminderData) dedupeTrash() {
+	trashed := make(map[string]*Note)
+	for _, note := range rd.Trash {
+		trashed[note.Id] = note
+	}
+	notes := Notes{}
+	for _, note := range rd.Notes {
+		if trashedNote := trashed[note.Id]; trashedNote != nil {
+			if note.UpdatedAt > trashedNote.UpdatedAt {
+				rd.Trash = rd.Trash.WithoutId(note.Id)
+			} else {
+				continue
+			}
+		}
+		notes = append(notes, note)
+	}
+	rd.Notes = notes
+}
@@ -0,0 +1,111 @@
+package model_test
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	model "github.com/goyalmunish/reminder/internal/model"
+	utils "github.com/goyalmunish/reminder/pkg/utils"
+)
+
+func TestTrashNote(t *testing.T) {
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll("temp_test_dir")
+	defer func(ct func() time.Time) { utils.CurrentTime = ct }(utils.CurrentTime)
+	utils.CurrentTime = func() time.Time { return time.Date(2030, 5, 10, 9, 0, 0, 0, time.UTC) }
+	// the trash is kept by each of the storage backends
+	for _, dataFilePath := range []string{"temp_test_dir/mydata.json", "temp_test_dir/mydata.db"} {
+		testTrashNote(t, dataFilePath)
+	}
+}
+
+func testTrashNote(t *testing.T, dataFilePath string) {
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, false)
+	bills, _ := reminderData.NewNoteRegistration([]int{}, "pay bills")
+	bank, _ := reminderData.NewNoteRegistration([]int{}, "call bank")
+	// move a note to the trash
+	utils.AssertEqual(t, reminderData.TrashNote(bills), nil)
+	utils.AssertEqual(t, reminderData.Notes, model.Notes{bank})
+	utils.AssertEqual(t, reminderData.Trash, model.Notes{bills})
+	utils.AssertEqual(t, bills.TrashedAt, utils.CurrentUnixTimestamp())
+	// a note in the trash cannot be changed, or trashed again
+	err := reminderData.UpdateNoteText(bills, "pay all the bills")
+	utils.AssertEqual(t, errors.Is(err, model.ErrorNoteTrashed), true)
+	utils.AssertEqual(t, bills.Text, "pay bills")
+	utils.AssertEqual(t, reminderData.TrashNote(bills) != nil, true)
+	// the trash is saved
+	reminderDataRe, _ := model.ReadDataFile(dataFilePath, false)
+	utils.AssertEqual(t, len(reminderDataRe.Notes), 1)
+	utils.AssertEqual(t, len(reminderDataRe.Trash), 1)
+	utils.AssertEqual(t, reminderDataRe.Trash[0].TrashedAt, bills.TrashedAt)
+	// restore the note
+	utils.AssertEqual(t, reminderData.RestoreNote(bank) != nil, true)
+	utils.AssertEqual(t, reminderData.RestoreNote(bills), nil)
+	utils.AssertEqual(t, reminderData.Notes, model.Notes{bank, bills})
+	utils.AssertEqual(t, len(reminderData.Trash), 0)
+	utils.AssertEqual(t, bills.TrashedAt, int64(0))
+	utils.AssertEqual(t, reminderData.UpdateNoteText(bills, "pay all the bills"), nil)
+	// purge the note
+	utils.AssertEqual(t, reminderData.PurgeNote(bills) != nil, true)
+	_ = reminderData.TrashNote(bills)
+	utils.AssertEqual(t, reminderData.PurgeNote(bills), nil)
+	reminderDataRe, _ = model.ReadDataFile(dataFilePath, false)
+	utils.AssertEqual(t, len(reminderDataRe.Notes), 1)
+	utils.AssertEqual(t, len(reminderDataRe.Trash), 0)
+}
+
+func TestPurgeTrash(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	defer func(ct func() time.Time) { utils.CurrentTime = ct }(utils.CurrentTime)
+	currentTime := time.Date(2030, 5, 10, 9, 0, 0, 0, time.UTC)
+	utils.CurrentTime = func() time.Time { return currentTime }
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, false)
+	bills, _ := reminderData.NewNoteRegistration([]int{}, "pay bills")
+	bank, _ := reminderData.NewNoteRegistration([]int{}, "call bank")
+	_ = reminderData.TrashNote(bills)
+	currentTime = currentTime.AddDate(0, 0, 20)
+	_ = reminderData.TrashNote(bank)
+	currentTime = currentTime.AddDate(0, 0, 15)
+	// the trash is kept as it is for non-positive retention
+	purged, err := reminderData.AutoPurgeTrash(0)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, len(purged), 0)
+	_, err = reminderData.PurgeTrash(-1)
+	utils.AssertEqual(t, err != nil, true)
+	// only the notes trashed before the retention period are purged
+	purged, err = reminderData.AutoPurgeTrash(30)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, purged, model.Notes{bills})
+	utils.AssertEqual(t, reminderData.Trash, model.Notes{bank})
+	// all the notes are purged for 0 days
+	purged, _ = reminderData.PurgeTrash(0)
+	utils.AssertEqual(t, purged, model.Notes{bank})
+	reminderDataRe, _ := model.ReadDataFile(dataFilePath, false)
+	utils.AssertEqual(t, len(reminderDataRe.Trash), 0)
+	utils.AssertEqual(t, len(reminderDataRe.Notes), 0)
+}
+
+func TestMergeTrashedNote(t *testing.T) {
+	base, theirs, mine := mergeTestData(), mergeTestData(), mergeTestData()
+	// they move the note to the trash
+	theirs.Notes[0].TrashedAt = 200
+	theirs.Notes[0].UpdatedAt = 200
+	theirs.Trash = model.Notes{theirs.Notes[0]}
+	theirs.Notes = model.Notes{}
+	// current session adds a note
+	mine.Notes = append(mine.Notes, &model.Note{Id: "note-2", Text: "my note"})
+	err := model.Merge(base, theirs, mine, model.RejectConflicts)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, len(mine.Notes), 1)
+	utils.AssertEqual(t, mine.Notes[0].Id, "note-2")
+	utils.AssertEqual(t, len(mine.Trash), 1)
+	utils.AssertEqual(t, mine.Trash[0].Id, "note-1")
+	utils.AssertEqual(t, mine.Trash[0].TrashedAt, int64(200))
+}
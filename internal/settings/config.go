@@ -6,6 +6,8 @@ import (
 	"github.com/goyalmunish/reminder/internal/appinfo"
 	"github.com/goyalmunish/reminder/pkg/calendar"
 	"github.com/goyalmunish/reminder/pkg/logger"
+	"github.com/goyalmunish/reminder/pkg/mailer"
+	"github.com/goyalmunish/reminder/pkg/notify"
 	"github.com/goyalmunish/reminder/pkg/utils"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
@@ -18,6 +20,8 @@ type Settings struct {
 	AppInfo  *appinfo.Options
 	Log      *logger.Options
 	Calendar *calendar.Options
+	Notify   *notify.Options
+	Mail     *mailer.Options
 }
 
 func DefaultSettings() *Settings {
@@ -25,6 +29,8 @@ func DefaultSettings() *Settings {
 		AppInfo:  appinfo.DefaultOptions(),
 		Log:      logger.DefaultOptions(),
 		Calendar: calendar.DefaultOptions(),
+		Notify:   notify.DefaultOptions(),
+		Mail:     mailer.DefaultOptions(),
 	}
 }
 
@@ -0,0 +1,443 @@
+/*
+Package tui implements the full-screen (multi-pane) terminal UI of the app.
+
+The screen has a sidebar of views (such as "Approaching Due Date", and one for each tag), the list of notes of
+the selected view (with the same columns as Notes.ExternalTexts), details of the selected note, a live search
+field, and a status bar. The notes are updated in place with single-key bindings (refer Help).
+*/
+package tui
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/goyalmunish/reminder/internal/model"
+	"github.com/goyalmunish/reminder/pkg/logger"
+	"github.com/goyalmunish/reminder/pkg/utils"
+	"github.com/rivo/tview"
+)
+
+// Help describes the key bindings.
+const Help = "x: done | s: suspend | p: pending | c: comment | t: tags | d: due date | D: trash | r: restore | a: archived | /: search | tab: switch pane | q: quit"
+
+// promptPage is the name of the page of an input prompt.
+const promptPage = "prompt"
+
+// confirmPage is the name of the page of a confirmation dialog.
+const confirmPage = "confirm"
+
+/*
+A view represents a list of notes shown in the UI, such as the notes approaching their due date, or the
+pending notes of a tag.
+*/
+type view struct {
+	name      string
+	byDueDate bool // whether the notes are sorted by their (projected) due dates
+	notes     func(rd *model.ReminderData) model.Notes
+}
+
+/*
+An App represents the full-screen UI over the reminder data.
+*/
+type App struct {
+	rd      *model.ReminderData
+	app     *tview.Application
+	pages   *tview.Pages
+	sidebar *tview.List
+	table   *tview.Table
+	detail  *tview.TextView
+	search  *tview.InputField
+	status  *tview.TextView
+	views   []view
+	current int               // index of the current view
+	shown   model.Occurrences // occurrences of the notes listed in the table
+}
+
+// New returns the UI over the reminder data.
+func New(rd *model.ReminderData) *App {
+	a := &App{
+		rd:      rd,
+		app:     tview.NewApplication(),
+		sidebar: tview.NewList().ShowSecondaryText(false),
+		table:   tview.NewTable().SetSelectable(true, false).SetFixed(1, 0),
+		detail:  tview.NewTextView().SetWrap(true).SetWordWrap(true),
+		search:  tview.NewInputField().SetLabel("Search: "),
+		status:  tview.NewTextView(),
+		views:   views(rd),
+	}
+	a.sidebar.SetBorder(true).SetTitle(" Views ")
+	a.table.SetBorder(true)
+	a.detail.SetBorder(true).SetTitle(" Note ")
+	for _, v := range a.views {
+		a.sidebar.AddItem(v.name, "", 0, nil)
+	}
+	a.sidebar.SetChangedFunc(func(index int, _ string, _ string, _ rune) {
+		a.current = index
+		a.refresh()
+	})
+	a.sidebar.SetSelectedFunc(func(int, string, string, rune) {
+		a.app.SetFocus(a.table)
+	})
+	a.table.SetSelectionChangedFunc(func(int, int) {
+		a.showDetail()
+	})
+	a.search.SetChangedFunc(func(string) {
+		a.refresh()
+	})
+	a.search.SetDoneFunc(func(key tcell.Key) {
+		// Esc clears the search
+		if key == tcell.KeyEscape {
+			a.search.SetText("")
+			a.refresh()
+		}
+		a.app.SetFocus(a.table)
+	})
+	a.status.SetText(Help)
+
+	notesPane := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(a.search, 1, 0, false).
+		AddItem(a.table, 0, 3, true).
+		AddItem(a.detail, 0, 2, false)
+	body := tview.NewFlex().
+		AddItem(a.sidebar, 30, 0, false).
+		AddItem(notesPane, 0, 1, true)
+	root := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(body, 0, 1, true).
+		AddItem(a.status, 1, 0, false)
+	a.pages = tview.NewPages().AddPage("main", root, true, true)
+	a.app.SetRoot(a.pages, true).SetFocus(a.table)
+	a.app.SetInputCapture(a.handleKey)
+	a.refresh()
+	return a
+}
+
+// views returns the views of the sidebar: the fixed ones, followed by one for each tag, and one for each saved
+// tag query.
+func views(rd *model.ReminderData) []view {
+	result := []view{
+		{name: "Approaching Due Date", byDueDate: true, notes: func(rd *model.ReminderData) model.Notes {
+			return rd.NotesApprachingDueDate("default")
+		}},
+		{name: "Main Notes", notes: func(rd *model.ReminderData) model.Notes {
+			return rd.Notes.OnlyMain().WithStatus(model.NoteStatus_Pending)
+		}},
+		{name: "Look Ahead", byDueDate: true, notes: func(rd *model.ReminderData) model.Notes {
+			return rd.NotesApprachingDueDate("long")
+		}},
+		{name: "Pending Notes", notes: func(rd *model.ReminderData) model.Notes {
+			return rd.Notes.WithStatus(model.NoteStatus_Pending)
+		}},
+		{name: "Suspended Notes", notes: func(rd *model.ReminderData) model.Notes {
+			return rd.Notes.WithStatus(model.NoteStatus_Suspended)
+		}},
+		{name: "Done Notes", notes: func(rd *model.ReminderData) model.Notes {
+			return rd.AllNotes().WithStatus(model.NoteStatus_Done)
+		}},
+		{name: "All Notes", notes: func(rd *model.ReminderData) model.Notes {
+			return rd.AllNotes()
+		}},
+		{name: "Trash", notes: func(rd *model.ReminderData) model.Notes {
+			return rd.Trash
+		}},
+	}
+	for _, slug := range rd.SortedTagSlugs() {
+		slug := slug
+		result = append(result, view{name: "#" + slug, notes: func(rd *model.ReminderData) model.Notes {
+			return rd.FindNotesByTagSlug(slug, model.NoteStatus_Pending)
+		}})
+	}
+	for _, name := range rd.TagQueries.Names() {
+		name := name
+		result = append(result, view{name: "?" + name, notes: func(rd *model.ReminderData) model.Notes {
+			tagQuery := rd.TagQueries.FromName(name)
+			if tagQuery == nil {
+				return nil
+			}
+			// an invalid query (such as edited by hand in the data file) just lists no note
+			notes, _ := rd.FindNotesByTagQuery(tagQuery, model.NoteStatus_Pending)
+			return notes
+		}})
+	}
+	return result
+}
+
+// Run runs the UI until it is quit. The log lines are discarded meanwhile, as they would garble the screen.
+func (a *App) Run() error {
+	previous := logger.SetOutput(io.Discard)
+	defer logger.SetOutput(previous)
+	return a.app.Run()
+}
+
+// Application returns the underlying tview application (such as for running it on a simulation screen).
+func (a *App) Application() *tview.Application {
+	return a.app
+}
+
+// SelectedNote returns the note selected in the list, or nil if the list is empty.
+func (a *App) SelectedNote() *model.Note {
+	row, _ := a.table.GetSelection()
+	if row < 1 || row > len(a.shown) {
+		return nil
+	}
+	return a.shown[row-1].Note
+}
+
+// ListedNotes returns the notes listed (as per the current view and the search).
+func (a *App) ListedNotes() model.Notes {
+	return a.shown.Notes()
+}
+
+// StatusText returns the text of the status bar.
+func (a *App) StatusText() string {
+	return a.status.GetText(false)
+}
+
+// HandleKey handles the key as the event loop does: with the key bindings of the app, or else by the focused pane.
+func (a *App) HandleKey(event *tcell.EventKey) {
+	if event = a.handleKey(event); event == nil || !a.pages.HasFocus() {
+		return
+	}
+	a.pages.InputHandler()(event, func(p tview.Primitive) {
+		a.app.SetFocus(p)
+	})
+}
+
+// handleKey handles the key bindings of the app; the other keys are passed to the focused pane.
+func (a *App) handleKey(event *tcell.EventKey) *tcell.EventKey {
+	if front, _ := a.pages.GetFrontPage(); front == promptPage || front == confirmPage || a.search.HasFocus() {
+		return event
+	}
+	switch event.Key() {
+	case tcell.KeyTab, tcell.KeyBacktab:
+		if a.sidebar.HasFocus() {
+			a.app.SetFocus(a.table)
+		} else {
+			a.app.SetFocus(a.sidebar)
+		}
+		return nil
+	case tcell.KeyRune:
+	default:
+		return event
+	}
+	switch event.Rune() {
+	case 'q':
+		a.app.Stop()
+	case '/':
+		a.app.SetFocus(a.search)
+	case 'x':
+		a.updateStatus(model.NoteStatus_Done)
+	case 's':
+		a.updateStatus(model.NoteStatus_Suspended)
+	case 'p':
+		a.updateStatus(model.NoteStatus_Pending)
+	case 'c':
+		a.withNote(func(note *model.Note) {
+			a.prompt("Comment: ", "", func(text string) error {
+				return a.rd.AddNoteComment(note, text)
+			}, "Added the comment.")
+		})
+	case 't':
+		a.withNote(func(note *model.Note) {
+			a.prompt("Tags (slugs): ", strings.Join(a.rd.TagsFromIds(note.TagIds), " "), func(text string) error {
+				tagIDs, err := a.tagIds(text)
+				if err != nil {
+					return err
+				}
+				return a.rd.UpdateNoteTags(note, tagIDs)
+			}, "Updated the tags.")
+		})
+	case 'd':
+		a.withNote(func(note *model.Note) {
+			a.prompt("Due date (such as 12-05, next friday 14:30, or nil): ", "", func(text string) error {
+				if strings.TrimSpace(text) == "nil" {
+					return a.rd.UpdateNoteCompleteBy(note, text)
+				}
+				// show the resolved date (as the input can be a phrase like "next friday") before saving it
+				description, err := utils.DescribeDueDate(text)
+				if err != nil {
+					return err
+				}
+				a.confirm(fmt.Sprintf("Set the due date to %s?", description), func() error {
+					return a.rd.UpdateNoteCompleteBy(note, text)
+				}, "Updated the due date.")
+				return nil
+			}, "")
+		})
+	case 'D':
+		a.withNote(func(note *model.Note) {
+			err := a.rd.TrashNote(note)
+			a.report(err, fmt.Sprintf("Moved %q to the trash.", note.Text))
+			a.refresh()
+		})
+	case 'r':
+		a.withNote(func(note *model.Note) {
+			err := a.rd.RestoreNote(note)
+			a.report(err, fmt.Sprintf("Restored %q from the trash.", note.Text))
+			a.refresh()
+		})
+	case 'a':
+		a.rd.SetIncludeArchived(!a.rd.IncludesArchived())
+		if a.rd.IncludesArchived() {
+			a.report(nil, "Including the archived notes.")
+		} else {
+			a.report(nil, "Leaving out the archived notes.")
+		}
+		a.refresh()
+	default:
+		return event
+	}
+	return nil
+}
+
+// withNote runs the action on the selected note, if any.
+func (a *App) withNote(action func(note *model.Note)) {
+	note := a.SelectedNote()
+	if note == nil {
+		a.report(nil, "No note is selected.")
+		return
+	}
+	action(note)
+}
+
+// updateStatus updates status of the selected note.
+func (a *App) updateStatus(status model.NoteStatus) {
+	a.withNote(func(note *model.Note) {
+		err := a.rd.UpdateNoteStatus(note, status)
+		a.report(err, fmt.Sprintf("Marked %q as %s.", note.Text, status))
+		a.refresh()
+	})
+}
+
+// prompt asks for a text (starting with the initial one) in a dialog, and submits it on Enter; Esc cancels it.
+// The done message is shown once submitted, unless it is empty (such as when submit asks for confirmation).
+func (a *App) prompt(label string, initial string, submit func(text string) error, done string) {
+	input := tview.NewInputField().SetLabel(label).SetText(initial)
+	input.SetBorder(true)
+	input.SetDoneFunc(func(key tcell.Key) {
+		a.pages.RemovePage(promptPage)
+		a.app.SetFocus(a.table)
+		if key != tcell.KeyEnter {
+			a.report(nil, "Cancelled.")
+			return
+		}
+		if err := submit(input.GetText()); (err != nil) || (done != "") {
+			a.report(err, done)
+		}
+		a.refresh()
+	})
+	dialog := tview.NewFlex().
+		AddItem(nil, 0, 1, false).
+		AddItem(tview.NewFlex().SetDirection(tview.FlexRow).
+			AddItem(nil, 0, 1, false).
+			AddItem(input, 3, 0, true).
+			AddItem(nil, 0, 1, false), 0, 3, true).
+		AddItem(nil, 0, 1, false)
+	a.pages.AddPage(promptPage, dialog, true, true)
+	a.app.SetFocus(input)
+}
+
+// confirm asks the question in a dialog, and runs yes once it is confirmed; Esc (or "No") cancels it.
+func (a *App) confirm(question string, yes func() error, done string) {
+	modal := tview.NewModal().SetText(question).AddButtons([]string{"Yes", "No"})
+	modal.SetDoneFunc(func(buttonIndex int, buttonLabel string) {
+		a.pages.RemovePage(confirmPage)
+		a.app.SetFocus(a.table)
+		if buttonLabel != "Yes" {
+			a.report(nil, "Cancelled.")
+			return
+		}
+		a.report(yes(), done)
+		a.refresh()
+	})
+	a.pages.AddPage(confirmPage, modal, true, true)
+	a.app.SetFocus(modal)
+}
+
+// tagIds converts the tag slugs (separated by spaces or commas) to tag ids.
+func (a *App) tagIds(text string) ([]int, error) {
+	tagIDs := []int{}
+	for _, slug := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ' ' }) {
+		tag := a.rd.TagFromSlug(strings.ToLower(slug))
+		if tag == nil {
+			return nil, fmt.Errorf("No tag found with slug %q", slug)
+		}
+		if !utils.IsMemberOfSlice(tag.Id, tagIDs) {
+			tagIDs = append(tagIDs, tag.Id)
+		}
+	}
+	return tagIDs, nil
+}
+
+// report shows the error (if any), or else the message, in the status bar.
+func (a *App) report(err error, message string) {
+	if err != nil {
+		message = "Error: " + err.Error()
+	}
+	a.status.SetText(message + "  (" + Help + ")")
+}
+
+// refresh lists the notes of the current view matching the search, keeping the selected note selected.
+func (a *App) refresh() {
+	selected := a.SelectedNote()
+	current := a.views[a.current]
+	notes := current.notes(a.rd)
+	if query := strings.ToLower(strings.TrimSpace(a.search.GetText())); query != "" {
+		var matching model.Notes
+		for _, note := range notes {
+			text, err := note.SearchableText()
+			if err == nil && strings.Contains(strings.ToLower(text), query) {
+				matching = append(matching, note)
+			}
+		}
+		notes = matching
+	}
+	if !current.byDueDate {
+		// sort a copy, as the notes may be the ones of the data
+		notes = append(model.Notes(nil), notes...)
+		sort.Sort(notes)
+	}
+	a.shown = a.rd.NoteOccurrences(notes, "default", utils.CurrentUnixTimestamp())
+	if current.byDueDate {
+		sort.Stable(a.shown)
+	}
+
+	a.table.Clear()
+	a.table.SetTitle(fmt.Sprintf(" %s (%d) ", current.name, len(a.shown)))
+	for column, header := range []string{"Note", "R", "C", "S", "D"} {
+		a.table.SetCell(0, column, tview.NewTableCell(header).SetSelectable(false).SetAttributes(tcell.AttrBold))
+	}
+	repeatAnnuallyTagId, repeatMonthlyTagId := a.rd.RepeatTagIds()
+	selectedRow := 1
+	for index, occurrence := range a.shown {
+		columns := occurrence.Note.ListColumns(0, repeatAnnuallyTagId, repeatMonthlyTagId, occurrence.DueAt)
+		for column, text := range columns {
+			cell := tview.NewTableCell(tview.Escape(text))
+			if column == 0 {
+				cell.SetExpansion(1)
+			}
+			a.table.SetCell(index+1, column, cell)
+		}
+		if occurrence.Note == selected {
+			selectedRow = index + 1
+		}
+	}
+	a.table.Select(selectedRow, 0)
+	a.showDetail()
+}
+
+// showDetail shows details of the selected note.
+func (a *App) showDetail() {
+	note := a.SelectedNote()
+	if note == nil {
+		a.detail.SetText("")
+		return
+	}
+	text, err := note.ExternalText(a.rd)
+	if err != nil {
+		text = "Error: " + err.Error()
+	}
+	a.detail.SetText(text).ScrollToBeginning()
+}
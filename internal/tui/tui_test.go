@@ -0,0 +1,188 @@
+package tui_test
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/goyalmunish/reminder/internal/model"
+	"github.com/goyalmunish/reminder/internal/tui"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+// press delivers the key to the app.
+func press(app *tui.App, key tcell.Key, r rune) {
+	app.HandleKey(tcell.NewEventKey(key, r, tcell.ModNone))
+}
+
+// screenText returns the text drawn on the screen, line by line.
+func screenText(screen tcell.SimulationScreen) []string {
+	cells, width, height := screen.GetContents()
+	lines := make([]string, 0, height)
+	for y := 0; y < height; y++ {
+		var line strings.Builder
+		for x := 0; x < width; x++ {
+			line.WriteString(string(cells[y*width+x].Runes))
+		}
+		lines = append(lines, line.String())
+	}
+	return lines
+}
+
+// typeText types the text, followed by Enter.
+func typeText(app *tui.App, text string) {
+	for _, r := range text {
+		press(app, tcell.KeyRune, r)
+	}
+	press(app, tcell.KeyEnter, 0)
+}
+
+// noteTexts returns texts of the notes.
+func noteTexts(notes model.Notes) []string {
+	texts := []string{}
+	for _, note := range notes {
+		texts = append(texts, note.Text)
+	}
+	return texts
+}
+
+func TestApp(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	defer func(ct func() time.Time) { utils.CurrentTime = ct }(utils.CurrentTime)
+	utils.CurrentTime = func() time.Time { return time.Date(2030, 5, 10, 9, 0, 0, 0, time.UTC) }
+	utils.Location = utils.UTCLocation()
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, true)
+	current := reminderData.TagFromSlug("current").Id
+	bills, _ := reminderData.NewNoteRegistration([]int{current}, "pay bills")
+	_ = reminderData.UpdateNoteCompleteBy(bills, "12-05-2030")
+	bank, _ := reminderData.NewNoteRegistration([]int{current}, "call bank")
+	_ = reminderData.UpdateNoteCompleteBy(bank, "11-05-2030")
+	_, _ = reminderData.NewNoteRegistration([]int{current}, "learn go")
+	app := tui.New(reminderData)
+	screen := tcell.NewSimulationScreen("UTF-8")
+	screen.SetSize(120, 30)
+	app.Application().SetScreen(screen).ForceDraw()
+	text := strings.Join(strings.Fields(strings.Join(screenText(screen), " ")), " ")
+	utils.AssertEqual(t, strings.Contains(text, "Approaching Due Date"), true)
+	utils.AssertEqual(t, strings.Contains(text, "Note R C S D"), true)
+	utils.AssertEqual(t, strings.Contains(text, "call bank - 00 P 11-May-30"), true)
+	utils.AssertEqual(t, strings.Contains(text, "Text: call bank"), true)
+	// the notes approaching their due date are listed, by due date
+	utils.AssertEqual(t, noteTexts(app.ListedNotes()), []string{"call bank", "pay bills"})
+	utils.AssertEqual(t, app.SelectedNote(), bank)
+	press(app, tcell.KeyDown, 0)
+	utils.AssertEqual(t, app.SelectedNote(), bills)
+	// mark the note as done
+	press(app, tcell.KeyRune, 'x')
+	utils.AssertEqual(t, bills.Status, model.NoteStatus_Done)
+	utils.AssertEqual(t, strings.HasPrefix(app.StatusText(), `Marked "pay bills" as done.`), true)
+	utils.AssertEqual(t, noteTexts(app.ListedNotes()), []string{"call bank"})
+	// comment on the note
+	press(app, tcell.KeyRune, 'c')
+	typeText(app, "call after 10")
+	utils.AssertEqual(t, bank.Comments[0].Text, "call after 10")
+	// a failure is reported
+	press(app, tcell.KeyRune, 't')
+	typeText(app, " unknown")
+	utils.AssertEqual(t, strings.HasPrefix(app.StatusText(), `Error: No tag found with slug "unknown"`), true)
+	// retag the note (the current tags are filled in)
+	press(app, tcell.KeyRune, 't')
+	typeText(app, " priority-urgent")
+	utils.AssertEqual(t, reminderData.TagsFromIds(bank.TagIds), []string{"current", "priority-urgent"})
+	// a cancelled prompt changes nothing
+	press(app, tcell.KeyRune, 'd')
+	press(app, tcell.KeyRune, '1')
+	press(app, tcell.KeyEscape, 0)
+	utils.AssertEqual(t, bank.DueText(bank.CompleteBy), "11-May-30")
+	// the resolved due date is shown for confirmation, and can be declined
+	press(app, tcell.KeyRune, 'd')
+	typeText(app, "25-12-2030")
+	app.Application().ForceDraw()
+	text = strings.Join(strings.Fields(strings.Join(screenText(screen), " ")), " ")
+	utils.AssertEqual(t, strings.Contains(text, "Set the due date to"), true)
+	utils.AssertEqual(t, strings.Contains(text, "Wednesday, 25 Dec 2030?"), true)
+	utils.AssertEqual(t, bank.DueText(bank.CompleteBy), "11-May-30")
+	press(app, tcell.KeyRight, 0)
+	press(app, tcell.KeyEnter, 0)
+	utils.AssertEqual(t, strings.HasPrefix(app.StatusText(), "Cancelled."), true)
+	utils.AssertEqual(t, bank.DueText(bank.CompleteBy), "11-May-30")
+	// update the due date
+	press(app, tcell.KeyRune, 'd')
+	typeText(app, "25-12-2030")
+	press(app, tcell.KeyEnter, 0)
+	utils.AssertEqual(t, strings.HasPrefix(app.StatusText(), "Updated the due date."), true)
+	utils.AssertEqual(t, bank.DueText(bank.CompleteBy), "25-Dec-30")
+	utils.AssertEqual(t, len(app.ListedNotes()), 0)
+	// switch to another view in the sidebar
+	press(app, tcell.KeyTab, 0)
+	press(app, tcell.KeyDown, 0)
+	press(app, tcell.KeyDown, 0)
+	utils.AssertEqual(t, noteTexts(app.ListedNotes()), []string{"call bank"})
+	press(app, tcell.KeyDown, 0)
+	utils.AssertEqual(t, noteTexts(app.ListedNotes()), []string{"call bank", "learn go"})
+	// live search
+	press(app, tcell.KeyTab, 0)
+	press(app, tcell.KeyRune, '/')
+	press(app, tcell.KeyRune, 'l')
+	press(app, tcell.KeyRune, 'e')
+	utils.AssertEqual(t, noteTexts(app.ListedNotes()), []string{"learn go"})
+	press(app, tcell.KeyEscape, 0)
+	utils.AssertEqual(t, len(app.ListedNotes()), 2)
+	// suspend the note (which stays selected)
+	learn := app.SelectedNote()
+	utils.AssertEqual(t, learn.Text, "learn go")
+	press(app, tcell.KeyRune, 's')
+	utils.AssertEqual(t, learn.Status, model.NoteStatus_Suspended)
+	// the changes are saved
+	reminderDataRe, _ := model.ReadDataFile(dataFilePath, true)
+	utils.AssertEqual(t, reminderDataRe.FindNoteById(learn.Id).Status, model.NoteStatus_Suspended)
+	utils.AssertEqual(t, reminderDataRe.FindNoteById(bank.Id).Comments[0].Text, "call after 10")
+	utils.AssertEqual(t, reminderDataRe.FindNoteById(bills.Id).Status, model.NoteStatus_Done)
+}
+
+func TestAppTrash(t *testing.T) {
+	var dataFilePath = "temp_test_dir/mydata.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(dataFilePath))
+	_ = model.MakeSureFileExists(dataFilePath, false)
+	reminderData, _ := model.ReadDataFile(dataFilePath, true)
+	current := reminderData.TagFromSlug("current").Id
+	bank, _ := reminderData.NewNoteRegistration([]int{current}, "call bank")
+	_, _ = reminderData.NewNoteRegistration([]int{current}, "learn go")
+	app := tui.New(reminderData)
+	// switch to the view of pending notes
+	press(app, tcell.KeyTab, 0)
+	for i := 0; i < 3; i++ {
+		press(app, tcell.KeyDown, 0)
+	}
+	press(app, tcell.KeyTab, 0)
+	utils.AssertEqual(t, app.SelectedNote(), bank)
+	// move the note to the trash
+	press(app, tcell.KeyRune, 'D')
+	utils.AssertEqual(t, strings.HasPrefix(app.StatusText(), `Moved "call bank" to the trash.`), true)
+	utils.AssertEqual(t, noteTexts(app.ListedNotes()), []string{"learn go"})
+	// a note which is not in the trash cannot be restored
+	press(app, tcell.KeyRune, 'r')
+	utils.AssertEqual(t, strings.HasPrefix(app.StatusText(), `Error: The note "learn go" is not in the trash`), true)
+	// restore the note from the trash view
+	press(app, tcell.KeyTab, 0)
+	for i := 0; i < 4; i++ {
+		press(app, tcell.KeyDown, 0)
+	}
+	press(app, tcell.KeyTab, 0)
+	utils.AssertEqual(t, noteTexts(app.ListedNotes()), []string{"call bank"})
+	press(app, tcell.KeyRune, 'r')
+	utils.AssertEqual(t, len(app.ListedNotes()), 0)
+	utils.AssertEqual(t, reminderData.FindNoteById(bank.Id), bank)
+	// toggle the archived notes
+	press(app, tcell.KeyRune, 'a')
+	utils.AssertEqual(t, reminderData.IncludesArchived(), true)
+	press(app, tcell.KeyRune, 'a')
+	utils.AssertEqual(t, reminderData.IncludesArchived(), false)
+}
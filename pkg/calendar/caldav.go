@@ -0,0 +1,356 @@
+package calendar
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/goyalmunish/reminder/pkg/ical"
+	"github.com/goyalmunish/reminder/pkg/logger"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+/*
+A CalDAVProvider represents a calendar (collection) on a CalDAV server, as a Provider.
+
+Each event is kept as its own resource (with UID of the note, as in the exported iCalendar), and is
+identified by its path. The properties of the events which aren't represented by Event (such as their
+alarms) are kept on update.
+*/
+type CalDAVProvider struct {
+	collection *url.URL
+	username   string
+	password   string
+	client     *http.Client
+	timeZone   string // time zone of the calendar, once it is read
+}
+
+// NewCalDAVProvider returns the provider for the calendar collection at the URL.
+// The credentials are used for basic authentication, if the username isn't empty.
+// The client is http.DefaultClient if nil.
+func NewCalDAVProvider(calendarURL string, username string, password string, client *http.Client) (*CalDAVProvider, error) {
+	collection, err := url.Parse(calendarURL)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid URL %q of the CalDAV calendar: %w", calendarURL, err)
+	}
+	if !strings.HasSuffix(collection.Path, "/") {
+		collection.Path += "/"
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &CalDAVProvider{collection: collection, username: username, password: password, client: client}, nil
+}
+
+// calendarQuery is the REPORT request for the events within a time range.
+const calendarQuery = `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/><c:calendar-data/></d:prop>
+  <c:filter>
+    <c:comp-filter name="VCALENDAR">
+      <c:comp-filter name="VEVENT"><c:time-range start="%s" end="%s"/></c:comp-filter>
+    </c:comp-filter>
+  </c:filter>
+</c:calendar-query>`
+
+// timeZoneQuery is the PROPFIND request for the time zone of the calendar, by its IANA name (RFC 7809)
+// or by its VTIMEZONE definition.
+const timeZoneQuery = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><c:calendar-timezone-id/><c:calendar-timezone/></d:prop>
+</d:propfind>`
+
+// multistatus is the response of a REPORT or PROPFIND request.
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"DAV: href"`
+		Propstat []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ETag         string `xml:"DAV: getetag"`
+				Data         string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+				TimeZoneId   string `xml:"urn:ietf:params:xml:ns:caldav calendar-timezone-id"`
+				TimeZoneData string `xml:"urn:ietf:params:xml:ns:caldav calendar-timezone"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// List returns the events of the calendar, along with the time zone of the calendar.
+func (provider *CalDAVProvider) List(query string, timeMin time.Time, timeMax time.Time) (*EventList, error) {
+	body := fmt.Sprintf(calendarQuery, timeMin.UTC().Format("20060102T150405Z"), timeMax.UTC().Format("20060102T150405Z"))
+	response, err := provider.do("REPORT", provider.collection.String(), strings.NewReader(body), map[string]string{
+		"Content-Type": "application/xml; charset=utf-8",
+		"Depth":        "1",
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusMultiStatus {
+		return nil, statusError("list the events", response)
+	}
+	var result multistatus
+	if err := xml.NewDecoder(response.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("Unable to read the events: %w", err)
+	}
+	timeZone, err := provider.calendarTimeZone()
+	if err != nil {
+		return nil, err
+	}
+	list := &EventList{TimeZone: timeZone}
+	for _, item := range result.Responses {
+		for _, propstat := range item.Propstat {
+			if propstat.Prop.Data == "" {
+				continue
+			}
+			component, err := parseEvent(strings.NewReader(propstat.Prop.Data))
+			if err != nil {
+				return nil, fmt.Errorf("Unable to read the event %q: %w", item.Href, err)
+			}
+			event := fromComponent(component)
+			if !strings.Contains(strings.ToLower(event.Title), strings.ToLower(query)) {
+				continue
+			}
+			event.Id, event.Version = provider.resolve(item.Href).Path, propstat.Prop.ETag
+			list.Items = append(list.Items, event)
+		}
+	}
+	return list, nil
+}
+
+// calendarTimeZone returns the time zone of the calendar, as per its calendar-timezone-id (or else its
+// calendar-timezone) property. The local time zone is used if the server doesn't tell a known (IANA) time zone.
+func (provider *CalDAVProvider) calendarTimeZone() (string, error) {
+	if provider.timeZone != "" {
+		return provider.timeZone, nil
+	}
+	response, err := provider.do("PROPFIND", provider.collection.String(), strings.NewReader(timeZoneQuery), map[string]string{
+		"Content-Type": "application/xml; charset=utf-8",
+		"Depth":        "0",
+	})
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	provider.timeZone = utils.LocalZoneName()
+	if response.StatusCode != http.StatusMultiStatus {
+		logger.Warn(fmt.Sprintf("Unable to read time zone of the calendar (%s); using the local time zone %q.", response.Status, provider.timeZone))
+		return provider.timeZone, nil
+	}
+	var result multistatus
+	if err := xml.NewDecoder(response.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("Unable to read time zone of the calendar: %w", err)
+	}
+	for _, item := range result.Responses {
+		for _, propstat := range item.Propstat {
+			candidates := []string{propstat.Prop.TimeZoneId}
+			// the VTIMEZONE definition is identified by its TZID (or by X-LIC-LOCATION, as written by some servers)
+			for _, line := range strings.Split(propstat.Prop.TimeZoneData, "\n") {
+				name, value, _ := strings.Cut(strings.TrimSpace(line), ":")
+				if name == "TZID" || name == "X-LIC-LOCATION" {
+					candidates = append(candidates, value)
+				}
+			}
+			for _, candidate := range candidates {
+				if _, err := time.LoadLocation(candidate); candidate != "" && err == nil {
+					provider.timeZone = candidate
+					return provider.timeZone, nil
+				}
+			}
+		}
+	}
+	return provider.timeZone, nil
+}
+
+func (provider *CalDAVProvider) Get(eventId string) (*Event, error) {
+	component, etag, err := provider.get(eventId)
+	if err != nil {
+		return nil, err
+	}
+	event := fromComponent(component)
+	event.Id, event.Version = eventId, etag
+	return event, nil
+}
+
+func (provider *CalDAVProvider) Insert(event *Event) (*Event, error) {
+	name := event.NoteId
+	if name == "" {
+		name = uuid.NewString()
+	}
+	eventId := provider.resolve(name + ".ics").Path
+	component := toComponent(event, name+"@reminder")
+	etag, err := provider.put(eventId, component, map[string]string{"If-None-Match": "*"})
+	if err != nil {
+		return nil, err
+	}
+	return provider.saved(event, eventId, etag)
+}
+
+func (provider *CalDAVProvider) Update(event *Event) (*Event, error) {
+	existing, etag, err := provider.get(event.Id)
+	if err != nil {
+		return nil, err
+	}
+	component := toComponent(event, existing.UID)
+	component.Extra = existing.Extra
+	etag, err = provider.put(event.Id, component, map[string]string{"If-Match": etag})
+	if err != nil {
+		return nil, err
+	}
+	return provider.saved(event, event.Id, etag)
+}
+
+func (provider *CalDAVProvider) Delete(eventId string) error {
+	response, err := provider.do(http.MethodDelete, provider.resolve(eventId).String(), nil, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusNoContent {
+		return statusError("delete the event "+eventId, response)
+	}
+	return nil
+}
+
+// get returns the event resource along with its ETag.
+func (provider *CalDAVProvider) get(eventId string) (*ical.Component, string, error) {
+	response, err := provider.do(http.MethodGet, provider.resolve(eventId).String(), nil, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, "", statusError("get the event "+eventId, response)
+	}
+	component, err := parseEvent(response.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("Unable to read the event %q: %w", eventId, err)
+	}
+	return component, response.Header.Get("ETag"), nil
+}
+
+// put writes the event resource, and returns its new ETag (which is empty if the server doesn't tell it).
+func (provider *CalDAVProvider) put(eventId string, component *ical.Component, headers map[string]string) (string, error) {
+	var buffer bytes.Buffer
+	// the times in a time zone are written along with its VTIMEZONE definition, as CalDAV requires
+	iCalendar := &ical.Calendar{ProdId: "-//goyalmunish//reminder//EN", Components: []*ical.Component{component}, EmbedTimeZones: true}
+	if err := iCalendar.Encode(&buffer); err != nil {
+		return "", err
+	}
+	headers["Content-Type"] = ical.ContentType
+	response, err := provider.do(http.MethodPut, provider.resolve(eventId).String(), &buffer, headers)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return response.Header.Get("ETag"), nil
+	case http.StatusPreconditionFailed:
+		return "", fmt.Errorf("Unable to save the event %q, as it was changed meanwhile", eventId)
+	}
+	return "", statusError("save the event "+eventId, response)
+}
+
+// saved returns the event as saved, with its id and version.
+func (provider *CalDAVProvider) saved(event *Event, eventId string, etag string) (*Event, error) {
+	if etag == "" {
+		// the server doesn't return ETag for modified content, so it is fetched again
+		return provider.Get(eventId)
+	}
+	saved := *event
+	saved.Id, saved.Version = eventId, etag
+	return &saved, nil
+}
+
+// do sends the request to the server.
+func (provider *CalDAVProvider) do(method string, target string, body io.Reader, headers map[string]string) (*http.Response, error) {
+	request, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	if provider.username != "" {
+		request.SetBasicAuth(provider.username, provider.password)
+	}
+	return provider.client.Do(request)
+}
+
+// resolve returns URL of the path (or URL) relative to the calendar collection.
+func (provider *CalDAVProvider) resolve(reference string) *url.URL {
+	target, err := url.Parse(reference)
+	if err != nil {
+		return provider.collection
+	}
+	return provider.collection.ResolveReference(target)
+}
+
+// statusError returns error for unexpected response of a request, wrapping ErrNotFound for a missing resource.
+func statusError(action string, response *http.Response) error {
+	if response.StatusCode == http.StatusNotFound || response.StatusCode == http.StatusGone {
+		return fmt.Errorf("%w: unable to %s", ErrNotFound, action)
+	}
+	return fmt.Errorf("Unable to %s: %s", action, response.Status)
+}
+
+// parseEvent returns the (first) event of the iCalendar data.
+func parseEvent(r io.Reader) (*ical.Component, error) {
+	iCalendar, err := ical.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, component := range iCalendar.Components {
+		if component.Kind == ical.KindEvent {
+			return component, nil
+		}
+	}
+	return nil, fmt.Errorf("Missing %s", ical.KindEvent)
+}
+
+// toComponent converts the event to iCalendar event with given UID.
+func toComponent(event *Event, uid string) *ical.Component {
+	component := &ical.Component{
+		Kind:        ical.KindEvent,
+		UID:         uid,
+		Summary:     event.Title,
+		Description: event.Description,
+		Start:       event.Start,
+		End:         event.End,
+		AllDay:      event.AllDay,
+		TimeZone:    event.TimeZone,
+		Status:      "CONFIRMED",
+		Stamp:       utils.CurrentTime(),
+	}
+	if len(event.Recurrence) > 0 {
+		component.RRule = event.Recurrence[0]
+	}
+	return component
+}
+
+// fromComponent converts iCalendar event to the event.
+func fromComponent(component *ical.Component) *Event {
+	event := &Event{
+		Title:       component.Summary,
+		Description: component.Description,
+		Start:       component.Start,
+		End:         component.End,
+		AllDay:      component.AllDay,
+		TimeZone:    component.TimeZone,
+	}
+	if noteId, ok := strings.CutSuffix(component.UID, "@reminder"); ok {
+		event.NoteId = noteId
+	}
+	if component.RRule != "" {
+		event.Recurrence = []string{component.RRule}
+	}
+	return event
+}
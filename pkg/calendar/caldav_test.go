@@ -0,0 +1,74 @@
+package calendar_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goyalmunish/reminder/pkg/calendar"
+	"github.com/goyalmunish/reminder/pkg/calendar/calendartest"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+func TestCalDAVProvider(t *testing.T) {
+	server := calendartest.NewCalDAVServer("/calendars/me/work/")
+	defer server.Close()
+	server.Username, server.Password, server.TimeZone = "me", "secret", "America/New_York"
+	london, _ := time.LoadLocation("Europe/London")
+	start := time.Date(2030, 5, 12, 14, 30, 0, 0, london)
+	// wrong credentials
+	provider, err := calendar.NewCalDAVProvider(server.URL(), "me", "wrong", nil)
+	utils.AssertEqual(t, err, nil)
+	_, err = provider.List(calendar.TitlePrefix, start.AddDate(-1, 0, 0), start.AddDate(1, 0, 0))
+	utils.AssertEqual(t, err.Error(), "Unable to list the events: 401 Unauthorized")
+	// insert
+	provider, _ = calendar.NewCalDAVProvider(strings.TrimSuffix(server.URL(), "/"), "me", "secret", nil)
+	event := &calendar.Event{NoteId: "abc", Title: "[reminder] call bank", Description: "about the loan", Start: start, End: start.Add(30 * time.Minute), TimeZone: "Europe/London", Recurrence: []string{"RRULE:FREQ=WEEKLY"}}
+	inserted, err := provider.Insert(event)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, inserted.Id, "/calendars/me/work/abc.ics")
+	utils.AssertEqual(t, inserted.Version, `"1"`)
+	utils.AssertEqual(t, server.Paths(), []string{"/calendars/me/work/abc.ics"})
+	data := server.Resource(inserted.Id)
+	utils.AssertEqual(t, strings.Contains(data, "UID:abc@reminder\r\n"), true)
+	utils.AssertEqual(t, strings.Contains(data, "DTSTART;TZID=Europe/London:20300512T143000\r\n"), true)
+	// along with definition of the time zone
+	utils.AssertEqual(t, strings.Contains(data, "BEGIN:VTIMEZONE\r\nTZID:Europe/London\r\n"), true)
+	// an event of another note can't overwrite it
+	_, err = provider.Insert(event)
+	utils.AssertEqual(t, err.Error(), `Unable to save the event "/calendars/me/work/abc.ics", as it was changed meanwhile`)
+	// list (filtered by the title) and get
+	server.PutResource("/calendars/me/work/other.ics", "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:other\r\nSUMMARY:dentist\r\nDTSTART;VALUE=DATE:20300513\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n")
+	list, err := provider.List(calendar.TitlePrefix, start.AddDate(-1, 0, 0), start.AddDate(1, 0, 0))
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, list.TimeZone, "America/New_York")
+	utils.AssertEqual(t, len(list.Items), 1)
+	listed := list.Items[0]
+	utils.AssertEqual(t, listed.Id, inserted.Id)
+	utils.AssertEqual(t, listed.Version, inserted.Version)
+	utils.AssertEqual(t, listed.NoteId, "abc")
+	utils.AssertEqual(t, listed.Start.Equal(start), true)
+	utils.AssertEqual(t, listed.Recurrence, []string{"RRULE:FREQ=WEEKLY"})
+	got, err := provider.Get(inserted.Id)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, got.Description, "about the loan")
+	utils.AssertEqual(t, got.Version, inserted.Version)
+	// update keeps the details added by another client
+	server.PutResource(inserted.Id, strings.Replace(data, "END:VEVENT", "LOCATION:home\r\nBEGIN:VALARM\r\nTRIGGER:-PT15M\r\nEND:VALARM\r\nEND:VEVENT", 1))
+	event.Id, event.Title, event.Recurrence = inserted.Id, "[reminder] call the bank", nil
+	updated, err := provider.Update(event)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, updated.Version, `"4"`)
+	data = server.Resource(inserted.Id)
+	utils.AssertEqual(t, strings.Contains(data, "SUMMARY:[reminder] call the bank\r\n"), true)
+	utils.AssertEqual(t, strings.Contains(data, "LOCATION:home\r\nBEGIN:VALARM\r\nTRIGGER:-PT15M\r\nEND:VALARM\r\nEND:VEVENT"), true)
+	utils.AssertEqual(t, strings.Contains(data, "RRULE:FREQ=WEEKLY"), false)
+	// delete
+	utils.AssertEqual(t, provider.Delete(inserted.Id), nil)
+	utils.AssertEqual(t, server.Paths(), []string{"/calendars/me/work/other.ics"})
+	_, err = provider.Get(inserted.Id)
+	utils.AssertEqual(t, calendar.IsNotFound(err), true)
+	_, err = provider.Update(event)
+	utils.AssertEqual(t, calendar.IsNotFound(err), true)
+	utils.AssertEqual(t, calendar.IsNotFound(provider.Delete(inserted.Id)), true)
+}
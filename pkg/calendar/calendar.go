@@ -1,256 +1,120 @@
+/*
+Package calendar syncs events with a calendar provider, such as Google Calendar or a CalDAV server
+(like Nextcloud, Radicale, or Fastmail).
+*/
 package calendar
 
 import (
-	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"html/template"
 	"strings"
 	"time"
-
-	"net/http"
-	"os"
-
-	"github.com/goyalmunish/reminder/pkg/logger"
-	"github.com/goyalmunish/reminder/pkg/utils"
-
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
-	gc "google.golang.org/api/calendar/v3"
-	"google.golang.org/api/option"
 )
 
 const TitlePrefix string = "[reminder] "
 
-// FetchUpcomingEvents returns slice of `Event` objects for
-// specified number of years, with some default settings.
-func FetchUpcomingEventsAndDetails(srv *gc.Service, backYears int, aheadYears int, query string) ([]*gc.Event, string, string, error) {
-	logger.Info("Start: FetchUpcomingEventsAndDetails")
-	defer logger.Info("End: FetchUpcomingEventsAndDetails")
-	// Get list of all upcomming events, with recurring events as a
-	// unit (and not as separate single events).
-	var allEvents []*gc.Event
-	var calendarDetails string
-	var timeZone string
-	currentTime := time.Now()
-	tStart := currentTime.AddDate(-backYears, 0, 0).Format(time.RFC3339)
-	tStop := currentTime.AddDate(aheadYears, 0, 0).Format(time.RFC3339) // until given number of aheadYears from now
-	var pageToken string
-	maxPage := 25 // just a temporarily hard limit (not expected to be reached) to keep the loop bounded
-	logger.Info(fmt.Sprintf("Fetching Calendar items with query %q, of %d years starting from %s", query, aheadYears, tStart))
-	for i := 0; i < maxPage; i++ {
-		logger.Info(fmt.Sprintf("Fetching Page-%d with token %q", i, pageToken))
-		eventsList := srv.Events.List("primary").
-			ShowDeleted(false).
-			SingleEvents(false).
-			TimeMin(tStart).
-			TimeMax(tStop).
-			MaxResults(250) // max no. of events per page; 250 is default and is maximum value; but results in each page may be far lesser then this upper limit
-		if query != "" {
-			eventsList = eventsList.Q(query)
-		}
-		if pageToken != "" {
-			eventsList = eventsList.PageToken(pageToken)
-		}
-		pageEvents, err := eventsList.Do()
-		if err != nil {
-			return nil, calendarDetails, timeZone, fmt.Errorf("Unable to retrieve the events: %w", err)
-		}
-		// fetch calendar details once once
-		if calendarDetails == "" {
-			logger.Info("Fetching calendar details")
-			calendarDetails, err = eventsDetails(pageEvents)
-			if err != nil {
-				return allEvents, calendarDetails, timeZone, err
-			}
-		}
-		if timeZone == "" {
-			timeZone = pageEvents.TimeZone
-		}
-		logger.Info(fmt.Sprintf("Found %d items; adding them to overall results", len(pageEvents.Items)))
-		allEvents = append(allEvents, pageEvents.Items...)
-		// break if token for next page is not found
-		pageToken = pageEvents.NextPageToken
-		if pageToken == "" {
-			break
-		}
-	}
-	logger.Info(fmt.Sprintf("Total number of events found: %d", len(allEvents)))
-	return allEvents, calendarDetails, timeZone, nil
+// NoteIdProperty is the private extended property of a Google Calendar event, which holds id of its note.
+const NoteIdProperty string = "reminder_note_id"
+
+// ErrNotFound is the error for a missing (or deleted) event.
+var ErrNotFound = errors.New("Event not found")
+
+/*
+An Event represents a calendar event, independent of the calendar provider.
+
+For an all-day event, only the dates of Start and End are used. The Version changes whenever the event is
+modified (in the app or in the calendar); it is the modification time for Google Calendar, and ETag for CalDAV.
+*/
+type Event struct {
+	Id          string
+	NoteId      string // id of the note of the event
+	Title       string
+	Description string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	TimeZone    string   // IANA time zone of Start and End
+	Recurrence  []string // such as "RRULE:FREQ=WEEKLY"
+	Version     string
 }
 
-// AddEvents adds passed calendar events to the Cloud
-func AddEvents(srv *gc.Service, events []*gc.Event, dryMode bool) error {
-	logger.Info("Start: AddEvents")
-	defer logger.Info("End: AddEvents")
-	for _, event := range events {
-		if dryMode {
-			logger.Warn(fmt.Sprintf("Dry mode is enabled; skipping insertion of event %q.\n", EventString(event)))
-			// continue with next iteration
-			continue
-		}
-		_, err := srv.Events.Insert("primary", event).Do()
-		if err != nil {
-			// just ignore, but log the error
-			utils.LogError(err)
-		}
-		logger.Info(fmt.Sprintf("Synced the event %q.\n", EventString(event)))
-	}
-	return nil
+/*
+An EventList represents the events of a calendar, along with time zone of the calendar.
+*/
+type EventList struct {
+	Items    []*Event
+	TimeZone string
 }
 
-// DeleteEvents delete passed cloud calendar events
-func DeleteEvents(srv *gc.Service, events []*gc.Event, dryMode bool) error {
-	logger.Info("Start: DeleteEvents")
-	defer logger.Info("End: DeleteEvents")
-	if len(events) == 0 {
-		logger.Warn("No registered events found.")
-	} else {
-		deletionCount := 0
-		for _, item := range events {
-			fmt.Printf("  - %q -- %q\n", EventString(item), item.Id)
-			if dryMode {
-				logger.Warn(fmt.Sprintf("Dry mode is enabled; skipping deletion of the event %q.", item.Id))
-				// continue with next iteration
-				continue
-			}
-			if err := srv.Events.Delete("primary", item.Id).Do(); err != nil {
-				return fmt.Errorf("Couldn't delete the Calendar event %q | %q | %w", item.Id, item.Summary, err)
-			} else {
-				deletionCount += 1
-				fmt.Printf("    - Deleted the Calendar event %q | %q\n", item.Id, EventString(item))
-			}
-		}
-		if deletionCount > 0 {
-			logger.Warn(fmt.Sprintf("\nWaring! Deletion count is %v; you might like to clear your trash manually by visiting https://calendar.google.com/calendar/u/0/r/trash\n", deletionCount))
-		}
-	}
-	return nil
+/*
+A Provider represents a calendar, which can be synced with.
+*/
+type Provider interface {
+	// List returns the events (with a recurring event as a single event) between timeMin and timeMax,
+	// whose title contains the query.
+	List(query string, timeMin time.Time, timeMax time.Time) (*EventList, error)
+	// Get returns the event; it fails with ErrNotFound for a missing (or deleted) event.
+	Get(eventId string) (*Event, error)
+	Insert(event *Event) (*Event, error)
+	// Update updates the event with id of the given event, keeping its details which aren't
+	// represented by Event (such as its location, or its alarms).
+	Update(event *Event) (*Event, error)
+	Delete(eventId string) error
 }
 
-// eventsDetails returns overall event details.
-func eventsDetails(events *gc.Events) (string, error) {
-	localTime := func(events gc.Events) string {
-		value, err := utils.StrToTime(events.Updated, events.TimeZone)
-		if err != nil {
-			logger.Error(err)
-			return ""
-		}
-		return value.String()
-	}
-	reportTemplate := `
-Calendar details:
-  - Summary:  {{.Summary}}
-  - TimeZone: {{.TimeZone}}
-  - Updated:  {{. | timeInLocation}}
-`
-	funcMap := template.FuncMap{
-		"timeInLocation": localTime,
-	}
-	return utils.TemplateResult(reportTemplate, funcMap, *events)
-}
-
-func EventString(event *gc.Event) string {
-	details := []string{}
-	details = append(details, event.Summary)
-	// Note: if an event is deleted, but still present in trash
-	// it will have event.Start as nil.
-	if event.Start != nil {
-		details = append(details, event.Start.DateTime)
-	}
-	details = append(details, event.Recurrence...)
-	return strings.Join(details, " | ")
-}
-
-// Get Calendar Service.
-func GetCalendarService(options *Options) (*gc.Service, error) {
-	logger.Info("Start: GetCalendarService")
-	defer logger.Info("End: GetCalendarService")
-	credFile := options.CredentialFile
-	b, err := os.ReadFile(utils.TryConvertTildaBasedPath(credFile))
-	if err != nil {
-		return nil, fmt.Errorf("Couldn't read the client secret file %q; Refer instructions on https://github.com/goyalmunish/reminder#setting-up-the-environment-for-google-calendar-sync; Underneath error: %w", credFile, err)
-	}
-	logger.Info(fmt.Sprintf("Read client secret file %q.", credFile))
-
-	// If modifying these scopes, delete your previously saved token file.
-	config, err := google.ConfigFromJSON(b, gc.CalendarEventsScope)
-	if err != nil {
-		return nil, fmt.Errorf("Unable to parse client secret file to config; If you changed the scope, then deleted your current %q token file and try again; Underneath error: %w", options.TokenFile, err)
-	}
-
-	client, err := getClient(config, options)
-	if err != nil {
+// NewProvider returns the provider selected by the options.
+func NewProvider(options *Options) (Provider, error) {
+	if err := options.Validate(); err != nil {
 		return nil, err
 	}
-
-	ctx := context.Background()
-
-	srv, err := gc.NewService(ctx, option.WithHTTPClient(client))
-	return srv, err
-}
-
-// Retrieve a token, saves the token, then returns the generated client.
-func getClient(config *oauth2.Config, options *Options) (*http.Client, error) {
-	// The file calendar_token.json stores the user's access and refresh tokens, and is
-	// created automatically when the authorization flow completes for the first time.
-	tokenFile := options.TokenFile
-	tok, err := tokenFromFile(utils.TryConvertTildaBasedPath(tokenFile))
-	if err != nil {
-		logger.Warn(fmt.Sprintf("Token file doesn't exist; envoking the authentication process to generate one at %q.", tokenFile))
-		tok, err := getTokenFromWeb(config)
-		if err != nil {
-			return nil, err
-		}
-		err = saveToken(tokenFile, tok)
+	switch options.Provider {
+	case ProviderCalDAV:
+		return NewCalDAVProvider(options.CalendarId, options.Username, options.CalDAVPassword(), nil)
+	default:
+		srv, err := GetCalendarService(options)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("Unable to retrieve Calendar client: %w", err)
 		}
-		logger.Info(fmt.Sprintf("Saved the token file %q.", tokenFile))
+		return NewGoogleProvider(srv, options.CalendarId), nil
 	}
-	return config.Client(context.Background(), tok), nil
 }
 
-// Retrieves a token from a local file.
-func tokenFromFile(file string) (*oauth2.Token, error) {
-	f, err := os.Open(file)
-	if err != nil {
-		return nil, err
-	}
-	defer f.Close()
-	tok := &oauth2.Token{}
-	err = json.NewDecoder(f).Decode(tok)
-	return tok, err
+// IsNotFound tells if the error is due to the event not being found (or being already deleted).
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
 }
 
-// Request a token from the web, then returns the retrieved token.
-func getTokenFromWeb(config *oauth2.Config) (*oauth2.Token, error) {
-	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-	fmt.Printf("Go to the following link in your browser, find the authorization code in the URL and type it here and hit ENTER: \n%v\n", authURL)
-
-	var authCode string
-	if _, err := fmt.Scan(&authCode); err != nil {
-		return nil, fmt.Errorf("Unable to read authorization code: %w", err)
+// String provides basic string representation of the event.
+func (event *Event) String() string {
+	details := []string{event.Title}
+	if !event.Start.IsZero() {
+		details = append(details, event.StartText())
 	}
+	details = append(details, event.Recurrence...)
+	return strings.Join(details, " | ")
+}
 
-	tok, err := config.Exchange(context.TODO(), authCode)
-	if err != nil {
-		return nil, fmt.Errorf("Unable to retrieve token from web: %w", err)
+// StartText returns start of the event, as date (for an all-day event) or as date-time in UTC, so that
+// the starts can be compared irrespective of their time zones.
+func (event *Event) StartText() string {
+	if event.AllDay {
+		return event.Start.Format("2006-01-02")
 	}
-	return tok, nil
+	return event.Start.UTC().Format(time.RFC3339)
 }
 
-// Saves a token to a file path.
-func saveToken(path string, token *oauth2.Token) error {
-	fmt.Printf("Saving credential file to: %s\n", path)
-	f, err := os.OpenFile(utils.TryConvertTildaBasedPath(path), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
-	if err != nil {
-		return fmt.Errorf("Unable to cache oauth token: %w", err)
-	}
-	defer f.Close()
-	err = json.NewEncoder(f).Encode(token)
+// Hash returns hash of the contents of the event (other than its id and version), which tells if the
+// event needs to be updated.
+func (event *Event) Hash() (string, error) {
+	contents := *event
+	contents.Id, contents.Version = "", ""
+	byteValue, err := json.Marshal(contents)
 	if err != nil {
-		return fmt.Errorf("Unable to encode token: %w", err)
+		return "", err
 	}
-	return nil
+	sum := sha256.Sum256(byteValue)
+	return hex.EncodeToString(sum[:16]), nil
 }
@@ -0,0 +1,198 @@
+package calendartest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/*
+A CalDAVServer represents an in-process CalDAV server with a single calendar collection.
+
+It supports just enough of CalDAV for calendar.CalDAVProvider: REPORT (listing all of the events of the
+collection, irrespective of the filter), PROPFIND (telling the time zone of the collection), and GET, PUT
+(with If-Match and If-None-Match preconditions) and DELETE of the event resources.
+*/
+type CalDAVServer struct {
+	Username string // if set, basic authentication is required
+	Password string
+	TimeZone string // if set, the time zone of the collection (as its calendar-timezone property)
+	server   *httptest.Server
+	path     string
+	mu       sync.Mutex
+	items    map[string]*resource
+	version  int
+}
+
+// resource is an event resource.
+type resource struct {
+	data string
+	etag string
+}
+
+// NewCalDAVServer starts the server, with the calendar collection at given path (such as "/calendars/me/work/").
+func NewCalDAVServer(collectionPath string) *CalDAVServer {
+	server := &CalDAVServer{path: collectionPath, items: map[string]*resource{}}
+	server.server = httptest.NewServer(server)
+	return server
+}
+
+// URL returns URL of the calendar collection.
+func (server *CalDAVServer) URL() string {
+	return server.server.URL + server.path
+}
+
+// Close shuts down the server.
+func (server *CalDAVServer) Close() {
+	server.server.Close()
+}
+
+// Resource returns the iCalendar data of the event at the path, or an empty string if there is no such event.
+func (server *CalDAVServer) Resource(path string) string {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if item, ok := server.items[path]; ok {
+		return item.data
+	}
+	return ""
+}
+
+// Paths returns paths of all of the events, sorted.
+func (server *CalDAVServer) Paths() []string {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	paths := make([]string, 0, len(server.items))
+	for path := range server.items {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// PutResource saves the event at the path (within the collection), as if it were saved by another client.
+func (server *CalDAVServer) PutResource(path string, data string) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.save(path, data)
+}
+
+// ServeHTTP handles the CalDAV requests.
+func (server *CalDAVServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if server.Username != "" {
+		if username, password, ok := r.BasicAuth(); !ok || username != server.Username || password != server.Password {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if r.Method == "REPORT" {
+		if r.URL.Path != server.path {
+			http.NotFound(w, r)
+			return
+		}
+		server.report(w)
+		return
+	}
+	if r.Method == "PROPFIND" && r.URL.Path == server.path {
+		server.propfind(w)
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, server.path) {
+		http.NotFound(w, r)
+		return
+	}
+	item := server.items[r.URL.Path]
+	switch r.Method {
+	case http.MethodGet:
+		if item == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("ETag", item.etag)
+		_, _ = io.WriteString(w, item.data)
+	case http.MethodPut:
+		if (r.Header.Get("If-None-Match") == "*" && item != nil) ||
+			(r.Header.Get("If-Match") != "" && (item == nil || r.Header.Get("If-Match") != item.etag)) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("ETag", server.save(r.URL.Path, string(data)))
+		if item == nil {
+			w.WriteHeader(http.StatusCreated)
+		} else {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	case http.MethodDelete:
+		if item == nil {
+			http.NotFound(w, r)
+			return
+		}
+		delete(server.items, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// report writes the multistatus response with all of the events; the caller must hold the lock.
+func (server *CalDAVServer) report(w http.ResponseWriter) {
+	paths := make([]string, 0, len(server.items))
+	for path := range server.items {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	var body strings.Builder
+	body.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n" + `<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`)
+	for _, path := range paths {
+		item := server.items[path]
+		body.WriteString("<d:response><d:href>")
+		_ = xml.EscapeText(&body, []byte(path))
+		body.WriteString("</d:href><d:propstat><d:prop><d:getetag>")
+		_ = xml.EscapeText(&body, []byte(item.etag))
+		body.WriteString("</d:getetag><c:calendar-data>")
+		_ = xml.EscapeText(&body, []byte(item.data))
+		body.WriteString("</c:calendar-data></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>")
+	}
+	body.WriteString("</d:multistatus>")
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	_, _ = io.WriteString(w, body.String())
+}
+
+// propfind writes the multistatus response with the time zone of the collection.
+func (server *CalDAVServer) propfind(w http.ResponseWriter) {
+	var body strings.Builder
+	body.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n" + `<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`)
+	body.WriteString("<d:response><d:href>")
+	_ = xml.EscapeText(&body, []byte(server.path))
+	body.WriteString("</d:href><d:propstat><d:prop>")
+	if server.TimeZone != "" {
+		body.WriteString("<c:calendar-timezone>")
+		_ = xml.EscapeText(&body, []byte("BEGIN:VCALENDAR\r\nBEGIN:VTIMEZONE\r\nTZID:"+server.TimeZone+"\r\nEND:VTIMEZONE\r\nEND:VCALENDAR\r\n"))
+		body.WriteString("</c:calendar-timezone>")
+	}
+	body.WriteString("</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response></d:multistatus>")
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	_, _ = io.WriteString(w, body.String())
+}
+
+// save saves the event, and returns its new ETag; the caller must hold the lock.
+func (server *CalDAVServer) save(path string, data string) string {
+	server.version++
+	etag := fmt.Sprintf(`"%d"`, server.version)
+	server.items[path] = &resource{data: data, etag: etag}
+	return etag
+}
@@ -0,0 +1,144 @@
+/*
+Package calendartest provides test doubles of calendars: an in-memory calendar.Provider, and an in-process
+CalDAV server.
+*/
+package calendartest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goyalmunish/reminder/pkg/calendar"
+)
+
+/*
+A Provider represents an in-memory calendar, implementing calendar.Provider.
+
+Every change of an event (including the ones made with Put, as if in the calendar itself) gives it a new
+Version. All of the events are listed, irrespective of the time range.
+*/
+type Provider struct {
+	TimeZone string
+	Calls    []string // log of the changes made through the provider, such as "insert event-1", "update event-1", or "delete event-1"
+	mu       sync.Mutex
+	items    map[string]*calendar.Event
+	lastId   int
+	version  int
+}
+
+// NewProvider returns an empty in-memory calendar.
+func NewProvider() *Provider {
+	return &Provider{TimeZone: "UTC", items: map[string]*calendar.Event{}}
+}
+
+// Event returns copy of the event with given id, or nil if there is no such event.
+func (provider *Provider) Event(eventId string) *calendar.Event {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if event, ok := provider.items[eventId]; ok {
+		return clone(event)
+	}
+	return nil
+}
+
+// Items returns copies of all the events, sorted by their ids.
+func (provider *Provider) Items() []*calendar.Event {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	ids := make([]string, 0, len(provider.items))
+	for id := range provider.items {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	items := make([]*calendar.Event, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, clone(provider.items[id]))
+	}
+	return items
+}
+
+// Put adds (or replaces) an event, as if it were created (or edited) in the calendar itself.
+// An event without an id gets a new one.
+func (provider *Provider) Put(event *calendar.Event) *calendar.Event {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	return provider.put(event)
+}
+
+// Remove deletes an event, as if it were deleted in the calendar itself.
+func (provider *Provider) Remove(eventId string) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	delete(provider.items, eventId)
+}
+
+func (provider *Provider) List(query string, timeMin time.Time, timeMax time.Time) (*calendar.EventList, error) {
+	list := &calendar.EventList{TimeZone: provider.TimeZone}
+	for _, event := range provider.Items() {
+		if strings.Contains(strings.ToLower(event.Title), strings.ToLower(query)) {
+			list.Items = append(list.Items, event)
+		}
+	}
+	return list, nil
+}
+
+func (provider *Provider) Get(eventId string) (*calendar.Event, error) {
+	if event := provider.Event(eventId); event != nil {
+		return event, nil
+	}
+	return nil, fmt.Errorf("%w: %q", calendar.ErrNotFound, eventId)
+}
+
+func (provider *Provider) Insert(event *calendar.Event) (*calendar.Event, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	event = clone(event)
+	event.Id = ""
+	inserted := provider.put(event)
+	provider.Calls = append(provider.Calls, "insert "+inserted.Id)
+	return inserted, nil
+}
+
+func (provider *Provider) Update(event *calendar.Event) (*calendar.Event, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if _, ok := provider.items[event.Id]; !ok {
+		return nil, fmt.Errorf("%w: %q", calendar.ErrNotFound, event.Id)
+	}
+	provider.Calls = append(provider.Calls, "update "+event.Id)
+	return provider.put(event), nil
+}
+
+func (provider *Provider) Delete(eventId string) error {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if _, ok := provider.items[eventId]; !ok {
+		return fmt.Errorf("%w: %q", calendar.ErrNotFound, eventId)
+	}
+	delete(provider.items, eventId)
+	provider.Calls = append(provider.Calls, "delete "+eventId)
+	return nil
+}
+
+// put saves copy of the event with a new version; the caller must hold the lock.
+func (provider *Provider) put(event *calendar.Event) *calendar.Event {
+	event = clone(event)
+	if event.Id == "" {
+		provider.lastId++
+		event.Id = fmt.Sprintf("event-%d", provider.lastId)
+	}
+	provider.version++
+	event.Version = fmt.Sprintf("v%d", provider.version)
+	provider.items[event.Id] = event
+	return clone(event)
+}
+
+// clone returns a copy of the event.
+func clone(event *calendar.Event) *calendar.Event {
+	copied := *event
+	copied.Recurrence = append([]string(nil), event.Recurrence...)
+	return &copied
+}
@@ -0,0 +1,277 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/goyalmunish/reminder/pkg/logger"
+	"github.com/goyalmunish/reminder/pkg/utils"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	gc "google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+/*
+A GoogleProvider represents a Google Calendar, as a Provider.
+
+The events get "Basil" color, the default reminders, and the app as their source; the id of the note is kept
+as a private extended property of its event.
+*/
+type GoogleProvider struct {
+	events     *gc.EventsService
+	calendarId string
+}
+
+// NewGoogleProvider returns the provider for the calendar (such as "primary") of the Google Calendar service.
+func NewGoogleProvider(srv *gc.Service, calendarId string) *GoogleProvider {
+	return &GoogleProvider{events: srv.Events, calendarId: calendarId}
+}
+
+func (provider *GoogleProvider) List(query string, timeMin time.Time, timeMax time.Time) (*EventList, error) {
+	logger.Info(fmt.Sprintf("Fetching Calendar items with query %q, between %s and %s", query, timeMin.Format(time.RFC3339), timeMax.Format(time.RFC3339)))
+	list := &EventList{}
+	var pageToken string
+	maxPage := 25 // just a temporarily hard limit (not expected to be reached) to keep the loop bounded
+	for i := 0; i < maxPage; i++ {
+		logger.Info(fmt.Sprintf("Fetching Page-%d with token %q", i, pageToken))
+		// recurring events are fetched as a unit (and not as separate single events)
+		eventsList := provider.events.List(provider.calendarId).
+			ShowDeleted(false).
+			SingleEvents(false).
+			TimeMin(timeMin.Format(time.RFC3339)).
+			TimeMax(timeMax.Format(time.RFC3339)).
+			MaxResults(250) // max no. of events per page; 250 is default and is maximum value; but results in each page may be far lesser then this upper limit
+		if query != "" {
+			eventsList = eventsList.Q(query)
+		}
+		if pageToken != "" {
+			eventsList = eventsList.PageToken(pageToken)
+		}
+		pageEvents, err := eventsList.Do()
+		if err != nil {
+			return nil, fmt.Errorf("Unable to retrieve the events: %w", err)
+		}
+		if list.TimeZone == "" {
+			list.TimeZone = pageEvents.TimeZone
+		}
+		logger.Info(fmt.Sprintf("Found %d items; adding them to overall results", len(pageEvents.Items)))
+		for _, item := range pageEvents.Items {
+			list.Items = append(list.Items, fromGoogleEvent(item))
+		}
+		// break if token for next page is not found
+		pageToken = pageEvents.NextPageToken
+		if pageToken == "" {
+			break
+		}
+	}
+	logger.Info(fmt.Sprintf("Total number of events found: %d", len(list.Items)))
+	return list, nil
+}
+
+func (provider *GoogleProvider) Get(eventId string) (*Event, error) {
+	event, err := provider.events.Get(provider.calendarId, eventId).Do()
+	if err != nil {
+		return nil, googleError(err)
+	}
+	// a deleted event is still found, but as cancelled
+	if event.Status == "cancelled" {
+		return nil, fmt.Errorf("%w: %q is cancelled", ErrNotFound, eventId)
+	}
+	return fromGoogleEvent(event), nil
+}
+
+func (provider *GoogleProvider) Insert(event *Event) (*Event, error) {
+	inserted, err := provider.events.Insert(provider.calendarId, toGoogleEvent(event)).Do()
+	if err != nil {
+		return nil, googleError(err)
+	}
+	return fromGoogleEvent(inserted), nil
+}
+
+func (provider *GoogleProvider) Update(event *Event) (*Event, error) {
+	// the event is patched, so that its other fields are kept; the cleared fields are sent
+	// as well, so that they get cleared in the calendar too
+	patch := toGoogleEvent(event)
+	patch.ForceSendFields = []string{"Description", "Recurrence"}
+	patched, err := provider.events.Patch(provider.calendarId, event.Id, patch).Do()
+	if err != nil {
+		return nil, googleError(err)
+	}
+	return fromGoogleEvent(patched), nil
+}
+
+func (provider *GoogleProvider) Delete(eventId string) error {
+	return googleError(provider.events.Delete(provider.calendarId, eventId).Do())
+}
+
+// googleError wraps the error of a missing (or deleted) event as ErrNotFound.
+func googleError(err error) error {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && (apiErr.Code == http.StatusNotFound || apiErr.Code == http.StatusGone) {
+		return fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	return err
+}
+
+// toGoogleEvent converts the event to Google Calendar event.
+func toGoogleEvent(event *Event) *gc.Event {
+	eventTime := func(t time.Time) *gc.EventDateTime {
+		if event.AllDay {
+			return &gc.EventDateTime{Date: t.Format("2006-01-02")}
+		}
+		return &gc.EventDateTime{DateTime: t.Format(time.RFC3339), TimeZone: event.TimeZone}
+	}
+	googleEvent := &gc.Event{
+		Summary:     event.Title,
+		Description: event.Description,
+		Start:       eventTime(event.Start),
+		End:         eventTime(event.End),
+		Recurrence:  event.Recurrence,
+		ColorId:     "10", // "Basil" color
+		Reminders: &gc.EventReminders{
+			Overrides:  []*gc.EventReminder{},
+			UseDefault: true,
+		},
+		EventType: "default",
+		Source: &gc.EventSource{
+			Title: "reminder",
+			Url:   "https://github.com/goyalmunish/reminder",
+		},
+		Status:       "confirmed",
+		Transparency: "transparent",
+		Visibility:   "default",
+	}
+	if event.NoteId != "" {
+		googleEvent.ExtendedProperties = &gc.EventExtendedProperties{
+			Private: map[string]string{NoteIdProperty: event.NoteId},
+		}
+	}
+	return googleEvent
+}
+
+// fromGoogleEvent converts Google Calendar event to the event.
+func fromGoogleEvent(googleEvent *gc.Event) *Event {
+	event := &Event{
+		Id:          googleEvent.Id,
+		Title:       googleEvent.Summary,
+		Description: googleEvent.Description,
+		Recurrence:  googleEvent.Recurrence,
+		Version:     googleEvent.Updated,
+	}
+	if googleEvent.ExtendedProperties != nil {
+		event.NoteId = googleEvent.ExtendedProperties.Private[NoteIdProperty]
+	}
+	eventTime := func(eventDateTime *gc.EventDateTime) time.Time {
+		if eventDateTime == nil {
+			return time.Time{}
+		}
+		if eventDateTime.Date != "" {
+			event.AllDay = true
+			t, _ := time.Parse("2006-01-02", eventDateTime.Date)
+			return t
+		}
+		event.TimeZone = eventDateTime.TimeZone
+		t, _ := time.Parse(time.RFC3339, eventDateTime.DateTime)
+		return t
+	}
+	event.Start = eventTime(googleEvent.Start)
+	event.End = eventTime(googleEvent.End)
+	return event
+}
+
+// Get Calendar Service.
+func GetCalendarService(options *Options) (*gc.Service, error) {
+	logger.Info("Start: GetCalendarService")
+	defer logger.Info("End: GetCalendarService")
+	credFile := options.CredentialFile
+	b, err := os.ReadFile(utils.TryConvertTildaBasedPath(credFile))
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't read the client secret file %q; Refer instructions on https://github.com/goyalmunish/reminder#setting-up-the-environment-for-google-calendar-sync; Underneath error: %w", credFile, err)
+	}
+	logger.Info(fmt.Sprintf("Read client secret file %q.", credFile))
+
+	// If modifying these scopes, delete your previously saved token file.
+	config, err := google.ConfigFromJSON(b, gc.CalendarEventsScope)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse client secret file to config; If you changed the scope, then deleted your current %q token file and try again; Underneath error: %w", options.TokenFile, err)
+	}
+
+	client, err := getClient(config, options)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+
+	srv, err := gc.NewService(ctx, option.WithHTTPClient(client))
+	return srv, err
+}
+
+// authTimeout is how long the authorization waits for the user to authorize the app in the browser.
+const authTimeout = 5 * time.Minute
+
+// Retrieve a token, saves the token, then returns the generated client.
+// The token is saved again whenever it gets refreshed.
+func getClient(config *oauth2.Config, options *Options) (*http.Client, error) {
+	// The file calendar_token.json stores the user's access and refresh tokens, and is
+	// created automatically when the authorization flow completes for the first time.
+	tokenFile := options.TokenFile
+	tok, err := tokenFromFile(utils.TryConvertTildaBasedPath(tokenFile))
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Token file doesn't exist; envoking the authentication process to generate one at %q.", tokenFile))
+		tok, err = getTokenFromWeb(config)
+		if err != nil {
+			return nil, err
+		}
+		err = saveToken(tokenFile, tok)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info(fmt.Sprintf("Saved the token file %q.", tokenFile))
+	}
+	ctx := context.Background()
+	return oauth2.NewClient(ctx, SavingTokenSource(config.TokenSource(ctx, tok), tokenFile, tok)), nil
+}
+
+// Retrieves a token from a local file.
+func tokenFromFile(file string) (*oauth2.Token, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	tok := &oauth2.Token{}
+	err = json.NewDecoder(f).Decode(tok)
+	return tok, err
+}
+
+// Request a token from the web (with the loopback redirect), then returns the retrieved token.
+func getTokenFromWeb(config *oauth2.Config) (*oauth2.Token, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), authTimeout)
+	defer cancel()
+	return Authorize(ctx, config, func(authURL string) {
+		fmt.Printf("Go to the following link in your browser, and authorize the app; it waits (for up to %v) to be redirected back to this machine: \n%v\n", authTimeout, authURL)
+	})
+}
+
+// Saves a token to a file path.
+// The file is written atomically, so that a crash while saving a refreshed token doesn't lose the refresh token.
+func saveToken(path string, token *oauth2.Token) error {
+	logger.Info(fmt.Sprintf("Saving credential file to: %s", path))
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("Unable to encode token: %w", err)
+	}
+	if err := utils.WriteFileAtomic(utils.TryConvertTildaBasedPath(path), data, 0600, false); err != nil {
+		return fmt.Errorf("Unable to cache oauth token: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package calendar
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/goyalmunish/reminder/pkg/logger"
+
+	"golang.org/x/oauth2"
+)
+
+// authorizedPage is shown in the browser once the app is authorized.
+const authorizedPage = `<html><body><p>The reminder app is authorized; you may close this window.</p></body></html>`
+
+// Authorize gets a token with the OAuth loopback redirect flow: it listens on an ephemeral port of the
+// loopback interface, prompts (such as prints) the URL to be opened in the browser, and exchanges the
+// authorization code that the browser is redirected back with. The request is protected by PKCE and a random
+// state; redirects with any other state are rejected (and are waited past). The context bounds the wait.
+func Authorize(ctx context.Context, config *oauth2.Config, prompt func(authURL string)) (*oauth2.Token, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("Unable to listen for the authorization redirect: %w", err)
+	}
+	defer listener.Close()
+	loopbackConfig := *config
+	loopbackConfig.RedirectURL = fmt.Sprintf("http://%s/", listener.Addr())
+	state, err := randomState()
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	type result struct {
+		code string
+		err  error
+	}
+	results := make(chan result, 1)
+	var once sync.Once
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		query := r.URL.Query()
+		if subtle.ConstantTimeCompare([]byte(query.Get("state")), []byte(state)) != 1 {
+			http.Error(w, "Invalid state of the authorization request", http.StatusBadRequest)
+			return
+		}
+		var res result
+		switch {
+		case query.Get("error") != "":
+			res.err = fmt.Errorf("Authorization was denied: %s", query.Get("error"))
+			http.Error(w, res.err.Error(), http.StatusForbidden)
+		case query.Get("code") == "":
+			res.err = errors.New("Missing authorization code in the redirect")
+			http.Error(w, res.err.Error(), http.StatusBadRequest)
+		default:
+			res.code = query.Get("code")
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = fmt.Fprint(w, authorizedPage)
+		}
+		once.Do(func() { results <- res })
+	})}
+	go func() { _ = server.Serve(listener) }()
+	defer server.Close()
+
+	prompt(loopbackConfig.AuthCodeURL(state, oauth2.AccessTypeOffline, oauth2.S256ChallengeOption(verifier)))
+	var res result
+	select {
+	case res = <-results:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("Didn't get the authorization redirect: %w", ctx.Err())
+	}
+	if res.err != nil {
+		return nil, res.err
+	}
+	token, err := loopbackConfig.Exchange(ctx, res.code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to retrieve token from web: %w", err)
+	}
+	return token, nil
+}
+
+// randomState returns a random state of an authorization request.
+func randomState() (string, error) {
+	value := make([]byte, 16)
+	if _, err := rand.Read(value); err != nil {
+		return "", fmt.Errorf("Unable to generate state of the authorization request: %w", err)
+	}
+	return hex.EncodeToString(value), nil
+}
+
+/*
+A savingTokenSource represents a token source which saves the token to the token file whenever it changes
+(such as when the access token is refreshed, or the refresh token is rotated).
+*/
+type savingTokenSource struct {
+	source    oauth2.TokenSource
+	tokenFile string
+	mu        sync.Mutex
+	last      *oauth2.Token
+}
+
+// SavingTokenSource returns the token source, which saves the tokens (other than the given current one) to
+// the token file, as they get refreshed.
+func SavingTokenSource(source oauth2.TokenSource, tokenFile string, current *oauth2.Token) oauth2.TokenSource {
+	return &savingTokenSource{source: source, tokenFile: tokenFile, last: current}
+}
+
+func (s *savingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := s.source.Token()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.last == nil || token.AccessToken != s.last.AccessToken || token.RefreshToken != s.last.RefreshToken {
+		// a failure to save doesn't fail the request, as the token is still valid
+		if err := saveToken(s.tokenFile, token); err != nil {
+			logger.Warn(fmt.Sprintf("Couldn't save the refreshed token: %v", err))
+		} else {
+			s.last = token
+		}
+	}
+	return token, nil
+}
@@ -0,0 +1,121 @@
+package calendar_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/goyalmunish/reminder/pkg/calendar"
+	"github.com/goyalmunish/reminder/pkg/utils"
+	"golang.org/x/oauth2"
+)
+
+func TestAuthorize(t *testing.T) {
+	var challenge string
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if r.PostForm.Get("code") != "abc" || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"access-1","refresh_token":"refresh-1","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer tokenServer.Close()
+	config := &oauth2.Config{ClientID: "client", Endpoint: oauth2.Endpoint{AuthURL: "https://accounts.example.com/auth", TokenURL: tokenServer.URL}}
+	var statuses []int
+	// the browser is redirected back, first with a forged state and then as authorized
+	browse := func(authURL string) {
+		parsed, _ := url.Parse(authURL)
+		query := parsed.Query()
+		challenge = query.Get("code_challenge")
+		utils.AssertEqual(t, query.Get("code_challenge_method"), "S256")
+		utils.AssertEqual(t, query.Get("access_type"), "offline")
+		utils.AssertEqual(t, len(query.Get("state")), 32)
+		redirect, _ := url.Parse(query.Get("redirect_uri"))
+		utils.AssertEqual(t, redirect.Hostname(), "127.0.0.1")
+		for _, state := range []string{"state-token", query.Get("state")} {
+			response, err := http.Get(redirect.String() + "?" + url.Values{"state": {state}, "code": {"abc"}}.Encode())
+			utils.AssertEqual(t, err, nil)
+			response.Body.Close()
+			statuses = append(statuses, response.StatusCode)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	token, err := calendar.Authorize(ctx, config, browse)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, token.AccessToken, "access-1")
+	utils.AssertEqual(t, token.RefreshToken, "refresh-1")
+	utils.AssertEqual(t, statuses, []int{http.StatusBadRequest, http.StatusOK})
+	// a denied authorization
+	token, err = calendar.Authorize(ctx, config, func(authURL string) {
+		parsed, _ := url.Parse(authURL)
+		query := parsed.Query()
+		response, err := http.Get(query.Get("redirect_uri") + "?" + url.Values{"state": {query.Get("state")}, "error": {"access_denied"}}.Encode())
+		utils.AssertEqual(t, err, nil)
+		response.Body.Close()
+		utils.AssertEqual(t, response.StatusCode, http.StatusForbidden)
+	})
+	utils.AssertEqual(t, token == nil, true)
+	utils.AssertEqual(t, err.Error(), "Authorization was denied: access_denied")
+	// no redirect in time
+	timeoutCtx, cancelTimeout := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelTimeout()
+	_, err = calendar.Authorize(timeoutCtx, config, func(authURL string) {})
+	utils.AssertEqual(t, err.Error(), "Didn't get the authorization redirect: context deadline exceeded")
+}
+
+// tokens is a token source returning the tokens in turn.
+type tokens []*oauth2.Token
+
+func (ts *tokens) Token() (*oauth2.Token, error) {
+	token := (*ts)[0]
+	if len(*ts) > 1 {
+		*ts = (*ts)[1:]
+	}
+	return token, nil
+}
+
+func TestSavingTokenSource(t *testing.T) {
+	tokenFile := "temp_test_dir/token.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(tokenFile))
+	_ = os.MkdirAll(path.Dir(tokenFile), 0755)
+	current := &oauth2.Token{AccessToken: "access-1", RefreshToken: "refresh-1"}
+	refreshed := &oauth2.Token{AccessToken: "access-2", RefreshToken: "refresh-1"}
+	rotated := &oauth2.Token{AccessToken: "access-3", RefreshToken: "refresh-2"}
+	saved := func() *oauth2.Token {
+		token := &oauth2.Token{}
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return nil
+		}
+		_ = json.Unmarshal(data, token)
+		return token
+	}
+	source := calendar.SavingTokenSource(&tokens{current, refreshed, refreshed, rotated}, tokenFile, current)
+	// the current token isn't saved again
+	token, err := source.Token()
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, token, current)
+	utils.AssertEqual(t, saved() == nil, true)
+	// the refreshed tokens are saved
+	_, _ = source.Token()
+	utils.AssertEqual(t, saved().AccessToken, "access-2")
+	info, _ := os.Stat(tokenFile)
+	utils.AssertEqual(t, info.Mode().Perm(), os.FileMode(0600))
+	_, _ = source.Token()
+	_, _ = source.Token()
+	utils.AssertEqual(t, saved().AccessToken, "access-3")
+	utils.AssertEqual(t, saved().RefreshToken, "refresh-2")
+}
@@ -1,15 +1,80 @@
 package calendar
 
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// Calendar providers.
+const (
+	ProviderGoogle = "google"
+	ProviderCalDAV = "caldav"
+)
+
+// Actions taken on a note whose event is deleted in the calendar.
+const (
+	OnDeleteSuspend = "suspend"
+	OnDeleteDone    = "done"
+)
+
+// CalDAVPasswordEnv is the environment variable used for the CalDAV password, if it isn't set in the options.
+const CalDAVPasswordEnv = "REMINDER_CALDAV_PASSWORD"
+
 type Options struct {
+	// Provider is "google" or "caldav"
+	Provider string `json:"provider" yaml:"provider" mapstructure:"provider"`
+	// CalendarId is id of the Google calendar (such as "primary"), or URL of the CalDAV calendar collection
+	CalendarId     string `json:"calendar_id" yaml:"calendar_id" mapstructure:"calendar_id"`
 	CredentialFile string `json:"credential_file" yaml:"credential_file" mapstructure:"credential_file"`
 	TokenFile      string `json:"token_file" yaml:"token_file" mapstructure:"token_file"`
-	DryMode        bool   `json:"dry_mode" yaml:"dry_mode" mapstructure:"dry_mode"`
+	// Username and Password are used for (basic) authentication with the CalDAV server; if the Password
+	// is empty, it is read from REMINDER_CALDAV_PASSWORD env variable
+	Username string `json:"username" yaml:"username" mapstructure:"username"`
+	Password string `json:"password" yaml:"password" mapstructure:"password"`
+	DryMode  bool   `json:"dry_mode" yaml:"dry_mode" mapstructure:"dry_mode"`
+	// OnDelete is what happens to a note whose event is deleted in the calendar: "suspend" or "done"
+	// (a recurring note is always suspended, so that its series can be resumed)
+	OnDelete string `json:"on_delete" yaml:"on_delete" mapstructure:"on_delete"`
 }
 
 func DefaultOptions() *Options {
 	return &Options{
+		Provider:       ProviderGoogle,
+		CalendarId:     "primary",
 		CredentialFile: "~/calendar_credentials.json",
 		TokenFile:      "~/calendar_token.json",
 		DryMode:        false,
+		OnDelete:       OnDeleteSuspend,
+	}
+}
+
+// Validate checks that the options are well formed.
+func (options *Options) Validate() error {
+	switch options.Provider {
+	case ProviderGoogle:
+		if options.CalendarId == "" {
+			return errors.New("Missing calendar_id of the calendar")
+		}
+	case ProviderCalDAV:
+		if calendarURL, err := url.Parse(options.CalendarId); err != nil || calendarURL.Host == "" {
+			return fmt.Errorf("Invalid calendar_id %q of the calendar; it should be URL of the CalDAV calendar", options.CalendarId)
+		}
+	default:
+		return fmt.Errorf("Invalid provider %q of the calendar; it should be %q or %q", options.Provider, ProviderGoogle, ProviderCalDAV)
+	}
+	if options.OnDelete != OnDeleteSuspend && options.OnDelete != OnDeleteDone {
+		return fmt.Errorf("Invalid on_delete %q of the calendar; it should be %q or %q", options.OnDelete, OnDeleteSuspend, OnDeleteDone)
+	}
+	return nil
+}
+
+// CalDAVPassword returns the password for the CalDAV server, falling back to REMINDER_CALDAV_PASSWORD
+// environment variable (so that it needn't be kept in the config file).
+func (options *Options) CalDAVPassword() string {
+	if options.Password != "" {
+		return options.Password
 	}
+	return os.Getenv(CalDAVPasswordEnv)
 }
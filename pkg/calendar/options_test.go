@@ -0,0 +1,26 @@
+package calendar_test
+
+import (
+	"testing"
+
+	"github.com/goyalmunish/reminder/pkg/calendar"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	options := calendar.DefaultOptions()
+	utils.AssertEqual(t, options.Validate(), nil)
+	options.CalendarId = ""
+	utils.AssertEqual(t, options.Validate().Error(), "Missing calendar_id of the calendar")
+	options.Provider = calendar.ProviderCalDAV
+	utils.AssertEqual(t, options.Validate().Error(), `Invalid calendar_id "" of the calendar; it should be URL of the CalDAV calendar`)
+	options.CalendarId = "https://dav.example.com/calendars/me/work/"
+	utils.AssertEqual(t, options.Validate(), nil)
+	options.Provider = "outlook"
+	utils.AssertEqual(t, options.Validate().Error(), `Invalid provider "outlook" of the calendar; it should be "google" or "caldav"`)
+	// the CalDAV password can be given by the environment
+	t.Setenv(calendar.CalDAVPasswordEnv, "secret")
+	utils.AssertEqual(t, options.CalDAVPassword(), "secret")
+	options.Password = "other"
+	utils.AssertEqual(t, options.CalDAVPassword(), "other")
+}
@@ -0,0 +1,198 @@
+/*
+Package ical reads and writes iCalendar (RFC 5545) data, with events (VEVENT) and to-dos (VTODO).
+
+Time zones are referred to by their IANA names (as TZID). All major calendar apps resolve IANA names on
+their own, so their VTIMEZONE definitions are embedded only if asked for (as CalDAV servers require them).
+*/
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ContentType is the MIME type of iCalendar data.
+const ContentType = "text/calendar; charset=utf-8"
+
+/*
+A Calendar represents an iCalendar object (VCALENDAR) with its components.
+*/
+type Calendar struct {
+	ProdId         string
+	Name           string // display name of the calendar (X-WR-CALNAME)
+	Components     []*Component
+	EmbedTimeZones bool // whether to write VTIMEZONE definitions of the time zones of the components
+}
+
+// Kinds of the components.
+const (
+	KindEvent = "VEVENT"
+	KindTodo  = "VTODO"
+)
+
+/*
+A Component represents an event (VEVENT) or a to-do (VTODO).
+
+For an event, the Start and End are its time span, whereas for a to-do the Start is its due time.
+With AllDay, only the dates of Start (and End) are used. With TimeZone (IANA name), the times are
+written as local times of the time zone; otherwise they are written in UTC.
+*/
+type Component struct {
+	Kind         string
+	UID          string
+	Summary      string
+	Description  string
+	Start        time.Time
+	End          time.Time
+	AllDay       bool
+	TimeZone     string
+	RRule        string // such as "RRULE:FREQ=WEEKLY" (the "RRULE:" prefix is optional)
+	Categories   []string
+	Status       string // such as CONFIRMED (event) or NEEDS-ACTION (to-do)
+	Stamp        time.Time
+	LastModified time.Time
+	Extra        []string // other (unfolded) content lines, including the nested components, written as they are
+}
+
+// Encode writes the calendar in iCalendar format.
+func (calendar *Calendar) Encode(w io.Writer) error {
+	writer := &lineWriter{out: bufio.NewWriter(w)}
+	writer.line("BEGIN", "VCALENDAR")
+	writer.line("VERSION", "2.0")
+	writer.line("PRODID", calendar.ProdId)
+	writer.line("CALSCALE", "GREGORIAN")
+	writer.line("METHOD", "PUBLISH")
+	if calendar.Name != "" {
+		writer.line("X-WR-CALNAME", EscapeText(calendar.Name))
+	}
+	if calendar.EmbedTimeZones {
+		calendar.encodeTimeZones(writer)
+	}
+	for _, component := range calendar.Components {
+		component.encode(writer)
+	}
+	writer.line("END", "VCALENDAR")
+	if writer.err != nil {
+		return writer.err
+	}
+	return writer.out.Flush()
+}
+
+// encodeTimeZones writes VTIMEZONE definitions of the time zones of the components, with their transitions
+// since the earliest start of the components.
+func (calendar *Calendar) encodeTimeZones(writer *lineWriter) {
+	var locations []*time.Location
+	fromYears := map[string]int{}
+	for _, component := range calendar.Components {
+		if component.AllDay || component.TimeZone == "" {
+			continue
+		}
+		location, err := time.LoadLocation(component.TimeZone)
+		if err != nil {
+			// written in UTC (refer timeProperty)
+			continue
+		}
+		year := component.Start.In(location).Year()
+		if fromYear, ok := fromYears[component.TimeZone]; !ok {
+			locations = append(locations, location)
+		} else if fromYear < year {
+			continue
+		}
+		fromYears[component.TimeZone] = year
+	}
+	for _, location := range locations {
+		encodeTimeZone(writer, location, fromYears[location.String()])
+	}
+}
+
+// encode writes the component.
+func (component *Component) encode(writer *lineWriter) {
+	writer.line("BEGIN", component.Kind)
+	writer.line("UID", component.UID)
+	writer.line("DTSTAMP", component.Stamp.UTC().Format("20060102T150405Z"))
+	if !component.LastModified.IsZero() {
+		writer.line("LAST-MODIFIED", component.LastModified.UTC().Format("20060102T150405Z"))
+	}
+	writer.line("SUMMARY", EscapeText(component.Summary))
+	if component.Description != "" {
+		writer.line("DESCRIPTION", EscapeText(component.Description))
+	}
+	if len(component.Categories) > 0 {
+		categories := make([]string, 0, len(component.Categories))
+		for _, category := range component.Categories {
+			categories = append(categories, EscapeText(category))
+		}
+		writer.line("CATEGORIES", strings.Join(categories, ","))
+	}
+	writer.line(component.timeProperty("DTSTART", component.Start))
+	if component.Kind == KindTodo {
+		writer.line(component.timeProperty("DUE", component.Start))
+	} else if !component.End.IsZero() {
+		writer.line(component.timeProperty("DTEND", component.End))
+	}
+	if component.RRule != "" {
+		writer.line("RRULE", strings.TrimPrefix(component.RRule, "RRULE:"))
+	}
+	if component.Status != "" {
+		writer.line("STATUS", component.Status)
+	}
+	for _, line := range component.Extra {
+		writer.write(line)
+	}
+	writer.line("END", component.Kind)
+}
+
+// timeProperty returns name (with parameters) and value of a date or date-time property.
+func (component *Component) timeProperty(name string, t time.Time) (string, string) {
+	switch {
+	case component.AllDay:
+		return name + ";VALUE=DATE", t.Format("20060102")
+	case component.TimeZone != "":
+		if location, err := time.LoadLocation(component.TimeZone); err == nil {
+			return fmt.Sprintf("%s;TZID=%s", name, component.TimeZone), t.In(location).Format("20060102T150405")
+		}
+	}
+	return name, t.UTC().Format("20060102T150405Z")
+}
+
+// EscapeText function escapes a TEXT value as per RFC 5545 (section 3.3.11).
+func EscapeText(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+	return replacer.Replace(text)
+}
+
+// lineWriter writes content lines, folded at 75 octets (without splitting a UTF-8 character), with CRLF line endings.
+type lineWriter struct {
+	out *bufio.Writer
+	err error
+}
+
+// line writes a property.
+func (writer *lineWriter) line(name string, value string) {
+	writer.write(name + ":" + value)
+}
+
+// write writes a content line.
+func (writer *lineWriter) write(content string) {
+	if writer.err != nil {
+		return
+	}
+	limit := 75
+	for len(content) > limit {
+		cut := limit
+		// don't split a multi-byte character
+		for cut > 0 && (content[cut]&0xC0) == 0x80 {
+			cut--
+		}
+		if _, writer.err = writer.out.WriteString(content[:cut] + "\r\n "); writer.err != nil {
+			return
+		}
+		content = content[cut:]
+		// continuation lines start with a space, which counts towards their length
+		limit = 74
+	}
+	_, writer.err = writer.out.WriteString(content + "\r\n")
+}
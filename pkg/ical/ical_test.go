@@ -0,0 +1,259 @@
+package ical_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goyalmunish/reminder/pkg/ical"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+func TestEncode(t *testing.T) {
+	stamp := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	calendar := &ical.Calendar{
+		ProdId: "-//test//EN",
+		Name:   "reminder",
+		Components: []*ical.Component{
+			{
+				Kind:        ical.KindEvent,
+				UID:         "a@reminder",
+				Summary:     "team sync; weekly, at office",
+				Description: "line 1\nline 2",
+				Start:       time.Date(2026, 5, 12, 14, 30, 0, 0, time.UTC),
+				End:         time.Date(2026, 5, 12, 15, 0, 0, 0, time.UTC),
+				TimeZone:    "Europe/London",
+				RRule:       "RRULE:FREQ=WEEKLY;BYDAY=TU",
+				Categories:  []string{"current", "work"},
+				Status:      "CONFIRMED",
+				Stamp:       stamp,
+			},
+			{
+				Kind:    ical.KindTodo,
+				UID:     "b@reminder",
+				Summary: "pay bills",
+				Start:   time.Date(2026, 5, 20, 0, 0, 0, 0, time.UTC),
+				AllDay:  true,
+				Status:  "NEEDS-ACTION",
+				Stamp:   stamp,
+			},
+		},
+	}
+	var buffer bytes.Buffer
+	utils.AssertEqual(t, calendar.Encode(&buffer), nil)
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//test//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:reminder",
+		"BEGIN:VEVENT",
+		"UID:a@reminder",
+		"DTSTAMP:20260501T080000Z",
+		`SUMMARY:team sync\; weekly\, at office`,
+		`DESCRIPTION:line 1\nline 2`,
+		"CATEGORIES:current,work",
+		"DTSTART;TZID=Europe/London:20260512T153000",
+		"DTEND;TZID=Europe/London:20260512T160000",
+		"RRULE:FREQ=WEEKLY;BYDAY=TU",
+		"STATUS:CONFIRMED",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:b@reminder",
+		"DTSTAMP:20260501T080000Z",
+		"SUMMARY:pay bills",
+		"DTSTART;VALUE=DATE:20260520",
+		"DUE;VALUE=DATE:20260520",
+		"STATUS:NEEDS-ACTION",
+		"END:VTODO",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	utils.AssertEqual(t, buffer.String(), want)
+}
+
+func TestEncodeFoldsLongLines(t *testing.T) {
+	summary := strings.Repeat("é", 60) // 120 octets
+	calendar := &ical.Calendar{ProdId: "-//test//EN", Components: []*ical.Component{
+		{Kind: ical.KindEvent, UID: "a", Summary: summary, Start: time.Date(2026, 5, 12, 14, 30, 0, 0, time.UTC)},
+	}}
+	var buffer bytes.Buffer
+	utils.AssertEqual(t, calendar.Encode(&buffer), nil)
+	var unfolded []string
+	for _, line := range strings.Split(strings.TrimSuffix(buffer.String(), "\r\n"), "\r\n") {
+		// each line is at most 75 octets, and is valid UTF-8
+		utils.AssertEqual(t, len(line) <= 75, true)
+		utils.AssertEqual(t, strings.ToValidUTF8(line, "?"), line)
+		if strings.HasPrefix(line, " ") {
+			unfolded[len(unfolded)-1] += line[1:]
+		} else {
+			unfolded = append(unfolded, line)
+		}
+	}
+	utils.AssertEqual(t, utils.IsMemberOfSlice("SUMMARY:"+summary, unfolded), true)
+	utils.AssertEqual(t, utils.IsMemberOfSlice("DTSTART:20260512T143000Z", unfolded), true)
+}
+
+func TestEncodeTimeZones(t *testing.T) {
+	london, _ := time.LoadLocation("Europe/London")
+	calendar := &ical.Calendar{ProdId: "-//test//EN", EmbedTimeZones: true, Components: []*ical.Component{
+		{Kind: ical.KindEvent, UID: "a", Summary: "team sync", Start: time.Date(2031, 5, 12, 14, 30, 0, 0, london), TimeZone: "Europe/London"},
+		{Kind: ical.KindEvent, UID: "b", Summary: "call", Start: time.Date(2030, 5, 12, 14, 30, 0, 0, london), TimeZone: "Europe/London"},
+		{Kind: ical.KindEvent, UID: "c", Summary: "standup", Start: time.Date(2030, 5, 12, 9, 0, 0, 0, time.UTC), TimeZone: "Asia/Kolkata"},
+		{Kind: ical.KindEvent, UID: "d", Summary: "holiday", Start: time.Date(2030, 5, 12, 0, 0, 0, 0, time.UTC), AllDay: true},
+	}}
+	var buffer bytes.Buffer
+	utils.AssertEqual(t, calendar.Encode(&buffer), nil)
+	encoded := buffer.String()
+	// each time zone is defined once (before the components), with its transitions since the earliest start
+	utils.AssertEqual(t, strings.Count(encoded, "BEGIN:VTIMEZONE"), 2)
+	utils.AssertEqual(t, strings.Index(encoded, "END:VTIMEZONE") < strings.Index(encoded, "BEGIN:VEVENT"), true)
+	utils.AssertEqual(t, strings.Contains(encoded, strings.Join([]string{
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/London",
+		"BEGIN:DAYLIGHT",
+		"DTSTART:20300331T010000",
+		"TZOFFSETFROM:+0000",
+		"TZOFFSETTO:+0100",
+		"TZNAME:BST",
+		"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU",
+		"END:DAYLIGHT",
+		"BEGIN:STANDARD",
+		"DTSTART:20301027T020000",
+		"TZOFFSETFROM:+0100",
+		"TZOFFSETTO:+0000",
+		"TZNAME:GMT",
+		"RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+	}, "\r\n")), true)
+	utils.AssertEqual(t, strings.Contains(encoded, strings.Join([]string{
+		"BEGIN:VTIMEZONE",
+		"TZID:Asia/Kolkata",
+		"BEGIN:STANDARD",
+		"DTSTART:20300101T000000",
+		"TZOFFSETFROM:+0530",
+		"TZOFFSETTO:+0530",
+		"TZNAME:IST",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+	}, "\r\n")), true)
+	// the transitions on n-th weekday of a month
+	calendar.Components = []*ical.Component{
+		{Kind: ical.KindEvent, UID: "a", Summary: "call", Start: time.Date(2030, 5, 12, 9, 0, 0, 0, time.UTC), TimeZone: "America/New_York"},
+	}
+	buffer.Reset()
+	utils.AssertEqual(t, calendar.Encode(&buffer), nil)
+	utils.AssertEqual(t, strings.Contains(buffer.String(), "RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU\r\n"), true)
+	utils.AssertEqual(t, strings.Contains(buffer.String(), "RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU\r\n"), true)
+	utils.AssertEqual(t, strings.Contains(buffer.String(), "RDATE"), false)
+	// the transitions which don't follow a yearly rule (such as the ones around Ramadan) are listed
+	calendar.Components[0].TimeZone = "Africa/Casablanca"
+	buffer.Reset()
+	utils.AssertEqual(t, calendar.Encode(&buffer), nil)
+	utils.AssertEqual(t, strings.Contains(buffer.String(), "RRULE:FREQ=YEARLY"), false)
+	utils.AssertEqual(t, strings.Count(buffer.String(), "RDATE:") > 10, true)
+}
+
+func TestEscapeText(t *testing.T) {
+	utils.AssertEqual(t, ical.EscapeText(`a\b;c,d`+"\r\ne\nf"), `a\\b\;c\,d\ne\nf`)
+}
+
+func TestParse(t *testing.T) {
+	data := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//test//EN",
+		"X-WR-CALNAME:Team\\, Work",
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/London",
+		"BEGIN:STANDARD",
+		"DTSTART:19701025T020000",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:a@example.com",
+		`SUMMARY:team sync\; weekly\, at`,
+		"  office",
+		"DESCRIPTION:line 1\\nline 2",
+		"CATEGORIES:Work,Team Meetings",
+		"CATEGORIES:current",
+		`DTSTART;TZID="Europe/London";X-NOTE="a:b;c":20260512T143000`,
+		"DTEND;TZID=Europe/London:20260512T150000",
+		"RRULE:FREQ=WEEKLY;BYDAY=TU",
+		"BEGIN:VALARM",
+		"TRIGGER:-PT15M",
+		"DESCRIPTION:alarm",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:b@example.com",
+		"SUMMARY:pay bills",
+		"LOCATION:bank",
+		"DTSTART;VALUE=DATE:20260501",
+		"DUE;VALUE=DATE:20260520",
+		"STATUS:needs-action",
+		"END:VTODO",
+		"BEGIN:VEVENT",
+		"UID:c@example.com",
+		"SUMMARY:flight",
+		"DTSTART:20260601T063000Z",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+	calendar, err := ical.Parse(strings.NewReader(data))
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, calendar.ProdId, "-//test//EN")
+	utils.AssertEqual(t, calendar.Name, "Team, Work")
+	utils.AssertEqual(t, len(calendar.Components), 3)
+	london, _ := time.LoadLocation("Europe/London")
+	event := calendar.Components[0]
+	utils.AssertEqual(t, event.Kind, ical.KindEvent)
+	utils.AssertEqual(t, event.UID, "a@example.com")
+	utils.AssertEqual(t, event.Summary, "team sync; weekly, at office")
+	utils.AssertEqual(t, event.Description, "line 1\nline 2")
+	utils.AssertEqual(t, event.Categories, []string{"Work", "Team Meetings", "current"})
+	utils.AssertEqual(t, event.Start.Equal(time.Date(2026, 5, 12, 14, 30, 0, 0, london)), true)
+	utils.AssertEqual(t, event.End.Equal(time.Date(2026, 5, 12, 15, 0, 0, 0, london)), true)
+	utils.AssertEqual(t, event.TimeZone, "Europe/London")
+	utils.AssertEqual(t, event.AllDay, false)
+	utils.AssertEqual(t, event.RRule, "RRULE:FREQ=WEEKLY;BYDAY=TU")
+	utils.AssertEqual(t, event.Extra, []string{"BEGIN:VALARM", "TRIGGER:-PT15M", "DESCRIPTION:alarm", "END:VALARM"})
+	// the due date of a to-do is its start
+	todo := calendar.Components[1]
+	utils.AssertEqual(t, todo.Kind, ical.KindTodo)
+	utils.AssertEqual(t, todo.Start, time.Date(2026, 5, 20, 0, 0, 0, 0, time.UTC))
+	utils.AssertEqual(t, todo.AllDay, true)
+	utils.AssertEqual(t, todo.Status, "NEEDS-ACTION")
+	utils.AssertEqual(t, todo.Extra, []string{"LOCATION:bank"})
+	utils.AssertEqual(t, calendar.Components[2].Start, time.Date(2026, 6, 1, 6, 30, 0, 0, time.UTC))
+	utils.AssertEqual(t, calendar.Components[2].TimeZone, "")
+}
+
+func TestParseEncoded(t *testing.T) {
+	// the encoded calendar reads back the same
+	calendar := &ical.Calendar{ProdId: "-//test//EN", Name: "reminder", Components: []*ical.Component{
+		{Kind: ical.KindEvent, UID: "a", Summary: strings.Repeat("long, text; ", 20), Description: "a\\b\nc",
+			Start: time.Date(2026, 5, 12, 0, 0, 0, 0, time.UTC), End: time.Date(2026, 5, 13, 0, 0, 0, 0, time.UTC), AllDay: true,
+			Categories: []string{"a,b", "c"}, RRule: "RRULE:FREQ=MONTHLY;BYMONTHDAY=-1", Status: "CONFIRMED", Stamp: time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC),
+			Extra: []string{"LOCATION:" + strings.Repeat("x", 100), "BEGIN:VALARM", "TRIGGER:-PT15M", "END:VALARM"}},
+	}}
+	var buffer bytes.Buffer
+	utils.AssertEqual(t, calendar.Encode(&buffer), nil)
+	parsed, err := ical.Parse(&buffer)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, parsed, calendar)
+}
+
+func TestParseErrors(t *testing.T) {
+	_, err := ical.Parse(strings.NewReader("BEGIN:VEVENT\r\nEND:VEVENT\r\n"))
+	utils.AssertEqual(t, err.Error(), "Missing BEGIN:VCALENDAR")
+	_, err = ical.Parse(strings.NewReader(""))
+	utils.AssertEqual(t, err.Error(), "Missing BEGIN:VCALENDAR")
+	_, err = ical.Parse(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nno colon\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"))
+	utils.AssertEqual(t, err.Error(), "Invalid line 3: Missing ':' in \"no colon\"")
+	_, err = ical.Parse(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:2026-05-12\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"))
+	utils.AssertEqual(t, strings.HasPrefix(err.Error(), "Invalid DTSTART on line 3"), true)
+}
@@ -0,0 +1,253 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+/*
+A property represents a content line, such as `DTSTART;TZID=Europe/London:20260512T143000`.
+*/
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse function parses iCalendar data, and returns its events (VEVENT) and to-dos (VTODO).
+// Other top-level components (such as VTIMEZONE) are ignored, whereas the other properties and nested components
+// (such as VALARM) of an event or a to-do are kept as its Extra lines.
+// For a to-do, its due time (or its start, if it has no due time) is set as its Start.
+func Parse(r io.Reader) (*Calendar, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, err
+	}
+	calendar := &Calendar{}
+	var current *Component
+	var due time.Time
+	depth := 0 // depth of the nested components (other than VCALENDAR)
+	inCalendar := false
+	for number, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		prop, err := parseProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid line %d: %w", number+1, err)
+		}
+		switch {
+		case prop.name == "BEGIN" && strings.ToUpper(prop.value) == "VCALENDAR":
+			inCalendar = true
+			continue
+		case !inCalendar:
+			return nil, errors.New("Missing BEGIN:VCALENDAR")
+		case prop.name == "BEGIN":
+			depth++
+			kind := strings.ToUpper(prop.value)
+			if depth == 1 && (kind == KindEvent || kind == KindTodo) {
+				current, due = &Component{Kind: kind}, time.Time{}
+			} else if depth > 1 && current != nil {
+				current.Extra = append(current.Extra, line)
+			}
+			continue
+		case prop.name == "END":
+			if depth > 1 && current != nil {
+				current.Extra = append(current.Extra, line)
+			}
+			if depth == 1 && current != nil {
+				if current.Kind == KindTodo && !due.IsZero() {
+					current.Start = due
+				}
+				calendar.Components = append(calendar.Components, current)
+				current = nil
+			}
+			depth--
+			continue
+		}
+		if depth == 0 {
+			switch prop.name {
+			case "PRODID":
+				calendar.ProdId = prop.value
+			case "X-WR-CALNAME":
+				calendar.Name = UnescapeText(prop.value)
+			}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if depth > 1 {
+			current.Extra = append(current.Extra, line)
+			continue
+		}
+		switch prop.name {
+		case "UID":
+			current.UID = prop.value
+		case "SUMMARY":
+			current.Summary = UnescapeText(prop.value)
+		case "DESCRIPTION":
+			current.Description = UnescapeText(prop.value)
+		case "CATEGORIES":
+			for _, category := range splitList(prop.value) {
+				if category = strings.TrimSpace(UnescapeText(category)); category != "" {
+					current.Categories = append(current.Categories, category)
+				}
+			}
+		case "RRULE":
+			current.RRule = "RRULE:" + prop.value
+		case "STATUS":
+			current.Status = strings.ToUpper(prop.value)
+		case "DTSTART", "DTEND", "DUE", "DTSTAMP", "LAST-MODIFIED":
+			t, allDay, timeZone, err := parseTime(prop)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid %s on line %d: %w", prop.name, number+1, err)
+			}
+			switch prop.name {
+			case "DTSTART":
+				current.Start, current.AllDay, current.TimeZone = t, allDay, timeZone
+			case "DTEND":
+				current.End = t
+			case "DUE":
+				due = t
+				current.AllDay, current.TimeZone = allDay, timeZone
+			case "DTSTAMP":
+				current.Stamp = t
+			case "LAST-MODIFIED":
+				current.LastModified = t
+			}
+		default:
+			current.Extra = append(current.Extra, line)
+		}
+	}
+	if !inCalendar {
+		return nil, errors.New("Missing BEGIN:VCALENDAR")
+	}
+	return calendar, nil
+}
+
+// unfoldLines reads the content lines, joining the folded ones.
+func unfoldLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseProperty parses a content line; parameter values can be quoted (to contain ";", ":" or ",").
+func parseProperty(line string) (*property, error) {
+	prop := &property{params: map[string]string{}}
+	// find the end of name (with parameters), skipping the quoted parameter values
+	inQuotes := false
+	colon := -1
+	for index, char := range line {
+		if char == '"' {
+			inQuotes = !inQuotes
+		} else if char == ':' && !inQuotes {
+			colon = index
+			break
+		}
+	}
+	if colon < 0 {
+		return nil, fmt.Errorf("Missing ':' in %q", line)
+	}
+	prop.value = line[colon+1:]
+	parts := splitOutsideQuotes(line[:colon], ';')
+	prop.name = strings.ToUpper(strings.TrimSpace(parts[0]))
+	if prop.name == "" {
+		return nil, fmt.Errorf("Missing property name in %q", line)
+	}
+	for _, param := range parts[1:] {
+		name, value, _ := strings.Cut(param, "=")
+		prop.params[strings.ToUpper(name)] = strings.Trim(value, `"`)
+	}
+	return prop, nil
+}
+
+// splitOutsideQuotes splits the text at the separator, except within quotes.
+func splitOutsideQuotes(text string, separator rune) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for index, char := range text {
+		if char == '"' {
+			inQuotes = !inQuotes
+		} else if char == separator && !inQuotes {
+			parts = append(parts, text[start:index])
+			start = index + 1
+		}
+	}
+	return append(parts, text[start:])
+}
+
+// splitList splits a (TEXT) list value at unescaped commas.
+func splitList(value string) []string {
+	var parts []string
+	start := 0
+	for index := 0; index < len(value); index++ {
+		switch value[index] {
+		case '\\':
+			index++
+		case ',':
+			parts = append(parts, value[start:index])
+			start = index + 1
+		}
+	}
+	return append(parts, value[start:])
+}
+
+// parseTime parses a date or date-time value.
+// It returns the time, whether it is just a date, and its time zone (if it is given with TZID).
+// A date is returned at 00:00:00 GMT+0000, and a date-time without time zone (a "floating" time) is
+// taken as a local time.
+func parseTime(prop *property) (time.Time, bool, string, error) {
+	value := strings.TrimSpace(prop.value)
+	if strings.ToUpper(prop.params["VALUE"]) == "DATE" || len(value) == 8 {
+		t, err := time.Parse("20060102", value)
+		return t, true, "", err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, "", err
+	}
+	if timeZone := prop.params["TZID"]; timeZone != "" {
+		// time zones other than IANA ones (such as "Pacific Standard Time") are taken as floating times
+		if location, err := time.LoadLocation(strings.TrimPrefix(timeZone, "/")); err == nil {
+			t, err := time.ParseInLocation("20060102T150405", value, location)
+			return t, false, location.String(), err
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, time.Local)
+	return t, false, "", err
+}
+
+// UnescapeText function unescapes a TEXT value (refer EscapeText).
+func UnescapeText(text string) string {
+	var builder strings.Builder
+	for index := 0; index < len(text); index++ {
+		if text[index] == '\\' && index+1 < len(text) {
+			index++
+			switch text[index] {
+			case 'n', 'N':
+				builder.WriteByte('\n')
+			default:
+				builder.WriteByte(text[index])
+			}
+			continue
+		}
+		builder.WriteByte(text[index])
+	}
+	return builder.String()
+}
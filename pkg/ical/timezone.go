@@ -0,0 +1,181 @@
+package ical
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+)
+
+// timeZoneYears is the number of years (from the earliest start of the components) for which the transitions of
+// a time zone are looked up, to write its VTIMEZONE definition.
+const timeZoneYears = 10
+
+/*
+A transition represents a change of the UTC offset of a time zone, such as the start of daylight saving time.
+*/
+type transition struct {
+	at         time.Time // the instant of the change
+	fromOffset int       // UTC offset (in seconds) before the change
+	toOffset   int       // UTC offset (in seconds) after the change
+	name       string    // abbreviation of the time zone after the change, such as BST
+	isDST      bool      // whether daylight saving time is in effect after the change
+}
+
+// local returns the wall clock time of the transition, as it is before the change.
+func (tr *transition) local() time.Time {
+	return tr.at.In(time.FixedZone("", tr.fromOffset))
+}
+
+// yearlyRules returns the BYMONTH and BYDAY parts of the yearly rules which match the transition, such as
+// "BYMONTH=3;BYDAY=-1SU" (last Sunday of March) and "BYMONTH=3;BYDAY=2SU" (second Sunday of March).
+func (tr *transition) yearlyRules() []string {
+	local := tr.local()
+	weekday := weekdayCodes[local.Weekday()]
+	rules := []string{fmt.Sprintf("BYMONTH=%d;BYDAY=%d%s", local.Month(), (local.Day()-1)/7+1, weekday)}
+	if local.AddDate(0, 0, 7).Month() != local.Month() {
+		rules = append(rules, fmt.Sprintf("BYMONTH=%d;BYDAY=-1%s", local.Month(), weekday))
+	}
+	return rules
+}
+
+// weekdayCodes are the codes of the weekdays, as used by RRULE.
+var weekdayCodes = map[time.Weekday]string{
+	time.Sunday: "SU", time.Monday: "MO", time.Tuesday: "TU", time.Wednesday: "WE",
+	time.Thursday: "TH", time.Friday: "FR", time.Saturday: "SA",
+}
+
+// timeZoneTransitions returns the transitions of the time zone from the start of fromYear till the start of toYear.
+func timeZoneTransitions(location *time.Location, fromYear int, toYear int) []*transition {
+	var transitions []*transition
+	offsetAt := func(unix int64) int {
+		_, offset := time.Unix(unix, 0).In(location).Zone()
+		return offset
+	}
+	end := time.Date(toYear, 1, 1, 0, 0, 0, 0, location)
+	for day := time.Date(fromYear, 1, 1, 0, 0, 0, 0, location); day.Before(end); day = day.Add(24 * time.Hour) {
+		low, high := day.Unix(), day.Add(24*time.Hour).Unix()
+		fromOffset, toOffset := offsetAt(low), offsetAt(high)
+		if fromOffset == toOffset {
+			continue
+		}
+		// find the first second with the new offset
+		for high-low > 1 {
+			middle := (low + high) / 2
+			if offsetAt(middle) == fromOffset {
+				low = middle
+			} else {
+				high = middle
+			}
+		}
+		at := time.Unix(high, 0).In(location)
+		name, _ := at.Zone()
+		transitions = append(transitions, &transition{at: at, fromOffset: fromOffset, toOffset: toOffset, name: name, isDST: at.IsDST()})
+	}
+	return transitions
+}
+
+// encodeTimeZone writes VTIMEZONE definition of the time zone, with its transitions since the start of fromYear.
+// The transitions which recur on the same day every year (such as on the last Sunday of March) are written with
+// a yearly RRULE, and the other ones are written as they are (with RDATE).
+func encodeTimeZone(writer *lineWriter, location *time.Location, fromYear int) {
+	writer.line("BEGIN", "VTIMEZONE")
+	writer.line("TZID", location.String())
+	transitions := timeZoneTransitions(location, fromYear, fromYear+timeZoneYears)
+	if len(transitions) == 0 {
+		// a time zone with fixed offset
+		start := time.Date(fromYear, 1, 1, 0, 0, 0, 0, location)
+		name, offset := start.Zone()
+		encodeObservance(writer, &transition{at: start, fromOffset: offset, toOffset: offset, name: name}, "", nil)
+		writer.line("END", "VTIMEZONE")
+		return
+	}
+	// group the similar transitions (such as all of the starts of daylight saving time)
+	type observance struct {
+		isDST      bool
+		fromOffset int
+		toOffset   int
+		name       string
+	}
+	groups := map[observance][]*transition{}
+	var observances []observance
+	for _, tr := range transitions {
+		key := observance{tr.isDST, tr.fromOffset, tr.toOffset, tr.name}
+		if _, ok := groups[key]; !ok {
+			observances = append(observances, key)
+		}
+		groups[key] = append(groups[key], tr)
+	}
+	sort.SliceStable(observances, func(i, j int) bool {
+		return groups[observances[i]][0].at.Before(groups[observances[j]][0].at)
+	})
+	for _, key := range observances {
+		group := groups[key]
+		rule := yearlyRule(group, timeZoneYears)
+		var dates []*transition
+		if rule == "" {
+			dates = group[1:]
+		}
+		encodeObservance(writer, group[0], rule, dates)
+	}
+	writer.line("END", "VTIMEZONE")
+}
+
+// yearlyRule returns the yearly RRULE followed by all of the transitions (one in each of the years), or an empty
+// string if they don't follow such a rule.
+func yearlyRule(transitions []*transition, years int) string {
+	if len(transitions) != years {
+		return ""
+	}
+	first := transitions[0].local()
+	for _, rule := range transitions[0].yearlyRules() {
+		matches := true
+		for index, tr := range transitions {
+			local := tr.local()
+			if local.Year() != first.Year()+index || local.Format("150405") != first.Format("150405") || !slices.Contains(tr.yearlyRules(), rule) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return "FREQ=YEARLY;" + rule
+		}
+	}
+	return ""
+}
+
+// encodeObservance writes the STANDARD (or DAYLIGHT) component starting with the transition, which recurs as
+// per the rule (if any) and on the dates.
+func encodeObservance(writer *lineWriter, first *transition, rule string, dates []*transition) {
+	kind := "STANDARD"
+	if first.isDST {
+		kind = "DAYLIGHT"
+	}
+	writer.line("BEGIN", kind)
+	writer.line("DTSTART", first.local().Format("20060102T150405"))
+	writer.line("TZOFFSETFROM", formatOffset(first.fromOffset))
+	writer.line("TZOFFSETTO", formatOffset(first.toOffset))
+	if first.name != "" {
+		writer.line("TZNAME", first.name)
+	}
+	if rule != "" {
+		writer.line("RRULE", rule)
+	}
+	for _, tr := range dates {
+		writer.line("RDATE", tr.local().Format("20060102T150405"))
+	}
+	writer.line("END", kind)
+}
+
+// formatOffset returns the UTC offset (in seconds) as in TZOFFSETFROM and TZOFFSETTO, such as -0500 or +0530.
+func formatOffset(offset int) string {
+	sign := "+"
+	if offset < 0 {
+		sign, offset = "-", -offset
+	}
+	text := fmt.Sprintf("%s%02d%02d", sign, offset/3600, offset%3600/60)
+	if seconds := offset % 60; seconds != 0 {
+		text += fmt.Sprintf("%02d", seconds)
+	}
+	return text
+}
@@ -0,0 +1,125 @@
+/*
+Package lockfile provides an advisory, OS-level lock based on a sidecar lock file.
+
+The lock file records the PID, hostname and start time of its holder, so that
+other processes can tell who is holding the lock. A lock left behind by a
+process which died without releasing it is detected and cleared automatically.
+*/
+package lockfile
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/goyalmunish/reminder/pkg/logger"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+// ErrLocked is returned when the lock is held by another process.
+var ErrLocked = errors.New("Lock is held by another process")
+
+/*
+An Info represents the holder of a lock.
+*/
+type Info struct {
+	PID       int    `json:"pid"`
+	Hostname  string `json:"hostname"`
+	StartedAt int64  `json:"started_at"`
+}
+
+// String provides basic string representation of the lock holder.
+func (info Info) String() string {
+	return fmt.Sprintf("{PID: %v, Hostname: %v, StartedAt: %v}", info.PID, info.Hostname, utils.UnixTimestampToLongTimeStr(info.StartedAt))
+}
+
+/*
+A LockedError is returned when the lock is held by another process.
+*/
+type LockedError struct {
+	Path   string
+	Holder Info
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%v; lock file %q is held by %v", ErrLocked, e.Path, e.Holder)
+}
+
+// Unwrap makes LockedError match ErrLocked with errors.Is.
+func (e *LockedError) Unwrap() error {
+	return ErrLocked
+}
+
+/*
+A Lock represents an acquired lock.
+*/
+type Lock struct {
+	Path string
+	Info Info
+	file *os.File
+}
+
+// Acquire acquires the lock represented by the lock file at given path, without blocking.
+// It returns a *LockedError if the lock is held by another (live) process.
+func Acquire(path string) (*Lock, error) {
+	path = utils.TryConvertTildaBasedPath(path)
+	hostname, _ := os.Hostname()
+	info := Info{PID: os.Getpid(), Hostname: hostname, StartedAt: utils.CurrentUnixTimestamp()}
+	file, err := acquire(path)
+	if err != nil {
+		return nil, err
+	}
+	lock := &Lock{Path: path, Info: info, file: file}
+	// record the holder of the lock
+	data, err := json.Marshal(info)
+	if err == nil {
+		err = file.Truncate(0)
+	}
+	if err == nil {
+		_, err = file.WriteAt(data, 0)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if err != nil {
+		_ = lock.Release()
+		return nil, err
+	}
+	logger.Info(fmt.Sprintf("Acquired the lock %q as %v.", path, info))
+	return lock, nil
+}
+
+// Release releases the lock and removes the lock file.
+func (lock *Lock) Release() error {
+	if lock == nil || lock.file == nil {
+		return nil
+	}
+	// remove the lock file before unlocking it, so that nobody can acquire a lock on a removed file
+	removeErr := os.Remove(lock.Path)
+	err := release(lock.file)
+	lock.file = nil
+	if err != nil {
+		return err
+	}
+	if removeErr != nil && !os.IsNotExist(removeErr) {
+		return removeErr
+	}
+	logger.Info(fmt.Sprintf("Released the lock %q.", lock.Path))
+	return nil
+}
+
+// ReadInfo reads info about the holder of the lock file at given path.
+func ReadInfo(path string) (Info, error) {
+	var info Info
+	data, err := os.ReadFile(utils.TryConvertTildaBasedPath(path))
+	if err != nil {
+		return info, err
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return info, nil
+	}
+	err = json.Unmarshal(data, &info)
+	return info, err
+}
@@ -0,0 +1,64 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package lockfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/goyalmunish/reminder/pkg/logger"
+)
+
+// acquire takes an exclusive flock on the lock file.
+// The kernel releases a flock when its holder dies, so whatever is recorded in
+// the lock file at the time of acquisition is a stale lock left by a dead process.
+func acquire(path string) (*os.File, error) {
+	for {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return nil, err
+		}
+		if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+			_ = file.Close()
+			if errors.Is(err, syscall.EWOULDBLOCK) {
+				holder, _ := ReadInfo(path)
+				return nil, &LockedError{Path: path, Holder: holder}
+			}
+			return nil, fmt.Errorf("Unable to lock %q: %w", path, err)
+		}
+		// make sure the lock file wasn't removed (by its previous holder) between opening and locking it
+		if sameFile(file, path) {
+			if holder, err := ReadInfo(path); err == nil && holder.PID != 0 {
+				logger.Warn(fmt.Sprintf("Cleared the stale lock %q left by %v.", path, holder))
+			}
+			return file, nil
+		}
+		_ = syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		_ = file.Close()
+	}
+}
+
+// release releases the flock on the lock file.
+func release(file *os.File) error {
+	err := syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// sameFile tells if the opened file is still the one at given path.
+func sameFile(file *os.File, path string) bool {
+	openedInfo, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	pathInfo, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(openedInfo, pathInfo)
+}
@@ -0,0 +1,57 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package lockfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/goyalmunish/reminder/pkg/logger"
+)
+
+// acquire creates the lock file exclusively, as flock is not available on this platform.
+// An existing lock file whose holder is a dead process on current host is considered stale, and is cleared.
+func acquire(path string) (*os.File, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			return file, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("Unable to lock %q: %w", path, err)
+		}
+		holder, _ := ReadInfo(path)
+		if !isStale(holder) {
+			return nil, &LockedError{Path: path, Holder: holder}
+		}
+		logger.Warn(fmt.Sprintf("Clearing the stale lock %q left by %v.", path, holder))
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	holder, _ := ReadInfo(path)
+	return nil, &LockedError{Path: path, Holder: holder}
+}
+
+// isStale tells if the holder is a dead process on current host.
+func isStale(holder Info) bool {
+	hostname, _ := os.Hostname()
+	return holder.PID > 0 && holder.Hostname == hostname && !processAlive(holder.PID)
+}
+
+// release closes the lock file.
+func release(file *os.File) error {
+	return file.Close()
+}
+
+// processAlive tells if the process with given PID is running.
+// It errs on the side of considering the process alive.
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = process.Release()
+	return true
+}
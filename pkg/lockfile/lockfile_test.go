@@ -0,0 +1,56 @@
+package lockfile_test
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/goyalmunish/reminder/pkg/lockfile"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+func TestAcquireAndRelease(t *testing.T) {
+	var lockFilePath = "temp_test_dir/data.json.lock"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(lockFilePath))
+	_ = os.MkdirAll(path.Dir(lockFilePath), 0751)
+	// case 1: lock is acquired, and its holder is recorded
+	lock, err := lockfile.Acquire(lockFilePath)
+	utils.AssertEqual(t, err, nil)
+	info, err := lockfile.ReadInfo(lockFilePath)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, info.PID, os.Getpid())
+	utils.AssertEqual(t, info.StartedAt > 0, true)
+	// case 2: lock cannot be acquired again while it is held
+	_, err = lockfile.Acquire(lockFilePath)
+	utils.AssertEqual(t, errors.Is(err, lockfile.ErrLocked), true)
+	var lockedErr *lockfile.LockedError
+	utils.AssertEqual(t, errors.As(err, &lockedErr), true)
+	utils.AssertEqual(t, lockedErr.Holder.PID, os.Getpid())
+	// case 3: lock is released, and lock file is removed
+	utils.AssertEqual(t, lock.Release(), nil)
+	_, err = os.Stat(lockFilePath)
+	utils.AssertEqual(t, os.IsNotExist(err), true)
+	// case 4: lock can be acquired once released
+	lock, err = lockfile.Acquire(lockFilePath)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, lock.Release(), nil)
+}
+
+func TestAcquireStaleLock(t *testing.T) {
+	var lockFilePath = "temp_test_dir/data.json.lock"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(lockFilePath))
+	_ = os.MkdirAll(path.Dir(lockFilePath), 0751)
+	// simulate a lock file left behind by a process which crashed
+	hostname, _ := os.Hostname()
+	staleInfo := `{"pid": 2147483646, "hostname": "` + hostname + `", "started_at": 1600000000}`
+	_ = os.WriteFile(lockFilePath, []byte(staleInfo), 0600)
+	// the stale lock is cleared automatically
+	lock, err := lockfile.Acquire(lockFilePath)
+	utils.AssertEqual(t, err, nil)
+	info, _ := lockfile.ReadInfo(lockFilePath)
+	utils.AssertEqual(t, info.PID, os.Getpid())
+	utils.AssertEqual(t, lock.Release(), nil)
+}
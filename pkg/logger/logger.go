@@ -2,6 +2,7 @@ package logger
 
 import (
 	"context"
+	"io"
 
 	"github.com/sirupsen/logrus"
 )
@@ -30,6 +31,14 @@ func SetWithOptions(options *Options) {
 	_log.SetFormatter(&logrus.TextFormatter{})
 }
 
+// SetOutput sets the output of the logger (which is stderr by default), and returns the previous one.
+// This is useful for a full-screen UI, which log lines would otherwise garble.
+func SetOutput(output io.Writer) io.Writer {
+	previous := _log.Out
+	_log.SetOutput(output)
+	return previous
+}
+
 // SetGlobalFields setups the global fields.
 func SetGlobalFields(fields map[string]interface{}) {
 	for key, value := range fields {
@@ -0,0 +1,131 @@
+/*
+Package mailer composes MIME email messages, and sends them through an SMTP server.
+*/
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+/*
+A Message represents an email message with plain-text and (optional) HTML bodies.
+*/
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Validate checks that the message has valid addresses.
+func (message *Message) Validate() error {
+	if _, err := mail.ParseAddress(message.From); err != nil {
+		return fmt.Errorf("Invalid sender address %q: %w", message.From, err)
+	}
+	if _, err := mail.ParseAddress(message.To); err != nil {
+		return fmt.Errorf("Invalid recipient address %q: %w", message.To, err)
+	}
+	return nil
+}
+
+// Bytes renders the message in MIME format; with an HTML body, it is a multipart/alternative message.
+func (message *Message) Bytes() ([]byte, error) {
+	var buffer bytes.Buffer
+	header := []string{
+		"From: " + message.From,
+		"To: " + message.To,
+		"Subject: " + mime.QEncoding.Encode("utf-8", message.Subject),
+		"Date: " + utils.CurrentTime().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+	}
+	for _, line := range header {
+		buffer.WriteString(line + "\r\n")
+	}
+	if message.HTML == "" {
+		buffer.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		buffer.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&buffer, message.Text); err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	}
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	buffer.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=%q\r\n\r\n", writer.Boundary()))
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", message.Text},
+		{"text/html; charset=utf-8", message.HTML},
+	} {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(partWriter, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	buffer.Write(body.Bytes())
+	return buffer.Bytes(), nil
+}
+
+// writeQuotedPrintable writes the content with quoted-printable encoding.
+func writeQuotedPrintable(out io.Writer, content string) error {
+	writer := quotedprintable.NewWriter(out)
+	if _, err := writer.Write([]byte(content)); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// Send function sends the message through the SMTP server as per options.
+// The connection is upgraded with STARTTLS if the server supports it; authentication is
+// used only if a username is configured.
+func Send(options *Options, message *Message) error {
+	if options.Host == "" {
+		return errors.New("SMTP host is not configured")
+	}
+	if err := message.Validate(); err != nil {
+		return err
+	}
+	data, err := message.Bytes()
+	if err != nil {
+		return err
+	}
+	var auth smtp.Auth
+	if options.Username != "" {
+		password := options.Password
+		if password == "" {
+			password = os.Getenv("REMINDER_SMTP_PASSWORD")
+		}
+		auth = smtp.PlainAuth("", options.Username, password, options.Host)
+	}
+	from, _ := mail.ParseAddress(message.From)
+	to, _ := mail.ParseAddress(message.To)
+	address := net.JoinHostPort(options.Host, strconv.Itoa(options.Port))
+	if err := smtp.SendMail(address, auth, from.Address, []string{to.Address}, data); err != nil {
+		return fmt.Errorf("Unable to send the email through %q: %w", address, err)
+	}
+	return nil
+}
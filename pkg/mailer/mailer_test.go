@@ -0,0 +1,56 @@
+package mailer_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goyalmunish/reminder/pkg/mailer"
+	"github.com/goyalmunish/reminder/pkg/mailer/mailertest"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+func TestMessageBytes(t *testing.T) {
+	defer func(ct func() time.Time) { utils.CurrentTime = ct }(utils.CurrentTime)
+	utils.CurrentTime = func() time.Time { return time.Date(2030, 5, 10, 9, 0, 0, 0, time.UTC) }
+	// plain-text only message
+	message := &mailer.Message{From: "reminder@example.com", To: "Jane <jane@example.com>", Subject: "Digest ✓", Text: "pay bills\n"}
+	data, err := message.Bytes()
+	utils.AssertEqual(t, err, nil)
+	want := "From: reminder@example.com\r\n" +
+		"To: Jane <jane@example.com>\r\n" +
+		"Subject: =?utf-8?q?Digest_=E2=9C=93?=\r\n" +
+		"Date: Fri, 10 May 2030 09:00:00 +0000\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=utf-8\r\n" +
+		"Content-Transfer-Encoding: quoted-printable\r\n\r\n" +
+		"pay bills\r\n"
+	utils.AssertEqual(t, string(data), want)
+	// message with html body has alternative parts
+	message.HTML = "<p>pay bills</p>"
+	data, err = message.Bytes()
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, strings.Contains(string(data), "Content-Type: multipart/alternative; boundary="), true)
+	utils.AssertEqual(t, strings.Contains(string(data), "Content-Type: text/plain; charset=utf-8"), true)
+	utils.AssertEqual(t, strings.Contains(string(data), "Content-Type: text/html; charset=utf-8"), true)
+	utils.AssertEqual(t, strings.Contains(string(data), "<p>pay bills</p>"), true)
+}
+
+func TestSend(t *testing.T) {
+	server, err := mailertest.NewServer()
+	utils.AssertEqual(t, err, nil)
+	defer server.Close()
+	options := &mailer.Options{Host: server.Host, Port: server.Port, From: "reminder@example.com"}
+	message := &mailer.Message{From: options.From, To: "Jane <jane@example.com>", Subject: "Digest", Text: "pay bills", HTML: "<p>pay bills</p>"}
+	utils.AssertEqual(t, mailer.Send(options, message), nil)
+	mails := server.Mails()
+	utils.AssertEqual(t, len(mails), 1)
+	utils.AssertEqual(t, mails[0].From, "reminder@example.com")
+	utils.AssertEqual(t, mails[0].To, []string{"jane@example.com"})
+	utils.AssertEqual(t, strings.Contains(mails[0].Data, "Subject: Digest"), true)
+	// invalid addresses are rejected before connecting to the server
+	message.To = "jane"
+	utils.AssertEqual(t, strings.HasPrefix(mailer.Send(options, message).Error(), "Invalid recipient address"), true)
+	utils.AssertEqual(t, mailer.Send(&mailer.Options{}, message).Error(), "SMTP host is not configured")
+	utils.AssertEqual(t, len(server.Mails()), 1)
+}
@@ -0,0 +1,138 @@
+/*
+Package mailertest provides a local SMTP stand-in for testing code that sends emails.
+*/
+package mailertest
+
+import (
+	"fmt"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+A Mail represents an email received by the Server.
+*/
+type Mail struct {
+	From string
+	To   []string
+	Data string
+}
+
+/*
+A Server is a minimal SMTP server listening on the loopback interface, which records the emails
+it receives (without delivering them). It supports neither TLS nor authentication.
+*/
+type Server struct {
+	Host     string
+	Port     int
+	listener net.Listener
+	mutex    sync.Mutex
+	mails    []Mail
+	wg       sync.WaitGroup
+}
+
+// NewServer function starts a new server; it must be closed once done.
+func NewServer() (*Server, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	address := listener.Addr().(*net.TCPAddr)
+	server := &Server{Host: address.IP.String(), Port: address.Port, listener: listener}
+	server.wg.Add(1)
+	go server.serve()
+	return server, nil
+}
+
+// Addr returns the address (host:port) of the server.
+func (server *Server) Addr() string {
+	return net.JoinHostPort(server.Host, strconv.Itoa(server.Port))
+}
+
+// Mails returns the emails received so far.
+func (server *Server) Mails() []Mail {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return append([]Mail{}, server.mails...)
+}
+
+// Close stops the server.
+func (server *Server) Close() error {
+	err := server.listener.Close()
+	server.wg.Wait()
+	return err
+}
+
+func (server *Server) serve() {
+	defer server.wg.Done()
+	for {
+		conn, err := server.listener.Accept()
+		if err != nil {
+			return
+		}
+		server.wg.Add(1)
+		go func() {
+			defer server.wg.Done()
+			defer conn.Close()
+			server.handle(textproto.NewConn(conn))
+		}()
+	}
+}
+
+// handle runs an SMTP session over the connection.
+func (server *Server) handle(conn *textproto.Conn) {
+	reply := func(code int, text string) bool {
+		return conn.PrintfLine("%d %s", code, text) == nil
+	}
+	if !reply(220, "localhost mailertest ready") {
+		return
+	}
+	var mail Mail
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply(250, "localhost")
+		case "MAIL":
+			mail = Mail{From: extractAddress(arg)}
+			reply(250, "OK")
+		case "RCPT":
+			mail.To = append(mail.To, extractAddress(arg))
+			reply(250, "OK")
+		case "DATA":
+			if !reply(354, "End data with <CR><LF>.<CR><LF>") {
+				return
+			}
+			data, err := conn.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			mail.Data = string(data)
+			server.mutex.Lock()
+			server.mails = append(server.mails, mail)
+			server.mutex.Unlock()
+			reply(250, "OK")
+		case "RSET", "NOOP":
+			reply(250, "OK")
+		case "QUIT":
+			reply(221, "Bye")
+			return
+		default:
+			reply(502, fmt.Sprintf("Command %q not implemented", verb))
+		}
+	}
+}
+
+// extractAddress extracts the address out of argument of MAIL or RCPT command (such as "FROM:<a@b.c>").
+func extractAddress(arg string) string {
+	_, address, _ := strings.Cut(arg, ":")
+	address, _, _ = strings.Cut(strings.TrimSpace(address), " ")
+	return strings.Trim(address, "<>")
+}
@@ -0,0 +1,25 @@
+package mailer
+
+type Options struct {
+	Host     string `json:"host" yaml:"host" mapstructure:"host"`
+	Port     int    `json:"port" yaml:"port" mapstructure:"port"`
+	Username string `json:"username" yaml:"username" mapstructure:"username"`
+	// Password of the SMTP user; if it is empty, it is read from REMINDER_SMTP_PASSWORD env variable
+	Password string `json:"password" yaml:"password" mapstructure:"password"`
+	From     string `json:"from" yaml:"from" mapstructure:"from"`
+	// TextTemplate and HTMLTemplate are paths of custom templates of the digest (built-in templates are used if empty)
+	TextTemplate string `json:"text_template" yaml:"text_template" mapstructure:"text_template"`
+	HTMLTemplate string `json:"html_template" yaml:"html_template" mapstructure:"html_template"`
+}
+
+func DefaultOptions() *Options {
+	return &Options{
+		Host:         "localhost",
+		Port:         587,
+		Username:     "",
+		Password:     "",
+		From:         "",
+		TextTemplate: "",
+		HTMLTemplate: "",
+	}
+}
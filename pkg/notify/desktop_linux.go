@@ -0,0 +1,37 @@
+//go:build linux
+
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+/*
+A DesktopNotifier shows the notification as a desktop notification.
+
+On Linux, it uses `notify-send`, and falls back to calling the freedesktop
+notification service over D-Bus (through `gdbus`) if `notify-send` is not installed.
+*/
+type DesktopNotifier struct{}
+
+// Notify shows the desktop notification.
+func (notifier *DesktopNotifier) Notify(notification Notification) error {
+	var cmd *exec.Cmd
+	if executable, err := exec.LookPath("notify-send"); err == nil {
+		cmd = exec.Command(executable, "--app-name=reminder", notification.Title, notification.Message)
+	} else if executable, err := exec.LookPath("gdbus"); err == nil {
+		cmd = exec.Command(executable, "call", "--session",
+			"--dest", "org.freedesktop.Notifications",
+			"--object-path", "/org/freedesktop/Notifications",
+			"--method", "org.freedesktop.Notifications.Notify",
+			"reminder", "0", "", notification.Title, notification.Message, "[]", "{}", "-1")
+	} else {
+		return fmt.Errorf("Neither notify-send nor gdbus is available for desktop notifications")
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("Unable to show desktop notification: %w (output: %q)", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package notify
+
+import (
+	"fmt"
+	"runtime"
+)
+
+/*
+A DesktopNotifier shows the notification as a desktop notification.
+
+Desktop notifications are supported only on Linux; on other platforms use the bell
+or the command notifier (for example, with `osascript` on macOS).
+*/
+type DesktopNotifier struct{}
+
+// Notify reports that desktop notifications are not supported on this platform.
+func (notifier *DesktopNotifier) Notify(notification Notification) error {
+	return fmt.Errorf("Desktop notifications are not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,115 @@
+/*
+Package notify provides pluggable notifiers, which announce notifications (such as notes
+approaching their due date) to the user through the desktop, the terminal, or a command hook.
+*/
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+/*
+A Notification represents a single announcement to the user.
+*/
+type Notification struct {
+	Id      string // identifier of the source (such as id of a note)
+	Title   string
+	Message string
+	DueAt   time.Time
+}
+
+// Notifier is interface representing a channel through which notifications are sent.
+type Notifier interface {
+	Notify(notification Notification) error
+}
+
+/*
+A MultiNotifier sends each notification through all of its notifiers.
+*/
+type MultiNotifier []Notifier
+
+// Notify sends the notification through all the notifiers, and returns their combined error (if any).
+func (notifiers MultiNotifier) Notify(notification Notification) error {
+	var errs []error
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(notification); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+/*
+A BellNotifier rings the terminal bell, and writes the notification as a line of text.
+*/
+type BellNotifier struct {
+	Out io.Writer
+}
+
+// Notify rings the bell, and writes the notification.
+func (notifier *BellNotifier) Notify(notification Notification) error {
+	_, err := fmt.Fprintf(notifier.Out, "\a%s: %s\n", notification.Title, notification.Message)
+	return err
+}
+
+/*
+A CommandNotifier runs a shell command for each notification.
+
+The notification is passed to the command through environment variables
+REMINDER_ID, REMINDER_TITLE, REMINDER_MESSAGE, and REMINDER_DUE_AT (in RFC3339 format).
+*/
+type CommandNotifier struct {
+	Command string
+}
+
+// Notify runs the command for the notification.
+func (notifier *CommandNotifier) Notify(notification Notification) error {
+	if strings.TrimSpace(notifier.Command) == "" {
+		return errors.New("Notification command is empty")
+	}
+	cmd := exec.Command("sh", "-c", notifier.Command)
+	cmd.Env = append(os.Environ(),
+		"REMINDER_ID="+notification.Id,
+		"REMINDER_TITLE="+notification.Title,
+		"REMINDER_MESSAGE="+notification.Message,
+		"REMINDER_DUE_AT="+notification.DueAt.Format(time.RFC3339),
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("Notification command %q failed: %w (output: %q)", notifier.Command, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// New function builds the notifier out of the options.
+// The terminal bell notifier writes to out.
+func New(options *Options, out io.Writer) (Notifier, error) {
+	if len(options.Notifiers) == 0 {
+		return nil, errors.New("No notifier is configured")
+	}
+	var notifiers MultiNotifier
+	for _, name := range options.Notifiers {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "desktop":
+			notifiers = append(notifiers, &DesktopNotifier{})
+		case "bell":
+			notifiers = append(notifiers, &BellNotifier{Out: out})
+		case "command":
+			if strings.TrimSpace(options.Command) == "" {
+				return nil, errors.New("The command notifier requires a command")
+			}
+			notifiers = append(notifiers, &CommandNotifier{Command: options.Command})
+		default:
+			return nil, fmt.Errorf("Unknown notifier %q; use desktop, bell, or command", name)
+		}
+	}
+	if len(notifiers) == 1 {
+		return notifiers[0], nil
+	}
+	return notifiers, nil
+}
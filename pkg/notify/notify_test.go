@@ -0,0 +1,95 @@
+package notify_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/goyalmunish/reminder/pkg/notify"
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+// fakeNotifier records the notifications, and fails if err is set.
+type fakeNotifier struct {
+	notifications []notify.Notification
+	err           error
+}
+
+func (f *fakeNotifier) Notify(notification notify.Notification) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.notifications = append(f.notifications, notification)
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	var out bytes.Buffer
+	notifier, err := notify.New(&notify.Options{Notifiers: []string{"bell"}}, &out)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, notifier, &notify.BellNotifier{Out: &out})
+	notifier, err = notify.New(&notify.Options{Notifiers: []string{"desktop", "command"}, Command: "true"}, &out)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, notifier, notify.MultiNotifier{&notify.DesktopNotifier{}, &notify.CommandNotifier{Command: "true"}})
+	_, err = notify.New(&notify.Options{Notifiers: []string{"command"}}, &out)
+	utils.AssertEqual(t, err.Error(), "The command notifier requires a command")
+	_, err = notify.New(&notify.Options{Notifiers: []string{"pigeon"}}, &out)
+	utils.AssertEqual(t, strings.Contains(err.Error(), "Unknown notifier"), true)
+	_, err = notify.New(&notify.Options{}, &out)
+	utils.AssertEqual(t, err.Error(), "No notifier is configured")
+}
+
+func TestNotifiers(t *testing.T) {
+	dir := "temp_test_dir"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(dir)
+	_ = os.MkdirAll(dir, 0751)
+	notification := notify.Notification{Id: "abc", Title: "pay bills", Message: "Due on 12-May-30", DueAt: time.Date(2030, 5, 12, 0, 0, 0, 0, time.UTC)}
+	// bell notifier
+	var out bytes.Buffer
+	utils.AssertEqual(t, (&notify.BellNotifier{Out: &out}).Notify(notification), nil)
+	utils.AssertEqual(t, out.String(), "\apay bills: Due on 12-May-30\n")
+	// command notifier gets the notification through environment variables
+	outFile := path.Join(dir, "hook.txt")
+	hook := &notify.CommandNotifier{Command: `echo "$REMINDER_ID|$REMINDER_TITLE|$REMINDER_MESSAGE|$REMINDER_DUE_AT" >> ` + outFile}
+	utils.AssertEqual(t, hook.Notify(notification), nil)
+	content, _ := os.ReadFile(outFile)
+	utils.AssertEqual(t, string(content), "abc|pay bills|Due on 12-May-30|2030-05-12T00:00:00Z\n")
+	err := (&notify.CommandNotifier{Command: "echo oops; exit 3"}).Notify(notification)
+	utils.AssertEqual(t, strings.Contains(err.Error(), "oops"), true)
+	// multi notifier notifies through all of its notifiers, even if one of them fails
+	ok, failing := &fakeNotifier{}, &fakeNotifier{err: errors.New("unavailable")}
+	err = notify.MultiNotifier{failing, ok}.Notify(notification)
+	utils.AssertEqual(t, err.Error(), "unavailable")
+	utils.AssertEqual(t, ok.notifications, []notify.Notification{notification})
+}
+
+func TestState(t *testing.T) {
+	stateFile := "temp_test_dir/state.json"
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(path.Dir(stateFile))
+	_ = os.MkdirAll(path.Dir(stateFile), 0751)
+	// a missing state file is an empty state
+	state, err := notify.LoadState(stateFile)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, state.Has("a@1"), false)
+	state.Mark("a@1", 100)
+	state.Mark("b@2", 200)
+	utils.AssertEqual(t, state.Save(), nil)
+	// the state persists
+	state, err = notify.LoadState(stateFile)
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, state.Notified, map[string]int64{"a@1": 100, "b@2": 200})
+	state.Retain([]string{"b@2", "c@3"})
+	utils.AssertEqual(t, state.Save(), nil)
+	state, _ = notify.LoadState(stateFile)
+	utils.AssertEqual(t, state.Notified, map[string]int64{"b@2": 200})
+	// a corrupt state file is reported
+	_ = os.WriteFile(stateFile, []byte("{"), 0600)
+	_, err = notify.LoadState(stateFile)
+	utils.AssertEqual(t, err != nil, true)
+}
@@ -0,0 +1,17 @@
+package notify
+
+type Options struct {
+	Notifiers []string `json:"notifiers" yaml:"notifiers" mapstructure:"notifiers"`
+	Command   string   `json:"command" yaml:"command" mapstructure:"command"`
+	Interval  string   `json:"interval" yaml:"interval" mapstructure:"interval"`
+	StateFile string   `json:"state_file" yaml:"state_file" mapstructure:"state_file"`
+}
+
+func DefaultOptions() *Options {
+	return &Options{
+		Notifiers: []string{"desktop"},
+		Command:   "",
+		Interval:  "1m",
+		StateFile: "", // defaults to a sidecar file of the data file
+	}
+}
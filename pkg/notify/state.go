@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/goyalmunish/reminder/pkg/utils"
+)
+
+/*
+A State records the notifications already sent, so that the same notification isn't sent twice.
+
+It is persisted to its own (sidecar) file, so that it can be updated without touching (or locking) the data file.
+*/
+type State struct {
+	// Notified maps key of each sent notification to the time (unix timestamp) it was sent at
+	Notified map[string]int64 `json:"notified"`
+	path     string
+	changed  bool
+}
+
+// LoadState function reads the state from the file at given path.
+// A missing file results in an empty state.
+func LoadState(path string) (*State, error) {
+	path = utils.TryConvertTildaBasedPath(path)
+	state := &State{Notified: map[string]int64{}, path: path}
+	byteValue, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(byteValue, state); err != nil {
+		return nil, err
+	}
+	if state.Notified == nil {
+		state.Notified = map[string]int64{}
+	}
+	return state, nil
+}
+
+// Has tells if the notification with given key was already sent.
+func (state *State) Has(key string) bool {
+	_, ok := state.Notified[key]
+	return ok
+}
+
+// Mark records the notification with given key as sent at given timestamp.
+func (state *State) Mark(key string, at int64) {
+	state.Notified[key] = at
+	state.changed = true
+}
+
+// Retain forgets the notifications whose keys are not in given keys,
+// so that the state doesn't keep growing.
+func (state *State) Retain(keys []string) {
+	for key := range state.Notified {
+		if !utils.IsMemberOfSlice(key, keys) {
+			delete(state.Notified, key)
+			state.changed = true
+		}
+	}
+}
+
+// Save persists the state to its file (if it has changed).
+func (state *State) Save() error {
+	if !state.changed {
+		return nil
+	}
+	byteValue, err := json.MarshalIndent(state, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := utils.WriteFileAtomic(state.path, byteValue, 0600, false); err != nil {
+		return err
+	}
+	state.changed = false
+	return nil
+}
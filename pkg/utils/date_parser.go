@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// dueTimeRegexp matches time (HH:MM) part of a due date string.
+	dueTimeRegexp = regexp.MustCompile(`^([01]?[0-9]|2[0-3]):[0-5][0-9]$`)
+	// ddmmRegexp matches DD-MM-YYYY and DD-MM dates.
+	ddmmRegexp = regexp.MustCompile(`^(0?[1-9]|[12][0-9]|3[01])-(0?[1-9]|1[012])(-((19|20)\d\d))?$`)
+	// relativeDateRegexp matches relative dates such as "in 3 weeks", "in a month", and "+10d".
+	relativeDateRegexp = regexp.MustCompile(`^(?:in\s+(\d+|an?)\s+(day|week|month|year)s?|\+(\d+)\s*([dwmy]))$`)
+)
+
+// weekdays maps (lowercase) names of weekdays, and their common abbreviations, to weekdays.
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// ParseDate function parses a (natural language) date phrase, relative to the current date (as per CurrentTime).
+// It returns the date at 00:00:00 GMT+0000.
+// Following forms of the phrase are accepted (case-insensitive):
+// - "today", "tomorrow", and "yesterday"
+// - "friday", "this friday", or "next friday" (all of which mean the first friday after today)
+// - "next week", "next month", and "next year"
+// - "in 3 days", "in a week", "in 2 months", "in 1 year", or shorter "+3d", "+1w", "+2m", and "+1y"
+// - "end of week" (sunday), "end of month", and "end of year"
+// - "2026-11-03" (YYYY-MM-DD), "03-11-2026" (DD-MM-YYYY), and "03-11" (DD-MM; current year if yet to come, otherwise next year)
+func ParseDate(text string) (time.Time, error) {
+	phrase := strings.Join(strings.Fields(strings.ToLower(text)), " ")
+	year, month, day := CurrentTime().Date()
+	today := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	switch phrase {
+	case "today":
+		return today, nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	case "next week":
+		return today.AddDate(0, 0, 7), nil
+	case "next month":
+		return today.AddDate(0, 1, 0), nil
+	case "next year":
+		return today.AddDate(1, 0, 0), nil
+	case "end of week":
+		return today.AddDate(0, 0, (7-int(today.Weekday()))%7), nil
+	case "end of month":
+		return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC), nil
+	case "end of year":
+		return time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC), nil
+	}
+	// weekdays
+	weekdayName := strings.TrimPrefix(strings.TrimPrefix(phrase, "next "), "this ")
+	if weekday, ok := weekdays[weekdayName]; ok {
+		days := (int(weekday) - int(today.Weekday()) + 7) % 7
+		if days == 0 {
+			days = 7
+		}
+		return today.AddDate(0, 0, days), nil
+	}
+	// relative dates
+	if matches := relativeDateRegexp.FindStringSubmatch(phrase); matches != nil {
+		count, unit := matches[1], matches[2]
+		if matches[3] != "" {
+			count, unit = matches[3], matches[4]
+		}
+		number := 1
+		if count != "a" && count != "an" {
+			number, _ = strconv.Atoi(count)
+		}
+		switch unit[0] {
+		case 'd':
+			return today.AddDate(0, 0, number), nil
+		case 'w':
+			return today.AddDate(0, 0, 7*number), nil
+		case 'm':
+			return today.AddDate(0, number, 0), nil
+		case 'y':
+			return today.AddDate(number, 0, 0), nil
+		}
+	}
+	// absolute dates
+	if date, err := time.Parse("2006-01-02", phrase); err == nil {
+		return date, nil
+	}
+	if ddmmRegexp.MatchString(phrase) {
+		if strings.Count(phrase, "-") == 1 {
+			year, err := YearForDueDateDDMM(phrase)
+			if err != nil {
+				return time.Time{}, err
+			}
+			phrase = fmt.Sprintf("%s-%d", phrase, year)
+		}
+		date, err := time.Parse("2-1-2006", phrase)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("Invalid date %q: %w", text, err)
+		}
+		return date, nil
+	}
+	return time.Time{}, fmt.Errorf("Unable to understand the date %q", text)
+}
+
+// NormalizeDueDate function converts a due date string, with date as accepted by ParseDate, optionally followed
+// by time (HH:MM, optionally preceded by "at") and then IANA time zone, to the form DD-MM-YYYY [HH:MM [ZONE]].
+// For example, "next friday at 14:30 Europe/London" on 2026-11-03 is converted to "06-11-2026 14:30 Europe/London".
+// The input "nil" is returned as it is.
+func NormalizeDueDate(text string) (string, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 1 && fields[0] == "nil" {
+		return "nil", nil
+	}
+	// split the date phrase and the time (with time zone)
+	dateFields, timeFields := fields, []string{}
+	for index, field := range fields {
+		if dueTimeRegexp.MatchString(field) {
+			dateFields, timeFields = fields[:index], fields[index:]
+			break
+		}
+	}
+	if len(dateFields) > 0 && strings.ToLower(dateFields[len(dateFields)-1]) == "at" {
+		dateFields = dateFields[:len(dateFields)-1]
+	}
+	if len(dateFields) == 0 {
+		return "", fmt.Errorf("The due date is missing in %q", text)
+	}
+	if len(timeFields) > 2 {
+		return "", fmt.Errorf("Unable to understand the time %q", strings.Join(timeFields, " "))
+	}
+	if len(timeFields) == 2 {
+		if _, err := time.LoadLocation(timeFields[1]); err != nil {
+			return "", fmt.Errorf("Invalid time zone %q: %w", timeFields[1], err)
+		}
+	}
+	date, err := ParseDate(strings.Join(dateFields, " "))
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(append([]string{date.Format("02-01-2006")}, timeFields...), " "), nil
+}
+
+// DescribeDueDate function returns human readable description of a due date string (as accepted by NormalizeDueDate),
+// such as "Friday, 06 Nov 2026 14:30 (Europe/London)".
+func DescribeDueDate(text string) (string, error) {
+	normalized, err := NormalizeDueDate(text)
+	if err != nil {
+		return "", err
+	}
+	if normalized == "nil" {
+		return "no due date", nil
+	}
+	fields := strings.Fields(normalized)
+	date, _ := time.Parse("02-01-2006", fields[0])
+	description := date.Format("Monday, 02 Jan 2006")
+	if len(fields) > 1 {
+		description = fmt.Sprintf("%s %s", description, fields[1])
+	}
+	if len(fields) > 2 {
+		description = fmt.Sprintf("%s (%s)", description, fields[2])
+	}
+	return description, nil
+}
+
+// ConfirmDueDate function shows the resolved due date (of a due date string, as accepted by NormalizeDueDate),
+// and asks the user to confirm it.
+func ConfirmDueDate(text string) (bool, error) {
+	return confirmDueDate(text, os.Stdin)
+}
+
+func confirmDueDate(text string, in io.Reader) (bool, error) {
+	description, err := DescribeDueDate(text)
+	if err != nil {
+		return false, err
+	}
+	return askBoolean(fmt.Sprintf("Resolved due date: %s. Is it correct?", description), in)
+}
@@ -0,0 +1,125 @@
+package utils_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	utils "github.com/goyalmunish/reminder/pkg/utils"
+)
+
+func TestParseDate(t *testing.T) {
+	defer func(ct func() time.Time) { utils.CurrentTime = ct }(utils.CurrentTime)
+	// Tue Nov 03 2026
+	utils.CurrentTime = func() time.Time {
+		return time.Date(2026, 11, 3, 18, 30, 0, 0, time.UTC)
+	}
+	var tests = []struct {
+		name      string
+		input     string
+		want      string // YYYY-MM-DD
+		wantedErr bool   // whether an error was expected
+	}{
+		{name: "today", input: "today", want: "2026-11-03"},
+		{name: "tomorrow", input: "Tomorrow", want: "2026-11-04"},
+		{name: "yesterday", input: "yesterday", want: "2026-11-02"},
+		{name: "next week", input: "next  week", want: "2026-11-10"},
+		{name: "next month", input: "next month", want: "2026-12-03"},
+		{name: "next year", input: "next year", want: "2027-11-03"},
+		{name: "end of week", input: "end of week", want: "2026-11-08"},
+		{name: "end of month", input: "end of month", want: "2026-11-30"},
+		{name: "end of year", input: "end of year", want: "2026-12-31"},
+		{name: "weekday", input: "friday", want: "2026-11-06"},
+		{name: "next weekday", input: "next friday", want: "2026-11-06"},
+		{name: "this weekday abbreviated", input: "this thu", want: "2026-11-05"},
+		{name: "same weekday", input: "tuesday", want: "2026-11-10"},
+		{name: "in days", input: "in 3 days", want: "2026-11-06"},
+		{name: "in weeks", input: "in 3 weeks", want: "2026-11-24"},
+		{name: "in a month", input: "in a month", want: "2026-12-03"},
+		{name: "in a year", input: "in 1 year", want: "2027-11-03"},
+		{name: "short days", input: "+10d", want: "2026-11-13"},
+		{name: "short months", input: "+2m", want: "2027-01-03"},
+		{name: "YYYY-MM-DD", input: "2026-11-03", want: "2026-11-03"},
+		{name: "DD-MM-YYYY", input: "25-12-2027", want: "2027-12-25"},
+		{name: "DD-MM yet to come", input: "25-12", want: "2026-12-25"},
+		{name: "DD-MM passed", input: "1-2", want: "2027-02-01"},
+		{name: "invalid DD-MM-YYYY", input: "31-02-2027", wantedErr: true},
+		{name: "invalid YYYY-MM-DD", input: "2026-13-01", wantedErr: true},
+		{name: "unknown phrase", input: "next fortnight", wantedErr: true},
+		{name: "empty", input: "", wantedErr: true},
+	}
+	for position, subtest := range tests {
+		got, err := utils.ParseDate(subtest.input)
+		if (err != nil) != subtest.wantedErr {
+			t.Fatalf("ParseDate case %q (position=%d) with input <%+v> returns error <%v>", subtest.name, position, subtest.input, err)
+		}
+		if err == nil && got.Format("2006-01-02") != subtest.want {
+			t.Errorf("ParseDate case %q (position=%d) failed for input %q; returns <%+v>; wants <%+v>", subtest.name, position, subtest.input, got, subtest.want)
+		}
+	}
+}
+
+func TestNormalizeDueDate(t *testing.T) {
+	defer func(ct func() time.Time) { utils.CurrentTime = ct }(utils.CurrentTime)
+	utils.CurrentTime = func() time.Time {
+		return time.Date(2026, 11, 3, 18, 30, 0, 0, time.UTC)
+	}
+	var tests = []struct {
+		name      string
+		input     string
+		want      string
+		wantedErr bool // whether an error was expected
+	}{
+		{name: "nil", input: "nil", want: "nil"},
+		{name: "only date", input: "next friday", want: "06-11-2026"},
+		{name: "date with time", input: "tomorrow 9:05", want: "04-11-2026 9:05"},
+		{name: "date at time with zone", input: "next friday at 14:30 Europe/London", want: "06-11-2026 14:30 Europe/London"},
+		{name: "DD-MM with time", input: "25-12 07:00", want: "25-12-2026 07:00"},
+		{name: "missing date", input: "at 14:30", wantedErr: true},
+		{name: "invalid time zone", input: "tomorrow 14:30 Mars/Olympus", wantedErr: true},
+		{name: "zone without time", input: "tomorrow Europe/London", wantedErr: true},
+		{name: "extra fields after zone", input: "tomorrow 14:30 Europe/London now", wantedErr: true},
+	}
+	for position, subtest := range tests {
+		got, err := utils.NormalizeDueDate(subtest.input)
+		if (err != nil) != subtest.wantedErr {
+			t.Fatalf("NormalizeDueDate case %q (position=%d) with input <%+v> returns error <%v>", subtest.name, position, subtest.input, err)
+		}
+		if got != subtest.want {
+			t.Errorf("NormalizeDueDate case %q (position=%d) failed for input %q; returns <%+v>; wants <%+v>", subtest.name, position, subtest.input, got, subtest.want)
+		}
+	}
+}
+
+func TestDescribeDueDate(t *testing.T) {
+	defer func(ct func() time.Time) { utils.CurrentTime = ct }(utils.CurrentTime)
+	utils.CurrentTime = func() time.Time {
+		return time.Date(2026, 11, 3, 18, 30, 0, 0, time.UTC)
+	}
+	got, err := utils.DescribeDueDate("next friday at 14:30 Europe/London")
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, got, "Friday, 06 Nov 2026 14:30 (Europe/London)")
+	got, err = utils.DescribeDueDate("end of month")
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, got, "Monday, 30 Nov 2026")
+	got, err = utils.DescribeDueDate("nil")
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, got, "no due date")
+}
+
+func TestConfirmDueDate(t *testing.T) {
+	defer func(ct func() time.Time) { utils.CurrentTime = ct }(utils.CurrentTime)
+	utils.CurrentTime = func() time.Time {
+		return time.Date(2026, 11, 3, 18, 30, 0, 0, time.UTC)
+	}
+	confirmed, err := utils.PrivateConfirmDueDate("in 3 weeks", strings.NewReader("y\n"))
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, confirmed, true)
+	confirmed, err = utils.PrivateConfirmDueDate("in 3 weeks", strings.NewReader("n\n"))
+	utils.AssertEqual(t, err, nil)
+	utils.AssertEqual(t, confirmed, false)
+	// the input isn't asked for if the due date can't be resolved
+	confirmed, err = utils.PrivateConfirmDueDate("someday", strings.NewReader("y\n"))
+	utils.AssertEqual(t, err != nil, true)
+	utils.AssertEqual(t, confirmed, false)
+}
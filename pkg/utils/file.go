@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// BackupFileSuffix is the suffix of the file which keeps previous version of a file written with WriteFileAtomic.
+const BackupFileSuffix = ".bak"
+
+// WriteFileAtomic function writes data to the file in a crash-safe manner.
+// The data is written to a temporary file in the same directory, which is fsynced and then renamed over the
+// original file; so, at any point of time, the file has either its old or its new content (and never a partial one).
+// If keepBackup is true, the previous content of the file (if any) is kept as a file with BackupFileSuffix.
+func WriteFileAtomic(filePath string, data []byte, perm os.FileMode, keepBackup bool) error {
+	if keepBackup {
+		oldData, err := os.ReadFile(filePath)
+		if err == nil {
+			if err := writeAndRename(filePath+BackupFileSuffix, oldData, perm); err != nil {
+				return fmt.Errorf("Unable to back up %q: %w", filePath, err)
+			}
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return writeAndRename(filePath, data, perm)
+}
+
+// writeAndRename writes data to a temporary file, and then renames it to filePath.
+func writeAndRename(filePath string, data []byte, perm os.FileMode) error {
+	dir, base := filepath.Split(filePath)
+	if dir == "" {
+		dir = "."
+	}
+	tmpFile, err := os.CreateTemp(dir, "."+base+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	// make sure the temporary file doesn't outlive a failed write
+	succeeded := false
+	defer func() {
+		if !succeeded {
+			_ = tmpFile.Close()
+			_ = os.Remove(tmpPath)
+		}
+	}()
+	if _, err := tmpFile.Write(data); err != nil {
+		return err
+	}
+	if err := tmpFile.Sync(); err != nil {
+		return err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return err
+	}
+	succeeded = true
+	// persist the rename itself; not all the platforms support syncing a directory, so just ignore the error
+	if dirFile, err := os.Open(dir); err == nil {
+		_ = dirFile.Sync()
+		_ = dirFile.Close()
+	}
+	return nil
+}
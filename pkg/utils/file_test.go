@@ -0,0 +1,46 @@
+package utils_test
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	utils "github.com/goyalmunish/reminder/pkg/utils"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	var dirPath = "temp_test_dir"
+	var filePath = path.Join(dirPath, "data.json")
+	// make sure temporary files and dirs are removed at the end of the test
+	defer os.RemoveAll(dirPath)
+	_ = os.MkdirAll(dirPath, 0751)
+	// case 1: new file without any backup
+	err := utils.WriteFileAtomic(filePath, []byte("v1"), 0644, true)
+	utils.AssertEqual(t, err, nil)
+	data, _ := os.ReadFile(filePath)
+	utils.AssertEqual(t, string(data), "v1")
+	_, err = os.Stat(filePath + utils.BackupFileSuffix)
+	utils.AssertEqual(t, os.IsNotExist(err), true)
+	// case 2: existing file with backup of previous version
+	err = utils.WriteFileAtomic(filePath, []byte("v2"), 0644, true)
+	utils.AssertEqual(t, err, nil)
+	data, _ = os.ReadFile(filePath)
+	utils.AssertEqual(t, string(data), "v2")
+	data, _ = os.ReadFile(filePath + utils.BackupFileSuffix)
+	utils.AssertEqual(t, string(data), "v1")
+	// case 3: existing file without touching the backup
+	err = utils.WriteFileAtomic(filePath, []byte("v3"), 0644, false)
+	utils.AssertEqual(t, err, nil)
+	data, _ = os.ReadFile(filePath)
+	utils.AssertEqual(t, string(data), "v3")
+	data, _ = os.ReadFile(filePath + utils.BackupFileSuffix)
+	utils.AssertEqual(t, string(data), "v1")
+	// no temporary files are left behind
+	entries, _ := os.ReadDir(dirPath)
+	utils.AssertEqual(t, len(entries), 2)
+	// case 4: failure to write doesn't touch the existing file
+	err = utils.WriteFileAtomic(path.Join(dirPath, "no_such_dir", "data.json"), []byte("v4"), 0644, true)
+	utils.AssertEqual(t, err != nil, true)
+	data, _ = os.ReadFile(filePath)
+	utils.AssertEqual(t, string(data), "v3")
+}
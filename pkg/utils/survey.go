@@ -72,6 +72,20 @@ func GeneratePrompt(promptName string, defaultText string) (string, error) {
 		}
 		validator = survey.MinLength(1)
 		err = survey.AskOne(prompt, &answer, survey.WithValidator(validator))
+	case "tag_query_name":
+		prompt := &survey.Input{
+			Message: "Tag Query Name: ",
+			Default: defaultText,
+		}
+		validator = survey.MinLength(1)
+		err = survey.AskOne(prompt, &answer, survey.WithValidator(validator))
+	case "tag_query":
+		prompt := &survey.Input{
+			Message: "Tag Query (such as: current AND priority-urgent AND NOT waiting): ",
+			Default: defaultText,
+		}
+		validator = survey.MinLength(1)
+		err = survey.AskOne(prompt, &answer, survey.WithValidator(validator))
 	case "tag_another":
 		prompt := &survey.Input{
 			Message: "Add another tag: yes/no (default: no): ",
@@ -102,10 +116,17 @@ func GeneratePrompt(promptName string, defaultText string) (string, error) {
 		err = survey.AskOne(prompt, &answer, survey.WithValidator(validator))
 	case "note_completed_by":
 		prompt := &survey.Input{
-			Message: "Due Date (format: DD-MM-YYYY or DD-MM), or enter nil to clear existing value: ",
+			Message: "Due Date (such as DD-MM-YYYY, DD-MM, YYYY-MM-DD, tomorrow, next friday, in 3 weeks, end of month, or +10d; optionally followed by HH:MM and time zone), or enter nil to clear existing value: ",
 			Default: defaultText,
 		}
 		err = survey.AskOne(prompt, &answer, survey.WithValidator(ValidateDateString()))
+	case "note_recurrence":
+		prompt := &survey.Input{
+			Message: "Recurrence (format: daily, weekly, monthly, or yearly; optionally followed by /N for every N, and :mo,we for weekdays or :last for last day of month), or enter nil to clear existing value: ",
+			Default: defaultText,
+		}
+		validator = survey.MinLength(1)
+		err = survey.AskOne(prompt, &answer, survey.WithValidator(validator))
 	}
 	return answer, err
 }
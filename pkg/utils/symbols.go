@@ -14,17 +14,23 @@ var Symbols = map[string]string{
 	"error":        "❌",
 	"glossary":     "📖",
 	"hat":          "🎩",
+	"merge":        "🔀",
+	"history":      "📜",
 	"home":         "⛺",
 	"noAction":     "❎",
 	"pad":          "📋",
+	"redo":         "↪️",
 	"redFlag":      "🚩",
 	"refresh":      "🔄",
+	"rightArrow":   "➡️",
 	"search":       "🔎",
 	"spark":        "⚡",
 	"tag":          "🏷t",
 	"telescope":    "🔭",
 	"text":         "📝",
 	"think":        "🤔",
+	"trash":        "🗑️",
+	"undo":         "↩️",
 	"upArrow":      "⬆️",
 	"upVote":       "👍",
 	"warning":      "⚠️ ",
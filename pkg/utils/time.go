@@ -2,8 +2,8 @@ package utils
 
 import (
 	"fmt"
+	"os"
 	"reflect"
-	"regexp"
 	"strings"
 	"time"
 
@@ -139,6 +139,27 @@ func GetLocalZone() (string, time.Duration) {
 	return abbr, dur
 }
 
+// LocalZoneName returns IANA name of the local time zone (such as "Australia/Melbourne").
+// It is determined from the TZ environment variable, or else from /etc/localtime; it falls back to "UTC".
+func LocalZoneName() string {
+	if name := os.Getenv("TZ"); name != "" {
+		if _, err := time.LoadLocation(name); err == nil {
+			return name
+		}
+	}
+	if name := time.Local.String(); name != "Local" {
+		return name
+	}
+	if target, err := os.Readlink("/etc/localtime"); err == nil {
+		if _, name, found := strings.Cut(target, "zoneinfo/"); found {
+			if _, err := time.LoadLocation(name); err == nil {
+				return name
+			}
+		}
+	}
+	return "UTC"
+}
+
 // GetZoneFromLocation returns zone offset (in time.Duration) for given location string like "Melbourne/Australia".
 func GetZoneFromLocation(loc string) (time.Duration, error) {
 	location, err := time.LoadLocation(loc)
@@ -151,7 +172,9 @@ func GetZoneFromLocation(loc string) (time.Duration, error) {
 	return dur, nil
 }
 
-// ValidateDateString function validates date string (DD-MM-YYYY) or (DD-MM).
+// ValidateDateString function validates due date string, that is a date (such as DD-MM-YYYY, DD-MM,
+// or a natural language phrase like "next friday"; refer ParseDate), optionally followed by
+// time (HH:MM) and then IANA time zone (such as "Europe/London").
 // nil is also valid input
 func ValidateDateString() survey.Validator {
 	// return a validator that checks the length of the string
@@ -159,11 +182,10 @@ func ValidateDateString() survey.Validator {
 		if str, ok := val.(string); ok {
 			// if the string is shorter than the given value
 			input := strings.TrimSpace(str)
-			re := regexp.MustCompile(`^((0?[1-9]|[12][0-9]|3[01])-(0?[1-9]|1[012])(-((19|20)\d\d))?|(nil))$`)
-			if re.MatchString(input) {
+			if _, err := NormalizeDueDate(input); err == nil {
 				return nil
 			} else {
-				return fmt.Errorf("The input must be in the format DD-MM-YYYY or DD-MM.")
+				return fmt.Errorf("The input must be a date (such as DD-MM-YYYY, DD-MM, YYYY-MM-DD, tomorrow, next friday, in 3 weeks, or +10d), optionally followed by HH:MM and time zone.")
 			}
 		} else {
 			// otherwise we cannot convert the value into a string and cannot enforce length
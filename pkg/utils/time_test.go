@@ -294,11 +294,17 @@ func TestGetZoneFromLocation(t *testing.T) {
 }
 
 func TestValidateDateString(t *testing.T) {
-	errorMsg := "The input must be in the format DD-MM-YYYY or DD-MM."
+	errorMsg := "The input must be a date (such as DD-MM-YYYY, DD-MM, YYYY-MM-DD, tomorrow, next friday, in 3 weeks, or +10d), optionally followed by HH:MM and time zone."
 	utils.AssertEqual(t, utils.ValidateDateString()("31-12-2020"), nil)
+	utils.AssertEqual(t, utils.ValidateDateString()("31-12-2020 14:30"), nil)
+	utils.AssertEqual(t, utils.ValidateDateString()("31-12 9:05 America/Argentina/Buenos_Aires"), nil)
 	utils.AssertEqual(t, utils.ValidateDateString()("nil"), nil)
+	utils.AssertEqual(t, utils.ValidateDateString()("31-12-2020 24:00"), errors.New(errorMsg))
+	utils.AssertEqual(t, utils.ValidateDateString()("31-12-2020 Europe/London"), errors.New(errorMsg))
 	utils.AssertEqual(t, utils.ValidateDateString()("12-31-2020"), errors.New(errorMsg))
-	utils.AssertEqual(t, utils.ValidateDateString()("2020-12-31"), errors.New(errorMsg))
+	utils.AssertEqual(t, utils.ValidateDateString()("2020-12-31"), nil)
+	utils.AssertEqual(t, utils.ValidateDateString()("next friday at 9:30"), nil)
+	utils.AssertEqual(t, utils.ValidateDateString()("next fortnight"), errors.New(errorMsg))
 	utils.AssertEqual(t, utils.ValidateDateString()("2020-31-"), errors.New(errorMsg))
 	utils.AssertEqual(t, utils.ValidateDateString()("2020-31"), errors.New(errorMsg))
 	utils.AssertEqual(t, utils.ValidateDateString()("2020-"), errors.New(errorMsg))
@@ -5,3 +5,7 @@ import "io"
 func PrivateAskBoolean(msg string, in io.Reader) (bool, error) {
 	return askBoolean(msg, in)
 }
+
+func PrivateConfirmDueDate(text string, in io.Reader) (bool, error) {
+	return confirmDueDate(text, in)
+}